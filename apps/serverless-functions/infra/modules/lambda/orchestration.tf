@@ -138,6 +138,7 @@ resource "aws_lambda_function" "scraper" {
   environment {
     variables = {
       DATABASE_URL = var.database_url
+      S3_BUCKET    = var.s3_pdf_bucket_name # Filing lists too large for Step Functions
     }
   }
 
@@ -215,6 +216,8 @@ resource "aws_lambda_function" "notify" {
 # Write Manifest Lambda
 # Invoked by Step Functions before submitting a Batch array job.
 # Writes the filing list as a JSON manifest to S3.
+# The scraper writes, and write-manifest and notify read, payloads
+# too large for Step Functions under claim-checks/.
 # ---------------------------------------------------------------
 
 resource "aws_iam_role_policy" "orchestration_s3_put" {
@@ -226,7 +229,8 @@ resource "aws_iam_role_policy" "orchestration_s3_put" {
     Statement = [{
       Effect = "Allow"
       Action = [
-        "s3:PutObject"
+        "s3:PutObject",
+        "s3:GetObject"
       ]
       Resource = "${var.s3_pdf_bucket_arn}/*"
     }]
@@ -298,7 +302,8 @@ resource "aws_iam_role_policy" "sfn_downloader_s3" {
       Effect = "Allow"
       Action = [
         "s3:PutObject",
-        "s3:PutObjectAcl"
+        "s3:PutObjectAcl",
+        "s3:GetObject" # Filing lists stored by the scraper
       ]
       Resource = "${var.s3_pdf_bucket_arn}/*"
     }]
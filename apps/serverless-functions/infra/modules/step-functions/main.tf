@@ -5,17 +5,24 @@
 #   Daily (no start_date):
 #     Scrape → CheckNewFilings → RouteBySize →
 #       ≤1000: Map(DownloadFilings) → NotifySuccess
+#       ≤1000, filings in S3: Map(DownloadFilingsByRef) → NotifySuccess
 #       >1000: WriteManifest → BatchDownload → NotifySuccess
 #
 #   Backfill (start_date + end_date provided):
 #     GenerateChunks → BackfillMonths Map(MaxConcurrency=1):
 #       ScrapeMonth → CheckMonthFilings → RouteMonthBySize →
 #         ≤1000: DownloadMonthFilings (Map) → MonthDone
+#         ≤1000, filings in S3: DownloadMonthFilingsByRef (Map) → MonthDone
 #         >1000: WriteMonthManifest → BatchDownloadMonth → MonthDone
 #         No filings: MonthNoFilings
 #     → NotifyBackfillSuccess
 # Each month's errors are isolated via Catch → MonthFailed.
 #
+# The scraper stores filing lists too large for the 256 KB state limit in S3
+# as JSONL and returns filings_ref, with the byte span of each line,
+# instead; write-manifest reads the list and sfn-downloader its own line
+# from there (claim check).
+#
 # -----------------------------------------------------------------------
 # Manual backfill via AWS CLI:
 #
@@ -68,7 +75,7 @@ variable "batch_job_definition_arn" {
 }
 
 variable "batch_filing_threshold" {
-  description = "Filing count threshold above which Batch is used instead of Map"
+  description = "Filing count threshold above which Batch is used instead of Map (at most 1000, the claimcheck.MaxLines spans the scraper records)"
   type        = number
   default     = 1000
 }
@@ -225,7 +232,10 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
                 "total_announcements.$" = "$.Payload.total_announcements"
                 "new_filings.$"         = "$.Payload.new_filings"
                 "updated_filings.$"     = "$.Payload.updated_filings"
+                "revised_filings.$"     = "$.Payload.revised_filings"
+                "download_filings.$"    = "$.Payload.download_filings"
                 "filings.$"             = "$.Payload.filings"
+                "filings_ref.$"         = "$.Payload.filings_ref"
                 "errors.$"              = "$.Payload.errors"
               }
               Next = "CheckMonthFilings"
@@ -239,7 +249,7 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
             CheckMonthFilings = {
               Type = "Choice"
               Choices = [{
-                Variable           = "$.scraperResult.new_filings"
+                Variable           = "$.scraperResult.download_filings"
                 NumericGreaterThan = 0
                 Next               = "RouteMonthBySize"
               }]
@@ -250,13 +260,66 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
             RouteMonthBySize = {
               Type = "Choice"
               Choices = [{
-                Variable           = "$.scraperResult.new_filings"
+                Variable           = "$.scraperResult.download_filings"
                 NumericGreaterThan = var.batch_filing_threshold
                 Next               = "WriteMonthManifest"
+                }, {
+                Variable = "$.scraperResult.filings_ref"
+                IsNull   = false
+                Next     = "DownloadMonthFilingsByRef"
               }]
               Default = "DownloadMonthFilings"
             }
 
+            # Filings stored in S3: map over the byte spans of their lines instead
+            DownloadMonthFilingsByRef = {
+              Type           = "Map"
+              ItemsPath      = "$.scraperResult.filings_ref.lines"
+              MaxConcurrency = var.download_max_concurrency
+              ItemSelector = {
+                filing_ref = {
+                  "bucket.$" = "$.scraperResult.filings_ref.bucket"
+                  "key.$"    = "$.scraperResult.filings_ref.key"
+                  "offset.$" = "$$.Map.Item.Value.offset"
+                  "length.$" = "$$.Map.Item.Value.length"
+                }
+              }
+              ItemProcessor = {
+                ProcessorConfig = {
+                  Mode = "INLINE"
+                }
+                StartAt = "DownloadReferencedMonthFiling"
+                States = {
+                  DownloadReferencedMonthFiling = {
+                    Type     = "Task"
+                    Resource = "arn:aws:states:::lambda:invoke"
+                    Parameters = {
+                      FunctionName = var.sfn_downloader_lambda_arn
+                      "Payload.$"  = "$"
+                    }
+                    ResultSelector = {
+                      "source_id.$" = "$.Payload.source_id"
+                      "success.$"   = "$.Payload.success"
+                    }
+                    End = true
+                    Retry = [{
+                      ErrorEquals     = ["States.TaskFailed", "Lambda.ServiceException", "Lambda.TooManyRequestsException"]
+                      IntervalSeconds = 30
+                      MaxAttempts     = 2
+                      BackoffRate     = 2.0
+                    }]
+                  }
+                }
+              }
+              ResultPath = "$.downloadResults"
+              Next       = "MonthDone"
+              Catch = [{
+                ErrorEquals = ["States.ALL"]
+                Next        = "MonthFailed"
+                ResultPath  = "$.error"
+              }]
+            }
+
             # Map state for small filing counts (avoids Batch array_size >= 2 requirement)
             DownloadMonthFilings = {
               Type           = "Map"
@@ -304,7 +367,8 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
               Parameters = {
                 FunctionName = var.write_manifest_lambda_arn
                 Payload = {
-                  "filings.$" = "$.scraperResult.filings"
+                  "filings.$"     = "$.scraperResult.filings"
+                  "filings_ref.$" = "$.scraperResult.filings_ref"
                 }
               }
               ResultPath = "$.manifestResult"
@@ -426,7 +490,10 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
           "total_announcements.$" = "$.Payload.total_announcements"
           "new_filings.$"         = "$.Payload.new_filings"
           "updated_filings.$"     = "$.Payload.updated_filings"
+          "revised_filings.$"     = "$.Payload.revised_filings"
+          "download_filings.$"    = "$.Payload.download_filings"
           "filings.$"             = "$.Payload.filings"
+          "filings_ref.$"         = "$.Payload.filings_ref"
           "errors.$"              = "$.Payload.errors"
         }
         Next = "CheckNewFilings"
@@ -438,12 +505,13 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
       }
 
       # ---------------------------------------------------------------
-      # Step 2: Branch — skip downloads if the scraper found nothing new.
+      # Step 2: Branch — skip downloads if the scraper found nothing new
+      # and no stored filing's document link changed.
       # ---------------------------------------------------------------
       CheckNewFilings = {
         Type = "Choice"
         Choices = [{
-          Variable           = "$.scraperResult.new_filings"
+          Variable           = "$.scraperResult.download_filings"
           NumericGreaterThan = 0
           Next               = "RouteBySize"
         }]
@@ -457,13 +525,70 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
       RouteBySize = {
         Type = "Choice"
         Choices = [{
-          Variable           = "$.scraperResult.new_filings"
+          Variable           = "$.scraperResult.download_filings"
           NumericGreaterThan = var.batch_filing_threshold
           Next               = "WriteManifest"
+          }, {
+          Variable = "$.scraperResult.filings_ref"
+          IsNull   = false
+          Next     = "DownloadFilingsByRef"
         }]
         Default = "DownloadFilings"
       }
 
+      # ---------------------------------------------------------------
+      # Step 2e: Filings stored in S3 (claim check) — map over the byte
+      # spans of their lines; each item gets a claim check of its own
+      # line, which sfn-downloader reads as a byte range.
+      # ---------------------------------------------------------------
+      DownloadFilingsByRef = {
+        Type           = "Map"
+        ItemsPath      = "$.scraperResult.filings_ref.lines"
+        MaxConcurrency = var.download_max_concurrency
+        ItemSelector = {
+          filing_ref = {
+            "bucket.$" = "$.scraperResult.filings_ref.bucket"
+            "key.$"    = "$.scraperResult.filings_ref.key"
+            "offset.$" = "$$.Map.Item.Value.offset"
+            "length.$" = "$$.Map.Item.Value.length"
+          }
+        }
+        ItemProcessor = {
+          ProcessorConfig = {
+            Mode = "INLINE"
+          }
+          StartAt = "DownloadReferencedFiling"
+          States = {
+            DownloadReferencedFiling = {
+              Type     = "Task"
+              Resource = "arn:aws:states:::lambda:invoke"
+              Parameters = {
+                FunctionName = var.sfn_downloader_lambda_arn
+                "Payload.$"  = "$"
+              }
+              ResultSelector = {
+                "source_id.$" = "$.Payload.source_id"
+                "success.$"   = "$.Payload.success"
+              }
+              End = true
+              Retry = [{
+                ErrorEquals     = ["States.TaskFailed", "Lambda.ServiceException", "Lambda.TooManyRequestsException"]
+                IntervalSeconds = 30
+                MaxAttempts     = 2
+                BackoffRate     = 2.0
+              }]
+            }
+          }
+        }
+        ResultPath = "$.downloadResults"
+        Next       = "NotifySuccess"
+        Catch = [{
+          ErrorEquals = ["States.ALL"]
+          Next        = "NotifyFailure"
+          ResultPath  = "$.error"
+        }]
+      }
+
       # ---------------------------------------------------------------
       # Step 2c: Write manifest to S3 for Batch array job consumption.
       # ---------------------------------------------------------------
@@ -473,7 +598,8 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
         Parameters = {
           FunctionName = var.write_manifest_lambda_arn
           Payload = {
-            "filings.$" = "$.scraperResult.filings"
+            "filings.$"     = "$.scraperResult.filings"
+            "filings_ref.$" = "$.scraperResult.filings_ref"
           }
         }
         ResultPath = "$.manifestResult"
@@ -585,6 +711,7 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
             "total_announcements.$" = "$.scraperResult.total_announcements"
             "new_filings.$"         = "$.scraperResult.new_filings"
             "updated_filings.$"     = "$.scraperResult.updated_filings"
+            "revised_filings.$"     = "$.scraperResult.revised_filings"
           }
         }
         End = true
@@ -600,6 +727,7 @@ resource "aws_sfn_state_machine" "hkex_workflow" {
             "total_announcements.$" = "$.scraperResult.total_announcements"
             "new_filings"           = 0
             "updated_filings.$"     = "$.scraperResult.updated_filings"
+            "revised_filings.$"     = "$.scraperResult.revised_filings"
           }
         }
         End = true
@@ -0,0 +1,98 @@
+// Package awsclient creates the AWS clients used across the pipeline from one
+// set of settings, so every S3, SQS and SNS client can be pointed at a local
+// stand-in such as MinIO or LocalStack instead of AWS.
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Options configures the clients. Empty fields keep the SDK defaults.
+type Options struct {
+	// Endpoint overrides the endpoint of every service, e.g.
+	// http://localhost:9000 for MinIO
+	Endpoint string
+	// PathStyle addresses S3 buckets in the path (endpoint/bucket/key) rather
+	// than the host name, as most stand-ins require
+	PathStyle bool
+	// Region overrides the region of the default configuration
+	Region string
+	// AccessKeyID and SecretAccessKey, if both set with an Endpoint, are used
+	// instead of the default credential chain. Without an endpoint override
+	// the chain is kept, so a Lambda's role credentials are refreshed.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// FromEnv reads Options from AWS_ENDPOINT_URL, AWS_S3_USE_PATH_STYLE,
+// AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func FromEnv() Options {
+	pathStyle, _ := strconv.ParseBool(os.Getenv("AWS_S3_USE_PATH_STYLE"))
+	return Options{
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+		PathStyle:       pathStyle,
+		Region:          os.Getenv("AWS_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Clients creates AWS service clients sharing one configuration
+type Clients struct {
+	cfg  aws.Config
+	opts Options
+}
+
+// New loads the default AWS configuration with opts applied
+func New(ctx context.Context, opts Options) (*Clients, error) {
+	var loadOpts []func(*config.LoadOptions) error
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	if opts.Endpoint != "" && opts.AccessKeyID != "" && opts.SecretAccessKey != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, opts.SessionToken)))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	if opts.Endpoint != "" {
+		cfg.BaseEndpoint = aws.String(opts.Endpoint)
+	}
+	return &Clients{cfg: cfg, opts: opts}, nil
+}
+
+// Config returns the shared configuration
+func (c *Clients) Config() aws.Config {
+	return c.cfg
+}
+
+// S3 returns a new S3 client
+func (c *Clients) S3() *s3.Client {
+	return s3.NewFromConfig(c.cfg, func(o *s3.Options) {
+		o.UsePathStyle = c.opts.PathStyle
+	})
+}
+
+// SQS returns a new SQS client
+func (c *Clients) SQS() *sqs.Client {
+	return sqs.NewFromConfig(c.cfg)
+}
+
+// SNS returns a new SNS client
+func (c *Clients) SNS() *sns.Client {
+	return sns.NewFromConfig(c.cfg)
+}
@@ -0,0 +1,110 @@
+package awsclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func TestClients_Endpoint(t *testing.T) {
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, err := New(ctx, Options{
+		Endpoint:        srv.URL,
+		PathStyle:       true,
+		Region:          "us-east-1",
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "minioadmin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.S3().PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("filings"),
+		Key:    aws.String("hkex/00005/a.pdf"),
+		Body:   strings.NewReader("%PDF-1.4"),
+	})
+	if err != nil {
+		t.Fatalf("PutObject() error = %v", err)
+	}
+	_, err = c.SQS().SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(srv.URL + "/000000000000/downloads"),
+		MessageBody: aws.String("{}"),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if got := requests[0].URL.Path; got != "/filings/hkex/00005/a.pdf" {
+		t.Errorf("S3 path = %s, want path-style", got)
+	}
+	for _, r := range requests {
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "Credential=minioadmin/") {
+			t.Errorf("Authorization = %q, want static credentials", auth)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "http://localhost:9000")
+	t.Setenv("AWS_S3_USE_PATH_STYLE", "true")
+	t.Setenv("AWS_REGION", "ap-east-1")
+
+	opts := FromEnv()
+	if opts.Endpoint != "http://localhost:9000" || !opts.PathStyle || opts.Region != "ap-east-1" {
+		t.Errorf("FromEnv() = %+v", opts)
+	}
+}
+
+func TestNew_Credentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "ASIACHAIN")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "chain-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "chain-token")
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{"AWS keeps the credential chain", "", "ASIACHAIN"},
+		{"Endpoint uses static credentials", "http://localhost:9000", "minioadmin"},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(ctx, Options{
+				Endpoint:        tt.endpoint,
+				Region:          "us-east-1",
+				AccessKeyID:     "minioadmin",
+				SecretAccessKey: "minioadmin",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			creds, err := c.Config().Credentials.Retrieve(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.AccessKeyID != tt.want {
+				t.Errorf("AccessKeyID = %q, want %q", creds.AccessKeyID, tt.want)
+			}
+		})
+	}
+}
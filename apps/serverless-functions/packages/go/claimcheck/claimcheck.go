@@ -0,0 +1,203 @@
+// Package claimcheck passes payloads too large for the 256 KB Step Functions
+// state limit through S3: the producer stores the payload and passes a Ref
+// in its place, and the consumer loads it back.
+package claimcheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+// DefaultThreshold is the encoded size above which payloads are stored,
+// leaving room under the 256 KB limit for the rest of the state
+const DefaultThreshold = 200 * 1024
+
+// Prefix is the key prefix of stored payloads
+const Prefix = "claim-checks/"
+
+// MaxLines is the longest JSONL list whose line spans a Ref carries, the
+// most filings the state machine downloads with a Map before it uses Batch
+const MaxLines = 1000
+
+// Ref is a claim check: the location of a stored payload. The Ref of a JSONL
+// list of at most MaxLines lines carries the byte span of each line, which a
+// Map state passes on as the Ref of one item (see Item).
+type Ref struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Count  int    `json:"count,omitempty"`  // Lines of a JSONL list
+	Lines  []Span `json:"lines,omitempty"`  // Byte span of each line of a JSONL list
+	Offset int64  `json:"offset,omitempty"` // Start of the line an item's Ref points to
+	Length int64  `json:"length,omitempty"` // Bytes of that line, newline excluded
+}
+
+// Span is the byte range of one line of a stored JSONL list
+type Span struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// Item returns the Ref of line i of a stored JSONL list, which has no length
+// if the list has no such line or its spans were not recorded
+func (r *Ref) Item(i int) *Ref {
+	item := &Ref{Bucket: r.Bucket, Key: r.Key}
+	if i >= 0 && i < len(r.Lines) {
+		item.Offset, item.Length = r.Lines[i].Offset, r.Lines[i].Length
+	}
+	return item
+}
+
+// Threshold returns the offload threshold in bytes, from
+// CLAIM_CHECK_THRESHOLD (default: DefaultThreshold)
+func Threshold() int {
+	if v := os.Getenv("CLAIM_CHECK_THRESHOLD"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return DefaultThreshold
+}
+
+// PutJSONL stores items, a slice, as JSON lines under a new key for name.
+// Lists of at most MaxLines items get the span of each line.
+func PutJSONL(ctx context.Context, blobs storage.Blobs, bucket, name string, items interface{}) (*Ref, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", name, err)
+	}
+	var lines []json.RawMessage
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return nil, fmt.Errorf("encoding %s: not a list: %w", name, err)
+	}
+
+	ref := &Ref{Bucket: bucket, Key: newKey(name, "jsonl"), Count: len(lines)}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if len(lines) <= MaxLines {
+			ref.Lines = append(ref.Lines, Span{Offset: int64(buf.Len()), Length: int64(len(line))})
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if _, err := blobs.PutKey(ctx, ref.Key, &buf, "application/x-ndjson"); err != nil {
+		return nil, fmt.Errorf("storing %s: %w", name, err)
+	}
+	return ref, nil
+}
+
+// PutJSON stores v as a JSON document under a new key for name
+func PutJSON(ctx context.Context, blobs storage.Blobs, bucket, name string, v interface{}) (*Ref, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", name, err)
+	}
+	ref := &Ref{Bucket: bucket, Key: newKey(name, "json")}
+	if _, err := blobs.PutKey(ctx, ref.Key, bytes.NewReader(data), "application/json"); err != nil {
+		return nil, fmt.Errorf("storing %s: %w", name, err)
+	}
+	return ref, nil
+}
+
+// GetJSONL loads a stored JSONL list into the slice v points to
+func GetJSONL(ctx context.Context, blobs storage.Blobs, ref *Ref, v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	n := 0
+	err := eachLine(ctx, blobs, ref, func(line []byte) bool {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+		n++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+
+	if ref.Count > 0 && n != ref.Count {
+		return fmt.Errorf("claim check %s has %d lines, want %d", ref.Key, n, ref.Count)
+	}
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return fmt.Errorf("decoding claim check %s: %w", ref.Key, err)
+	}
+	return nil
+}
+
+// GetItem loads the line an item's Ref points to into v, reading only that
+// line's bytes
+func GetItem(ctx context.Context, blobs storage.Blobs, ref *Ref, v interface{}) error {
+	if ref.Length <= 0 {
+		return fmt.Errorf("claim check %s has no line at offset %d", ref.Key, ref.Offset)
+	}
+	rc, err := blobs.OpenRange(ctx, ref.Key, ref.Offset, ref.Length)
+	if err != nil {
+		return fmt.Errorf("opening line at offset %d of claim check %s: %w", ref.Offset, ref.Key, err)
+	}
+	defer rc.Close()
+
+	line, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("reading line at offset %d of claim check %s: %w", ref.Offset, ref.Key, err)
+	}
+	if err := json.Unmarshal(line, v); err != nil {
+		return fmt.Errorf("decoding line at offset %d of claim check %s: %w", ref.Offset, ref.Key, err)
+	}
+	return nil
+}
+
+// GetJSON loads a stored JSON document into v
+func GetJSON(ctx context.Context, blobs storage.Blobs, ref *Ref, v interface{}) error {
+	rc, err := blobs.OpenKey(ctx, ref.Key)
+	if err != nil {
+		return fmt.Errorf("opening claim check %s: %w", ref.Key, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("decoding claim check %s: %w", ref.Key, err)
+	}
+	return nil
+}
+
+// eachLine calls fn with each non-empty line of a stored document until fn
+// returns false
+func eachLine(ctx context.Context, blobs storage.Blobs, ref *Ref, fn func(line []byte) bool) error {
+	rc, err := blobs.OpenKey(ctx, ref.Key)
+	if err != nil {
+		return fmt.Errorf("opening claim check %s: %w", ref.Key, err)
+	}
+	defer rc.Close()
+
+	r := bufio.NewReader(rc)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 && !fn(line) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading claim check %s: %w", ref.Key, err)
+		}
+	}
+}
+
+// newKey returns a unique key for a payload named name
+func newKey(name, ext string) string {
+	var b [4]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("%s%s/%s-%x.%s", Prefix, name, time.Now().UTC().Format("20060102T150405Z"), b, ext)
+}
@@ -0,0 +1,91 @@
+package claimcheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+type item struct {
+	ID   string `json:"id"`
+	Note string `json:"note"`
+}
+
+func TestJSONL(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+	items := []item{{"a", "first\nline"}, {"b", ""}, {"c", "third"}}
+
+	ref, err := PutJSONL(ctx, blobs, "bucket", "filings", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Bucket != "bucket" || ref.Count != 3 || len(ref.Lines) != 3 || !strings.HasPrefix(ref.Key, "claim-checks/filings/") || !strings.HasSuffix(ref.Key, ".jsonl") {
+		t.Errorf("ref = %+v", ref)
+	}
+
+	var got []item
+	if err := GetJSONL(ctx, blobs, ref, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != items[0] || got[2] != items[2] {
+		t.Errorf("GetJSONL() = %+v", got)
+	}
+
+	for i := range items {
+		var line item
+		if err := GetItem(ctx, blobs, ref.Item(i), &line); err != nil || line != items[i] {
+			t.Errorf("GetItem(%d) = %+v, %v", i, line, err)
+		}
+	}
+	var line item
+	if err := GetItem(ctx, blobs, ref.Item(3), &line); err == nil {
+		t.Error("GetItem(3) succeeded past the end")
+	}
+
+	if second := ref.Lines[1]; second.Offset != int64(len(`{"id":"a","note":"first\nline"}`)+1) || second.Length != int64(len(`{"id":"b","note":""}`)) {
+		t.Errorf("Lines[1] = %+v", second)
+	}
+
+	// A truncated list is detected
+	ref.Count = 4
+	if err := GetJSONL(ctx, blobs, ref, &got); err == nil {
+		t.Error("GetJSONL() succeeded with a missing line")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+
+	ref, err := PutJSON(ctx, blobs, "bucket", "notify", item{ID: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got item
+	if err := GetJSON(ctx, blobs, ref, &got); err != nil || got.ID != "x" {
+		t.Errorf("GetJSON() = %+v, %v", got, err)
+	}
+	if _, err := PutJSONL(ctx, blobs, "bucket", "x", item{}); err == nil {
+		t.Error("PutJSONL() of a non-list succeeded")
+	}
+}
+
+func TestJSONL_Long(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+
+	ref, err := PutJSONL(ctx, blobs, "bucket", "filings", make([]item, MaxLines+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Count != MaxLines+1 || ref.Lines != nil {
+		t.Errorf("ref has count %d and %d spans, want %d and none", ref.Count, len(ref.Lines), MaxLines+1)
+	}
+	var got []item
+	if err := GetJSONL(ctx, blobs, ref, &got); err != nil || len(got) != MaxLines+1 {
+		t.Errorf("GetJSONL() = %d items, %v", len(got), err)
+	}
+}
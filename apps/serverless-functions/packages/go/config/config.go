@@ -3,14 +3,39 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds application configuration
 type Config struct {
 	// HKEX API settings
-	BaseURL   string
-	MaxPages  int
-	RateLimit int // requests per second
+	BaseURL      string
+	MaxPages     int
+	RateLimit    int    // requests per second
+	DIBaseURL    string // Disclosure of Interests service
+	CCASSBaseURL string // CCASS shareholding search
+
+	// Instrument classification settings
+	ClassifyFromList  bool     // classify stock codes from the securities list, not just code ranges
+	SecuritiesListURL string   // empty for securities.ListURL
+	SkipCategories    []string // categories or sub-categories the scraper skips; nil for securities.DefaultSkipCategories
+
+	// DART (OpenDART) settings
+	DARTBaseURL   string
+	DARTAPIKey    string
+	DARTRateLimit int // requests per second
+
+	// cninfo (SSE/SZSE) settings
+	CNInfoBaseURL   string
+	CNInfoStaticURL string // PDF host
+	CNInfoRateLimit int    // requests per second
+
+	// MOPS (TWSE/TPEx) settings
+	MOPSBaseURL   string
+	MOPSDocURL    string // electronic documents (periodic reports)
+	MOPSISINURL   string // securities lists
+	MOPSRateLimit int    // requests per second
+	MOPSReports   bool   // also scan each company's periodic reports (one request per company and year)
 
 	// Storage settings
 	S3Bucket  string
@@ -18,8 +43,8 @@ type Config struct {
 	LocalPath string
 
 	// Database settings
-	DatabaseURL  string
-	EnableDB     bool
+	DatabaseURL string
+	EnableDB    bool
 
 	// Processing settings
 	DownloadPDFs    bool
@@ -30,17 +55,33 @@ type Config struct {
 // Load creates a Config from environment variables with defaults
 func Load() *Config {
 	return &Config{
-		BaseURL:         getEnv("HKEX_BASE_URL", "https://www1.hkexnews.hk"),
-		MaxPages:        getEnvInt("HKEX_MAX_PAGES", 10),
-		RateLimit:       getEnvInt("HKEX_RATE_LIMIT", 2),
-		S3Bucket:        getEnv("S3_BUCKET", ""),
-		S3Region:        getEnv("AWS_REGION", "ap-east-1"), // Hong Kong region
-		LocalPath:       getEnv("LOCAL_STORAGE_PATH", "./downloads"),
-		DatabaseURL:     getEnv("DATABASE_URL", "./hkex.db"),
-		EnableDB:        getEnvBool("ENABLE_DB", true),
-		DownloadPDFs:    getEnvBool("DOWNLOAD_PDFS", true),
-		ExtractTables:   getEnvBool("EXTRACT_TABLES", false),
-		TableServiceURL: getEnv("TABLE_SERVICE_URL", "http://localhost:8001"),
+		BaseURL:           getEnv("HKEX_BASE_URL", "https://www1.hkexnews.hk"),
+		MaxPages:          getEnvInt("HKEX_MAX_PAGES", 10),
+		RateLimit:         getEnvInt("HKEX_RATE_LIMIT", 2),
+		DIBaseURL:         getEnv("HKEX_DI_BASE_URL", "https://di.hkex.com.hk"),
+		CCASSBaseURL:      getEnv("HKEX_CCASS_BASE_URL", "https://www3.hkexnews.hk"),
+		ClassifyFromList:  getEnvBool("HKEX_CLASSIFY_FROM_LIST", true),
+		SecuritiesListURL: getEnv("HKEX_SECURITIES_LIST_URL", ""),
+		SkipCategories:    getEnvList("HKEX_SKIP_CATEGORIES", nil),
+		DARTBaseURL:       getEnv("DART_BASE_URL", "https://opendart.fss.or.kr"),
+		DARTAPIKey:        getEnv("DART_API_KEY", ""),
+		DARTRateLimit:     getEnvInt("DART_RATE_LIMIT", 5),
+		CNInfoBaseURL:     getEnv("CNINFO_BASE_URL", "http://www.cninfo.com.cn"),
+		CNInfoStaticURL:   getEnv("CNINFO_STATIC_URL", "http://static.cninfo.com.cn"),
+		CNInfoRateLimit:   getEnvInt("CNINFO_RATE_LIMIT", 2),
+		MOPSBaseURL:       getEnv("MOPS_BASE_URL", "https://mops.twse.com.tw"),
+		MOPSDocURL:        getEnv("MOPS_DOC_URL", "https://doc.twse.com.tw"),
+		MOPSISINURL:       getEnv("MOPS_ISIN_URL", "https://isin.twse.com.tw"),
+		MOPSRateLimit:     getEnvInt("MOPS_RATE_LIMIT", 1),
+		MOPSReports:       getEnvBool("MOPS_PERIODIC_REPORTS", false),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3Region:          getEnv("AWS_REGION", "ap-east-1"), // Hong Kong region
+		LocalPath:         getEnv("LOCAL_STORAGE_PATH", "./downloads"),
+		DatabaseURL:       getEnv("DATABASE_URL", "./hkex.db"),
+		EnableDB:          getEnvBool("ENABLE_DB", true),
+		DownloadPDFs:      getEnvBool("DOWNLOAD_PDFS", true),
+		ExtractTables:     getEnvBool("EXTRACT_TABLES", false),
+		TableServiceURL:   getEnv("TABLE_SERVICE_URL", "http://localhost:8001"),
 	}
 }
 
@@ -73,3 +114,11 @@ func getEnvBool(key string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+// getEnvList reads a comma-separated list
+func getEnvList(key string, defaultVal []string) []string {
+	if val := os.Getenv(key); val != "" {
+		return strings.Split(val, ",")
+	}
+	return defaultVal
+}
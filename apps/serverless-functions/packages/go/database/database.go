@@ -31,11 +31,25 @@ func New(dsn string) (*DB, error) {
 
 	db := &DB{conn: conn}
 
+	// Older databases declared companies.stock_code UNIQUE, which prevents a
+	// recycled stock code from belonging to a second company
+	if err := db.migrateCompaniesStockCode(); err != nil {
+		return nil, fmt.Errorf("migrating companies table: %w", err)
+	}
+
 	// Initialize schema
 	if err := db.initSchema(); err != nil {
 		return nil, fmt.Errorf("initializing schema: %w", err)
 	}
 
+	if err := db.addMissingColumns(); err != nil {
+		return nil, fmt.Errorf("adding columns: %w", err)
+	}
+
+	if err := db.backfillListings(); err != nil {
+		return nil, fmt.Errorf("backfilling company listings: %w", err)
+	}
+
 	return db, nil
 }
 
@@ -49,16 +63,34 @@ func (db *DB) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS companies (
 		id TEXT PRIMARY KEY,
-		stock_code TEXT UNIQUE NOT NULL,
+		stock_code TEXT NOT NULL,
 		company_name TEXT NOT NULL,
 		company_name_en TEXT,
 		market_type TEXT NOT NULL DEFAULT 'SEHK',
 		industry TEXT,
 		exchange TEXT NOT NULL DEFAULT 'HKEX',
+		instrument_category TEXT,
+		instrument_sub_category TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_companies_stock_code ON companies(exchange, stock_code);
+
+	CREATE TABLE IF NOT EXISTS company_listings (
+		id TEXT PRIMARY KEY,
+		company_id TEXT NOT NULL REFERENCES companies(id),
+		exchange TEXT NOT NULL DEFAULT 'HKEX',
+		stock_code TEXT NOT NULL,
+		listed_from DATETIME,
+		listed_to DATETIME,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE INDEX IF NOT EXISTS idx_company_listings_stock_code ON company_listings(exchange, stock_code);
+	CREATE INDEX IF NOT EXISTS idx_company_listings_company_id ON company_listings(company_id);
+
 	CREATE TABLE IF NOT EXISTS filings (
 		id TEXT PRIMARY KEY,
 		company_id TEXT NOT NULL REFERENCES companies(id),
@@ -111,25 +143,136 @@ func (db *DB) initSchema() error {
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
+
+	CREATE TABLE IF NOT EXISTS filing_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		exchange TEXT NOT NULL,
+		source_id TEXT NOT NULL,
+		field TEXT NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_filing_revisions_filing ON filing_revisions(exchange, source_id);
 	`
 
 	_, err := db.conn.Exec(schema)
 	return err
 }
 
+// migrateCompaniesStockCode rebuilds the companies table without the UNIQUE
+// constraint on stock_code. It is a no-op for new or already-migrated databases.
+func (db *DB) migrateCompaniesStockCode() error {
+	var tableSQL string
+	err := db.conn.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'companies'`).Scan(&tableSQL)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(tableSQL, "stock_code TEXT UNIQUE") {
+		return nil
+	}
+
+	// Foreign keys must be off while the referenced table is swapped out
+	if _, err := db.conn.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer db.conn.Exec("PRAGMA foreign_keys = ON")
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE companies_new (
+			id TEXT PRIMARY KEY,
+			stock_code TEXT NOT NULL,
+			company_name TEXT NOT NULL,
+			company_name_en TEXT,
+			market_type TEXT NOT NULL DEFAULT 'SEHK',
+			industry TEXT,
+			exchange TEXT NOT NULL DEFAULT 'HKEX',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO companies_new SELECT id, stock_code, company_name, company_name_en, market_type,
+			industry, exchange, created_at, updated_at FROM companies`,
+		`DROP TABLE companies`,
+		`ALTER TABLE companies_new RENAME TO companies`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addedColumns are columns added to existing tables after their initial schema
+var addedColumns = []struct{ table, column, typ string }{
+	{"companies", "instrument_category", "TEXT"},
+	{"companies", "instrument_sub_category", "TEXT"},
+	{"filings", "translation_of", "TEXT"},
+	{"filings", "released_at", "DATETIME"},
+}
+
+// addMissingColumns adds columns introduced after a database was created
+func (db *DB) addMissingColumns() error {
+	for _, c := range addedColumns {
+		var exists bool
+		err := db.conn.QueryRow(
+			`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column,
+		).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.typ)); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// backfillListings gives every company without a listing an open-ended one
+// for its current stock code, so point-in-time lookups cover legacy rows.
+func (db *DB) backfillListings() error {
+	_, err := db.conn.Exec(`
+		INSERT INTO company_listings (id, company_id, exchange, stock_code, created_at, updated_at)
+		SELECT 'lst_' || c.exchange || '_' || c.id, c.id, c.exchange, c.stock_code, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM companies c
+		WHERE NOT EXISTS (SELECT 1 FROM company_listings l WHERE l.company_id = c.id)
+	`)
+	return err
+}
+
 // UpsertCompany creates or updates a company
 func (db *DB) UpsertCompany(ctx context.Context, company *models.Company) error {
 	query := `
-		INSERT INTO companies (id, stock_code, company_name, company_name_en, market_type, industry, exchange, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(stock_code) DO UPDATE SET
+		INSERT INTO companies (id, stock_code, company_name, company_name_en, market_type, industry, exchange,
+			instrument_category, instrument_sub_category, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			stock_code = excluded.stock_code,
 			company_name = excluded.company_name,
 			company_name_en = excluded.company_name_en,
 			market_type = excluded.market_type,
 			industry = excluded.industry,
+			instrument_category = excluded.instrument_category,
+			instrument_sub_category = excluded.instrument_sub_category,
 			updated_at = excluded.updated_at
 	`
 
+	company.ID = models.NormalizeCompanyID(company.Exchange, company.ID)
+	company.StockCode = models.NormalizeStockCode(company.Exchange, company.StockCode.String())
+
 	now := time.Now()
 	if company.CreatedAt.IsZero() {
 		company.CreatedAt = now
@@ -144,21 +287,63 @@ func (db *DB) UpsertCompany(ctx context.Context, company *models.Company) error
 		company.MarketType,
 		company.Industry,
 		company.Exchange,
+		company.InstrumentCategory,
+		company.InstrumentSubCategory,
 		company.CreatedAt,
 		company.UpdatedAt,
 	)
 	return err
 }
 
-// GetCompanyByStockCode retrieves a company by stock code
+// GetCompanyByStockCode retrieves the company currently holding a stock code.
+// Use ResolveCompany for point-in-time lookups of historical filings.
 func (db *DB) GetCompanyByStockCode(ctx context.Context, stockCode string) (*models.Company, error) {
-	query := `SELECT id, stock_code, company_name, company_name_en, market_type, industry, exchange, created_at, updated_at
-			  FROM companies WHERE stock_code = ?`
+	return db.ResolveCompany(ctx, "HKEX", stockCode, time.Now())
+}
+
+// GetCompanyByID retrieves a company by its ID
+func (db *DB) GetCompanyByID(ctx context.Context, exchange, id string) (*models.Company, error) {
+	query := `SELECT id, stock_code, company_name, company_name_en, market_type, industry, exchange,
+			  COALESCE(instrument_category, ''), COALESCE(instrument_sub_category, ''), created_at, updated_at
+			  FROM companies WHERE exchange = ? AND id = ?`
+
+	var c models.Company
+	var code string
+	err := db.conn.QueryRowContext(ctx, query, exchange, models.NormalizeCompanyID(exchange, id)).Scan(
+		&c.ID, &code, &c.CompanyName, &c.CompanyNameEn,
+		&c.MarketType, &c.Industry, &c.Exchange, &c.InstrumentCategory, &c.InstrumentSubCategory,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.StockCode = models.NormalizeStockCode(c.Exchange, code)
+	return &c, nil
+}
+
+// ResolveCompany retrieves the company whose listing of stockCode covered the
+// given date. Returns nil if no listing covers it.
+func (db *DB) ResolveCompany(ctx context.Context, exchange, stockCode string, asOf time.Time) (*models.Company, error) {
+	query := `SELECT c.id, c.stock_code, c.company_name, c.company_name_en, c.market_type, c.industry, c.exchange,
+			  COALESCE(c.instrument_category, ''), COALESCE(c.instrument_sub_category, ''), c.created_at, c.updated_at
+			  FROM company_listings l JOIN companies c ON c.id = l.company_id
+			  WHERE l.exchange = ? AND l.stock_code = ?
+			  AND (l.listed_from IS NULL OR l.listed_from <= ?)
+			  AND (l.listed_to IS NULL OR l.listed_to > ?)
+			  ORDER BY l.listed_from DESC
+			  LIMIT 1`
+
+	asOfDate := models.ListingDate(asOf)
 
 	var c models.Company
-	err := db.conn.QueryRowContext(ctx, query, stockCode).Scan(
-		&c.ID, &c.StockCode, &c.CompanyName, &c.CompanyNameEn,
-		&c.MarketType, &c.Industry, &c.Exchange, &c.CreatedAt, &c.UpdatedAt,
+	var code string
+	err := db.conn.QueryRowContext(ctx, query, exchange, models.NormalizeStockCode(exchange, stockCode), asOfDate, asOfDate).Scan(
+		&c.ID, &code, &c.CompanyName, &c.CompanyNameEn,
+		&c.MarketType, &c.Industry, &c.Exchange, &c.InstrumentCategory, &c.InstrumentSubCategory,
+		&c.CreatedAt, &c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -166,21 +351,86 @@ func (db *DB) GetCompanyByStockCode(ctx context.Context, stockCode string) (*mod
 	if err != nil {
 		return nil, err
 	}
+	c.StockCode = models.NormalizeStockCode(c.Exchange, code)
 	return &c, nil
 }
 
+// GetListings retrieves all listing periods for a stock code, oldest first
+func (db *DB) GetListings(ctx context.Context, exchange, stockCode string) ([]models.CompanyListing, error) {
+	query := `SELECT id, company_id, exchange, stock_code, listed_from, listed_to, created_at, updated_at
+			  FROM company_listings WHERE exchange = ? AND stock_code = ?
+			  ORDER BY listed_from IS NOT NULL, listed_from`
+
+	rows, err := db.conn.QueryContext(ctx, query, exchange, models.NormalizeStockCode(exchange, stockCode))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.CompanyListing
+	for rows.Next() {
+		var l models.CompanyListing
+		var code string
+		if err := rows.Scan(&l.ID, &l.CompanyID, &l.Exchange, &code,
+			&l.ListedFrom, &l.ListedTo, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		l.StockCode = models.NormalizeStockCode(l.Exchange, code)
+		listings = append(listings, l)
+	}
+
+	return listings, rows.Err()
+}
+
+// UpsertListing creates or updates a company listing period
+func (db *DB) UpsertListing(ctx context.Context, listing *models.CompanyListing) error {
+	query := `
+		INSERT INTO company_listings (id, company_id, exchange, stock_code, listed_from, listed_to, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			company_id = excluded.company_id,
+			listed_from = excluded.listed_from,
+			listed_to = excluded.listed_to,
+			updated_at = excluded.updated_at
+	`
+
+	listing.CompanyID = models.NormalizeCompanyID(listing.Exchange, listing.CompanyID)
+	listing.StockCode = models.NormalizeStockCode(listing.Exchange, listing.StockCode.String())
+
+	now := time.Now()
+	if listing.CreatedAt.IsZero() {
+		listing.CreatedAt = now
+	}
+	listing.UpdatedAt = now
+
+	_, err := db.conn.ExecContext(ctx, query,
+		listing.ID,
+		listing.CompanyID,
+		listing.Exchange,
+		listing.StockCode,
+		listing.ListedFrom,
+		listing.ListedTo,
+		listing.CreatedAt,
+		listing.UpdatedAt,
+	)
+	return err
+}
+
 // UpsertFiling creates or updates a filing
 func (db *DB) UpsertFiling(ctx context.Context, filing *models.Filing) error {
 	query := `
 		INSERT INTO filings (id, company_id, source_id, exchange, filing_type, filing_sub_type,
 			report_date, title, title_en, source_url, pdf_s3_key, local_path, page_count, file_size,
-			file_extension, language, processing_status, processing_error, ingested_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			file_extension, language, processing_status, processing_error, ingested_at, released_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(exchange, source_id) DO UPDATE SET
+			company_id = excluded.company_id,
 			filing_type = excluded.filing_type,
 			filing_sub_type = excluded.filing_sub_type,
 			title = excluded.title,
 			title_en = excluded.title_en,
+			source_url = excluded.source_url,
+			file_extension = excluded.file_extension,
 			pdf_s3_key = excluded.pdf_s3_key,
 			local_path = excluded.local_path,
 			page_count = excluded.page_count,
@@ -188,9 +438,12 @@ func (db *DB) UpsertFiling(ctx context.Context, filing *models.Filing) error {
 			processing_status = excluded.processing_status,
 			processing_error = excluded.processing_error,
 			ingested_at = excluded.ingested_at,
+			released_at = COALESCE(excluded.released_at, filings.released_at),
 			updated_at = excluded.updated_at
 	`
 
+	filing.CompanyID = models.NormalizeCompanyID(filing.Exchange, filing.CompanyID)
+
 	now := time.Now()
 	if filing.CreatedAt.IsZero() {
 		filing.CreatedAt = now
@@ -217,17 +470,28 @@ func (db *DB) UpsertFiling(ctx context.Context, filing *models.Filing) error {
 		filing.ProcessingStatus,
 		filing.ProcessingError,
 		filing.IngestedAt,
+		releasedAt(filing),
 		filing.CreatedAt,
 		filing.UpdatedAt,
 	)
 	return err
 }
 
+// releasedAt returns a filing's release time in UTC, so stored times compare
+// in order
+func releasedAt(filing *models.Filing) *time.Time {
+	if filing.ReleasedAt == nil {
+		return nil
+	}
+	t := filing.ReleasedAt.UTC()
+	return &t
+}
+
 // GetFilingBySourceID retrieves a filing by its source ID
 func (db *DB) GetFilingBySourceID(ctx context.Context, exchangeType, sourceID string) (*models.Filing, error) {
 	query := `SELECT id, company_id, source_id, exchange, filing_type, filing_sub_type,
 			  report_date, title, title_en, source_url, pdf_s3_key, local_path, page_count, file_size,
-			  file_extension, language, processing_status, processing_error, ingested_at, created_at, updated_at
+			  file_extension, language, processing_status, processing_error, ingested_at, released_at, created_at, updated_at
 			  FROM filings WHERE exchange = ? AND source_id = ?`
 
 	var f models.Filing
@@ -235,7 +499,7 @@ func (db *DB) GetFilingBySourceID(ctx context.Context, exchangeType, sourceID st
 		&f.ID, &f.CompanyID, &f.SourceID, &f.Exchange, &f.FilingType, &f.FilingSubType,
 		&f.ReportDate, &f.Title, &f.TitleEn, &f.SourceURL, &f.PDFS3Key, &f.LocalPath,
 		&f.PageCount, &f.FileSize, &f.FileExtension, &f.Language, &f.ProcessingStatus,
-		&f.ProcessingError, &f.IngestedAt, &f.CreatedAt, &f.UpdatedAt,
+		&f.ProcessingError, &f.IngestedAt, &f.ReleasedAt, &f.CreatedAt, &f.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -246,6 +510,40 @@ func (db *DB) GetFilingBySourceID(ctx context.Context, exchangeType, sourceID st
 	return &f, nil
 }
 
+// InsertRevisions records changes to the fields of stored filings
+func (db *DB) InsertRevisions(ctx context.Context, revisions []models.FilingRevision) error {
+	query := `INSERT INTO filing_revisions (exchange, source_id, field, old_value, new_value, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	for _, r := range revisions {
+		if _, err := db.conn.ExecContext(ctx, query, r.Exchange, r.SourceID, r.Field, r.OldValue, r.NewValue, r.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRevisions returns the recorded changes to a filing, oldest first
+func (db *DB) GetRevisions(ctx context.Context, exchange, sourceID string) ([]models.FilingRevision, error) {
+	query := `SELECT exchange, source_id, field, COALESCE(old_value, ''), COALESCE(new_value, ''), created_at
+			  FROM filing_revisions WHERE exchange = ? AND source_id = ? ORDER BY id`
+
+	rows, err := db.conn.QueryContext(ctx, query, exchange, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.FilingRevision
+	for rows.Next() {
+		var r models.FilingRevision
+		if err := rows.Scan(&r.Exchange, &r.SourceID, &r.Field, &r.OldValue, &r.NewValue, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
 // UpdateFilingStatus updates the processing status of a filing
 func (db *DB) UpdateFilingStatus(ctx context.Context, filingID string, status models.ProcessingStatus, errorMsg string) error {
 	query := `UPDATE filings SET processing_status = ?, processing_error = ?, updated_at = ? WHERE id = ?`
@@ -409,6 +707,25 @@ func (db *DB) UpdateFilingDownload(ctx context.Context, filingID, localPath stri
 	return err
 }
 
+// SetLocalPath records where a filing's document is stored locally, without
+// changing its status
+func (db *DB) SetLocalPath(ctx context.Context, filingID, localPath string) error {
+	query := `UPDATE filings SET local_path = ?, updated_at = ? WHERE id = ?`
+	_, err := db.conn.ExecContext(ctx, query, localPath, time.Now(), filingID)
+	return err
+}
+
+// ResetDownload marks the filing whose document was stored at localPath for
+// download again, returning the number of filings reset
+func (db *DB) ResetDownload(ctx context.Context, localPath string) (int64, error) {
+	query := `UPDATE filings SET local_path = '', processing_status = ?, processing_error = '', updated_at = ? WHERE local_path = ?`
+	res, err := db.conn.ExecContext(ctx, query, models.ProcessingStatusPending, time.Now(), localPath)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // UpdateFilingDownloadFull updates filing with local path, S3 key, status, and error
 func (db *DB) UpdateFilingDownloadFull(ctx context.Context, filingID, localPath, s3Key string, status models.ProcessingStatus, errorMsg string) error {
 	query := `UPDATE filings SET local_path = ?, pdf_s3_key = ?, processing_status = ?, processing_error = ?, updated_at = ? WHERE id = ?`
@@ -457,3 +774,67 @@ func (db *DB) GetFilingsByIDs(ctx context.Context, ids []string) ([]models.Filin
 
 	return filings, rows.Err()
 }
+
+// GetUnpairedFilings returns the filings of an exchange released between
+// from and to that have no other-language version recorded
+func (db *DB) GetUnpairedFilings(ctx context.Context, exchange string, from, to time.Time) ([]models.Filing, error) {
+	query := `SELECT id, company_id, source_id, exchange, filing_type, COALESCE(filing_sub_type, ''),
+			  report_date, released_at, title, source_url, COALESCE(file_size, 0), language
+			  FROM filings WHERE exchange = ? AND COALESCE(released_at, report_date) BETWEEN ? AND ?
+			  AND COALESCE(translation_of, '') = '' ORDER BY COALESCE(released_at, report_date)`
+
+	rows, err := db.conn.QueryContext(ctx, query, exchange, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filings []models.Filing
+	for rows.Next() {
+		var f models.Filing
+		if err := rows.Scan(
+			&f.ID, &f.CompanyID, &f.SourceID, &f.Exchange, &f.FilingType, &f.FilingSubType,
+			&f.ReportDate, &f.ReleasedAt, &f.Title, &f.SourceURL, &f.FileSize, &f.Language,
+		); err != nil {
+			return nil, err
+		}
+		filings = append(filings, f)
+	}
+
+	return filings, rows.Err()
+}
+
+// SetTranslation records two filings as language versions of each other
+func (db *DB) SetTranslation(ctx context.Context, exchange, sourceID, translationSourceID string) error {
+	query := `UPDATE filings SET translation_of = CASE source_id WHEN ? THEN ? ELSE ? END, updated_at = ?
+			  WHERE exchange = ? AND source_id IN (?, ?)`
+	_, err := db.conn.ExecContext(ctx, query, sourceID, translationSourceID, sourceID, time.Now(),
+		exchange, sourceID, translationSourceID)
+	return err
+}
+
+// GetTranslation returns the other-language version of a filing, or nil if
+// none is known
+func (db *DB) GetTranslation(ctx context.Context, exchange, sourceID string) (*models.Filing, error) {
+	query := `SELECT t.id, t.company_id, t.source_id, t.exchange, t.filing_type, t.filing_sub_type,
+			  t.report_date, t.title, t.title_en, t.source_url, t.pdf_s3_key, t.local_path, t.page_count, t.file_size,
+			  t.file_extension, t.language, t.processing_status, t.processing_error, t.ingested_at,
+			  COALESCE(t.translation_of, ''), t.created_at, t.updated_at
+			  FROM filings f JOIN filings t ON t.exchange = f.exchange AND t.source_id = f.translation_of
+			  WHERE f.exchange = ? AND f.source_id = ?`
+
+	var f models.Filing
+	err := db.conn.QueryRowContext(ctx, query, exchange, sourceID).Scan(
+		&f.ID, &f.CompanyID, &f.SourceID, &f.Exchange, &f.FilingType, &f.FilingSubType,
+		&f.ReportDate, &f.Title, &f.TitleEn, &f.SourceURL, &f.PDFS3Key, &f.LocalPath,
+		&f.PageCount, &f.FileSize, &f.FileExtension, &f.Language, &f.ProcessingStatus,
+		&f.ProcessingError, &f.IngestedAt, &f.TranslationOf, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
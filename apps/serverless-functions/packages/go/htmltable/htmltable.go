@@ -0,0 +1,153 @@
+// Package htmltable reads HTML tables from exchange sites whose pages are
+// tables laid out for display: columns are found by header text rather than
+// position, and section headings spanning a row are kept.
+package htmltable
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Row is a table row keyed by column header. Rows with a single cell, such
+// as section headings, have nil Cells and their text in Section.
+type Row struct {
+	Node    *html.Node
+	Cells   map[string]*html.Node
+	Section string
+}
+
+// Text returns the text of a column, or "" if the table has no such column
+func (r Row) Text(header string) string {
+	if n, ok := r.Cells[header]; ok {
+		return Text(n)
+	}
+	return ""
+}
+
+// Find finds the first table with a header row containing all the given
+// headers and returns the rows below it
+func Find(doc *html.Node, headers ...string) ([]Row, bool) {
+	for _, table := range FindAll(doc, atom.Table) {
+		trs := tableRows(table)
+		for i, tr := range trs {
+			cells := rowCells(tr)
+			names := make([]string, len(cells))
+			for j, c := range cells {
+				names[j] = Text(c)
+			}
+			if !containsAll(names, headers) {
+				continue
+			}
+
+			var rows []Row
+			for _, tr := range trs[i+1:] {
+				cells := rowCells(tr)
+				switch len(cells) {
+				case 0:
+				case 1:
+					rows = append(rows, Row{Node: tr, Section: Text(cells[0])})
+				default:
+					r := Row{Node: tr, Cells: make(map[string]*html.Node)}
+					for j, c := range cells {
+						if j < len(names) {
+							r.Cells[names[j]] = c
+						}
+					}
+					rows = append(rows, r)
+				}
+			}
+			return rows, true
+		}
+	}
+	return nil, false
+}
+
+// FindAll returns the elements of a kind under n, in document order
+func FindAll(n *html.Node, a atom.Atom) []*html.Node {
+	var found []*html.Node
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		found = append(found, n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		found = append(found, FindAll(c, a)...)
+	}
+	return found
+}
+
+// Text returns the text under n with runs of whitespace, including
+// ideographic and non-breaking spaces, collapsed to one space. Line breaks
+// (<br>) count as whitespace.
+func Text(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		sb.WriteByte(' ')
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// Attr returns an attribute of n, or ""
+func Attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// tableRows returns the rows of a table, excluding those of nested tables
+func tableRows(table *html.Node) []*html.Node {
+	var trs []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Tr:
+				trs = append(trs, c)
+			case atom.Table:
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(table)
+	return trs
+}
+
+// rowCells returns the th and td cells of a row
+func rowCells(tr *html.Node) []*html.Node {
+	var cells []*html.Node
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// containsAll reports whether names includes every header
+func containsAll(names, headers []string) bool {
+	for _, h := range headers {
+		found := false
+		for _, n := range names {
+			if n == h {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
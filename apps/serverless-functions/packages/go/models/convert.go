@@ -11,20 +11,15 @@ import (
 // product range (Derivative Warrants, CBBCs, Inline Warrants). These are
 // short-lived leveraged products with boilerplate filings that should be
 // excluded from the fundamental research database.
+//
+// This only checks code ranges; securities.Classifier uses the HKEX
+// securities list and falls back to these ranges.
 func IsStructuredProduct(stockCode string) bool {
-	code, err := strconv.Atoi(strings.TrimLeft(stockCode, "0"))
+	code, err := ParseStockCode(stockCode)
 	if err != nil {
 		return false
 	}
-	switch {
-	case code >= 10000 && code <= 29999: // Derivative Warrants
-		return true
-	case code >= 50000 && code <= 69999: // CBBCs
-		return true
-	case code >= 80000 && code <= 89999: // Inline Warrants
-		return true
-	}
-	return false
+	return code.IsStructuredProduct()
 }
 
 // GenerateID generates a unique ID with a prefix
@@ -35,7 +30,7 @@ func GenerateID(prefix string) string {
 // AnnouncementToFiling converts an HKEX Announcement to a Filing record
 func AnnouncementToFiling(ann *Announcement, companyID string) *Filing {
 	// Parse release time (format: "DD/MM/YYYY HH:MM")
-	reportDate := parseHKEXDate(ann.RelTime)
+	reportDate, releasedAt := parseHKEXDate(ann.RelTime)
 
 	// Determine language from title
 	language := detectLanguage(ann.Title)
@@ -49,12 +44,13 @@ func AnnouncementToFiling(ann *Announcement, companyID string) *Filing {
 
 	return &Filing{
 		ID:               GenerateID("fil"),
-		CompanyID:        companyID,
+		CompanyID:        NormalizeCompanyID("HKEX", companyID),
 		SourceID:         strconv.Itoa(ann.NewsID),
 		Exchange:         "HKEX",
 		FilingType:       filingType,
 		FilingSubType:    ann.T2Code,
 		ReportDate:       reportDate,
+		ReleasedAt:       releasedAt,
 		Title:            ann.Title,
 		SourceURL:        ann.DocumentURL(),
 		FileSize:         fileSize,
@@ -78,9 +74,11 @@ func StockToCompany(stock *Stock) *Company {
 		companyName = stock.SN // Use English as primary if that's what we have
 	}
 
+	stockCode := NormalizeStockCode("HKEX", stock.SC)
+
 	return &Company{
-		ID:            stock.SC, // Use stock code as the company ID
-		StockCode:     stock.SC,
+		ID:            stockCode.String(), // Use stock code as the company ID
+		StockCode:     stockCode,
 		CompanyName:   companyName,
 		CompanyNameEn: companyNameEn,
 		MarketType:    MarketTypeSEHK,
@@ -90,22 +88,40 @@ func StockToCompany(stock *Stock) *Company {
 	}
 }
 
-// parseHKEXDate parses date in format "DD/MM/YYYY HH:MM"
-func parseHKEXDate(s string) time.Time {
+// CompanyIDForListing returns the ID for a company that takes over a stock
+// code from listedFrom onwards. The first issuer seen for a code keeps the bare
+// code as its ID (matching existing rows and S3 keys); a later issuer of a
+// recycled code gets the listing start date appended, e.g. "01234_20240105",
+// which fits the 32 characters of companies.company_id.
+func CompanyIDForListing(stockCode StockCode, listedFrom *time.Time) string {
+	if listedFrom == nil {
+		return stockCode.String()
+	}
+	return stockCode.String() + "_" + listedFrom.Format("20060102")
+}
+
+// ListingDate truncates t to the calendar date used for listing periods
+func ListingDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseHKEXDate parses an HKEX release time, "DD/MM/YYYY HH:MM" in Hong
+// Kong time, into the report date and the release time. A date without a
+// time has no release time; an unparseable string falls back to today.
+func parseHKEXDate(s string) (time.Time, *time.Time) {
 	// Try full datetime format
-	t, err := time.Parse("02/01/2006 15:04", s)
-	if err == nil {
-		return t
+	if t, err := ParseHKEXTime(s); err == nil {
+		return HKEXDate(t), &t
 	}
 
 	// Try date only
-	t, err = time.Parse("02/01/2006", s)
+	t, err := time.Parse("02/01/2006", strings.TrimSpace(s))
 	if err == nil {
-		return t
+		return t, nil
 	}
 
-	// Return current time as fallback
-	return time.Now()
+	// Return current date as fallback
+	return HKEXDate(time.Now()), nil
 }
 
 // detectLanguage detects if text is primarily English, Chinese, or mixed
@@ -187,7 +203,7 @@ func parseFileSize(s string) int {
 // SearchResultToFiling converts a Search API result to a Filing record
 func SearchResultToFiling(newsID, title, stockCode, stockName, dateTime, fileType, fileInfo, fileLink, longText string, companyID string) *Filing {
 	// Parse release time (format: "DD/MM/YYYY HH:MM")
-	reportDate := parseHKEXDate(dateTime)
+	reportDate, releasedAt := parseHKEXDate(dateTime)
 
 	// Determine language from title
 	language := detectLanguage(title)
@@ -200,12 +216,13 @@ func SearchResultToFiling(newsID, title, stockCode, stockName, dateTime, fileTyp
 
 	return &Filing{
 		ID:               GenerateID("fil"),
-		CompanyID:        companyID,
+		CompanyID:        NormalizeCompanyID("HKEX", companyID),
 		SourceID:         newsID,
 		Exchange:         "HKEX",
 		FilingType:       filingType,
 		FilingSubType:    "",
 		ReportDate:       reportDate,
+		ReleasedAt:       releasedAt,
 		Title:            title,
 		SourceURL:        buildDocumentURL(fileLink),
 		FileSize:         fileSize,
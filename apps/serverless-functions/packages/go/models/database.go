@@ -14,6 +14,8 @@ const (
 	ProcessingStatusFailed      ProcessingStatus = "FAILED"
 	ProcessingStatusURLFailure  ProcessingStatus = "URL_FAILURE"  // Source URL returns 404 or is broken
 	ProcessingStatusRateLimited ProcessingStatus = "RATE_LIMITED" // Rate limited by HKEX (403/429), can retry later
+	ProcessingStatusWithdrawn   ProcessingStatus = "WITHDRAWN"    // No longer listed at the source
+	ProcessingStatusReplaced    ProcessingStatus = "REPLACED"     // Source now points at a different document
 )
 
 // Language represents the document language
@@ -22,6 +24,7 @@ type Language string
 const (
 	LanguageEN    Language = "EN"
 	LanguageZH    Language = "ZH"
+	LanguageKO    Language = "KO"
 	LanguageMixed Language = "MIXED"
 )
 
@@ -29,24 +32,143 @@ const (
 type MarketType string
 
 const (
-	MarketTypeSEHK  MarketType = "SEHK"  // Main Board
-	MarketTypeGEM   MarketType = "GEM"   // Growth Enterprise Market
+	MarketTypeSEHK   MarketType = "SEHK"   // Main Board
+	MarketTypeGEM    MarketType = "GEM"    // Growth Enterprise Market
+	MarketTypeKOSPI  MarketType = "KOSPI"  // KRX main market
+	MarketTypeKOSDAQ MarketType = "KOSDAQ" // KRX growth market
+	MarketTypeKONEX  MarketType = "KONEX"  // KRX SME market
+
+	MarketTypeSSEMain  MarketType = "SSE_MAIN"  // Shanghai main board
+	MarketTypeSTAR     MarketType = "STAR"      // Shanghai STAR Market
+	MarketTypeSZSEMain MarketType = "SZSE_MAIN" // Shenzhen main board (incl. former SME board)
+	MarketTypeChiNext  MarketType = "CHINEXT"   // Shenzhen ChiNext
+	MarketTypeBShare   MarketType = "B_SHARE"   // Shanghai/Shenzhen B shares
+
+	MarketTypeTWSE MarketType = "TWSE" // Taiwan Stock Exchange (listed)
+	MarketTypeTPEx MarketType = "TPEX" // Taipei Exchange (OTC)
+
 	MarketTypeOther MarketType = "OTHER"
 )
 
 // Company represents a listed company (compatible with SmartDART)
 type Company struct {
 	ID            string     `json:"id" db:"id"`
-	StockCode     string     `json:"stockCode" db:"stock_code"`
+	StockCode     StockCode  `json:"stockCode" db:"stock_code"`
 	CompanyName   string     `json:"companyName" db:"company_name"`
 	CompanyNameEn string     `json:"companyNameEn,omitempty" db:"company_name_en"`
 	MarketType    MarketType `json:"marketType" db:"market_type"`
 	Industry      string     `json:"industry,omitempty" db:"industry"`
 	Exchange      string     `json:"exchange" db:"exchange"` // "HKEX", "DART", etc.
+
+	// Instrument category and sub-category from the exchange's securities
+	// list, e.g. "Real Estate Investment Trusts" / "Exchange Traded Funds"
+	InstrumentCategory    string `json:"instrumentCategory,omitempty" db:"instrument_category"`
+	InstrumentSubCategory string `json:"instrumentSubCategory,omitempty" db:"instrument_sub_category"`
+
 	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
 }
 
+// CompanyLinkType describes how two companies are related
+type CompanyLinkType string
+
+const (
+	CompanyLinkAH  CompanyLinkType = "A+H" // same issuer listed in mainland China and Hong Kong
+	CompanyLinkIPO CompanyLinkType = "IPO" // listing applicant (HKEX_APP) and the HKEX company it listed as
+)
+
+// CompanyLink relates companies on different exchanges that belong to the
+// same issuer, e.g. the A-share and H-share lines of a dual-listed bank
+type CompanyLink struct {
+	Exchange        string          `json:"exchange" db:"exchange"`
+	CompanyID       string          `json:"companyId" db:"company_id"`
+	LinkedExchange  string          `json:"linkedExchange" db:"linked_exchange"`
+	LinkedCompanyID string          `json:"linkedCompanyId" db:"linked_company_id"`
+	LinkType        CompanyLinkType `json:"linkType" db:"link_type"`
+	CreatedAt       time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// InterestNotice is a disclosure of interests notice filed with HKEX by a
+// substantial shareholder, director or chief executive of a listed company.
+// Share counts and percentages are of the filer's long position.
+type InterestNotice struct {
+	SerialNumber   string    `json:"serialNumber" db:"serial_number"` // form serial number, e.g. "CS20240315E00265"
+	Exchange       string    `json:"exchange" db:"exchange"`
+	CompanyID      string    `json:"companyId" db:"company_id"`
+	StockCode      StockCode `json:"stockCode" db:"stock_code"`
+	FormType       string    `json:"formType" db:"form_type"` // "1", "2", "3A", ...
+	FilerName      string    `json:"filerName" db:"filer_name"`
+	ReasonCode     string    `json:"reasonCode" db:"reason_code"`      // e.g. "1101" (acquisition)
+	Capacity       string    `json:"capacity,omitempty" db:"capacity"` // e.g. "2101 Beneficial owner"
+	SharesInvolved int64     `json:"sharesInvolved,omitempty" db:"shares_involved"`
+	AveragePrice   string    `json:"averagePrice,omitempty" db:"average_price"` // with currency, e.g. "HKD 38.2500"
+	SharesBefore   int64     `json:"sharesBefore" db:"shares_before"`
+	PercentBefore  float64   `json:"percentBefore" db:"percent_before"`
+	SharesAfter    int64     `json:"sharesAfter" db:"shares_after"`
+	PercentAfter   float64   `json:"percentAfter" db:"percent_after"`
+	EventDate      time.Time `json:"eventDate" db:"event_date"`
+	SourceURL      string    `json:"sourceUrl" db:"source_url"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// CCASSHolding is a CCASS participant's shareholding in a stock at the end
+// of a day, from the HKEXnews CCASS shareholding search
+type CCASSHolding struct {
+	Exchange        string    `json:"exchange" db:"exchange"`
+	StockCode       StockCode `json:"stockCode" db:"stock_code"`
+	HoldingDate     time.Time `json:"holdingDate" db:"holding_date"`
+	ParticipantID   string    `json:"participantId" db:"participant_id"` // e.g. "C00019"; consenting investors use their name
+	ParticipantName string    `json:"participantName" db:"participant_name"`
+	Address         string    `json:"address,omitempty" db:"address"`
+	Shareholding    int64     `json:"shareholding" db:"shareholding"`
+	Percent         float64   `json:"percent" db:"percent"` // of issued shares
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+}
+
+// CCASSChange is the change in a participant's shareholding between two
+// consecutive snapshots of a stock. Participants entering or leaving the
+// list change from or to zero.
+type CCASSChange struct {
+	Exchange        string    `json:"exchange" db:"exchange"`
+	StockCode       StockCode `json:"stockCode" db:"stock_code"`
+	HoldingDate     time.Time `json:"holdingDate" db:"holding_date"`
+	PreviousDate    time.Time `json:"previousDate" db:"previous_date"`
+	ParticipantID   string    `json:"participantId" db:"participant_id"`
+	ParticipantName string    `json:"participantName" db:"participant_name"`
+	SharesBefore    int64     `json:"sharesBefore" db:"shares_before"`
+	SharesAfter     int64     `json:"sharesAfter" db:"shares_after"`
+	Change          int64     `json:"change" db:"change"`
+	PercentBefore   float64   `json:"percentBefore" db:"percent_before"`
+	PercentAfter    float64   `json:"percentAfter" db:"percent_after"`
+}
+
+// CompanyListing records the period during which a stock code identified a
+// company. HKEX reassigns codes after delistings, so one code can belong to
+// several companies over time; filings are attributed to the company whose
+// listing covers the filing's report date.
+type CompanyListing struct {
+	ID         string     `json:"id" db:"id"`
+	CompanyID  string     `json:"companyId" db:"company_id"`
+	Exchange   string     `json:"exchange" db:"exchange"`
+	StockCode  StockCode  `json:"stockCode" db:"stock_code"`
+	ListedFrom *time.Time `json:"listedFrom,omitempty" db:"listed_from"` // nil = before our records begin
+	ListedTo   *time.Time `json:"listedTo,omitempty" db:"listed_to"`     // exclusive; nil = still listed
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// ActiveAt reports whether the listing covers the given date
+func (l *CompanyListing) ActiveAt(t time.Time) bool {
+	if l.ListedFrom != nil && t.Before(*l.ListedFrom) {
+		return false
+	}
+	if l.ListedTo != nil && !t.Before(*l.ListedTo) {
+		return false
+	}
+	return true
+}
+
 // Filing represents a filing/announcement (compatible with SmartDART)
 type Filing struct {
 	ID            string `json:"id" db:"id"`
@@ -55,7 +177,8 @@ type Filing struct {
 	Exchange      string `json:"exchange" db:"exchange"`        // "HKEX", "DART"
 	FilingType    string `json:"filingType" db:"filing_type"`   // HKEX: T1Code | DART: report_type
 	FilingSubType string `json:"filingSubType,omitempty" db:"filing_sub_type"` // HKEX: T2Code
-	ReportDate       time.Time        `json:"reportDate" db:"report_date"`
+	ReportDate       time.Time        `json:"reportDate" db:"report_date"` // business date
+	ReleasedAt       *time.Time       `json:"releasedAt,omitempty" db:"released_at"` // UTC, minute precision
 	Title            string           `json:"title" db:"title"`
 	TitleEn          string           `json:"titleEn,omitempty" db:"title_en"`
 	SourceURL        string           `json:"sourceUrl" db:"source_url"` // Original HKEX URL
@@ -68,6 +191,7 @@ type Filing struct {
 	ProcessingStatus ProcessingStatus `json:"processingStatus" db:"processing_status"`
 	ProcessingError  string           `json:"processingError,omitempty" db:"processing_error"`
 	IngestedAt       *time.Time       `json:"ingestedAt,omitempty" db:"ingested_at"`
+	TranslationOf    string           `json:"translationOf,omitempty" db:"translation_of"` // source ID of the other-language version
 	CreatedAt        time.Time        `json:"createdAt" db:"created_at"`
 	UpdatedAt        time.Time        `json:"updatedAt" db:"updated_at"`
 
@@ -76,6 +200,48 @@ type Filing struct {
 	Tables  []ExtractedTable `json:"tables,omitempty"`
 }
 
+// FilingEventType describes a change to a filing found at its source
+type FilingEventType string
+
+const (
+	FilingEventWithdrawn    FilingEventType = "WITHDRAWN"     // filing no longer listed at the source
+	FilingEventRestored     FilingEventType = "RESTORED"      // withdrawn filing listed again
+	FilingEventTitleChanged FilingEventType = "TITLE_CHANGED" // title differs from the source
+	FilingEventURLChanged   FilingEventType = "URL_CHANGED"   // document link differs from the source
+)
+
+// FilingEvent records a change to a stored filing, found by reconciling it
+// against its source
+type FilingEvent struct {
+	ID        int64           `json:"id" db:"id"`
+	Exchange  string          `json:"exchange" db:"exchange"`
+	SourceID  string          `json:"sourceId" db:"source_id"`
+	EventType FilingEventType `json:"eventType" db:"event_type"`
+	OldValue  string          `json:"oldValue,omitempty" db:"old_value"`
+	NewValue  string          `json:"newValue,omitempty" db:"new_value"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// FilingDocument is an attachment of a filing: a further document linked
+// from an HTML announcement or a multi-document index page. Attachments are
+// downloaded and stored like filings, with their own key and status.
+type FilingDocument struct {
+	ID               string           `json:"id" db:"document_id"` // <filing source ID>_<link hash>
+	Exchange         string           `json:"exchange" db:"exchange"`
+	FilingSourceID   string           `json:"filingSourceId" db:"filing_source_id"`
+	Seq              int              `json:"seq" db:"seq"`     // order of the link on the page
+	Title            string           `json:"title" db:"title"` // link text
+	SourceURL        string           `json:"sourceUrl" db:"source_url"`
+	FileExtension    string           `json:"fileExtension" db:"file_extension"`
+	PDFS3Key         string           `json:"pdfS3Key,omitempty" db:"pdf_s3_key"`
+	LocalPath        string           `json:"localPath,omitempty" db:"local_path"`
+	FileSize         int              `json:"fileSize,omitempty" db:"file_size"`
+	ProcessingStatus ProcessingStatus `json:"processingStatus" db:"processing_status"`
+	ProcessingError  string           `json:"processingError,omitempty" db:"processing_error"`
+	CreatedAt        time.Time        `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time        `json:"updatedAt" db:"updated_at"`
+}
+
 // ExtractedTable represents a table extracted from a document (compatible with SmartDART)
 type ExtractedTable struct {
 	ID         string        `json:"id" db:"id"`
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// HongKong is the Asia/Hong_Kong zone, in which HKEX publishes release times.
+// Hong Kong has not observed daylight saving since 1979, so a fixed UTC+8
+// zone stands in where the tz database is unavailable.
+var HongKong = loadLocation("Asia/Hong_Kong", 8*3600)
+
+func loadLocation(name string, offset int) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(name, offset)
+	}
+	return loc
+}
+
+// ParseHKEXTime parses an HKEX release time such as "28/03/2024 22:45",
+// which is Hong Kong time, and returns the UTC instant
+func ParseHKEXTime(s string) (time.Time, error) {
+	t, err := time.ParseInLocation("02/01/2006 15:04", strings.TrimSpace(s), HongKong)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// HKEXDate returns the Hong Kong calendar date of t as midnight UTC, the
+// form in which HKEX report dates are stored
+func HKEXDate(t time.Time) time.Time {
+	t = t.In(HongKong)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
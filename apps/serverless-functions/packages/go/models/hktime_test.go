@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchResultToFiling_ReleaseTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		dateTime     string
+		wantReleased time.Time
+		wantDate     time.Time
+	}{
+		{
+			name:         "Evening",
+			dateTime:     "28/03/2024 22:45",
+			wantReleased: time.Date(2024, 3, 28, 14, 45, 0, 0, time.UTC),
+			wantDate:     time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "Early morning",
+			dateTime:     "29/03/2024 07:30",
+			wantReleased: time.Date(2024, 3, 28, 23, 30, 0, 0, time.UTC),
+			wantDate:     time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := SearchResultToFiling("11223344", "Annual Results", "5", "HSBC HOLDINGS",
+				tt.dateTime, "PDF", "250KB", "/listedco/listconews/sehk/2024/0328/2024032800124.pdf",
+				"Announcements and Notices - [Final Results]", "")
+			if f.ReleasedAt == nil || !f.ReleasedAt.Equal(tt.wantReleased) {
+				t.Errorf("ReleasedAt = %v, want %v", f.ReleasedAt, tt.wantReleased)
+			}
+			if f.ReleasedAt != nil && f.ReleasedAt.Location() != time.UTC {
+				t.Errorf("ReleasedAt location = %v, want UTC", f.ReleasedAt.Location())
+			}
+			if !f.ReportDate.Equal(tt.wantDate) {
+				t.Errorf("ReportDate = %v, want %v", f.ReportDate, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestSearchResultToFiling_DateOnly(t *testing.T) {
+	f := SearchResultToFiling("11223344", "Annual Results", "5", "HSBC HOLDINGS",
+		"28/03/2024", "PDF", "250KB", "/listedco/listconews/sehk/2024/0328/2024032800124.pdf", "", "")
+	if f.ReleasedAt != nil {
+		t.Errorf("ReleasedAt = %v, want nil", f.ReleasedAt)
+	}
+	if want := time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC); !f.ReportDate.Equal(want) {
+		t.Errorf("ReportDate = %v, want %v", f.ReportDate, want)
+	}
+}
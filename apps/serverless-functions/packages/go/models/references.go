@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompanyReference is a pair of columns holding a company's (exchange,
+// company_id), with or without a foreign key to companies
+type CompanyReference struct {
+	Table          string
+	ExchangeColumn string
+	CompanyColumn  string
+	// Key is a unique key of the table that includes CompanyColumn; nil if
+	// the company is in none
+	Key []string
+	// Touch is set for tables whose updated_at follows row changes
+	Touch bool
+	// DateColumn dates each row, placing it in one listing period of a
+	// reused stock code; empty for undated rows
+	DateColumn string
+}
+
+// KeepsKey returns an SQL condition, for an UPDATE of Table aliased t setting
+// CompanyColumn to the placeholder target, that skips rows whose Key the
+// target company already has; "" if the table has no such key
+func (r CompanyReference) KeepsKey(target string) string {
+	if len(r.Key) == 0 {
+		return ""
+	}
+	conds := make([]string, len(r.Key))
+	for i, col := range r.Key {
+		if col == r.CompanyColumn {
+			conds[i] = "o." + col + " = " + target
+		} else {
+			conds[i] = "o." + col + " = t." + col
+		}
+	}
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s o WHERE %s)", r.Table, strings.Join(conds, " AND "))
+}
+
+// CompanyReferences lists every column pair referencing a company. Tools
+// that merge or re-key companies repoint all of them; add new tables here.
+var CompanyReferences = []CompanyReference{
+	{Table: "filings", ExchangeColumn: "exchange", CompanyColumn: "company_id", Touch: true, DateColumn: "report_date"},
+	{Table: "company_listings", ExchangeColumn: "exchange", CompanyColumn: "company_id", Touch: true},
+	{Table: "company_links", ExchangeColumn: "exchange", CompanyColumn: "company_id",
+		Key: []string{"exchange", "company_id", "linked_exchange", "linked_company_id"}},
+	{Table: "company_links", ExchangeColumn: "linked_exchange", CompanyColumn: "linked_company_id",
+		Key: []string{"exchange", "company_id", "linked_exchange", "linked_company_id"}},
+	{Table: "company_name_history", ExchangeColumn: "exchange", CompanyColumn: "company_id", DateColumn: "valid_from"},
+	{Table: "interest_notices", ExchangeColumn: "exchange", CompanyColumn: "company_id", DateColumn: "event_date"},
+	{Table: "interest_sync", ExchangeColumn: "exchange", CompanyColumn: "company_id",
+		Key: []string{"exchange", "company_id"}},
+	{Table: "ccass_snapshots", ExchangeColumn: "exchange", CompanyColumn: "company_id", DateColumn: "holding_date"},
+}
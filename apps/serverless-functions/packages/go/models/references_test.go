@@ -0,0 +1,17 @@
+package models
+
+import "testing"
+
+func TestKeepsKey(t *testing.T) {
+	links := CompanyReference{Table: "company_links", ExchangeColumn: "linked_exchange", CompanyColumn: "linked_company_id",
+		Key: []string{"exchange", "company_id", "linked_exchange", "linked_company_id"}}
+	want := "NOT EXISTS (SELECT 1 FROM company_links o WHERE o.exchange = t.exchange AND o.company_id = t.company_id" +
+		" AND o.linked_exchange = t.linked_exchange AND o.linked_company_id = $1)"
+	if got := links.KeepsKey("$1"); got != want {
+		t.Errorf("KeepsKey() = %q, want %q", got, want)
+	}
+
+	if got := (CompanyReference{Table: "filings", CompanyColumn: "company_id"}).KeepsKey("$1"); got != "" {
+		t.Errorf("KeepsKey() without a key = %q", got)
+	}
+}
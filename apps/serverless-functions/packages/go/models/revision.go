@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// FilingRevision records a change to a field of a stored filing, found when
+// the filing is scraped again
+type FilingRevision struct {
+	Exchange  string    `json:"exchange" db:"exchange"`
+	SourceID  string    `json:"sourceId" db:"source_id"`
+	Field     string    `json:"field" db:"field"` // column name, e.g. "source_url"
+	OldValue  string    `json:"oldValue" db:"old_value"`
+	NewValue  string    `json:"newValue" db:"new_value"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Revisions returns the changes between a stored filing and its newly
+// scraped version in the tracked fields: title, source URL, category, file
+// size and extension. An unknown (zero) new file size is not a change.
+func Revisions(stored, updated *Filing) []FilingRevision {
+	var revs []FilingRevision
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			revs = append(revs, FilingRevision{
+				Exchange:  stored.Exchange,
+				SourceID:  stored.SourceID,
+				Field:     field,
+				OldValue:  oldValue,
+				NewValue:  newValue,
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+
+	add("title", stored.Title, updated.Title)
+	add("source_url", stored.SourceURL, updated.SourceURL)
+	add("filing_type", stored.FilingType, updated.FilingType)
+	add("filing_sub_type", stored.FilingSubType, updated.FilingSubType)
+	if updated.FileSize > 0 {
+		add("file_size", strconv.Itoa(stored.FileSize), strconv.Itoa(updated.FileSize))
+	}
+	add("file_extension", stored.FileExtension, updated.FileExtension)
+	return revs
+}
+
+// DocumentChanged reports whether revisions change a filing's document, which
+// then has to be downloaded again
+func DocumentChanged(revs []FilingRevision) bool {
+	for _, r := range revs {
+		if r.Field == "source_url" {
+			return true
+		}
+	}
+	return false
+}
+
+// Revise prepares a scraped filing to update its stored version and returns
+// the changed fields. The stored download is kept unless the document
+// changed, in which case the filing is left pending to be downloaded again.
+// Every database writes the prepared filing's download fields as given.
+func Revise(existing, filing *Filing) []FilingRevision {
+	filing.ID = existing.ID
+	filing.CreatedAt = existing.CreatedAt
+
+	revisions := Revisions(existing, filing)
+	if DocumentChanged(revisions) {
+		filing.ProcessingStatus = ProcessingStatusPending
+		filing.PDFS3Key = ""
+		filing.LocalPath = ""
+		filing.ProcessingError = ""
+		return revisions
+	}
+
+	filing.PDFS3Key = existing.PDFS3Key
+	filing.LocalPath = existing.LocalPath
+	filing.PageCount = existing.PageCount
+	filing.ProcessingStatus = existing.ProcessingStatus
+	filing.ProcessingError = existing.ProcessingError
+	if filing.FileSize == 0 {
+		filing.FileSize = existing.FileSize
+	}
+	return revisions
+}
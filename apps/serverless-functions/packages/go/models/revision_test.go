@@ -0,0 +1,94 @@
+package models
+
+import "testing"
+
+func TestRevisions(t *testing.T) {
+	stored := &Filing{
+		Exchange:      "HKEX",
+		SourceID:      "11223344",
+		Title:         "Annual Results",
+		SourceURL:     "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800124.pdf",
+		FilingType:    "Announcements and Notices",
+		FileSize:      250000,
+		FileExtension: "pdf",
+	}
+
+	tests := []struct {
+		name       string
+		update     func(f *Filing)
+		wantFields []string
+		wantReset  bool
+	}{
+		{name: "Unchanged", update: func(f *Filing) {}},
+		{name: "Unknown size", update: func(f *Filing) { f.FileSize = 0 }},
+		{name: "Title", update: func(f *Filing) { f.Title = "Annual Results (Revised)" }, wantFields: []string{"title"}},
+		{name: "Size", update: func(f *Filing) { f.FileSize = 260000 }, wantFields: []string{"file_size"}},
+		{
+			name: "Replaced document",
+			update: func(f *Filing) {
+				f.SourceURL = "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800124.htm"
+				f.FileExtension = "htm"
+			},
+			wantFields: []string{"source_url", "file_extension"},
+			wantReset:  true,
+		},
+		{
+			name:       "Category",
+			update:     func(f *Filing) { f.FilingType, f.FilingSubType = "Financial Statements", "Final Results" },
+			wantFields: []string{"filing_type", "filing_sub_type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := *stored
+			tt.update(&updated)
+
+			revs := Revisions(stored, &updated)
+			if len(revs) != len(tt.wantFields) {
+				t.Fatalf("got %d revisions %+v, want fields %v", len(revs), revs, tt.wantFields)
+			}
+			for i, r := range revs {
+				if r.Field != tt.wantFields[i] {
+					t.Errorf("revision %d field = %s, want %s", i, r.Field, tt.wantFields[i])
+				}
+				if r.Exchange != "HKEX" || r.SourceID != "11223344" {
+					t.Errorf("revision %d filing = %s:%s", i, r.Exchange, r.SourceID)
+				}
+			}
+			if got := DocumentChanged(revs); got != tt.wantReset {
+				t.Errorf("DocumentChanged() = %v, want %v", got, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestRevise(t *testing.T) {
+	existing := &Filing{
+		ID:               "HKEX:11223344",
+		Exchange:         "HKEX",
+		SourceID:         "11223344",
+		SourceURL:        "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800124.pdf",
+		PDFS3Key:         "HKEX/00001/2024/11223344.pdf",
+		PageCount:        12,
+		FileSize:         250000,
+		ProcessingStatus: ProcessingStatusCompleted,
+	}
+
+	kept := &Filing{Exchange: "HKEX", SourceID: "11223344", SourceURL: existing.SourceURL}
+	if revs := Revise(existing, kept); len(revs) != 0 {
+		t.Fatalf("unchanged filing: got revisions %+v", revs)
+	}
+	if kept.ID != existing.ID || kept.PDFS3Key != existing.PDFS3Key || kept.PageCount != 12 ||
+		kept.FileSize != 250000 || kept.ProcessingStatus != ProcessingStatusCompleted {
+		t.Errorf("unchanged filing lost its download: %+v", kept)
+	}
+
+	replaced := &Filing{Exchange: "HKEX", SourceID: "11223344", SourceURL: existing.SourceURL + "?v=2"}
+	if revs := Revise(existing, replaced); !DocumentChanged(revs) {
+		t.Fatalf("replaced document: got revisions %+v", revs)
+	}
+	if replaced.PDFS3Key != "" || replaced.ProcessingStatus != ProcessingStatusPending {
+		t.Errorf("replaced document not reset: %+v", replaced)
+	}
+}
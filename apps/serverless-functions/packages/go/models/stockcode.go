@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StockCode is a normalised HKEX stock code: five zero-padded digits, e.g.
+// "00001". Codes arrive as "1", "00001", "com_00001" (legacy company IDs) or
+// exchange-qualified forms like "HKEX:00001" and "0001.HK"; ParseStockCode
+// accepts all of them so every package stores the same value. Codes of other
+// exchanges are held as NormalizeStockCode returns them.
+type StockCode string
+
+// StockCodeRange classifies a stock code by the HKEX numeric range it falls in
+type StockCodeRange string
+
+const (
+	StockCodeRangeEquity            StockCodeRange = "EQUITY"             // 00001-09999 (Main Board, GEM, ETFs, REITs, debt)
+	StockCodeRangeDerivativeWarrant StockCodeRange = "DERIVATIVE_WARRANT" // 10000-29999
+	StockCodeRangeCBBC              StockCodeRange = "CBBC"               // 50000-69999
+	StockCodeRangeInlineWarrant     StockCodeRange = "INLINE_WARRANT"     // 80000-89999
+	StockCodeRangeOther             StockCodeRange = "OTHER"
+)
+
+// stockCodeDigits is the width of a normalised HKEX stock code
+const stockCodeDigits = 5
+
+// ParseStockCode parses and normalises a stock code in any of the formats
+// seen across the pipeline
+func ParseStockCode(s string) (StockCode, error) {
+	code := strings.TrimSpace(s)
+	code = strings.TrimPrefix(code, "com_")
+
+	if exchange, rest, ok := strings.Cut(code, ":"); ok {
+		if !strings.EqualFold(exchange, "HKEX") {
+			return "", fmt.Errorf("stock code %q: unsupported exchange %q", s, exchange)
+		}
+		code = rest
+	}
+	if len(code) > 3 && strings.EqualFold(code[len(code)-3:], ".HK") {
+		code = code[:len(code)-3]
+	}
+
+	if code == "" || len(code) > stockCodeDigits {
+		return "", fmt.Errorf("stock code %q: must be 1-%d digits", s, stockCodeDigits)
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil || n <= 0 || strings.ContainsAny(code, "+-") {
+		return "", fmt.Errorf("stock code %q: not a positive number", s)
+	}
+
+	return StockCode(fmt.Sprintf("%0*d", stockCodeDigits, n)), nil
+}
+
+// NormalizeStockCode returns the normalised form of an exchange's stock code.
+// Only HKEX codes are reformatted; codes for other exchanges, and HKEX codes
+// that fail to parse, are returned trimmed of whitespace.
+func NormalizeStockCode(exchange, s string) StockCode {
+	if exchange != "HKEX" {
+		return StockCode(strings.TrimSpace(s))
+	}
+	code, err := ParseStockCode(s)
+	if err != nil {
+		return StockCode(strings.TrimSpace(s))
+	}
+	return code
+}
+
+// NormalizeCompanyID normalises HKEX company IDs derived from stock codes
+// ("1", "com_00001") to the five-digit form. IDs of companies on a recycled
+// code ("01234_20240105") keep their suffix, and the legacy "com_" prefix is
+// stripped for every exchange.
+func NormalizeCompanyID(exchange, id string) string {
+	id = strings.TrimPrefix(id, "com_")
+	if exchange != "HKEX" {
+		return id
+	}
+	if code, err := ParseStockCode(id); err == nil {
+		return code.String()
+	}
+	return id
+}
+
+// String returns the five-digit code
+func (c StockCode) String() string {
+	return string(c)
+}
+
+// Int returns the numeric value of the code, or 0 for the zero value
+func (c StockCode) Int() int {
+	n, _ := strconv.Atoi(string(c))
+	return n
+}
+
+// Qualified returns the code prefixed with its exchange, e.g. "HKEX:00001"
+func (c StockCode) Qualified(exchange string) string {
+	return exchange + ":" + string(c)
+}
+
+// Range returns the HKEX numeric range the code falls in
+func (c StockCode) Range() StockCodeRange {
+	n := c.Int()
+	switch {
+	case n >= 1 && n <= 9999:
+		return StockCodeRangeEquity
+	case n >= 10000 && n <= 29999:
+		return StockCodeRangeDerivativeWarrant
+	case n >= 50000 && n <= 69999:
+		return StockCodeRangeCBBC
+	case n >= 80000 && n <= 89999:
+		return StockCodeRangeInlineWarrant
+	}
+	return StockCodeRangeOther
+}
+
+// IsStructuredProduct reports whether the code is in a structured product
+// range (Derivative Warrants, CBBCs, Inline Warrants)
+func (c StockCode) IsStructuredProduct() bool {
+	switch c.Range() {
+	case StockCodeRangeDerivativeWarrant, StockCodeRangeCBBC, StockCodeRangeInlineWarrant:
+		return true
+	}
+	return false
+}
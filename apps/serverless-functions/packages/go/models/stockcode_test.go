@@ -0,0 +1,91 @@
+package models
+
+import "testing"
+
+func TestParseStockCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected StockCode
+		wantErr  bool
+	}{
+		{name: "Unpadded", input: "1", expected: "00001"},
+		{name: "Padded", input: "00001", expected: "00001"},
+		{name: "Four digits", input: "0700", expected: "00700"},
+		{name: "Legacy company ID", input: "com_00001", expected: "00001"},
+		{name: "Exchange qualified", input: "HKEX:5", expected: "00005"},
+		{name: "Yahoo suffix", input: "0005.HK", expected: "00005"},
+		{name: "Whitespace", input: " 388 ", expected: "00388"},
+		{name: "Structured product", input: "12345", expected: "12345"},
+		{name: "Empty", input: "", wantErr: true},
+		{name: "Zero", input: "00000", wantErr: true},
+		{name: "Too long", input: "123456", wantErr: true},
+		{name: "Non-numeric", input: "ABC", wantErr: true},
+		{name: "Signed", input: "+1", wantErr: true},
+		{name: "Other exchange", input: "DART:005930", wantErr: true},
+		{name: "Recycled company ID", input: "01234_20240105", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseStockCode(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseStockCode(%q) = %q, want error", tt.input, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStockCode(%q) error: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseStockCode(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStockCodeRange(t *testing.T) {
+	tests := []struct {
+		code       StockCode
+		expected   StockCodeRange
+		structured bool
+	}{
+		{"00001", StockCodeRangeEquity, false},
+		{"08001", StockCodeRangeEquity, false},
+		{"12345", StockCodeRangeDerivativeWarrant, true},
+		{"55555", StockCodeRangeCBBC, true},
+		{"85000", StockCodeRangeInlineWarrant, true},
+		{"40001", StockCodeRangeOther, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			if got := tt.code.Range(); got != tt.expected {
+				t.Errorf("Range() = %q, want %q", got, tt.expected)
+			}
+			if got := tt.code.IsStructuredProduct(); got != tt.structured {
+				t.Errorf("IsStructuredProduct() = %v, want %v", got, tt.structured)
+			}
+		})
+	}
+}
+
+func TestNormalizeCompanyID(t *testing.T) {
+	tests := []struct {
+		exchange string
+		input    string
+		expected string
+	}{
+		{"HKEX", "1", "00001"},
+		{"HKEX", "com_00001", "00001"},
+		{"HKEX", "01234_20240105", "01234_20240105"},
+		{"HKEX", "com_abc", "abc"},
+		{"TWSE", "2330", "2330"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCompanyID(tt.exchange, tt.input); got != tt.expected {
+			t.Errorf("NormalizeCompanyID(%q, %q) = %q, want %q", tt.exchange, tt.input, got, tt.expected)
+		}
+	}
+}
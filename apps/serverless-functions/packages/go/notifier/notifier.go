@@ -0,0 +1,90 @@
+// Package notifier sends workflow notifications, through SNS or, for local
+// runs and tests, to a writer or memory.
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// Notifier sends a notification, returning its message ID
+type Notifier interface {
+	Notify(ctx context.Context, subject, message string) (string, error)
+}
+
+// SNSNotifier publishes notifications to an SNS topic
+type SNSNotifier struct {
+	client   *sns.Client
+	topicARN string
+}
+
+// NewSNSNotifier creates a notifier for an SNS topic
+func NewSNSNotifier(client *sns.Client, topicARN string) *SNSNotifier {
+	return &SNSNotifier{client: client, topicARN: topicARN}
+}
+
+// Notify publishes a notification to the topic
+func (n *SNSNotifier) Notify(ctx context.Context, subject, message string) (string, error) {
+	out, err := n.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.topicARN),
+		Subject:  aws.String(subject),
+		Message:  aws.String(message),
+	})
+	if err != nil {
+		return "", fmt.Errorf("publishing to SNS: %w", err)
+	}
+	return aws.ToString(out.MessageId), nil
+}
+
+// Notification is a notification sent to a MemoryNotifier
+type Notification struct {
+	ID      string
+	Subject string
+	Message string
+}
+
+// MemoryNotifier records notifications. If W is set they are also printed
+// to it.
+type MemoryNotifier struct {
+	W io.Writer
+
+	mu   sync.Mutex
+	sent []Notification
+}
+
+// NewMemoryNotifier creates a notifier that records notifications
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// NewWriterNotifier creates a notifier that prints notifications to w
+func NewWriterNotifier(w io.Writer) *MemoryNotifier {
+	return &MemoryNotifier{W: w}
+}
+
+// Notify records a notification
+func (n *MemoryNotifier) Notify(ctx context.Context, subject, message string) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := strconv.Itoa(len(n.sent) + 1)
+	n.sent = append(n.sent, Notification{ID: id, Subject: subject, Message: message})
+	if n.W != nil {
+		if _, err := fmt.Fprintf(n.W, "Subject: %s\n\n%s\n", subject, message); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// Sent returns the notifications sent so far
+func (n *MemoryNotifier) Sent() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notification(nil), n.sent...)
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultVisibilityTimeout is how long a MemoryQueue hides received messages
+const DefaultVisibilityTimeout = 30 * time.Second
+
+// MemoryQueue implements Queue in memory. Receive does not wait for messages.
+type MemoryQueue struct {
+	visibility time.Duration
+
+	mu       sync.Mutex
+	nextID   int
+	receipts int
+	messages []*memoryMessage
+}
+
+type memoryMessage struct {
+	id      string
+	body    string
+	receipt string
+	visible time.Time // hidden until then after a receive
+}
+
+// NewMemoryQueue creates an empty queue. Received messages not acknowledged
+// within visibility (DefaultVisibilityTimeout if 0) are delivered again.
+func NewMemoryQueue(visibility time.Duration) *MemoryQueue {
+	if visibility <= 0 {
+		visibility = DefaultVisibilityTimeout
+	}
+	return &MemoryQueue{visibility: visibility}
+}
+
+// SendBatch appends messages to the queue
+func (q *MemoryQueue) SendBatch(ctx context.Context, bodies []string) error {
+	if err := ctx.Err(); err != nil {
+		failed := make([]BatchFailure, len(bodies))
+		for i := range bodies {
+			failed[i] = BatchFailure{Index: i, Err: err}
+		}
+		return &BatchError{Failed: failed}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, body := range bodies {
+		q.nextID++
+		q.messages = append(q.messages, &memoryMessage{id: strconv.Itoa(q.nextID), body: body})
+	}
+	return nil
+}
+
+// Receive returns up to limit visible messages, oldest first
+func (q *MemoryQueue) Receive(ctx context.Context, limit int) ([]Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	var msgs []Message
+	for _, m := range q.messages {
+		if len(msgs) >= limit {
+			break
+		}
+		if m.visible.After(now) {
+			continue
+		}
+		q.receipts++
+		m.receipt = m.id + "-" + strconv.Itoa(q.receipts)
+		m.visible = now.Add(q.visibility)
+		msgs = append(msgs, Message{ID: m.id, Body: m.body, ReceiptHandle: m.receipt})
+	}
+	return msgs, nil
+}
+
+// Ack deletes messages. A receipt superseded by a later receive is ignored,
+// as SQS does once the message is delivered again.
+func (q *MemoryQueue) Ack(ctx context.Context, msgs ...Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	acked := make(map[string]bool, len(msgs))
+	for _, m := range msgs {
+		acked[m.ReceiptHandle] = true
+	}
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		if m.receipt == "" || !acked[m.receipt] {
+			kept = append(kept, m)
+		}
+	}
+	q.messages = kept
+	return nil
+}
+
+// Len returns the number of messages not yet acknowledged
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
@@ -0,0 +1,63 @@
+// Package queue defines the message queue the orchestrator and workers share,
+// with an SQS implementation and an in-memory one for local runs and tests.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Message is a received message
+type Message struct {
+	ID   string
+	Body string
+
+	// ReceiptHandle identifies this receipt of the message for Ack
+	ReceiptHandle string
+}
+
+// Queue is an at-least-once message queue. Received messages are hidden from
+// other receivers until acknowledged, or delivered again once their
+// visibility timeout expires.
+type Queue interface {
+	// SendBatch sends messages. If some could not be sent the error is a
+	// *BatchError listing them; the others were sent.
+	SendBatch(ctx context.Context, bodies []string) error
+	// Receive returns up to limit messages, waiting briefly if none are
+	// visible; it returns no messages rather than blocking indefinitely
+	Receive(ctx context.Context, limit int) ([]Message, error)
+	// Ack deletes processed messages
+	Ack(ctx context.Context, msgs ...Message) error
+}
+
+// BatchFailure is a message of a batch that could not be sent
+type BatchFailure struct {
+	Index int // index in the batch
+	Err   error
+}
+
+// BatchError reports the messages of a batch that could not be sent
+type BatchError struct {
+	Failed []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Failed) == 1 {
+		return fmt.Sprintf("sending message %d: %v", e.Failed[0].Index, e.Failed[0].Err)
+	}
+	msgs := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%d: %v", f.Index, f.Err))
+	}
+	return fmt.Sprintf("sending %d messages failed (%s)", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+// FailedIndexes returns the set of batch indexes that were not sent
+func (e *BatchError) FailedIndexes() map[int]bool {
+	failed := make(map[int]bool, len(e.Failed))
+	for _, f := range e.Failed {
+		failed[f.Index] = true
+	}
+	return failed
+}
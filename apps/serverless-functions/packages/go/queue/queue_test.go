@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(20 * time.Millisecond)
+
+	if err := q.SendBatch(ctx, []string{"a", "b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := q.Receive(ctx, 2)
+	if len(msgs) != 2 || msgs[0].Body != "a" || msgs[1].Body != "b" {
+		t.Fatalf("Receive() = %+v", msgs)
+	}
+	// Received messages are hidden
+	rest, _ := q.Receive(ctx, 10)
+	if len(rest) != 1 || rest[0].Body != "c" {
+		t.Fatalf("second Receive() = %+v", rest)
+	}
+
+	// a is processed; b fails and is delivered again after the timeout
+	q.Ack(ctx, msgs[0], rest[0])
+	time.Sleep(30 * time.Millisecond)
+	again, _ := q.Receive(ctx, 10)
+	if len(again) != 1 || again[0].Body != "b" {
+		t.Fatalf("Receive() after timeout = %+v", again)
+	}
+
+	// The stale receipt no longer deletes it
+	q.Ack(ctx, msgs[1])
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d after stale ack, want 1", q.Len())
+	}
+	q.Ack(ctx, again[0])
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", q.Len())
+	}
+}
+
+func TestMemoryQueue_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewMemoryQueue(0).SendBatch(ctx, []string{"a", "b"})
+	be, ok := err.(*BatchError)
+	if !ok || len(be.FailedIndexes()) != 2 {
+		t.Errorf("SendBatch() error = %v, want both messages failed", err)
+	}
+}
+
+func TestSQSQueue_SendBatch(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// The first request rejects entry 3; the second fails outright
+		if requests == 2 {
+			http.Error(w, `{"__type":"InternalError"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		fmt.Fprint(w, `{"Successful":[],"Failed":[{"Id":"3","SenderFault":true,"Code":"InvalidMessageContents","Message":"bad"}]}`)
+	}))
+	defer srv.Close()
+
+	clients, err := awsclient.New(context.Background(), awsclient.Options{
+		Endpoint: srv.URL, Region: "us-east-1", AccessKeyID: "test", SecretAccessKey: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := sqs.NewFromConfig(clients.Config(), func(o *sqs.Options) { o.RetryMaxAttempts = 1 })
+	q := NewSQSQueue(client, srv.URL+"/000000000000/downloads")
+
+	bodies := make([]string, 15)
+	for i := range bodies {
+		bodies[i] = "{}"
+	}
+	err = q.SendBatch(context.Background(), bodies)
+	be, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("SendBatch() error = %v, want *BatchError", err)
+	}
+	failed := be.FailedIndexes()
+	if len(failed) != 6 || !failed[3] || !failed[10] || !failed[14] {
+		t.Errorf("failed = %v, want 3 and 10-14", failed)
+	}
+}
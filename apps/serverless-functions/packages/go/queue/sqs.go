@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sqsBatchLimit is the most entries an SQS batch request takes
+const sqsBatchLimit = 10
+
+// SQSQueue implements Queue for an SQS queue
+type SQSQueue struct {
+	client *sqs.Client
+	url    string
+
+	// WaitSeconds is how long Receive long-polls for messages (default: 20)
+	WaitSeconds int32
+}
+
+// NewSQSQueue creates a queue for the SQS queue at url
+func NewSQSQueue(client *sqs.Client, url string) *SQSQueue {
+	return &SQSQueue{client: client, url: url, WaitSeconds: 20}
+}
+
+// URL returns the queue URL
+func (q *SQSQueue) URL() string {
+	return q.url
+}
+
+// SendBatch sends messages in batches of ten. A request that fails outright
+// fails its messages and those after it.
+func (q *SQSQueue) SendBatch(ctx context.Context, bodies []string) error {
+	var failed []BatchFailure
+	for start := 0; start < len(bodies); start += sqsBatchLimit {
+		end := min(start+sqsBatchLimit, len(bodies))
+
+		entries := make([]types.SendMessageBatchRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(i)),
+				MessageBody: aws.String(bodies[i]),
+			})
+		}
+
+		out, err := q.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(q.url),
+			Entries:  entries,
+		})
+		if err != nil {
+			for i := start; i < len(bodies); i++ {
+				failed = append(failed, BatchFailure{Index: i, Err: err})
+			}
+			break
+		}
+		for _, f := range out.Failed {
+			i, _ := strconv.Atoi(aws.ToString(f.Id))
+			failed = append(failed, BatchFailure{
+				Index: i,
+				Err:   fmt.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message)),
+			})
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BatchError{Failed: failed}
+	}
+	return nil
+}
+
+// Receive long-polls for up to limit messages (at most ten)
+func (q *SQSQueue) Receive(ctx context.Context, limit int) ([]Message, error) {
+	out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.url),
+		MaxNumberOfMessages: int32(min(max(limit, 1), sqsBatchLimit)),
+		WaitTimeSeconds:     q.WaitSeconds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("receiving messages: %w", err)
+	}
+
+	msgs := make([]Message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		msgs = append(msgs, Message{
+			ID:            aws.ToString(m.MessageId),
+			Body:          aws.ToString(m.Body),
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+		})
+	}
+	return msgs, nil
+}
+
+// Ack deletes messages in batches of ten
+func (q *SQSQueue) Ack(ctx context.Context, msgs ...Message) error {
+	var errs []error
+	for start := 0; start < len(msgs); start += sqsBatchLimit {
+		end := min(start+sqsBatchLimit, len(msgs))
+
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(msgs[i].ReceiptHandle),
+			})
+		}
+
+		out, err := q.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(q.url),
+			Entries:  entries,
+		})
+		if err != nil {
+			return fmt.Errorf("deleting messages: %w", err)
+		}
+		for _, f := range out.Failed {
+			errs = append(errs, fmt.Errorf("deleting message %s: %s", aws.ToString(f.Id), aws.ToString(f.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
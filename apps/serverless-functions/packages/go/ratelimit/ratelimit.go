@@ -0,0 +1,105 @@
+// Package ratelimit spaces out the requests of the exchange API clients.
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter allows one request per interval. Callers wait in turn; a nil
+// Limiter doesn't limit.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// New returns a limiter of perSecond requests per second, or nil (no limit)
+// if perSecond is not positive
+func New(perSecond int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the next request may be made or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elapsed := time.Since(l.last); elapsed < l.interval {
+		t := time.NewTimer(l.interval - elapsed)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	l.last = time.Now()
+	return nil
+}
+
+var (
+	sitesMu sync.Mutex
+	sites   = map[string]*Limiter{}
+)
+
+// ForHost returns the process's limiter for the site serving rawURL, created
+// at perSecond requests per second by its first caller. Clients of one site
+// share it, so www1.hkexnews.hk and www3.hkexnews.hk stay within one rate
+// between them. It returns nil (no limit) if perSecond is not positive.
+func ForHost(rawURL string, perSecond int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	key := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		key = site(u.Hostname())
+	}
+
+	sitesMu.Lock()
+	defer sitesMu.Unlock()
+	l, ok := sites[key]
+	if !ok {
+		l = New(perSecond)
+		sites[key] = l
+	}
+	return l
+}
+
+// secondLevel are the labels under a country code that are not a site of
+// their own, as in hkex.com.hk or fss.or.kr
+var secondLevel = map[string]bool{
+	"ac": true, "co": true, "com": true, "edu": true, "gov": true, "net": true, "or": true, "org": true,
+}
+
+// site returns the registered domain of host: its last two labels, or three
+// under a generic second level. IP addresses and single labels are their own
+// site.
+func site(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(strings.ToLower(host), ".")
+	n := 2
+	if len(labels) > 2 && secondLevel[labels[len(labels)-2]] {
+		n = 3
+	}
+	if len(labels) <= n {
+		return strings.Join(labels, ".")
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	l := New(20) // 50ms apart
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests took %s, want at least 100ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(cancelled); err == nil {
+		t.Error("Wait() with a cancelled context succeeded during the interval")
+	}
+
+	unlimited := New(0)
+	if unlimited != nil || unlimited.Wait(cancelled) != nil {
+		t.Error("New(0) limits")
+	}
+}
+
+func TestForHost(t *testing.T) {
+	search := ForHost("https://www1.hkexnews.hk", 2)
+	if search == nil || ForHost("https://www3.hkexnews.hk/sdw/search", 5) != search {
+		t.Error("ForHost() returned different limiters for one site")
+	}
+	if ForHost("https://di.hkex.com.hk", 2) == search {
+		t.Error("ForHost() shared a limiter across sites")
+	}
+	if ForHost("https://www1.hkexnews.hk", 0) != nil {
+		t.Error("ForHost() with no rate limits")
+	}
+
+	tests := map[string]string{
+		"www1.hkexnews.hk":   "hkexnews.hk",
+		"di.hkex.com.hk":     "hkex.com.hk",
+		"opendart.fss.or.kr": "fss.or.kr",
+		"mops.twse.com.tw":   "twse.com.tw",
+		"localhost":          "localhost",
+		"127.0.0.1":          "127.0.0.1",
+	}
+	for host, want := range tests {
+		if got := site(host); got != want {
+			t.Errorf("site(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
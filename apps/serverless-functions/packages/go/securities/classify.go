@@ -0,0 +1,193 @@
+package securities
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// Securities list categories
+const (
+	CategoryEquity            = "Equity"
+	CategoryREIT              = "Real Estate Investment Trusts"
+	CategoryETP               = "Exchange Traded Products"
+	CategoryDebt              = "Debt Securities"
+	CategoryDerivativeWarrant = "Derivative Warrants"
+	CategoryCBBC              = "Callable Bull/Bear Contracts"
+	CategoryInlineWarrant     = "Inline Warrants"
+)
+
+// DefaultSkipCategories are structured products we skip entirely.
+// DWs, CBBCs, and Inline Warrants are thousands of short-lived leveraged
+// products with repetitive boilerplate filings — they bloat storage and
+// clutter search results for fundamental researchers.
+var DefaultSkipCategories = []string{
+	CategoryDerivativeWarrant,
+	CategoryCBBC,
+	CategoryInlineWarrant,
+}
+
+// Classification is the instrument category of a stock code
+type Classification struct {
+	Category    string
+	SubCategory string
+	FromList    bool // false when guessed from the stock code range
+}
+
+// Apply copies a list-derived classification onto a company and reports
+// whether it changed. Range-based guesses are not stored on companies.
+func (c Classification) Apply(company *models.Company) bool {
+	if !c.FromList {
+		return false
+	}
+	if company.InstrumentCategory == c.Category && company.InstrumentSubCategory == c.SubCategory {
+		return false
+	}
+	company.InstrumentCategory = c.Category
+	company.InstrumentSubCategory = c.SubCategory
+	return true
+}
+
+// Classifier classifies stock codes from a securities list. A nil Classifier
+// classifies by stock code range only.
+type Classifier struct {
+	byCode map[models.StockCode]Security
+}
+
+// NewClassifier creates a Classifier from a securities list
+func NewClassifier(list *List) *Classifier {
+	c := &Classifier{byCode: make(map[models.StockCode]Security, len(list.Securities))}
+	for _, s := range list.Securities {
+		c.byCode[s.StockCode] = s
+	}
+	return c
+}
+
+// LoadClassifier fetches the securities list at url and builds a Classifier
+func LoadClassifier(ctx context.Context, url string) (*Classifier, error) {
+	list, err := Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewClassifier(list), nil
+}
+
+// LoadClassifierWithFallback is LoadClassifier for callers that can run
+// without the list: on failure it logs a warning and returns nil, which
+// classifies by stock code range.
+func LoadClassifierWithFallback(ctx context.Context, url string) *Classifier {
+	c, err := LoadClassifier(ctx, url)
+	if err != nil {
+		log.Printf("Warning: securities list unavailable, classifying by stock code range: %v", err)
+		return nil
+	}
+	log.Printf("Loaded %d securities for classification", c.Len())
+	return c
+}
+
+// ClassifierTTL is how long CachedClassifier reuses a securities list. HKEX
+// publishes a new list every trading day.
+const ClassifierTTL = 24 * time.Hour
+
+var classifierCache struct {
+	mu      sync.Mutex
+	url     string
+	fetched time.Time
+	c       *Classifier
+}
+
+// CachedClassifier is LoadClassifierWithFallback for long-lived processes
+// such as warm Lambda containers: the list is fetched again only after
+// ClassifierTTL or for a different url. Failed fetches are not cached.
+func CachedClassifier(ctx context.Context, url string) *Classifier {
+	classifierCache.mu.Lock()
+	defer classifierCache.mu.Unlock()
+
+	if classifierCache.c != nil && classifierCache.url == url && time.Since(classifierCache.fetched) < ClassifierTTL {
+		return classifierCache.c
+	}
+	c := LoadClassifierWithFallback(ctx, url)
+	if c != nil {
+		classifierCache.url, classifierCache.fetched, classifierCache.c = url, time.Now(), c
+	}
+	return c
+}
+
+// Classify returns the category of a stock code from the securities list,
+// falling back to the code's numeric range for codes not in the list
+func (c *Classifier) Classify(stockCode string) Classification {
+	code, err := models.ParseStockCode(stockCode)
+	if err != nil {
+		return Classification{}
+	}
+
+	if c != nil {
+		if s, ok := c.byCode[code]; ok {
+			return Classification{Category: s.Category, SubCategory: s.SubCategory, FromList: true}
+		}
+	}
+
+	return Classification{Category: RangeCategory(code)}
+}
+
+// Len returns the number of securities the Classifier knows about
+func (c *Classifier) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.byCode)
+}
+
+// RangeCategory guesses a category from the stock code range. Codes in the
+// 00001-09999 range are reported as Equity although they also cover ETFs,
+// REITs and debt securities.
+func RangeCategory(code models.StockCode) string {
+	switch code.Range() {
+	case models.StockCodeRangeEquity:
+		return CategoryEquity
+	case models.StockCodeRangeDerivativeWarrant:
+		return CategoryDerivativeWarrant
+	case models.StockCodeRangeCBBC:
+		return CategoryCBBC
+	case models.StockCodeRangeInlineWarrant:
+		return CategoryInlineWarrant
+	}
+	return ""
+}
+
+// Filter decides which instrument categories the scraper skips
+type Filter struct {
+	skip map[string]bool
+}
+
+// SkipCategories returns the configured categories to skip, or
+// DefaultSkipCategories if none are configured
+func SkipCategories(configured []string) []string {
+	if configured == nil {
+		return DefaultSkipCategories
+	}
+	return configured
+}
+
+// NewFilter creates a Filter skipping the given categories or sub-categories
+// (case-insensitive), e.g. "Exchange Traded Funds"; nil skips
+// DefaultSkipCategories
+func NewFilter(skip []string) *Filter {
+	skip = SkipCategories(skip)
+	f := &Filter{skip: make(map[string]bool, len(skip))}
+	for _, s := range skip {
+		if s = strings.TrimSpace(s); s != "" {
+			f.skip[strings.ToLower(s)] = true
+		}
+	}
+	return f
+}
+
+// Skip reports whether filings for the classification should be skipped
+func (f *Filter) Skip(c Classification) bool {
+	return f.skip[strings.ToLower(c.Category)] || f.skip[strings.ToLower(c.SubCategory)]
+}
@@ -0,0 +1,166 @@
+package securities
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// EventType is a change to a security between two securities list snapshots
+type EventType string
+
+const (
+	EventListed          EventType = "LISTED"
+	EventDelisted        EventType = "DELISTED"
+	EventRenamed         EventType = "RENAMED"
+	EventCategoryChanged EventType = "CATEGORY_CHANGED"
+	EventBoardTransfer   EventType = "BOARD_TRANSFER" // e.g. GEM -> Main Board
+	EventLotSizeChanged  EventType = "LOT_SIZE_CHANGED"
+)
+
+// Event is one change to a security. OldValue is empty for listings and
+// NewValue is empty for delistings; both hold names for listings, delistings
+// and renames, "category / sub-category" for category changes and board
+// transfers, and board lots for lot size changes.
+type Event struct {
+	StockCode models.StockCode
+	Type      EventType
+	OldValue  string
+	NewValue  string
+}
+
+// Diff compares two snapshots of the securities list and returns the changes,
+// ordered by stock code
+func Diff(prev, curr []Security) []Event {
+	before := make(map[models.StockCode]Security, len(prev))
+	for _, s := range prev {
+		before[s.StockCode] = s
+	}
+	after := make(map[models.StockCode]Security, len(curr))
+	for _, s := range curr {
+		after[s.StockCode] = s
+	}
+
+	var events []Event
+	for code, a := range after {
+		b, ok := before[code]
+		if !ok {
+			events = append(events, Event{StockCode: code, Type: EventListed, NewValue: a.Name})
+			continue
+		}
+		events = append(events, diffSecurity(b, a)...)
+	}
+	for code, b := range before {
+		if _, ok := after[code]; !ok {
+			events = append(events, Event{StockCode: code, Type: EventDelisted, OldValue: b.Name})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].StockCode != events[j].StockCode {
+			return events[i].StockCode < events[j].StockCode
+		}
+		return events[i].Type < events[j].Type
+	})
+	return events
+}
+
+// diffSecurity returns the changes to a security present in both snapshots
+func diffSecurity(b, a Security) []Event {
+	var events []Event
+
+	if b.Name != a.Name {
+		events = append(events, Event{StockCode: a.StockCode, Type: EventRenamed, OldValue: b.Name, NewValue: a.Name})
+	}
+
+	if b.Category != a.Category || b.SubCategory != a.SubCategory {
+		typ := EventCategoryChanged
+		if b.Category == a.Category && board(b.SubCategory) != "" && board(a.SubCategory) != "" {
+			typ = EventBoardTransfer
+		}
+		events = append(events, Event{
+			StockCode: a.StockCode,
+			Type:      typ,
+			OldValue:  categoryValue(b),
+			NewValue:  categoryValue(a),
+		})
+	}
+
+	// A missing board lot is a parsing gap, not a change
+	if b.BoardLot != a.BoardLot && b.BoardLot != 0 && a.BoardLot != 0 {
+		events = append(events, Event{
+			StockCode: a.StockCode,
+			Type:      EventLotSizeChanged,
+			OldValue:  strconv.Itoa(b.BoardLot),
+			NewValue:  strconv.Itoa(a.BoardLot),
+		})
+	}
+
+	return events
+}
+
+// board returns the board named in a sub-category such as
+// "Equity Securities (GEM)", or "" if it names none
+func board(subCategory string) string {
+	switch {
+	case strings.Contains(subCategory, "(Main Board)"):
+		return "Main Board"
+	case strings.Contains(subCategory, "(GEM)"):
+		return "GEM"
+	}
+	return ""
+}
+
+// categoryValue formats a security's category for an event
+func categoryValue(s Security) string {
+	if s.SubCategory == "" {
+		return s.Category
+	}
+	return s.Category + " / " + s.SubCategory
+}
+
+// WriteReport writes a plain-text report of events grouped by type
+func WriteReport(w io.Writer, events []Event) error {
+	byType := make(map[EventType][]Event)
+	for _, e := range events {
+		byType[e.Type] = append(byType[e.Type], e)
+	}
+
+	if len(events) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	order := []EventType{EventListed, EventDelisted, EventRenamed, EventBoardTransfer, EventCategoryChanged, EventLotSizeChanged}
+	for _, typ := range order {
+		group := byType[typ]
+		if len(group) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "=== %s (%d) ===\n", typ, len(group)); err != nil {
+			return err
+		}
+		for _, e := range group {
+			var line string
+			switch {
+			case e.OldValue == "":
+				line = fmt.Sprintf("  %s  %s\n", e.StockCode, e.NewValue)
+			case e.NewValue == "":
+				line = fmt.Sprintf("  %s  %s\n", e.StockCode, e.OldValue)
+			default:
+				line = fmt.Sprintf("  %s  %s -> %s\n", e.StockCode, e.OldValue, e.NewValue)
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
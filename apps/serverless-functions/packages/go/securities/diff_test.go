@@ -0,0 +1,64 @@
+package securities
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	prev := []Security{
+		{StockCode: "00001", Name: "CKH HOLDINGS", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 500},
+		{StockCode: "00002", Name: "CLP HOLDINGS", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 500},
+		{StockCode: "08001", Name: "GEM CO", Category: CategoryEquity, SubCategory: "Equity Securities (GEM)", BoardLot: 2000},
+		{StockCode: "00999", Name: "GONE LTD", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 1000},
+		{StockCode: "02800", Name: "TRACKER FUND", Category: CategoryETP, SubCategory: "Exchange Traded Funds", BoardLot: 500},
+	}
+	curr := []Security{
+		{StockCode: "00001", Name: "CKH HOLDINGS", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 500},
+		{StockCode: "00002", Name: "CLP HLDGS", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 1000},
+		{StockCode: "08001", Name: "GEM CO", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 2000},
+		{StockCode: "02800", Name: "TRACKER FUND", Category: CategoryREIT, BoardLot: 500},
+		{StockCode: "09999", Name: "NEW CO", Category: CategoryEquity, SubCategory: "Equity Securities (Main Board)", BoardLot: 100},
+	}
+
+	want := []Event{
+		{StockCode: "00002", Type: EventLotSizeChanged, OldValue: "500", NewValue: "1000"},
+		{StockCode: "00002", Type: EventRenamed, OldValue: "CLP HOLDINGS", NewValue: "CLP HLDGS"},
+		{StockCode: "00999", Type: EventDelisted, OldValue: "GONE LTD"},
+		{StockCode: "02800", Type: EventCategoryChanged, OldValue: "Exchange Traded Products / Exchange Traded Funds", NewValue: "Real Estate Investment Trusts"},
+		{StockCode: "08001", Type: EventBoardTransfer, OldValue: "Equity / Equity Securities (GEM)", NewValue: "Equity / Equity Securities (Main Board)"},
+		{StockCode: "09999", Type: EventListed, NewValue: "NEW CO"},
+	}
+
+	got := Diff(prev, curr)
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReport(&buf, []Event{
+		{StockCode: "00002", Type: EventRenamed, OldValue: "CLP HOLDINGS", NewValue: "CLP HLDGS"},
+		{StockCode: "09999", Type: EventListed, NewValue: "NEW CO"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := buf.String()
+	for _, want := range []string{"=== LISTED (1) ===", "09999  NEW CO", "00002  CLP HOLDINGS -> CLP HLDGS"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Index(report, "LISTED") > strings.Index(report, "RENAMED") {
+		t.Errorf("listings should be reported before renames:\n%s", report)
+	}
+}
@@ -0,0 +1,148 @@
+// Package securities fetches and parses the HKEX List of Securities
+// (ListOfSecurities.xlsx) and classifies stock codes by instrument category.
+package securities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// ListURL is the HKEX List of Securities, refreshed every trading day
+	ListURL   = "https://www.hkex.com.hk/eng/services/trading/securities/securitieslists/ListOfSecurities.xlsx"
+	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// Security represents a row from the HKEX securities list.
+type Security struct {
+	StockCode   models.StockCode
+	Name        string
+	Category    string // e.g. "Equity", "Debt Securities", "Real Estate Investment Trusts"
+	SubCategory string // e.g. "Equity Securities (Main Board)", "Exchange Traded Funds"
+	BoardLot    int
+	ISIN        string
+}
+
+// List is a parsed securities list
+type List struct {
+	UpdatedAt  time.Time // "Updated as at" date; zero if the header is missing
+	Securities []Security
+}
+
+// Fetch downloads and parses the securities list at url, or at ListURL if url
+// is empty
+func Fetch(ctx context.Context, url string) (*List, error) {
+	if url == "" {
+		url = ListURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return Parse(resp.Body)
+}
+
+// Parse reads a securities list xlsx
+func Parse(r io.Reader) (*List, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening xlsx: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("reading rows: %w", err)
+	}
+
+	if len(rows) < 4 {
+		return nil, fmt.Errorf("unexpected spreadsheet format: only %d rows", len(rows))
+	}
+
+	// The HKEX xlsx layout has:
+	//   Row 0: "List of Securities" (title)
+	//   Row 1: "Updated as at DD/MM/YYYY" (date)
+	//   Row 2: Merged header row (not machine-parseable)
+	//   Row 3+: Data with fixed columns:
+	//     [0] Stock Code  [1] Name of Securities  [2] Category  [3] Sub-Category
+	//     [4] Board Lot   [5] ISIN                [6] Expiry Date ...
+	const (
+		colStockCode   = 0
+		colName        = 1
+		colCategory    = 2 // "Equity", "Debt Securities", "Derivative Warrants", etc.
+		colSubCategory = 3 // "Equity Securities (Main Board)", "Exchange Traded Funds", etc.
+		colBoardLot    = 4
+		colISIN        = 5
+	)
+
+	list := &List{}
+	if len(rows[1]) > 0 {
+		list.UpdatedAt = parseUpdatedAt(rows[1][0])
+	}
+
+	// Skip title (row 0), date (row 1), and header (row 2)
+	for _, row := range rows[3:] {
+		if len(row) <= colCategory || strings.TrimSpace(row[colStockCode]) == "" {
+			continue
+		}
+
+		code, err := models.ParseStockCode(row[colStockCode])
+		if err != nil {
+			continue
+		}
+
+		s := Security{
+			StockCode: code,
+			Name:      strings.TrimSpace(row[colName]),
+			Category:  strings.TrimSpace(row[colCategory]),
+		}
+		if len(row) > colSubCategory {
+			s.SubCategory = strings.TrimSpace(row[colSubCategory])
+		}
+		if len(row) > colBoardLot {
+			s.BoardLot, _ = strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(row[colBoardLot]), ",", ""))
+		}
+		if len(row) > colISIN {
+			s.ISIN = strings.TrimSpace(row[colISIN])
+		}
+
+		list.Securities = append(list.Securities, s)
+	}
+
+	return list, nil
+}
+
+// parseUpdatedAt extracts the date from "Updated as at DD/MM/YYYY"
+func parseUpdatedAt(s string) time.Time {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	t, err := time.Parse("02/01/2006", fields[len(fields)-1])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
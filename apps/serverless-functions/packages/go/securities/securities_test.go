@@ -0,0 +1,152 @@
+package securities
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// buildList writes a minimal ListOfSecurities.xlsx with the given data rows
+func buildList(t *testing.T, rows [][]string) *bytes.Buffer {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+
+	all := append([][]string{
+		{"List of Securities"},
+		{"Updated as at 17/10/2026"},
+		{"Stock Code", "Name of Securities", "Category", "Sub-Category", "Board Lot", "ISIN"},
+	}, rows...)
+	for i, row := range all {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = v
+		}
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	buf := buildList(t, [][]string{
+		{"1", "CKH HOLDINGS", "Equity", "Equity Securities (Main Board)", "500", "KYG217651051"},
+		{"2800", "TRACKER FUND", "Exchange Traded Products", "Exchange Traded Funds", "500", "HK2800008867"},
+		{"", "BLANK ROW", "Equity", "", "", ""},
+	})
+
+	list, err := Parse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC); !list.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want %v", list.UpdatedAt, want)
+	}
+	if len(list.Securities) != 2 {
+		t.Fatalf("got %d securities, want 2", len(list.Securities))
+	}
+
+	s := list.Securities[0]
+	if s.StockCode != "00001" || s.Category != CategoryEquity || s.BoardLot != 500 || s.ISIN != "KYG217651051" {
+		t.Errorf("unexpected first security: %+v", s)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	c := NewClassifier(&List{Securities: []Security{
+		{StockCode: "02800", Category: CategoryETP, SubCategory: "Exchange Traded Funds"},
+		{StockCode: "00823", Category: CategoryREIT},
+		{StockCode: "12345", Category: CategoryEquity}, // code moved out of its usual range
+	}})
+
+	tests := []struct {
+		code     string
+		category string
+		fromList bool
+	}{
+		{"2800", CategoryETP, true},
+		{"00823", CategoryREIT, true},
+		{"12345", CategoryEquity, true},
+		{"00005", CategoryEquity, false},
+		{"23456", CategoryDerivativeWarrant, false},
+		{"55555", CategoryCBBC, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got := c.Classify(tt.code)
+			if got.Category != tt.category || got.FromList != tt.fromList {
+				t.Errorf("Classify(%q) = %+v, want category %q fromList=%v", tt.code, got, tt.category, tt.fromList)
+			}
+		})
+	}
+
+	var nilClassifier *Classifier
+	if got := nilClassifier.Classify("12345"); got.Category != CategoryDerivativeWarrant {
+		t.Errorf("nil Classifier: got %q, want range fallback", got.Category)
+	}
+}
+
+func TestCachedClassifier(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		buildList(t, [][]string{{"02800", "TRACKER FUND", CategoryETP, "Exchange Traded Funds"}}).WriteTo(w)
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		c := CachedClassifier(context.Background(), srv.URL)
+		if got := c.Classify("02800"); !got.FromList {
+			t.Fatalf("call %d: Classify(02800) = %+v, want from list", i, got)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetched the list %d times, want 1", fetches)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := NewFilter([]string{"exchange traded funds", CategoryDerivativeWarrant})
+
+	tests := []struct {
+		name string
+		cls  Classification
+		skip bool
+	}{
+		{"ETF by sub-category", Classification{Category: CategoryETP, SubCategory: "Exchange Traded Funds"}, true},
+		{"REIT kept", Classification{Category: CategoryREIT}, false},
+		{"DW", Classification{Category: CategoryDerivativeWarrant}, true},
+		{"CBBC kept", Classification{Category: CategoryCBBC}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.Skip(tt.cls); got != tt.skip {
+				t.Errorf("Skip(%+v) = %v, want %v", tt.cls, got, tt.skip)
+			}
+		})
+	}
+}
+
+func TestFilter_Default(t *testing.T) {
+	f := NewFilter(nil)
+	if !f.Skip(Classification{Category: CategoryCBBC}) || f.Skip(Classification{Category: CategoryEquity}) {
+		t.Error("NewFilter(nil) does not skip DefaultSkipCategories")
+	}
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CachingStorage is a Storage that keeps the documents read from another
+// Storage, usually S3, in a local directory bounded in size, evicting the
+// least recently read. Every read is validated against the backend's ETag
+// (or hash, for backends without ETags) with a Stat, so a changed document
+// is fetched again. Concurrent reads of a document that is not cached share
+// one fetch. Writes go to the backend and drop the cached copy.
+//
+// The cache survives restarts: each document is stored as a file named by
+// the hash of its key and version, with a .json file recording both.
+type CachingStorage struct {
+	filings
+	backend  Storage
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key -> *cacheEntry
+	lru     *list.List               // most recently read first
+	size    int64
+	stats   CacheStats
+
+	group singleflight.Group
+}
+
+// CacheStats counts the reads served by a CachingStorage
+type CacheStats struct {
+	Hits      int64 // reads served from the cache
+	Misses    int64 // reads that fetched from the backend
+	Shared    int64 // reads that waited for another read's fetch
+	Evictions int64
+	Fetched   int64 // bytes fetched from the backend
+	Entries   int   // documents cached
+	Size      int64 // bytes cached
+}
+
+type cacheEntry struct {
+	Key       string `json:"key"`
+	Validator string `json:"validator"`
+	Size      int64  `json:"size"`
+	name      string
+}
+
+// NewCachingStorage creates a cache of backend's documents in dir, holding
+// up to maxBytes. The newest document is kept even if it alone exceeds
+// maxBytes. Documents cached by an earlier run are reused.
+func NewCachingStorage(backend Storage, dir string, maxBytes int64) (*CachingStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	c := &CachingStorage{
+		backend:  backend,
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+	c.filings = filings{c}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("loading cache: %w", err)
+	}
+	return c, nil
+}
+
+// Stats returns the cache's counters
+func (c *CachingStorage) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.stats
+	st.Entries = c.lru.Len()
+	st.Size = c.size
+	return st
+}
+
+// OpenKey returns a reader of a cached document, fetching it from the
+// backend if it is not cached or has changed
+func (c *CachingStorage) OpenKey(ctx context.Context, key string) (io.ReadCloser, error) {
+	info, err := c.backend.StatKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.drop(key)
+		}
+		return nil, err
+	}
+	v := validator(info)
+
+	if f := c.open(key, v); f != nil {
+		c.count(func(st *CacheStats) { st.Hits++ })
+		return f, nil
+	}
+
+	// Only the caller whose fetch ran counts a miss
+	fetched := false
+	_, err, _ = c.group.Do(key+"\x00"+v, func() (interface{}, error) {
+		fetched = true
+		return nil, c.fetch(ctx, key, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fetched {
+		c.count(func(st *CacheStats) { st.Misses++ })
+	} else {
+		c.count(func(st *CacheStats) { st.Shared++ })
+	}
+
+	if f := c.open(key, v); f != nil {
+		return f, nil
+	}
+	// Evicted or replaced since the fetch
+	return c.backend.OpenKey(ctx, key)
+}
+
+// OpenRange returns a reader of part of a document in the backend. Ranges
+// are not cached.
+func (c *CachingStorage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return c.backend.OpenRange(ctx, key, offset, length)
+}
+
+// PutKey stores a document in the backend
+func (c *CachingStorage) PutKey(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	c.drop(key)
+	return c.backend.PutKey(ctx, key, r, contentType)
+}
+
+// StatKey describes a document in the backend
+func (c *CachingStorage) StatKey(ctx context.Context, key string) (*Info, error) {
+	return c.backend.StatKey(ctx, key)
+}
+
+// CopyKey copies a document within the backend
+func (c *CachingStorage) CopyKey(ctx context.Context, from, to string) error {
+	c.drop(to)
+	return c.backend.CopyKey(ctx, from, to)
+}
+
+// DeleteKey removes a document from the backend and the cache
+func (c *CachingStorage) DeleteKey(ctx context.Context, key string) error {
+	c.drop(key)
+	return c.backend.DeleteKey(ctx, key)
+}
+
+// List lists the backend's documents
+func (c *CachingStorage) List(ctx context.Context, prefix string, fn func(*Info) error) error {
+	return c.backend.List(ctx, prefix, fn)
+}
+
+// Location returns where the backend stores a key
+func (c *CachingStorage) Location(key string) string {
+	return c.backend.Location(key)
+}
+
+// validator identifies a version of a document: its ETag, else its hash,
+// else its size and modification time
+func validator(info *Info) string {
+	if info.ETag != "" {
+		return info.ETag
+	}
+	if info.Hash != "" {
+		return info.Hash
+	}
+	return fmt.Sprintf("%d/%d", info.Size, info.ModTime.UnixNano())
+}
+
+// open returns the cached file of a key if it holds version v, marking it
+// recently read
+func (c *CachingStorage) open(key, v string) *os.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if e.Validator != v {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(c.dir, e.name))
+	if err != nil {
+		c.remove(el)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	now := time.Now()
+	os.Chtimes(f.Name(), now, now) // recency survives restarts
+	return f
+}
+
+// fetch copies a document from the backend into the cache
+func (c *CachingStorage) fetch(ctx context.Context, key, v string) error {
+	r, err := c.backend.OpenKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	name := cacheName(key, v)
+	size, _, err := writeAtomic(filepath.Join(c.dir, name), r)
+	if err != nil {
+		return fmt.Errorf("caching %s: %w", key, err)
+	}
+	e := &cacheEntry{Key: key, Validator: v, Size: size, name: name}
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, _, err := writeAtomic(filepath.Join(c.dir, name+".json"), strings.NewReader(string(meta))); err != nil {
+		return fmt.Errorf("caching %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Fetched += size
+	if el, ok := c.entries[key]; ok {
+		if el.Value.(*cacheEntry).name != name {
+			c.remove(el) // an older version
+		} else {
+			c.size -= el.Value.(*cacheEntry).Size
+			c.lru.Remove(el)
+		}
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += size
+	c.evict()
+	return nil
+}
+
+// evict removes the least recently read documents until the cache fits,
+// keeping the newest. c.mu must be held.
+func (c *CachingStorage) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove deletes a cached document. c.mu must be held.
+func (c *CachingStorage) remove(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.Key)
+	c.size -= e.Size
+	os.Remove(filepath.Join(c.dir, e.name))
+	os.Remove(filepath.Join(c.dir, e.name+".json"))
+}
+
+// drop removes the cached copy of a key, if any
+func (c *CachingStorage) drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *CachingStorage) count(fn func(*CacheStats)) {
+	c.mu.Lock()
+	fn(&c.stats)
+	c.mu.Unlock()
+}
+
+// load reads the documents cached by an earlier run, ordered by when they
+// were last read, and removes incomplete ones
+func (c *CachingStorage) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type loaded struct {
+		entry   *cacheEntry
+		modTime time.Time
+	}
+	var found []loaded
+	described := make(map[string]bool)
+	for _, d := range dirEntries {
+		name := d.Name()
+		if isHidden(name) {
+			os.Remove(filepath.Join(c.dir, name)) // temporary file of an interrupted fetch
+			continue
+		}
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		dataName := strings.TrimSuffix(name, ".json")
+		data, err := os.ReadFile(filepath.Join(c.dir, name))
+		if err != nil {
+			return err
+		}
+		var e cacheEntry
+		fi, statErr := os.Stat(filepath.Join(c.dir, dataName))
+		if json.Unmarshal(data, &e) != nil || statErr != nil || fi.Size() != e.Size || cacheName(e.Key, e.Validator) != dataName {
+			os.Remove(filepath.Join(c.dir, name))
+			os.Remove(filepath.Join(c.dir, dataName))
+			continue
+		}
+		e.name = dataName
+		described[dataName] = true
+		found = append(found, loaded{entry: &e, modTime: fi.ModTime()})
+	}
+
+	// Documents whose fetch stopped before their .json was written
+	for _, d := range dirEntries {
+		name := d.Name()
+		if !isHidden(name) && !strings.HasSuffix(name, ".json") && !described[name] {
+			os.Remove(filepath.Join(c.dir, name))
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.After(found[j].modTime) })
+	for _, l := range found {
+		c.entries[l.entry.Key] = c.lru.PushBack(l.entry)
+		c.size += l.entry.Size
+	}
+	c.evict()
+	c.stats.Evictions = 0
+	return nil
+}
+
+// cacheName is the file name of a version of a key's cached document
+func cacheName(key, v string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + v))
+	return hex.EncodeToString(sum[:])
+}
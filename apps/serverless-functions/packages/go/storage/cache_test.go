@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingStorage counts reads of a MemoryStorage and can hold them until
+// released
+type countingStorage struct {
+	*MemoryStorage
+	opens   atomic.Int64
+	release chan struct{}
+}
+
+func (s *countingStorage) OpenKey(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.opens.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.MemoryStorage.OpenKey(ctx, key)
+}
+
+func read(t *testing.T, c *CachingStorage, key string) string {
+	t.Helper()
+	r, err := c.OpenKey(context.Background(), key)
+	if err != nil {
+		t.Fatalf("OpenKey(%s) error = %v", key, err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
+
+func TestCachingStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingStorage{MemoryStorage: NewMemoryStorage()}
+	for _, key := range []string{"a", "b", "c"} {
+		backend.PutKey(ctx, key, strings.NewReader(strings.Repeat(key, 10)), "text/plain")
+	}
+
+	dir := t.TempDir()
+	c, err := NewCachingStorage(backend, dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read(t, c, "a")
+	if got := read(t, c, "a"); got != strings.Repeat("a", 10) {
+		t.Errorf("cached read = %q", got)
+	}
+	if n := backend.opens.Load(); n != 1 {
+		t.Errorf("backend reads = %d, want 1", n)
+	}
+
+	// A changed document is fetched again
+	backend.PutKey(ctx, "a", strings.NewReader("changed"), "text/plain")
+	if got := read(t, c, "a"); got != "changed" {
+		t.Errorf("read after change = %q, want changed", got)
+	}
+
+	// b and c exceed the limit with a; a was read least recently
+	read(t, c, "b")
+	read(t, c, "c")
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 4 || st.Evictions != 1 || st.Entries != 2 || st.Size != 20 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	// The cache is reused by the next run
+	c, err = NewCachingStorage(backend, dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := backend.opens.Load()
+	read(t, c, "b")
+	read(t, c, "c")
+	if n := backend.opens.Load() - before; n != 0 {
+		t.Errorf("backend reads after restart = %d, want 0", n)
+	}
+}
+
+func TestCachingStorage_SingleFlight(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingStorage{MemoryStorage: NewMemoryStorage(), release: make(chan struct{})}
+	backend.PutKey(ctx, "doc.pdf", strings.NewReader("%PDF-1.4"), "application/pdf")
+
+	c, err := NewCachingStorage(backend, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := read(t, c, "doc.pdf"); got != "%PDF-1.4" {
+				t.Errorf("read = %q", got)
+			}
+		}()
+	}
+	// Let the readers queue behind the first fetch
+	time.Sleep(50 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	if n := backend.opens.Load(); n != 1 {
+		t.Errorf("backend reads = %d, want 1", n)
+	}
+	if st := c.Stats(); st.Misses != 1 || st.Shared+st.Hits != 7 {
+		t.Errorf("Stats() = %+v", st)
+	}
+}
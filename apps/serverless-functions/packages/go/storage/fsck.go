@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// QuarantineDir is the directory under a LocalStorage base path that Fsck
+// moves damaged documents to, keeping their keys
+const QuarantineDir = ".quarantine"
+
+// FsckStatus is the outcome of checking a file
+type FsckStatus string
+
+const (
+	FsckOK         FsckStatus = "ok"         // checksum matches
+	FsckAdopted    FsckStatus = "adopted"    // no checksum, looks complete; checksum written
+	FsckPartial    FsckStatus = "partial"    // no checksum, looks truncated; quarantined
+	FsckCorrupt    FsckStatus = "corrupt"    // checksum does not match; quarantined
+	FsckTemp       FsckStatus = "temp"       // temporary file of an interrupted write; removed
+	FsckOrphan     FsckStatus = "orphan"     // checksum without a document; removed
+	FsckUnreadable FsckStatus = "unreadable" // could not be checked
+)
+
+// FsckResult is a file checked by Fsck
+type FsckResult struct {
+	Key    string // document key, or the path of a temporary file relative to the base
+	Status FsckStatus
+	Reason string
+}
+
+// Fsck checks every file under the base path and calls fn with the outcome.
+// Documents whose checksum does not match, and documents written before
+// checksums existed that look truncated, are moved under QuarantineDir;
+// complete documents without a checksum get one. Temporary files left by
+// interrupted writes and orphaned checksums are removed. With dryRun nothing
+// is changed.
+func (s *LocalStorage) Fsck(ctx context.Context, dryRun bool, fn func(*FsckResult)) error {
+	return filepath.WalkDir(s.basePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != s.basePath && d.Name() == QuarantineDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		name := d.Name()
+
+		var result *FsckResult
+		switch {
+		case isHidden(name) && strings.Contains(name, tmpInfix):
+			result = &FsckResult{Key: key, Status: FsckTemp}
+			if !dryRun {
+				err = os.Remove(p)
+			}
+		case strings.HasSuffix(name, sidecarExt):
+			if _, statErr := os.Stat(strings.TrimSuffix(p, sidecarExt)); !os.IsNotExist(statErr) {
+				return nil // checked with its document
+			}
+			result = &FsckResult{Key: key, Status: FsckOrphan}
+			if !dryRun {
+				err = os.Remove(p)
+			}
+		default:
+			result, err = s.fsckDocument(key, p, dryRun)
+		}
+		if err != nil {
+			result = &FsckResult{Key: key, Status: FsckUnreadable, Reason: err.Error()}
+		}
+		fn(result)
+		return nil
+	})
+}
+
+// fsckDocument checks one document against its checksum, or for signs of
+// truncation if it has none
+func (s *LocalStorage) fsckDocument(key, path string, dryRun bool) (*FsckResult, error) {
+	want, err := readSidecar(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if want != "" {
+		got, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if got == want {
+			return &FsckResult{Key: key, Status: FsckOK}, nil
+		}
+		result := &FsckResult{Key: key, Status: FsckCorrupt, Reason: fmt.Sprintf("sha256 %s, want %s", got, want)}
+		if !dryRun {
+			err = s.quarantine(key)
+		}
+		return result, err
+	}
+
+	if reason, err := truncation(path); err != nil {
+		return nil, err
+	} else if reason != "" {
+		result := &FsckResult{Key: key, Status: FsckPartial, Reason: reason}
+		if !dryRun {
+			err = s.quarantine(key)
+		}
+		return result, err
+	}
+
+	if !dryRun {
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeSidecar(path, hash); err != nil {
+			return nil, err
+		}
+	}
+	return &FsckResult{Key: key, Status: FsckAdopted}, nil
+}
+
+// quarantine moves a document and its checksum under QuarantineDir
+func (s *LocalStorage) quarantine(key string) error {
+	from, to := s.Location(key), s.QuarantinePath(key)
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	if err := os.Rename(from+sidecarExt, to+sidecarExt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// QuarantinePath returns where Fsck moves the document of a key
+func (s *LocalStorage) QuarantinePath(key string) string {
+	return filepath.Join(s.basePath, QuarantineDir, filepath.FromSlash(key))
+}
+
+// tailSize is how much of the end of a file truncation examines
+const tailSize = 64 * 1024
+
+// truncation returns why a document looks truncated, or "" if it looks
+// complete. PDFs must end with %%EOF, ZIP-based documents (DART archives,
+// xlsx, docx) with an end of central directory record, and HTML with a
+// closing html tag; other documents only need to be non-empty.
+func truncation(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() == 0 {
+		return "empty file", nil
+	}
+
+	head := make([]byte, 8)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+
+	offset := fi.Size() - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, fi.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		// The marker belongs in the last 1024 bytes; allow trailing junk
+		if !bytes.Contains(tail[max(0, len(tail)-4096):], []byte("%%EOF")) {
+			return "PDF without %%EOF", nil
+		}
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		if !bytes.Contains(tail, []byte("PK\x05\x06")) {
+			return "ZIP without end of central directory", nil
+		}
+	case ext == "htm" || ext == "html":
+		if !bytes.Contains(bytes.ToLower(tail[max(0, len(tail)-4096):]), []byte("</html")) {
+			return "HTML without </html>", nil
+		}
+	}
+	return "", nil
+}
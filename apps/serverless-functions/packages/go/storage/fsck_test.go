@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFsck(t *testing.T) {
+	ctx := context.Background()
+	base := t.TempDir()
+	s, err := NewLocalStorage(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(key, data string) {
+		t.Helper()
+		p := s.Location(key)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Written by Put: no temporary file is left, the checksum is recorded
+	if _, err := s.PutKey(ctx, "hkex/00005/2024/03/28/1.pdf", strings.NewReader("%PDF-1.4\n%%EOF\n"), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(s.Location("hkex/00005/2024/03/28/1.pdf")))
+	if len(entries) != 2 {
+		t.Fatalf("Put() left %d files, want document and checksum", len(entries))
+	}
+
+	// Written by older versions without checksums
+	write("hkex/00005/2024/03/28/2.pdf", "%PDF-1.4\n%%EOF\n")
+	write("hkex/00005/2024/03/28/3.pdf", "%PDF-1.4\n1 0 obj")
+	write("hkex/00005/2024/03/28/4.htm", "")
+	// Changed after it was written
+	if _, err := s.PutKey(ctx, "hkex/00005/2024/03/28/5.htm", strings.NewReader("<html></html>"), "text/html"); err != nil {
+		t.Fatal(err)
+	}
+	write("hkex/00005/2024/03/28/5.htm", "<html></ht")
+	// Left by an interrupted write
+	write("hkex/00005/2024/03/28/.6.pdf.tmp-123", "%PDF")
+
+	got := make(map[string]FsckStatus)
+	err = s.Fsck(ctx, false, func(r *FsckResult) { got[r.Key] = r.Status })
+	if err != nil {
+		t.Fatalf("Fsck() error = %v", err)
+	}
+
+	want := map[string]FsckStatus{
+		"hkex/00005/2024/03/28/1.pdf":          FsckOK,
+		"hkex/00005/2024/03/28/2.pdf":          FsckAdopted,
+		"hkex/00005/2024/03/28/3.pdf":          FsckPartial,
+		"hkex/00005/2024/03/28/4.htm":          FsckPartial,
+		"hkex/00005/2024/03/28/5.htm":          FsckCorrupt,
+		"hkex/00005/2024/03/28/.6.pdf.tmp-123": FsckTemp,
+	}
+	for key, status := range want {
+		if got[key] != status {
+			t.Errorf("Fsck() %s = %q, want %q", key, got[key], status)
+		}
+	}
+
+	var listed []string
+	s.List(ctx, "", func(info *Info) error {
+		listed = append(listed, info.Key)
+		return nil
+	})
+	if len(listed) != 2 {
+		t.Errorf("List() after Fsck = %v, want the complete documents", listed)
+	}
+	if _, err := os.Stat(s.QuarantinePath("hkex/00005/2024/03/28/3.pdf")); err != nil {
+		t.Errorf("partial file not quarantined: %v", err)
+	}
+	if hash, _ := readSidecar(s.Location("hkex/00005/2024/03/28/2.pdf")); hash == "" {
+		t.Error("adopted file has no checksum")
+	}
+
+	// A second pass finds nothing to do
+	got = make(map[string]FsckStatus)
+	s.Fsck(ctx, false, func(r *FsckResult) { got[r.Key] = r.Status })
+	for key, status := range got {
+		if status != FsckOK {
+			t.Errorf("second Fsck() %s = %q, want ok", key, status)
+		}
+	}
+}
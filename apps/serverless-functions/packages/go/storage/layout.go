@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// LayoutVersion is the version of the document key layout written by Key.
+//
+//	0: {year}/{stock_code}/{news_id}.{ext}, written by the feed scraper; the
+//	   local downloader appended the title to the file name of the version 1
+//	   path
+//	1: {exchange}/{company_id}/{YYYY}/{MM}/{DD}/{source_id}.{ext}
+//
+// Keys of older versions are moved with tools/migrate-keys.
+const LayoutVersion = 1
+
+// Key returns the key of a filing's document, relative to the bucket or
+// local root. Every writer stores documents under this key. The date is the
+// filing's report date; company IDs are normalised so keys match across
+// sources, and titles are left out to keep keys free of non-ASCII
+// characters.
+func Key(filing *models.Filing) string {
+	exchange := strings.ToLower(filing.Exchange)
+	if exchange == "" {
+		exchange = "unknown"
+	}
+	companyID := models.NormalizeCompanyID(filing.Exchange, filing.CompanyID)
+	if companyID == "" {
+		companyID = "unknown"
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s.%s", exchange, companyID,
+		filing.ReportDate.Format("2006/01/02"), filing.SourceID, Extension(filing))
+}
+
+// Extension returns the file extension of a filing's document: its
+// FileExtension, else the extension of its source URL, else "pdf"
+func Extension(filing *models.Filing) string {
+	if filing.FileExtension != "" {
+		return filing.FileExtension
+	}
+	if ext := ExtensionFromURL(filing.SourceURL); ext != "" {
+		return ext
+	}
+	return "pdf"
+}
+
+// knownExts are the document extensions recognised in URLs
+var knownExts = map[string]bool{
+	"pdf": true, "htm": true, "html": true,
+	"xlsx": true, "xls": true, "doc": true, "docx": true,
+	"txt": true, "rtf": true, "csv": true, "xml": true,
+}
+
+// ExtensionFromURL returns the lower-case extension of the last segment of a
+// URL, or "" unless it is a known document extension (so "example.com" has
+// none)
+func ExtensionFromURL(url string) string {
+	lastSlash := strings.LastIndex(url, "/")
+	if lastSlash == -1 {
+		return ""
+	}
+	filename := url[lastSlash+1:]
+	if queryIdx := strings.Index(filename, "?"); queryIdx != -1 {
+		filename = filename[:queryIdx]
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+	if !knownExts[ext] {
+		return ""
+	}
+	return ext
+}
+
+// ParseFeedKey returns the HKEX source ID of a key in the version 0 feed
+// layout, {year}/{stock_code}/{news_id}.{ext}, and false for other keys
+func ParseFeedKey(key string) (string, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 {
+		return "", false
+	}
+	if year := parts[0]; year != "unknown" && (len(year) != 4 || !isDigits(year)) {
+		return "", false
+	}
+	ext := path.Ext(parts[2])
+	sourceID := strings.TrimSuffix(parts[2], ext)
+	if ext == "" || !isDigits(sourceID) {
+		return "", false
+	}
+	return sourceID, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
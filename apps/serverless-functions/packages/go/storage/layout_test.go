@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+func TestKey(t *testing.T) {
+	date := time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filing *models.Filing
+		want   string
+	}{
+		{
+			name:   "HKEX",
+			filing: &models.Filing{Exchange: "HKEX", CompanyID: "00005", SourceID: "11223344", ReportDate: date, FileExtension: "pdf"},
+			want:   "hkex/00005/2024/03/28/11223344.pdf",
+		},
+		{
+			name:   "Legacy company ID",
+			filing: &models.Filing{Exchange: "HKEX", CompanyID: "com_5", SourceID: "11223344", ReportDate: date, FileExtension: "pdf"},
+			want:   "hkex/00005/2024/03/28/11223344.pdf",
+		},
+		{
+			name:   "Extension from URL",
+			filing: &models.Filing{Exchange: "HKEX", CompanyID: "00005", SourceID: "11223344", ReportDate: date, SourceURL: "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800124.htm"},
+			want:   "hkex/00005/2024/03/28/11223344.htm",
+		},
+		{
+			name:   "Unknown",
+			filing: &models.Filing{SourceID: "1", ReportDate: date, SourceURL: "https://example.com"},
+			want:   "unknown/unknown/2024/03/28/1.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Key(tt.filing); got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtensionFromURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "PDF file",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2025/0330/2025033000043.pdf",
+			expected: "pdf",
+		},
+		{
+			name:     "HTM file",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2015/1217/ltn20151217071.htm",
+			expected: "htm",
+		},
+		{
+			name:     "HTML file",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/file.html",
+			expected: "html",
+		},
+		{
+			name:     "XLSX file",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/data.xlsx",
+			expected: "xlsx",
+		},
+		{
+			name:     "URL with query string",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/file.pdf?download=true",
+			expected: "pdf",
+		},
+		{
+			name:     "Uppercase extension",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/FILE.PDF",
+			expected: "pdf",
+		},
+		{
+			name:     "No extension",
+			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/noext",
+			expected: "",
+		},
+		{
+			name:     "Empty URL",
+			url:      "",
+			expected: "",
+		},
+		{
+			name:     "URL without path",
+			url:      "https://example.com",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExtensionFromURL(tt.url)
+			if result != tt.expected {
+				t.Errorf("ExtensionFromURL(%q) = %q, want %q", tt.url, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDocumentPath(t *testing.T) {
+	ann := &models.Announcement{
+		NewsID:  11223344,
+		Stock:   []models.Stock{{SC: "5", SN: "HSBC HOLDINGS"}},
+		WebPath: "/listedco/listconews/sehk/2024/0328/2024032800124.pdf",
+		Ext:     "pdf",
+		RelTime: "28/03/2024 22:45",
+	}
+	if got, want := DocumentPath(ann), "hkex/00005/2024/03/28/11223344.pdf"; got != want {
+		t.Errorf("DocumentPath() = %q, want %q", got, want)
+	}
+}
+
+func TestParseFeedKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		wantID string
+		wantOK bool
+	}{
+		{key: "2024/5/11223344.pdf", wantID: "11223344", wantOK: true},
+		{key: "unknown/unknown/11223344.htm", wantID: "11223344", wantOK: true},
+		{key: "hkex/00005/2024/03/28/11223344.pdf"},
+		{key: "2024/5/11223344"},
+		{key: "2024/5/notes.pdf"},
+		{key: "24/5/11223344.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			id, ok := ParseFeedKey(tt.key)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("ParseFeedKey() = %q, %v, want %q, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
@@ -2,15 +2,19 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-
-	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"strings"
 )
 
 // LocalStorage implements Storage for local filesystem
 type LocalStorage struct {
+	filings
 	basePath string
 }
 
@@ -21,59 +25,277 @@ func NewLocalStorage(basePath string) (*LocalStorage, error) {
 		return nil, fmt.Errorf("creating base directory: %w", err)
 	}
 
-	return &LocalStorage{
-		basePath: basePath,
-	}, nil
+	s := &LocalStorage{basePath: basePath}
+	s.filings = filings{s}
+	return s, nil
+}
+
+// BasePath returns the base path for local storage
+func (s *LocalStorage) BasePath() string {
+	return s.basePath
+}
+
+// Location returns the file path of a key
+func (s *LocalStorage) Location(key string) string {
+	return filepath.Join(s.basePath, filepath.FromSlash(key))
 }
 
-// Save stores a document to the local filesystem
-func (s *LocalStorage) Save(ctx context.Context, announcement *models.Announcement, data []byte) (string, error) {
-	relPath := DocumentPath(announcement)
-	fullPath := filepath.Join(s.basePath, relPath)
+// PutKey writes a document to the local filesystem. The document is written
+// to a temporary file in the same directory, synced and renamed into place,
+// so a crash never leaves a partial file under its key; its SHA-256 is then
+// written to a sidecar file.
+func (s *LocalStorage) PutKey(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	fullPath := s.Location(key)
 
 	// Create parent directories
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", fmt.Errorf("creating directory %s: %w", dir, err)
+		return nil, fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+
+	size, hash, err := writeAtomic(fullPath, r)
+	if err != nil {
+		return nil, fmt.Errorf("writing file %s: %w", fullPath, err)
+	}
+	if err := writeSidecar(fullPath, hash); err != nil {
+		return nil, fmt.Errorf("writing checksum of %s: %w", fullPath, err)
+	}
+	if err := syncDir(dir); err != nil {
+		return nil, fmt.Errorf("syncing directory %s: %w", dir, err)
+	}
+
+	info := &Info{
+		Key:         key,
+		Size:        size,
+		Hash:        hash,
+		ContentType: contentType,
+	}
+	if fi, err := os.Stat(fullPath); err == nil {
+		info.ModTime = fi.ModTime()
+	}
+	return info, nil
+}
+
+// OpenKey opens a document in local storage
+func (s *LocalStorage) OpenKey(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.Location(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	return f, nil
+}
+
+// OpenRange returns a reader of part of a file in local storage
+func (s *LocalStorage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := s.OpenKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	file := f.(*os.File)
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(file, offset, length), file}, nil
+}
+
+// StatKey describes a document in local storage. The hash is read from the
+// checksum sidecar, or computed for files written without one.
+func (s *LocalStorage) StatKey(ctx context.Context, key string) (*Info, error) {
+	fullPath := s.Location(key)
+	fi, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checking file %s: %w", fullPath, err)
+	}
+
+	hash, err := readSidecar(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading checksum of %s: %w", fullPath, err)
 	}
+	if hash == "" {
+		if hash, err = hashFile(fullPath); err != nil {
+			return nil, fmt.Errorf("hashing file %s: %w", fullPath, err)
+		}
+	}
+	return &Info{
+		Key:         key,
+		Size:        fi.Size(),
+		Hash:        hash,
+		ContentType: contentTypeOf(key),
+		ModTime:     fi.ModTime(),
+	}, nil
+}
 
-	// Write the file
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
-		return "", fmt.Errorf("writing file %s: %w", fullPath, err)
+// CopyKey copies a document within local storage
+func (s *LocalStorage) CopyKey(ctx context.Context, from, to string) error {
+	r, err := s.OpenKey(ctx, from)
+	if err != nil {
+		return err
 	}
+	defer r.Close()
 
-	return fullPath, nil
+	_, err = s.PutKey(ctx, to, r, contentTypeOf(to))
+	return err
 }
 
-// Exists checks if a document already exists in local storage
-func (s *LocalStorage) Exists(ctx context.Context, announcement *models.Announcement) (bool, error) {
-	relPath := DocumentPath(announcement)
-	fullPath := filepath.Join(s.basePath, relPath)
+// DeleteKey removes a document and its checksum from local storage
+func (s *LocalStorage) DeleteKey(ctx context.Context, key string) error {
+	fullPath := s.Location(key)
+	for _, p := range []string{fullPath, fullPath + sidecarExt} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing file: %w", err)
+		}
+	}
+	return nil
+}
 
-	_, err := os.Stat(fullPath)
-	if err == nil {
-		return true, nil
+// List walks the directory holding prefix and calls fn for each document
+// whose key starts with prefix. Checksums, temporary files and hidden
+// directories such as the quarantine are skipped; listed documents are not
+// hashed.
+func (s *LocalStorage) List(ctx context.Context, prefix string, fn func(*Info) error) error {
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i != -1 {
+		dir = prefix[:i]
 	}
+
+	root := s.Location(dir)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && isHidden(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isHidden(d.Name()) || strings.HasSuffix(d.Name(), sidecarExt) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(&Info{Key: key, Size: fi.Size(), ContentType: contentTypeOf(key), ModTime: fi.ModTime()})
+	})
 	if os.IsNotExist(err) {
-		return false, nil
+		return nil
+	}
+	return err
+}
+
+// hashFile returns the hex SHA-256 of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sidecarExt is appended to a document's path to name its checksum file,
+// which has the format of sha256sum output
+const sidecarExt = ".sha256"
+
+// writeAtomic writes r to a temporary file next to path, syncs it and renames
+// it to path, returning the size and hex SHA-256 written
+func writeAtomic(path string, r io.Reader) (int64, string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+tmpInfix+"*")
+	if err != nil {
+		return 0, "", err
 	}
-	return false, fmt.Errorf("checking file %s: %w", fullPath, err)
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return 0, "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, "", err
+	}
+	committed = true
+	return size, hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// Get retrieves a document from local storage
-func (s *LocalStorage) Get(ctx context.Context, announcement *models.Announcement) ([]byte, error) {
-	relPath := DocumentPath(announcement)
-	fullPath := filepath.Join(s.basePath, relPath)
+// tmpInfix marks the temporary files of writeAtomic, named
+// .<file name>.tmp-<random>
+const tmpInfix = ".tmp-"
+
+// writeSidecar records the hash of the document at path
+func writeSidecar(path, hash string) error {
+	line := hash + "  " + filepath.Base(path) + "\n"
+	_, _, err := writeAtomic(path+sidecarExt, strings.NewReader(line))
+	return err
+}
 
-	data, err := os.ReadFile(fullPath)
+// readSidecar returns the hash recorded for the document at path, or "" if
+// it has none
+func readSidecar(path string) (string, error) {
+	data, err := os.ReadFile(path + sidecarExt)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("reading file %s: %w", fullPath, err)
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", nil
 	}
+	return fields[0], nil
+}
 
-	return data, nil
+// syncDir syncs a directory so renames into it survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
-// BasePath returns the base path for local storage
-func (s *LocalStorage) BasePath() string {
-	return s.basePath
+// isHidden reports whether a file or directory name is hidden, as temporary
+// files and the quarantine are
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
 }
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage implements Storage in memory, for tests and dry runs
+type MemoryStorage struct {
+	filings
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info Info
+}
+
+// NewMemoryStorage creates an empty in-memory storage instance
+func NewMemoryStorage() *MemoryStorage {
+	s := &MemoryStorage{objects: make(map[string]memoryObject)}
+	s.filings = filings{s}
+	return s
+}
+
+// Location returns the mem:// URL of a key
+func (s *MemoryStorage) Location(key string) string {
+	return "mem://" + key
+}
+
+// PutKey stores a document in memory
+func (s *MemoryStorage) PutKey(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading document: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	info := Info{
+		Key:         key,
+		Size:        int64(len(data)),
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: contentType,
+		ModTime:     time.Now(),
+	}
+
+	s.mu.Lock()
+	s.objects[key] = memoryObject{data: data, info: info}
+	s.mu.Unlock()
+	return &info, nil
+}
+
+// OpenKey returns a reader of a document in memory
+func (s *MemoryStorage) OpenKey(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// OpenRange returns a reader of part of a document in memory
+func (s *MemoryStorage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(io.NewSectionReader(bytes.NewReader(obj.data), offset, length)), nil
+}
+
+// StatKey describes a document in memory
+func (s *MemoryStorage) StatKey(ctx context.Context, key string) (*Info, error) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	info := obj.info
+	return &info, nil
+}
+
+// CopyKey copies a document in memory
+func (s *MemoryStorage) CopyKey(ctx context.Context, from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[from]
+	if !ok {
+		return ErrNotFound
+	}
+	obj.info.Key = to
+	obj.info.ModTime = time.Now()
+	s.objects[to] = obj
+	return nil
+}
+
+// DeleteKey removes a document from memory
+func (s *MemoryStorage) DeleteKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.objects, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// List calls fn for each document under prefix, in key order
+func (s *MemoryStorage) List(ctx context.Context, prefix string, fn func(*Info) error) error {
+	s.mu.RLock()
+	var infos []Info
+	for key, obj := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, obj.info)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for i := range infos {
+		if err := fn(&infos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,110 +1,234 @@
 package storage
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
-	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
 )
 
+// hashMetadata is the S3 user metadata holding a document's SHA-256
+const hashMetadata = "sha256"
+
 // S3Storage implements Storage for AWS S3
 type S3Storage struct {
+	filings
 	client *s3.Client
 	bucket string
-	prefix string
 }
 
-// NewS3Storage creates a new S3 storage instance
-func NewS3Storage(ctx context.Context, bucket, region, prefix string) (*S3Storage, error) {
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+// NewS3Storage creates a new S3 storage instance configured by
+// awsclient.FromEnv. A non-empty region overrides AWS_REGION.
+func NewS3Storage(ctx context.Context, bucket, region string) (*S3Storage, error) {
+	opts := awsclient.FromEnv()
+	if region != "" {
+		opts.Region = region
+	}
+	clients, err := awsclient.New(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("loading AWS config: %w", err)
+		return nil, err
 	}
 
-	client := s3.NewFromConfig(cfg)
+	return NewS3StorageFromClient(clients.S3(), bucket), nil
+}
 
-	return &S3Storage{
-		client: client,
-		bucket: bucket,
-		prefix: prefix,
-	}, nil
+// NewS3StorageFromClient creates an S3 storage instance using client
+func NewS3StorageFromClient(client *s3.Client, bucket string) *S3Storage {
+	s := &S3Storage{client: client, bucket: bucket}
+	s.filings = filings{s}
+	return s
 }
 
-// Save stores a document to S3
-func (s *S3Storage) Save(ctx context.Context, announcement *models.Announcement, data []byte) (string, error) {
-	key := s.objectKey(announcement)
+// Bucket returns the bucket documents are stored in
+func (s *S3Storage) Bucket() string {
+	return s.bucket
+}
 
-	contentType := "application/octet-stream"
-	if announcement.IsPDF() {
-		contentType = "application/pdf"
-	} else if announcement.IsHTML() {
-		contentType = "text/html"
-	}
+// Location returns the s3:// URL of a key
+func (s *S3Storage) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key)
+}
 
-	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(data),
-		ContentType: aws.String(contentType),
+// PutKey uploads a document to S3 with its SHA-256 in the object metadata.
+// Readers that cannot seek are spooled to a temporary file first, since the
+// upload needs the content length.
+func (s *S3Storage) PutKey(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	body, size, hash, cleanup, err := seekable(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading document: %w", err)
+	}
+	defer cleanup()
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+		Metadata:      map[string]string{hashMetadata: hash},
 	})
 	if err != nil {
-		return "", fmt.Errorf("uploading to S3: %w", err)
+		return nil, fmt.Errorf("uploading to S3: %w", err)
 	}
 
-	return fmt.Sprintf("s3://%s/%s", s.bucket, key), nil
+	return &Info{Key: key, Size: size, Hash: hash, ContentType: contentType}, nil
 }
 
-// Exists checks if a document already exists in S3
-func (s *S3Storage) Exists(ctx context.Context, announcement *models.Announcement) (bool, error) {
-	key := s.objectKey(announcement)
+// OpenKey streams a document from S3
+func (s *S3Storage) OpenKey(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting S3 object: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// OpenRange returns a reader of part of a document in S3
+func (s *S3Storage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting S3 object range: %w", err)
+	}
+	return resp.Body, nil
+}
 
-	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+// StatKey describes a document in S3. Documents uploaded before hashes were
+// recorded have no Hash.
+func (s *S3Storage) StatKey(ctx context.Context, key string) (*Info, error) {
+	resp, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
 		var notFound *types.NotFound
 		if errors.As(err, &notFound) {
-			return false, nil
+			return nil, ErrNotFound
 		}
-		return false, fmt.Errorf("checking S3 object: %w", err)
+		return nil, fmt.Errorf("checking S3 object: %w", err)
 	}
 
-	return true, nil
+	return &Info{
+		Key:         key,
+		Size:        aws.ToInt64(resp.ContentLength),
+		Hash:        resp.Metadata[hashMetadata],
+		ETag:        aws.ToString(resp.ETag),
+		ContentType: aws.ToString(resp.ContentType),
+		ModTime:     aws.ToTime(resp.LastModified),
+	}, nil
 }
 
-// Get retrieves a document from S3
-func (s *S3Storage) Get(ctx context.Context, announcement *models.Announcement) ([]byte, error) {
-	key := s.objectKey(announcement)
+// CopyKey copies a document within the bucket, keeping its metadata
+func (s *S3Storage) CopyKey(ctx context.Context, from, to string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(to),
+		CopySource: aws.String(s.bucket + "/" + (&url.URL{Path: from}).EscapedPath()),
+	})
+	if err != nil {
+		return fmt.Errorf("copying S3 object: %w", err)
+	}
+	return nil
+}
 
-	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+// DeleteKey removes a document from S3
+func (s *S3Storage) DeleteKey(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getting S3 object: %w", err)
+		return fmt.Errorf("deleting S3 object: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading S3 object body: %w", err)
+// List pages through the objects under prefix. Listed documents have no
+// Hash or ContentType.
+func (s *S3Storage) List(ctx context.Context, prefix string, fn func(*Info) error) error {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
 	}
-
-	return data, nil
+	p := s3.NewListObjectsV2Paginator(s.client, input)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("listing S3 objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			info := &Info{
+				Key:     aws.ToString(obj.Key),
+				Size:    aws.ToInt64(obj.Size),
+				ETag:    aws.ToString(obj.ETag),
+				ModTime: aws.ToTime(obj.LastModified),
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (s *S3Storage) objectKey(announcement *models.Announcement) string {
-	path := DocumentPath(announcement)
-	if s.prefix != "" {
-		return s.prefix + "/" + path
+// seekable returns r as a seeker positioned at the start, with its size and
+// SHA-256. Readers that cannot seek are copied to a temporary file, removed
+// by cleanup.
+func seekable(r io.Reader) (io.ReadSeeker, int64, string, func(), error) {
+	h := sha256.New()
+	if rs, ok := r.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, "", nil, err
+		}
+		size, err := io.Copy(h, rs)
+		if err != nil {
+			return nil, 0, "", nil, err
+		}
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, "", nil, err
+		}
+		return rs, size, hex.EncodeToString(h.Sum(nil)), func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "storage-*")
+	if err != nil {
+		return nil, 0, "", nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, "", nil, err
 	}
-	return path
+	return tmp, size, hex.EncodeToString(h.Sum(nil)), cleanup, nil
 }
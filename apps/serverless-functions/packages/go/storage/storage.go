@@ -2,73 +2,109 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"time"
 
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
 )
 
-// Storage defines the interface for storing documents
+// ErrNotFound is returned for documents that are not stored
+var ErrNotFound = errors.New("document not found")
+
+// Info describes a stored document
+type Info struct {
+	Key         string // key within the store (see Key)
+	Size        int64
+	Hash        string // hex SHA-256 of the content; empty if unknown
+	ETag        string // S3 entity tag; empty for other stores
+	ContentType string
+	ModTime     time.Time
+}
+
+// Blobs stores documents by key. Keys are slash-separated and relative to
+// the store's root. Tools handling keys outside the current layout use it
+// directly; everything else goes through Storage.
+type Blobs interface {
+	// PutKey stores a document read from r, replacing any document stored
+	// under key
+	PutKey(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error)
+	// OpenKey returns a reader of a stored document
+	OpenKey(ctx context.Context, key string) (io.ReadCloser, error)
+	// OpenRange returns a reader of length bytes of a stored document from
+	// offset; it is cut short at the end of the document
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// StatKey describes a stored document
+	StatKey(ctx context.Context, key string) (*Info, error)
+	// CopyKey copies a stored document to another key
+	CopyKey(ctx context.Context, from, to string) error
+	// DeleteKey removes a stored document; removing a missing document is
+	// not an error
+	DeleteKey(ctx context.Context, key string) error
+	// List calls fn for each document whose key starts with prefix. Listed
+	// documents may have no Hash or ContentType.
+	List(ctx context.Context, prefix string, fn func(*Info) error) error
+	// Location returns where a key is stored: a file path or a URL
+	Location(key string) string
+}
+
+// Storage stores filing documents under their document key (see Key). The
+// filing methods return ErrNotFound for documents that are not stored.
 type Storage interface {
-	// Save stores a document and returns the storage path
-	Save(ctx context.Context, announcement *models.Announcement, data []byte) (string, error)
-	// Exists checks if a document already exists
-	Exists(ctx context.Context, announcement *models.Announcement) (bool, error)
-	// Get retrieves a stored document
-	Get(ctx context.Context, announcement *models.Announcement) ([]byte, error)
+	Blobs
+	Put(ctx context.Context, filing *models.Filing, r io.Reader, contentType string) (*Info, error)
+	Open(ctx context.Context, filing *models.Filing) (io.ReadCloser, error)
+	Stat(ctx context.Context, filing *models.Filing) (*Info, error)
+	Delete(ctx context.Context, filing *models.Filing) error
 }
 
-// DocumentPath generates a consistent path for storing documents
-// Format: {year}/{stockCode}/{newsId}.{ext}
-func DocumentPath(announcement *models.Announcement) string {
-	stockCode := "unknown"
-	if len(announcement.Stock) > 0 {
-		stockCode = announcement.Stock[0].SC
-	}
+// filings implements the filing methods of Storage over a store's keys
+type filings struct {
+	blobs Blobs
+}
 
-	// Extract year from WebPath (format: /listedco/listconews/sehk/2025/1127/filename.pdf)
-	year := "unknown"
-	parts := splitPath(announcement.WebPath)
-	for i, p := range parts {
-		if len(p) == 4 && p[0] == '2' && p[1] == '0' {
-			year = p
-			break
-		}
-		// Fallback: year is typically after "sehk"
-		if p == "sehk" && i+1 < len(parts) {
-			year = parts[i+1]
-			break
-		}
-	}
+// Put stores a filing's document
+func (f filings) Put(ctx context.Context, filing *models.Filing, r io.Reader, contentType string) (*Info, error) {
+	return f.blobs.PutKey(ctx, Key(filing), r, contentType)
+}
 
-	return year + "/" + stockCode + "/" + itoa(announcement.NewsID) + "." + announcement.Ext
+// Open returns a reader of a filing's document
+func (f filings) Open(ctx context.Context, filing *models.Filing) (io.ReadCloser, error) {
+	return f.blobs.OpenKey(ctx, Key(filing))
 }
 
-func splitPath(path string) []string {
-	var parts []string
-	var current []byte
-	for i := 0; i < len(path); i++ {
-		if path[i] == '/' {
-			if len(current) > 0 {
-				parts = append(parts, string(current))
-				current = nil
-			}
-		} else {
-			current = append(current, path[i])
-		}
-	}
-	if len(current) > 0 {
-		parts = append(parts, string(current))
-	}
-	return parts
+// Stat describes a filing's document
+func (f filings) Stat(ctx context.Context, filing *models.Filing) (*Info, error) {
+	return f.blobs.StatKey(ctx, Key(filing))
+}
+
+// Delete removes a filing's document
+func (f filings) Delete(ctx context.Context, filing *models.Filing) error {
+	return f.blobs.DeleteKey(ctx, Key(filing))
 }
 
-func itoa(n int) string {
-	if n == 0 {
-		return "0"
+// DocumentPath returns the key of an announcement's document (see Key). The
+// stock code stands in for the company ID.
+func DocumentPath(announcement *models.Announcement) string {
+	return Key(AnnouncementFiling(announcement))
+}
+
+// AnnouncementFiling converts a feed announcement to the filing its document
+// is stored under
+func AnnouncementFiling(announcement *models.Announcement) *models.Filing {
+	stockCode := ""
+	if len(announcement.Stock) > 0 {
+		stockCode = announcement.Stock[0].SC
 	}
-	var digits []byte
-	for n > 0 {
-		digits = append([]byte{byte('0' + n%10)}, digits...)
-		n /= 10
+	return models.AnnouncementToFiling(announcement, stockCode)
+}
+
+// contentTypeOf guesses the content type of a key from its extension
+func contentTypeOf(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
 	}
-	return string(digits)
+	return "application/octet-stream"
 }
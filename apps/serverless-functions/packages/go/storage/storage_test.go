@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+func TestStorage(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]Storage{
+		"local":  local,
+		"memory": NewMemoryStorage(),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) { testStorage(t, s) })
+	}
+}
+
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	filing := &models.Filing{
+		Exchange:      "HKEX",
+		CompanyID:     "00005",
+		SourceID:      "11223344",
+		ReportDate:    time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC),
+		FileExtension: "pdf",
+	}
+
+	if _, err := s.Stat(ctx, filing); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat() before Put error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Open(ctx, filing); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open() before Put error = %v, want ErrNotFound", err)
+	}
+
+	// A reader that cannot seek, as from an HTTP response
+	put, err := s.Put(ctx, filing, io.MultiReader(strings.NewReader("%PDF-1.4 ")), "application/pdf")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if put.Key != "hkex/00005/2024/03/28/11223344.pdf" || put.Size != 9 {
+		t.Errorf("Put() = %+v", put)
+	}
+
+	info, err := s.Stat(ctx, filing)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 9 || info.Hash != put.Hash || info.ContentType != "application/pdf" {
+		t.Errorf("Stat() = %+v, want size 9, hash %s", info, put.Hash)
+	}
+	if len(info.Hash) != 64 {
+		t.Errorf("Stat() hash = %q, want hex SHA-256", info.Hash)
+	}
+
+	r, err := s.Open(ctx, filing)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "%PDF-1.4 " {
+		t.Errorf("Open() read %q", data)
+	}
+
+	r, err = s.OpenRange(ctx, put.Key, 1, 3)
+	if err != nil {
+		t.Fatalf("OpenRange() error = %v", err)
+	}
+	data, _ = io.ReadAll(r)
+	r.Close()
+	if string(data) != "PDF" {
+		t.Errorf("OpenRange() read %q", data)
+	}
+
+	if err := s.CopyKey(ctx, put.Key, "2024/5/11223344.pdf"); err != nil {
+		t.Fatalf("CopyKey() error = %v", err)
+	}
+	var keys []string
+	err = s.List(ctx, "hkex/00005/", func(info *Info) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0] != put.Key {
+		t.Errorf("List(hkex/00005/) = %v, want [%s]", keys, put.Key)
+	}
+
+	if err := s.Delete(ctx, filing); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, filing); err != nil {
+		t.Errorf("Delete() of missing document error = %v", err)
+	}
+	if _, err := s.Stat(ctx, filing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() after Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.StatKey(ctx, "2024/5/11223344.pdf"); err != nil {
+		t.Errorf("StatKey() of copy error = %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package warc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// IndexEntry is a line of a CDXJ index: the location of a response record
+type IndexEntry struct {
+	SURT      string `json:"-"`
+	Timestamp string `json:"-"` // YYYYMMDDhhmmss
+	URL       string `json:"url"`
+	MIME      string `json:"mime,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Digest    string `json:"digest"`
+	Length    string `json:"length"`
+	Offset    string `json:"offset"`
+	Filename  string `json:"filename"`
+
+	// Fields holds every field of the line, including the exchange's
+	// metadata
+	Fields map[string]interface{} `json:"-"`
+}
+
+// indexLine returns the CDXJ line of an exchange's response record. The
+// exchange's metadata is added to the standard fields.
+func indexLine(e *Exchange, entry IndexEntry) (string, error) {
+	fields := make(map[string]interface{}, len(e.Metadata)+7)
+	for k, v := range e.Metadata {
+		fields[k] = v
+	}
+	std, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(std, &fields); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", SURT(e.TargetURI), e.Date.UTC().Format("20060102150405"), data), nil
+}
+
+// ParseIndexLine parses a CDXJ line
+func ParseIndexLine(line string) (*IndexEntry, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed CDXJ line: %q", line)
+	}
+	entry := &IndexEntry{SURT: parts[0], Timestamp: parts[1]}
+	if err := json.Unmarshal([]byte(parts[2]), entry); err != nil {
+		return nil, fmt.Errorf("parsing CDXJ fields: %w", err)
+	}
+	if err := json.Unmarshal([]byte(parts[2]), &entry.Fields); err != nil {
+		return nil, fmt.Errorf("parsing CDXJ fields: %w", err)
+	}
+	return entry, nil
+}
+
+// SURT returns the Sort-friendly URI Reordering Transform of a URL, the
+// CDXJ sort key: the host's labels reversed and comma-separated without a
+// leading www., default ports and the scheme, then the path and sorted
+// query, lower-cased. For example
+// https://www1.hkexnews.hk/listedco/a.pdf becomes
+// hk,hkexnews,www1)/listedco/a.pdf.
+func SURT(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	key := strings.Join(labels, ",")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + path
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		sort.Strings(params)
+		key += "?" + strings.Join(params, "&")
+	}
+	return strings.ToLower(key)
+}
@@ -0,0 +1,75 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Record is a WARC record
+type Record struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+// Type returns the record's WARC-Type
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// ReadRecord reads the record of the gzip member at the start of r, as
+// found at an index entry's offset. To read the records that follow, r must
+// be an io.ByteReader such as a bufio.Reader, so no more than the member is
+// consumed.
+func ReadRecord(r io.Reader) (*Record, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening record: %w", err)
+	}
+	zr.Multistream(false)
+	defer zr.Close()
+
+	br := bufio.NewReader(zr)
+	version, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading record version: %w", err)
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("not a WARC record: %q", strings.TrimSpace(version))
+	}
+
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("reading record header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("reading record length: %w", err)
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(br, block); err != nil {
+		return nil, fmt.Errorf("reading record block: %w", err)
+	}
+	return &Record{Header: header, Block: block}, nil
+}
+
+// Response parses the HTTP response of a response record, returning it with
+// its body after transfer decoding
+func (r *Record) Response() (*http.Response, []byte, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing HTTP response: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading HTTP response body: %w", err)
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,278 @@
+// Package warc writes WARC 1.1 archives of HTTP exchanges with CDXJ indexes,
+// and reads their records back.
+//
+// Each record is a separate gzip member, so a record can be read from its
+// offset alone. Every file starts with a warcinfo record; each exchange is
+// written as a request, a response and a JSON metadata record.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Version is the WARC version written
+const Version = "WARC/1.1"
+
+// DefaultMaxSize is the size after which a file is rolled over
+const DefaultMaxSize = 1 << 30
+
+// Exchange is a captured HTTP request and its response
+type Exchange struct {
+	TargetURI string    // URL the capture is filed under
+	Date      time.Time // when the response was received
+	IPAddress string    // server address, if known
+	Request   []byte    // HTTP request message
+	Response  []byte    // HTTP response message, including the body
+	Payload   []byte    // response body after transfer decoding, for the payload digest
+	MIME      string    // response content type
+	Status    int
+	Metadata  map[string]interface{} // written as the metadata record and copied to the index
+}
+
+// Config configures a Writer
+type Config struct {
+	// Dir is the directory files are written in
+	Dir string
+	// Prefix starts file names: <prefix>-<timestamp>-<serial>-<random>.warc.gz
+	Prefix string
+	// MaxSize is the size after which a file is rolled over (default: 1 GiB)
+	MaxSize int64
+	// Software is recorded in each file's warcinfo record
+	Software string
+	// OnClose is called with the paths of each completed file and its CDXJ
+	// index
+	OnClose func(warcPath, cdxjPath string) error
+}
+
+// Writer writes exchanges to size-limited WARC files. It is safe for
+// concurrent use; the records of an exchange are written together.
+type Writer struct {
+	cfg Config
+
+	mu     sync.Mutex
+	serial int
+	file   *os.File
+	name   string
+	offset int64
+	index  []string
+}
+
+// NewWriter creates a Writer. Files are created as exchanges are written.
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "capture"
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating WARC directory: %w", err)
+	}
+	return &Writer{cfg: cfg}, nil
+}
+
+// WriteExchange appends the request, response and metadata records of an
+// exchange, rolling over to a new file first if the current one is full
+func (w *Writer) WriteExchange(e *Exchange) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil && w.offset >= w.cfg.MaxSize {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		if err := w.openFile(); err != nil {
+			return err
+		}
+	}
+
+	date := e.Date.UTC().Format(time.RFC3339)
+	responseID, requestID, metadataID := recordID(), recordID(), recordID()
+	payloadDigest := digest(e.Payload)
+
+	responseOffset := w.offset
+	err := w.writeRecord([][2]string{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", e.TargetURI},
+		{"WARC-IP-Address", e.IPAddress},
+		{"WARC-Payload-Digest", payloadDigest},
+		{"Content-Type", "application/http;msgtype=response"},
+	}, e.Response)
+	if err != nil {
+		return err
+	}
+	responseLength := w.offset - responseOffset
+
+	err = w.writeRecord([][2]string{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", requestID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", e.TargetURI},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/http;msgtype=request"},
+	}, e.Request)
+	if err != nil {
+		return err
+	}
+
+	if e.Metadata != nil {
+		meta, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return fmt.Errorf("encoding metadata: %w", err)
+		}
+		err = w.writeRecord([][2]string{
+			{"WARC-Type", "metadata"},
+			{"WARC-Record-ID", metadataID},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", e.TargetURI},
+			{"WARC-Refers-To", responseID},
+			{"Content-Type", "application/json"},
+		}, meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	line, err := indexLine(e, IndexEntry{
+		URL:      e.TargetURI,
+		MIME:     e.MIME,
+		Status:   strconv.Itoa(e.Status),
+		Digest:   payloadDigest,
+		Length:   strconv.FormatInt(responseLength, 10),
+		Offset:   strconv.FormatInt(responseOffset, 10),
+		Filename: w.name,
+	})
+	if err != nil {
+		return err
+	}
+	w.index = append(w.index, line)
+
+	return w.file.Sync()
+}
+
+// Close completes the current file, if any
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.closeFile()
+}
+
+func (w *Writer) openFile() error {
+	w.serial++
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	w.name = fmt.Sprintf("%s-%s-%05d-%x.warc.gz", w.cfg.Prefix, time.Now().UTC().Format("20060102150405"), w.serial, suffix)
+
+	f, err := os.Create(filepath.Join(w.cfg.Dir, w.name))
+	if err != nil {
+		return fmt.Errorf("creating WARC file: %w", err)
+	}
+	w.file, w.offset, w.index = f, 0, nil
+
+	info := fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.1\r\nconformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n", w.cfg.Software)
+	return w.writeRecord([][2]string{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", recordID()},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+		{"WARC-Filename", w.name},
+		{"Content-Type", "application/warc-fields"},
+	}, []byte(info))
+}
+
+// closeFile closes the current file, writes its index and calls OnClose
+func (w *Writer) closeFile() error {
+	warcPath := w.file.Name()
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("closing WARC file: %w", err)
+	}
+	w.file = nil
+
+	sort.Strings(w.index)
+	cdxjPath := warcPath[:len(warcPath)-len(".warc.gz")] + ".cdxj"
+	var buf bytes.Buffer
+	for _, line := range w.index {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(cdxjPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing CDXJ index: %w", err)
+	}
+
+	if w.cfg.OnClose != nil {
+		return w.cfg.OnClose(warcPath, cdxjPath)
+	}
+	return nil
+}
+
+// writeRecord writes one record as a gzip member. Empty header values are
+// left out.
+func (w *Writer) writeRecord(fields [][2]string, block []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	for _, f := range fields {
+		if f[1] != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", f[0], f[1])
+		}
+	}
+	fmt.Fprintf(&buf, "WARC-Block-Digest: %s\r\n", digest(block))
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	cw := &countingWriter{w: w.file}
+	zw := gzip.NewWriter(cw)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing WARC record: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("writing WARC record: %w", err)
+	}
+	w.offset += cw.n
+	return nil
+}
+
+// digest returns the labelled SHA-256 digest of data
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// recordID returns a new random record ID
+func recordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,120 @@
+package warc
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	dir := t.TempDir()
+	var closed [][2]string
+	w, err := NewWriter(Config{
+		Dir:      dir,
+		Prefix:   "test",
+		MaxSize:  1, // one exchange per file
+		Software: "test",
+		OnClose: func(warcPath, cdxjPath string) error {
+			closed = append(closed, [2]string{warcPath, cdxjPath})
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range []string{"11223344", "11223345"} {
+		body := "%PDF-1.4 " + id
+		err := w.WriteExchange(&Exchange{
+			TargetURI: "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/" + id + ".pdf",
+			Date:      time.Date(2024, 3, 28, 14, 45, i, 0, time.UTC),
+			IPAddress: "203.0.113.7",
+			Request:   []byte("GET /listedco/listconews/sehk/2024/0328/" + id + ".pdf HTTP/1.1\r\nHost: www1.hkexnews.hk\r\n\r\n"),
+			Response:  []byte("HTTP/1.1 200 OK\r\nContent-Type: application/pdf\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body),
+			Payload:   []byte(body),
+			MIME:      "application/pdf",
+			Status:    200,
+			Metadata:  map[string]interface{}{"filing_id": "fil_" + id},
+		})
+		if err != nil {
+			t.Fatalf("WriteExchange() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(closed) != 2 {
+		t.Fatalf("files closed = %d, want 2", len(closed))
+	}
+
+	// Locate the second capture through its index
+	index, err := os.ReadFile(closed[1][1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := ParseIndexLine(strings.TrimSpace(string(index)))
+	if err != nil {
+		t.Fatalf("ParseIndexLine() error = %v", err)
+	}
+	if entry.SURT != "hk,hkexnews,www1)/listedco/listconews/sehk/2024/0328/11223345.pdf" || entry.Timestamp != "20240328144501" {
+		t.Errorf("index key = %s %s", entry.SURT, entry.Timestamp)
+	}
+	if entry.Fields["filing_id"] != "fil_11223345" || entry.Status != "200" {
+		t.Errorf("index fields = %v", entry.Fields)
+	}
+
+	f, err := os.Open(closed[1][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	offset, _ := strconv.ParseInt(entry.Offset, 10, 64)
+	if _, err := f.Seek(offset, 0); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(f)
+	rec, err := ReadRecord(br)
+	if err != nil {
+		t.Fatalf("ReadRecord() error = %v", err)
+	}
+	if rec.Type() != "response" || rec.Header.Get("WARC-Payload-Digest") != entry.Digest {
+		t.Errorf("record header = %v", rec.Header)
+	}
+	resp, body, err := rec.Response()
+	if err != nil {
+		t.Fatalf("Response() error = %v", err)
+	}
+	if resp.StatusCode != 200 || string(body) != "%PDF-1.4 11223345" {
+		t.Errorf("Response() = %d %q", resp.StatusCode, body)
+	}
+
+	// The metadata record follows the request
+	for _, want := range []string{"request", "metadata"} {
+		rec, err := ReadRecord(br)
+		if err != nil {
+			t.Fatalf("ReadRecord() error = %v", err)
+		}
+		if rec.Type() != want {
+			t.Errorf("record type = %s, want %s", rec.Type(), want)
+		}
+	}
+}
+
+func TestSURT(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www1.hkexnews.hk/listedco/A.pdf", "hk,hkexnews,www1)/listedco/a.pdf"},
+		{"http://www.example.com:8080/p?b=2&a=1", "com,example:8080)/p?a=1&b=2"},
+		{"https://example.com", "com,example)/"},
+	}
+	for _, tt := range tests {
+		if got := SURT(tt.url); got != tt.want {
+			t.Errorf("SURT(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/warc"
+)
+
+// SetArchive sets the WARC writer every fetch is captured to. A capture
+// that can't be written fails its download.
+func (d *Downloader) SetArchive(w *warc.Writer) {
+	d.archive = w
+}
+
+// NewArchive creates a WARC writer for SetArchive that writes files under
+// dir, rolling over after maxSize bytes (0 for warc.DefaultMaxSize). If
+// store is set, each completed file and its CDXJ index are uploaded under
+// prefix and removed from dir.
+func NewArchive(dir string, store storage.Blobs, prefix string, maxSize int64) (*warc.Writer, error) {
+	cfg := warc.Config{
+		Dir:      dir,
+		Prefix:   "filings",
+		MaxSize:  maxSize,
+		Software: "hkex-scraper downloader",
+	}
+	if store != nil {
+		cfg.OnClose = func(warcPath, cdxjPath string) error {
+			for _, p := range []string{warcPath, cdxjPath} {
+				if err := upload(store, path.Join(prefix, filepath.Base(p)), p); err != nil {
+					return err
+				}
+			}
+			log.Printf("Uploaded archive %s", path.Join(prefix, filepath.Base(warcPath)))
+			return nil
+		}
+	}
+	return warc.NewWriter(cfg)
+}
+
+// upload stores a local file under key and removes it
+func upload(store storage.Blobs, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	contentType := "application/warc"
+	if filepath.Ext(localPath) == ".cdxj" {
+		contentType = "text/plain"
+	}
+	if _, err := store.PutKey(context.Background(), key, f, contentType); err != nil {
+		return fmt.Errorf("uploading %s: %w", key, err)
+	}
+	return os.Remove(localPath)
+}
+
+// capture writes an exchange to the archive, filed under the filing's
+// source URL even when it was fetched through the proxy
+func (d *Downloader) capture(filing *models.Filing, sourceURL string, req *http.Request, resp *http.Response, body []byte, remoteAddr string) error {
+	// DumpRequestOut replays the request, so leave out the client trace
+	request, err := httputil.DumpRequestOut(req.WithContext(context.Background()), false)
+	if err != nil {
+		return fmt.Errorf("dumping request: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	response, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return fmt.Errorf("dumping response: %w", err)
+	}
+
+	var ip string
+	if remoteAddr != "" {
+		ip, _, _ = net.SplitHostPort(remoteAddr)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	sum := sha256.Sum256(body)
+
+	metadata := map[string]interface{}{
+		"filing_id": filing.ID,
+		"exchange":  filing.Exchange,
+		"source_id": filing.SourceID,
+		"sha256":    hex.EncodeToString(sum[:]),
+	}
+	if fetchURL := req.URL.String(); fetchURL != sourceURL {
+		metadata["proxy"] = d.config.ProxyBaseURL
+		metadata["fetch_url"] = fetchURL
+	}
+
+	return d.archive.WriteExchange(&warc.Exchange{
+		TargetURI: sourceURL,
+		Date:      time.Now(),
+		IPAddress: ip,
+		Request:   request,
+		Response:  response,
+		Payload:   body,
+		MIME:      mediaType,
+		Status:    resp.StatusCode,
+		Metadata:  metadata,
+	})
+}
@@ -0,0 +1,186 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/htmltable"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DocumentStore persists the attachments of filings
+type DocumentStore interface {
+	// RegisterDocuments records attachments, keeping the status of those
+	// already known, and returns the ones still to be downloaded
+	RegisterDocuments(ctx context.Context, docs []models.FilingDocument) ([]models.FilingDocument, error)
+	UpdateDocumentDownload(ctx context.Context, exchange, documentID, localPath, s3Key string, fileSize int, status models.ProcessingStatus, errorMsg string) error
+}
+
+// isHTML reports whether a downloaded filing is an HTML page: an
+// announcement in HTML or a multi-document index page
+func isHTML(filing *models.Filing, contentType string) bool {
+	ext := filing.FileExtension
+	if ext == "" {
+		ext = storage.ExtensionFromURL(filing.SourceURL)
+	}
+	return ext == "htm" || ext == "html" || strings.HasPrefix(contentType, "text/html")
+}
+
+// FindAttachments returns the documents linked from an HTML filing: links
+// on the same site as the page to files with a known document extension, in
+// page order. Links to other HTML pages are navigation, not attachments.
+func FindAttachments(filing *models.Filing, pageURL string, body []byte) []models.FilingDocument {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{base.String(): true}
+	var docs []models.FilingDocument
+	for _, a := range htmltable.FindAll(doc, atom.A) {
+		href := strings.TrimSpace(htmltable.Attr(a, "href"))
+		if href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+		u, err := base.Parse(href)
+		if err != nil || !strings.EqualFold(u.Host, base.Host) {
+			continue
+		}
+		u.Fragment = ""
+		link := u.String()
+
+		ext := storage.ExtensionFromURL(link)
+		if ext == "" || ext == "htm" || ext == "html" || seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		docs = append(docs, models.FilingDocument{
+			ID:               attachmentID(filing.SourceID, u),
+			Exchange:         filing.Exchange,
+			FilingSourceID:   filing.SourceID,
+			Seq:              len(docs) + 1,
+			Title:            htmltable.Text(a),
+			SourceURL:        link,
+			FileExtension:    ext,
+			ProcessingStatus: models.ProcessingStatusPending,
+			CreatedAt:        time.Now(),
+			UpdatedAt:        time.Now(),
+		})
+	}
+	return docs
+}
+
+// attachmentID derives an attachment's ID from its filing and the path and
+// query of its link, which are on the filing's site. File names are not
+// used: they repeat across directories and may be non-ASCII.
+func attachmentID(sourceID string, u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.RequestURI()))
+	return sourceID + "_" + hex.EncodeToString(sum[:8])
+}
+
+// DownloadDocument downloads an attachment of a filing. The attachment is
+// stored next to its filing under its own ID; its links are not followed.
+func (d *Downloader) DownloadDocument(ctx context.Context, parent *models.Filing, doc *models.FilingDocument) *Result {
+	start := time.Now()
+	result := &Result{
+		FilingID: doc.ID,
+	}
+
+	if d.config.DryRun {
+		result.Success = true
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	child := *parent
+	child.ID = doc.ID
+	child.SourceID = doc.ID
+	child.SourceURL = doc.SourceURL
+	child.FileExtension = doc.FileExtension
+	if doc.Title != "" {
+		child.Title = doc.Title
+	}
+
+	body, contentType, err := d.fetch(ctx, &child, doc.SourceURL)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	result.FileSize = int64(len(body))
+
+	result.LocalPath, result.S3Key, err = d.store(ctx, &child, body, contentType)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	result.Success = true
+	result.Duration = time.Since(start)
+	return result
+}
+
+// ProcessAttachments registers the attachments found while downloading a
+// filing and downloads those not yet downloaded, recording each one's
+// status. It returns the attachment results.
+func (d *Downloader) ProcessAttachments(ctx context.Context, parent *models.Filing, r *Result, store DocumentStore) ([]*Result, error) {
+	if !r.Success || len(r.Attachments) == 0 {
+		return nil, nil
+	}
+
+	pending, err := store.RegisterDocuments(ctx, r.Attachments)
+	if err != nil {
+		return nil, fmt.Errorf("registering attachments of %s: %w", parent.SourceID, err)
+	}
+
+	var results []*Result
+	for i := range pending {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		doc := &pending[i]
+		dr := d.DownloadDocument(ctx, parent, doc)
+		status, errorMsg := ResultStatus(dr)
+		if err := store.UpdateDocumentDownload(ctx, doc.Exchange, doc.ID, dr.LocalPath, dr.S3Key, int(dr.FileSize), status, errorMsg); err != nil {
+			log.Printf("Failed to update attachment %s: %v", doc.ID, err)
+		}
+		results = append(results, dr)
+	}
+	return results, nil
+}
+
+// ResultStatus maps a download result to a processing status and error
+// message: 404s are permanent URL failures, 403/429s can be retried later
+func ResultStatus(r *Result) (models.ProcessingStatus, string) {
+	if r.Success {
+		return models.ProcessingStatusCompleted, ""
+	}
+
+	var errorMsg string
+	if r.Error != nil {
+		errorMsg = r.Error.Error()
+	}
+	switch {
+	case IsURLNotFoundError(r.Error):
+		return models.ProcessingStatusURLFailure, errorMsg
+	case IsRateLimitError(r.Error):
+		return models.ProcessingStatusRateLimited, errorMsg
+	}
+	return models.ProcessingStatusFailed, errorMsg
+}
@@ -30,6 +30,7 @@ type FilingStore interface {
 type BatchDownloader struct {
 	downloader *Downloader
 	store      FilingStore
+	documents  DocumentStore
 }
 
 // NewBatchDownloader creates a new batch downloader
@@ -40,6 +41,12 @@ func NewBatchDownloader(downloader *Downloader, store FilingStore) *BatchDownloa
 	}
 }
 
+// SetDocumentStore enables attachment downloads: attachments found in HTML
+// filings are registered in store and downloaded after their filing
+func (b *BatchDownloader) SetDocumentStore(store DocumentStore) {
+	b.documents = store
+}
+
 // DownloadBatch downloads multiple filings concurrently
 func (b *BatchDownloader) DownloadBatch(ctx context.Context, filings []models.Filing) *BatchResult {
 	start := time.Now()
@@ -199,30 +206,18 @@ func (b *BatchDownloader) DownloadBatchWithStatusUpdates(ctx context.Context, fi
 				}
 
 				// Update database IMMEDIATELY after each download
-				var status models.ProcessingStatus
-				var errorMsg string
-
-				if r.Success {
-					status = models.ProcessingStatusCompleted
-				} else {
-					// Check if it's a URL not found error (404) - permanent failure
-					if IsURLNotFoundError(r.Error) {
-						status = models.ProcessingStatusURLFailure
-					} else if IsRateLimitError(r.Error) {
-						// Rate limited (403/429) - can retry later
-						status = models.ProcessingStatusRateLimited
-					} else {
-						status = models.ProcessingStatusFailed
-					}
-					if r.Error != nil {
-						errorMsg = r.Error.Error()
-					}
-				}
-
+				status, errorMsg := ResultStatus(r)
 				if err := b.store.UpdateFilingDownload(ctx, r.FilingID, r.LocalPath, r.S3Key, status, errorMsg); err != nil {
 					log.Printf("Failed to update filing %s: %v", r.FilingID, err)
 				}
 
+				// Then register and download its attachments, if any
+				if b.documents != nil {
+					if _, err := b.downloader.ProcessAttachments(ctx, filing, r, b.documents); err != nil {
+						log.Printf("Failed to process attachments of filing %s: %v", r.FilingID, err)
+					}
+				}
+
 				results <- r
 			}
 		}(i)
@@ -0,0 +1,260 @@
+// Package batchworker downloads one chunk of a manifest written by
+// write-manifest, as a child of an AWS Batch array job.
+package batchworker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/warc"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader"
+)
+
+// FilingPayload matches the manifest JSONL format (same as sfn-downloader)
+type FilingPayload struct {
+	SourceID      string `json:"source_id"`
+	SourceURL     string `json:"source_url"`
+	CompanyID     string `json:"company_id"`
+	FileExtension string `json:"file_extension"`
+	Exchange      string `json:"exchange"`
+	ReportDate    string `json:"report_date"`
+}
+
+// Job is the manifest chunk processed by one child of a Batch array job
+type Job struct {
+	ManifestBucket string
+	ManifestKey    string
+	ArrayIndex     int
+	ChunkSize      int
+	JobID          string
+	S3Bucket       string // documents bucket
+	DatabaseURL    string
+	ProxyBaseURL   string
+	WARCPrefix     string
+}
+
+// NewJob reads a job from the Batch container environment through getenv
+func NewJob(getenv func(string) string) (Job, error) {
+	job := Job{
+		ManifestBucket: getenv("MANIFEST_BUCKET"),
+		ManifestKey:    getenv("MANIFEST_KEY"),
+		S3Bucket:       getenv("S3_BUCKET"),
+		DatabaseURL:    getenv("DATABASE_URL"),
+		ProxyBaseURL:   getenv("PROXY_BASE_URL"),
+		WARCPrefix:     getenv("WARC_PREFIX"),
+		ChunkSize:      getEnvInt(getenv, "CHUNK_SIZE", 50),
+		ArrayIndex:     getEnvInt(getenv, "AWS_BATCH_JOB_ARRAY_INDEX", 0),
+		JobID:          getenv("AWS_BATCH_JOB_ID"),
+	}
+	for _, key := range []string{"MANIFEST_BUCKET", "MANIFEST_KEY", "S3_BUCKET"} {
+		if getenv(key) == "" {
+			return job, fmt.Errorf("required environment variable %s is not set", key)
+		}
+	}
+	return job, nil
+}
+
+// Run downloads the filings in the job's manifest chunk
+func Run(ctx context.Context, job Job) error {
+	log.Printf("Batch worker starting: job_id=%s array_index=%d chunk_size=%d", job.JobID, job.ArrayIndex, job.ChunkSize)
+	log.Printf("Manifest: s3://%s/%s", job.ManifestBucket, job.ManifestKey)
+
+	// Read manifest from S3
+	clients, err := awsclient.New(ctx, awsclient.FromEnv())
+	if err != nil {
+		return fmt.Errorf("creating AWS clients: %w", err)
+	}
+	s3Client := clients.S3()
+
+	manifest, err := storage.NewS3StorageFromClient(s3Client, job.ManifestBucket).OpenKey(ctx, job.ManifestKey)
+	if err != nil {
+		return fmt.Errorf("reading manifest from S3: %w", err)
+	}
+	defer manifest.Close()
+
+	// Parse the chunk for this array index
+	filings, err := readChunk(manifest, job.ArrayIndex, job.ChunkSize)
+	if err != nil {
+		return fmt.Errorf("reading chunk: %w", err)
+	}
+
+	if len(filings) == 0 {
+		log.Printf("No filings in chunk %d, exiting", job.ArrayIndex)
+		return nil
+	}
+
+	log.Printf("Processing %d filings in chunk %d", len(filings), job.ArrayIndex)
+
+	// Create downloader with worker pool concurrency
+	dlConfig := downloader.Config{
+		Concurrency:     5,
+		ProxyBaseURL:    job.ProxyBaseURL,
+		Timeout:         30 * time.Second,
+		RetryAttempts:   3,
+		RetryDelay:      5 * time.Second,
+		MinRequestDelay: 500 * time.Millisecond,
+		MaxRequestDelay: 2 * time.Second,
+	}
+	dl := downloader.New(dlConfig)
+
+	// Upload documents through the S3 client that read the manifest
+	s3Store := storage.NewS3StorageFromClient(s3Client, job.S3Bucket)
+	dl.SetStorage(s3Store)
+
+	// Archive fetches as WARC files uploaded under WARC_PREFIX, if set
+	var archive *warc.Writer
+	if job.WARCPrefix != "" {
+		archive, err = downloader.NewArchive(filepath.Join(os.TempDir(), "warc"), s3Store, job.WARCPrefix, 0)
+		if err != nil {
+			return fmt.Errorf("creating archive: %w", err)
+		}
+		dl.SetArchive(archive)
+	}
+
+	// Connect to database if configured
+	var db *PostgresDB
+	if job.DatabaseURL != "" {
+		db, err = NewPostgresDB(ctx, job.DatabaseURL)
+		if err != nil {
+			log.Printf("Warning: failed to connect to database: %v", err)
+		} else {
+			defer db.Close()
+		}
+	}
+
+	// Process filings using the batch downloader's worker pool pattern
+	modelFilings := make([]models.Filing, len(filings))
+	for i, fp := range filings {
+		reportDate, parseErr := time.Parse(time.RFC3339, fp.ReportDate)
+		if parseErr != nil {
+			log.Printf("Warning: invalid report_date %q for %s, using current time", fp.ReportDate, fp.SourceID)
+			reportDate = time.Now()
+		}
+
+		modelFilings[i] = models.Filing{
+			ID:            fp.SourceID,
+			SourceID:      fp.SourceID,
+			SourceURL:     fp.SourceURL,
+			CompanyID:     fp.CompanyID,
+			FileExtension: fp.FileExtension,
+			Exchange:      fp.Exchange,
+			ReportDate:    reportDate,
+		}
+	}
+
+	// Build lookup map from SourceID → Exchange for DB updates
+	exchangeByID := make(map[string]string, len(filings))
+	for _, fp := range filings {
+		exchangeByID[fp.SourceID] = fp.Exchange
+	}
+	filingByID := make(map[string]*models.Filing, len(modelFilings))
+	for i := range modelFilings {
+		filingByID[modelFilings[i].ID] = &modelFilings[i]
+	}
+
+	// Use BatchDownloader for concurrent processing with status updates
+	batchDl := downloader.NewBatchDownloader(dl, nil)
+	start := time.Now()
+
+	result := batchDl.DownloadBatch(ctx, modelFilings)
+
+	// Update database for each result
+	if db != nil {
+		for _, r := range result.Results {
+			status, errorMsg := downloader.ResultStatus(r)
+
+			exchange := exchangeByID[r.FilingID]
+			if exchange == "" {
+				exchange = "HKEX"
+			}
+
+			if updateErr := db.UpdateFilingDownloadFull(ctx, exchange, r.FilingID, r.LocalPath, r.S3Key, status, errorMsg); updateErr != nil {
+				log.Printf("Warning: failed to update filing %s: %v", r.FilingID, updateErr)
+			}
+		}
+
+		// Register and download attachments of HTML filings
+		for _, r := range result.Results {
+			filing := filingByID[r.FilingID]
+			if len(r.Attachments) == 0 || filing == nil {
+				continue
+			}
+			if filing.Exchange == "" {
+				for i := range r.Attachments {
+					r.Attachments[i].Exchange = "HKEX"
+				}
+			}
+			if _, attErr := dl.ProcessAttachments(ctx, filing, r, db); attErr != nil {
+				log.Printf("Warning: failed to process attachments of %s: %v", r.FilingID, attErr)
+			}
+		}
+	}
+
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			log.Printf("Warning: failed to upload archive: %v", err)
+		}
+	}
+
+	duration := time.Since(start)
+	log.Printf("Batch complete: total=%d success=%d failed=%d duration=%s",
+		result.Total, result.Successful, result.Failed, duration)
+
+	if result.Failed > 0 {
+		log.Printf("Warning: %d filings failed to download", result.Failed)
+	}
+	return nil
+}
+
+// readChunk reads the manifest JSONL and extracts the chunk for the given array index
+func readChunk(reader io.Reader, arrayIndex, chunkSize int) ([]FilingPayload, error) {
+	scanner := bufio.NewScanner(reader)
+	// Increase scanner buffer for large lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	startLine := arrayIndex * chunkSize
+	endLine := startLine + chunkSize
+
+	var filings []FilingPayload
+	lineNum := 0
+
+	for scanner.Scan() {
+		if lineNum >= endLine {
+			break
+		}
+		if lineNum >= startLine {
+			var fp FilingPayload
+			if err := json.Unmarshal(scanner.Bytes(), &fp); err != nil {
+				return nil, fmt.Errorf("parsing line %d: %w", lineNum, err)
+			}
+			filings = append(filings, fp)
+		}
+		lineNum++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	return filings, nil
+}
+
+func getEnvInt(getenv func(string) string, key string, defaultVal int) int {
+	if val := getenv(key); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			return parsed
+		}
+	}
+	return defaultVal
+}
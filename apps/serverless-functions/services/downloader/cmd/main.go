@@ -10,7 +10,13 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
 	"github.com/nicholaszhao/hkex-scraper/services/downloader"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/dart"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkexapp"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/mops"
 )
 
 // Config holds Lambda configuration from environment
@@ -39,8 +45,8 @@ func Handler(ctx context.Context, sqsEvent events.SQSEvent) error {
 	}
 	defer db.Close()
 
-	// Create S3 client
-	s3Client, err := downloader.NewS3ClientWithRegion(ctx, config.S3Region)
+	// Create S3 storage
+	s3Store, err := storage.NewS3Storage(ctx, config.S3Bucket, config.S3Region)
 	if err != nil {
 		return fmt.Errorf("creating S3 client: %w", err)
 	}
@@ -48,7 +54,6 @@ func Handler(ctx context.Context, sqsEvent events.SQSEvent) error {
 	// Create downloader with rate-limiting protection
 	dlConfig := downloader.Config{
 		Concurrency:     config.Concurrency,
-		S3Bucket:        config.S3Bucket,
 		ProxyBaseURL:    config.ProxyBaseURL,
 		Timeout:         30 * time.Second,
 		RetryAttempts:   3,
@@ -58,10 +63,11 @@ func Handler(ctx context.Context, sqsEvent events.SQSEvent) error {
 	}
 
 	dl := downloader.New(dlConfig)
-	dl.SetS3Client(s3Client)
+	dl.SetStorage(s3Store)
 
 	store := downloader.NewDBStore(db)
 	batchDl := downloader.NewBatchDownloader(dl, store)
+	batchDl.SetDocumentStore(db)
 
 	// Process each SQS message (each contains a batch of filing IDs)
 	var totalProcessed, totalFailed int
@@ -9,11 +9,13 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader"
 )
 
 // PostgresDB wraps a PostgreSQL connection pool
 type PostgresDB struct {
 	pool *pgxpool.Pool
+	*downloader.PostgresDocuments
 }
 
 // NewPostgresDB creates a new PostgreSQL connection
@@ -28,7 +30,7 @@ func NewPostgresDB(ctx context.Context, dsn string) (*PostgresDB, error) {
 		return nil, fmt.Errorf("pinging database: %w", err)
 	}
 
-	return &PostgresDB{pool: pool}, nil
+	return &PostgresDB{pool: pool, PostgresDocuments: downloader.NewPostgresDocuments(pool)}, nil
 }
 
 // Close closes the database connection
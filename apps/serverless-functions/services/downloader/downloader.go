@@ -1,18 +1,23 @@
 package downloader
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/http/httptrace"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/warc"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
 )
 
 // URLNotFoundError indicates the source URL returned 404 (file doesn't exist at source)
@@ -84,10 +89,8 @@ type Config struct {
 	// Concurrency is the number of concurrent downloads (default: 5)
 	Concurrency int
 
-	// S3Bucket is the S3 bucket for uploads (empty = local only)
-	S3Bucket string
-
-	// LocalPath is the local directory for downloads (empty = S3 only)
+	// LocalPath is the local directory for downloads (empty = uploads only,
+	// see SetStorage)
 	LocalPath string
 
 	// DryRun logs actions without downloading
@@ -110,6 +113,10 @@ type Config struct {
 
 	// MaxRequestDelay is the maximum delay between requests (default: 500ms)
 	MaxRequestDelay time.Duration
+
+	// ExchangeConfig configures the exchange adapters that resolve document
+	// URLs (default: config.Load())
+	ExchangeConfig *config.Config
 }
 
 // DefaultConfig returns a default configuration
@@ -133,18 +140,22 @@ type Result struct {
 	FileSize  int64
 	Error     error
 	Duration  time.Duration
+
+	// Attachments are the documents linked from an HTML filing
+	Attachments []models.FilingDocument
 }
 
 // Downloader handles file downloads from HKEX
 type Downloader struct {
 	config     Config
 	httpClient *http.Client
-	s3Client   S3Uploader
-}
+	local      *storage.LocalStorage // LocalPath, if set
+	localErr   error
+	remote     storage.Storage // upload store, if set
+	archive    *warc.Writer    // WARC capture of fetches, if set
 
-// S3Uploader interface for S3 operations (allows mocking)
-type S3Uploader interface {
-	Upload(ctx context.Context, bucket, key string, body io.Reader, contentType string) error
+	exchangesMu sync.Mutex
+	exchanges   map[string]exchange.Exchange
 }
 
 // New creates a new Downloader
@@ -168,12 +179,16 @@ func New(config Config) *Downloader {
 		config.MaxRequestDelay = 500 * time.Millisecond
 	}
 
-	return &Downloader{
+	d := &Downloader{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
 	}
+	if config.LocalPath != "" {
+		d.local, d.localErr = storage.NewLocalStorage(config.LocalPath)
+	}
+	return d
 }
 
 // randomDelay returns a random duration between min and max request delay
@@ -195,12 +210,13 @@ func randomAcceptLanguage() string {
 	return acceptLanguages[rand.Intn(len(acceptLanguages))]
 }
 
-// SetS3Client sets the S3 client for uploads
-func (d *Downloader) SetS3Client(client S3Uploader) {
-	d.s3Client = client
+// SetStorage sets the store documents are uploaded to, usually S3
+func (d *Downloader) SetStorage(store storage.Storage) {
+	d.remote = store
 }
 
-// Download downloads a single filing
+// Download downloads a single filing. Attachments linked from HTML filings
+// are returned in the result for ProcessAttachments.
 func (d *Downloader) Download(ctx context.Context, filing *models.Filing) *Result {
 	start := time.Now()
 	result := &Result{
@@ -213,29 +229,57 @@ func (d *Downloader) Download(ctx context.Context, filing *models.Filing) *Resul
 		return result
 	}
 
+	sourceURL, err := d.documentURL(ctx, filing)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	body, contentType, err := d.fetch(ctx, filing, sourceURL)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	result.FileSize = int64(len(body))
+
+	result.LocalPath, result.S3Key, err = d.store(ctx, filing, body, contentType)
+	if err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	if isHTML(filing, contentType) {
+		result.Attachments = FindAttachments(filing, sourceURL, body)
+	}
+
+	result.Success = true
+	result.Duration = time.Since(start)
+	return result
+}
+
+// fetch downloads a filing's URL with a random delay and retries
+func (d *Downloader) fetch(ctx context.Context, filing *models.Filing, sourceURL string) ([]byte, string, error) {
 	// Add random delay before request to avoid detection
-	delay := d.randomDelay()
 	select {
 	case <-ctx.Done():
-		result.Error = ctx.Err()
-		result.Duration = time.Since(start)
-		return result
-	case <-time.After(delay):
-		// Continue after delay
+		return nil, "", ctx.Err()
+	case <-time.After(d.randomDelay()):
 	}
 
 	// Build the download URL (optionally through proxy)
-	downloadURL := d.buildURL(filing.SourceURL)
+	downloadURL := d.buildURL(sourceURL)
 
-	// Download with retries
 	var body []byte
 	var contentType string
 	var lastErr error
 
 	for attempt := 1; attempt <= d.config.RetryAttempts; attempt++ {
-		body, contentType, lastErr = d.downloadWithContext(ctx, downloadURL)
+		body, contentType, lastErr = d.downloadWithContext(ctx, filing, sourceURL, downloadURL)
 		if lastErr == nil {
-			break
+			return body, contentType, nil
 		}
 
 		// Don't retry 404s - they're permanent failures
@@ -261,49 +305,43 @@ func (d *Downloader) Download(ctx context.Context, filing *models.Filing) *Resul
 
 			select {
 			case <-ctx.Done():
-				result.Error = ctx.Err()
-				result.Duration = time.Since(start)
-				return result
+				return nil, "", ctx.Err()
 			case <-time.After(backoff):
 				// Continue after backoff
 			}
 		}
 	}
 
-	if lastErr != nil {
-		result.Error = fmt.Errorf("download failed after %d attempts: %w", d.config.RetryAttempts, lastErr)
-		result.Duration = time.Since(start)
-		return result
-	}
+	return nil, "", fmt.Errorf("download failed after %d attempts: %w", d.config.RetryAttempts, lastErr)
+}
 
-	result.FileSize = int64(len(body))
+// store saves a downloaded document locally and uploads it, as configured,
+// returning the local path and uploaded key
+func (d *Downloader) store(ctx context.Context, filing *models.Filing, body []byte, contentType string) (string, string, error) {
+	var localPath, s3Key string
 
 	// Save locally if configured
 	if d.config.LocalPath != "" {
-		localPath, err := d.saveLocal(filing, body)
+		if d.localErr != nil {
+			return "", "", fmt.Errorf("saving locally: %w", d.localErr)
+		}
+		info, err := d.local.Put(ctx, filing, bytes.NewReader(body), contentType)
 		if err != nil {
-			result.Error = fmt.Errorf("saving locally: %w", err)
-			result.Duration = time.Since(start)
-			return result
+			return "", "", fmt.Errorf("saving locally: %w", err)
 		}
-		result.LocalPath = localPath
+		localPath = d.local.Location(info.Key)
 	}
 
-	// Upload to S3 if configured
-	if d.config.S3Bucket != "" && d.s3Client != nil {
-		s3Key := d.buildS3Key(filing)
-		err := d.s3Client.Upload(ctx, d.config.S3Bucket, s3Key, strings.NewReader(string(body)), contentType)
+	// Upload if configured
+	if d.remote != nil {
+		info, err := d.remote.Put(ctx, filing, bytes.NewReader(body), contentType)
 		if err != nil {
-			result.Error = fmt.Errorf("uploading to S3: %w", err)
-			result.Duration = time.Since(start)
-			return result
+			return localPath, "", fmt.Errorf("uploading: %w", err)
 		}
-		result.S3Key = s3Key
+		s3Key = info.Key
 	}
 
-	result.Success = true
-	result.Duration = time.Since(start)
-	return result
+	return localPath, s3Key, nil
 }
 
 // buildURL constructs the download URL, optionally through a proxy
@@ -321,8 +359,18 @@ func (d *Downloader) buildURL(sourceURL string) string {
 	return sourceURL
 }
 
-// downloadWithContext performs the HTTP download
-func (d *Downloader) downloadWithContext(ctx context.Context, url string) ([]byte, string, error) {
+// downloadWithContext performs the HTTP download of a filing's source URL
+// from url, archiving the exchange if an archive is set
+func (d *Downloader) downloadWithContext(ctx context.Context, filing *models.Filing, sourceURL, url string) ([]byte, string, error) {
+	var remoteAddr string
+	if d.archive != nil {
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				remoteAddr = info.Conn.RemoteAddr().String()
+			},
+		})
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("creating request: %w", err)
@@ -347,6 +395,18 @@ func (d *Downloader) downloadWithContext(ctx context.Context, url string) ([]byt
 	}
 	defer resp.Body.Close()
 
+	// Archive every response, including errors
+	if d.archive != nil {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading body: %w", err)
+		}
+		if err := d.capture(filing, sourceURL, req, resp, body, remoteAddr); err != nil {
+			return nil, "", fmt.Errorf("archiving: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
 	// Handle rate limiting (429 Too Many Requests or 403 Forbidden from HKEX)
 	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
 		return nil, "", &RateLimitError{StatusCode: resp.StatusCode, URL: url}
@@ -373,138 +433,3 @@ func (d *Downloader) downloadWithContext(ctx context.Context, url string) ([]byt
 
 	return body, contentType, nil
 }
-
-// saveLocal saves the file to the local filesystem
-func (d *Downloader) saveLocal(filing *models.Filing, body []byte) (string, error) {
-	// Build path: LocalPath/exchange/company_id/YYYY/MM/DD/sourceID_title.ext
-	exchange := strings.ToLower(filing.Exchange)
-	if exchange == "" {
-		exchange = "unknown"
-	}
-	companyID := filing.CompanyID
-	if companyID == "" {
-		companyID = "unknown"
-	}
-	year := filing.ReportDate.Format("2006")
-	month := filing.ReportDate.Format("01")
-	day := filing.ReportDate.Format("02")
-
-	dir := filepath.Join(d.config.LocalPath, exchange, companyID, year, month, day)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", fmt.Errorf("creating directory: %w", err)
-	}
-
-	// Get extension from FileExtension field, or extract from SourceURL as fallback
-	ext := filing.FileExtension
-	if ext == "" {
-		ext = extractExtensionFromURL(filing.SourceURL)
-	}
-	if ext == "" {
-		ext = "pdf"
-	}
-
-	// Sanitize filename
-	filename := sanitizeFilename(fmt.Sprintf("%s_%s.%s", filing.SourceID, filing.Title, ext))
-	fullPath := filepath.Join(dir, filename)
-
-	if err := os.WriteFile(fullPath, body, 0644); err != nil {
-		return "", fmt.Errorf("writing file: %w", err)
-	}
-
-	return fullPath, nil
-}
-
-// buildS3Key generates the S3 key for a filing
-func (d *Downloader) buildS3Key(filing *models.Filing) string {
-	// Path: exchange/company_id/YYYY/MM/DD/sourceID.ext
-	// Note: title removed from filename to avoid S3 URI issues with Chinese characters
-	exchange := strings.ToLower(filing.Exchange)
-	if exchange == "" {
-		exchange = "unknown"
-	}
-	companyID := filing.CompanyID
-	if companyID == "" {
-		companyID = "unknown"
-	}
-	// Strip "com_" prefix if present (legacy HKEX format)
-	companyID = strings.TrimPrefix(companyID, "com_")
-
-	year := filing.ReportDate.Format("2006")
-	month := filing.ReportDate.Format("01")
-	day := filing.ReportDate.Format("02")
-
-	// Get extension from FileExtension field, or extract from SourceURL as fallback
-	ext := filing.FileExtension
-	if ext == "" {
-		ext = extractExtensionFromURL(filing.SourceURL)
-	}
-	// Default to "pdf" if still empty
-	if ext == "" {
-		ext = "pdf"
-	}
-
-	filename := fmt.Sprintf("%s.%s", filing.SourceID, ext)
-
-	return fmt.Sprintf("%s/%s/%s/%s/%s/%s", exchange, companyID, year, month, day, filename)
-}
-
-// extractExtensionFromURL extracts the file extension from a URL path
-func extractExtensionFromURL(url string) string {
-	// Find the last path segment
-	lastSlash := strings.LastIndex(url, "/")
-	if lastSlash == -1 {
-		return ""
-	}
-	filename := url[lastSlash+1:]
-
-	// Remove query string if present
-	if queryIdx := strings.Index(filename, "?"); queryIdx != -1 {
-		filename = filename[:queryIdx]
-	}
-
-	// Extract extension
-	ext := filepath.Ext(filename)
-	if ext == "" {
-		return ""
-	}
-	// Remove leading dot and convert to lowercase
-	ext = strings.ToLower(ext[1:])
-
-	// Only return known document extensions to avoid false positives (e.g., "example.com")
-	knownExts := map[string]bool{
-		"pdf": true, "htm": true, "html": true,
-		"xlsx": true, "xls": true, "doc": true, "docx": true,
-		"txt": true, "rtf": true, "csv": true, "xml": true,
-	}
-	if !knownExts[ext] {
-		return ""
-	}
-	return ext
-}
-
-// sanitizeFilename removes invalid characters from a filename
-func sanitizeFilename(name string) string {
-	// Replace problematic characters
-	replacer := strings.NewReplacer(
-		"/", "_",
-		"\\", "_",
-		":", "_",
-		"*", "_",
-		"?", "_",
-		"\"", "_",
-		"<", "_",
-		">", "_",
-		"|", "_",
-		"\n", "_",
-		"\r", "_",
-	)
-	name = replacer.Replace(name)
-
-	// Truncate if too long (max 200 chars for filename)
-	if len(name) > 200 {
-		ext := filepath.Ext(name)
-		name = name[:200-len(ext)] + ext
-	}
-
-	return name
-}
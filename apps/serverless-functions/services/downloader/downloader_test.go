@@ -1,132 +1,361 @@
 package downloader
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/warc"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo/cninfotest"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/dart"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/dart/darttest"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/mops"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/mops/mopstest"
 )
 
-func TestExtractExtensionFromURL(t *testing.T) {
-	tests := []struct {
-		name     string
-		url      string
-		expected string
-	}{
-		{
-			name:     "PDF file",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2025/0330/2025033000043.pdf",
-			expected: "pdf",
-		},
-		{
-			name:     "HTM file",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2015/1217/ltn20151217071.htm",
-			expected: "htm",
-		},
-		{
-			name:     "HTML file",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/file.html",
-			expected: "html",
-		},
-		{
-			name:     "XLSX file",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/data.xlsx",
-			expected: "xlsx",
-		},
-		{
-			name:     "URL with query string",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/file.pdf?download=true",
-			expected: "pdf",
-		},
-		{
-			name:     "Uppercase extension",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/FILE.PDF",
-			expected: "pdf",
-		},
-		{
-			name:     "No extension",
-			url:      "https://www1.hkexnews.hk/listedco/listconews/sehk/2020/0101/noext",
-			expected: "",
-		},
-		{
-			name:     "Empty URL",
-			url:      "",
-			expected: "",
-		},
-		{
-			name:     "URL without path",
-			url:      "https://example.com",
-			expected: "",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractExtensionFromURL(tt.url)
-			if result != tt.expected {
-				t.Errorf("extractExtensionFromURL(%q) = %q, want %q", tt.url, result, tt.expected)
-			}
-		})
+func TestDownload_ResolvesURLThroughExchange(t *testing.T) {
+	srv := darttest.NewServer("test-key")
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LocalPath = t.TempDir()
+	cfg.MinRequestDelay = time.Millisecond
+	cfg.MaxRequestDelay = time.Millisecond
+	cfg.ExchangeConfig = &config.Config{DARTBaseURL: srv.URL, DARTAPIKey: "test-key"}
+	d := New(cfg)
+
+	// SourceURL is the DART viewer page; the adapter resolves the document API
+	result := d.Download(context.Background(), &models.Filing{
+		ID:            "fil_1",
+		SourceID:      "20240115000456",
+		Exchange:      "DART",
+		CompanyID:     "00164779",
+		ReportDate:    time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Title:         "주요사항보고서(자기주식취득결정)",
+		SourceURL:     "https://dart.fss.or.kr/dsaf001/main.do?rcpNo=20240115000456",
+		FileExtension: "zip",
+	})
+	if !result.Success {
+		t.Fatalf("download failed: %v", result.Error)
+	}
+
+	f, err := os.Open(result.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, _ := f.Stat()
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		t.Fatalf("downloaded file is not a zip: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "20240115000456.xml" {
+		t.Errorf("unexpected archive contents")
+	}
+}
+
+func TestDownload_CNInfoPDF(t *testing.T) {
+	srv := cninfotest.NewServer()
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LocalPath = t.TempDir()
+	cfg.MinRequestDelay = time.Millisecond
+	cfg.MaxRequestDelay = time.Millisecond
+	cfg.ExchangeConfig = &config.Config{CNInfoBaseURL: srv.URL, CNInfoStaticURL: srv.URL}
+	d := New(cfg)
+
+	result := d.Download(context.Background(), &models.Filing{
+		ID:            "fil_2",
+		SourceID:      "1219456781",
+		Exchange:      "SSE",
+		CompanyID:     "600036",
+		ReportDate:    time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC),
+		Title:         "招商银行2023年年度报告",
+		SourceURL:     srv.URL + "/finalpage/2024-03-28/1219456781.PDF",
+		FileExtension: "pdf",
+	})
+	if !result.Success {
+		t.Fatalf("download failed: %v", result.Error)
+	}
+
+	body, err := os.ReadFile(result.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, cninfotest.PDF) {
+		t.Errorf("unexpected document body %q", body)
 	}
 }
 
-func TestBuildS3Key_FallbackExtension(t *testing.T) {
-	d := New(DefaultConfig())
-
-	tests := []struct {
-		name        string
-		filing      *models.Filing
-		expectedExt string
-	}{
-		{
-			name: "Uses FileExtension when present",
-			filing: &models.Filing{
-				SourceID:      "12345",
-				Exchange:      "HKEX",
-				CompanyID:     "00001",
-				ReportDate:    time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
-				FileExtension: "pdf",
-				SourceURL:     "https://example.com/file.htm",
-			},
-			expectedExt: "pdf",
-		},
-		{
-			name: "Extracts from URL when FileExtension empty",
-			filing: &models.Filing{
-				SourceID:      "12345",
-				Exchange:      "HKEX",
-				CompanyID:     "00001",
-				ReportDate:    time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
-				FileExtension: "",
-				SourceURL:     "https://www1.hkexnews.hk/listedco/listconews/sehk/2025/0115/file.htm",
-			},
-			expectedExt: "htm",
-		},
-		{
-			name: "Defaults to pdf when both empty",
-			filing: &models.Filing{
-				SourceID:      "12345",
-				Exchange:      "HKEX",
-				CompanyID:     "00001",
-				ReportDate:    time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
-				FileExtension: "",
-				SourceURL:     "https://example.com/noext",
-			},
-			expectedExt: "pdf",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := d.buildS3Key(tt.filing)
-			expectedSuffix := "." + tt.expectedExt
-			if !contains(result, expectedSuffix) {
-				t.Errorf("buildS3Key() = %q, expected to end with %q", result, expectedSuffix)
-			}
+func TestDownload_MOPSReport(t *testing.T) {
+	srv := mopstest.NewServer()
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LocalPath = t.TempDir()
+	cfg.MinRequestDelay = time.Millisecond
+	cfg.MaxRequestDelay = time.Millisecond
+	cfg.ExchangeConfig = &config.Config{MOPSBaseURL: srv.URL, MOPSDocURL: srv.URL, MOPSISINURL: srv.URL}
+	d := New(cfg)
+
+	// SourceURL is the document page; the PDF link is only found on it
+	client := mops.NewClient(srv.URL, srv.URL, srv.URL, nil)
+	result := d.Download(context.Background(), &models.Filing{
+		ID:            "fil_3",
+		SourceID:      "202304_2330_AI1.pdf",
+		Exchange:      "TWSE",
+		CompanyID:     "2330",
+		FilingType:    "IFRSs合併財報",
+		ReportDate:    time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		Title:         "IFRSs合併財報 2023Q4",
+		SourceURL:     client.ReportPageURL("A", "2330", "202304_2330_AI1.pdf"),
+		FileExtension: "pdf",
+	})
+	if !result.Success {
+		t.Fatalf("download failed: %v", result.Error)
+	}
+
+	body, err := os.ReadFile(result.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, mopstest.PDF) {
+		t.Errorf("unexpected document body %q", body)
+	}
+}
+
+// indexPage is a multi-document announcement's index page
+const indexPage = `<html><body>
+<p>Announcement and circular</p>
+<a href="/listedco/listconews/sehk/2024/0328/2024032800123.pdf">Announcement</a>
+<a href="2024032800124.pdf#page=2">Circular</a>
+<a href="2024032800124.pdf">Circular (again)</a>
+<a href="2024032800125.pdf">Proxy form</a>
+<a href="/index.htm">Home</a>
+<a href="https://www.example.com/other.pdf">Elsewhere</a>
+</body></html>`
+
+// memoryDocumentStore is a DocumentStore in memory
+type memoryDocumentStore struct {
+	docs map[string]models.FilingDocument
+}
+
+func (s *memoryDocumentStore) RegisterDocuments(ctx context.Context, docs []models.FilingDocument) ([]models.FilingDocument, error) {
+	var pending []models.FilingDocument
+	for _, d := range docs {
+		if known, ok := s.docs[d.ID]; ok {
+			d.ProcessingStatus = known.ProcessingStatus
+		}
+		s.docs[d.ID] = d
+		if d.ProcessingStatus != models.ProcessingStatusCompleted && d.ProcessingStatus != models.ProcessingStatusURLFailure {
+			pending = append(pending, d)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memoryDocumentStore) UpdateDocumentDownload(ctx context.Context, exchange, documentID, localPath, s3Key string, fileSize int, status models.ProcessingStatus, errorMsg string) error {
+	d := s.docs[documentID]
+	d.LocalPath, d.FileSize, d.ProcessingStatus, d.ProcessingError = localPath, fileSize, status, errorMsg
+	s.docs[documentID] = d
+	return nil
+}
+
+func TestDownload_Attachments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/listedco/listconews/sehk/2024/0328/2024032800122.htm":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(indexPage))
+		case "/listedco/listconews/sehk/2024/0328/2024032800123.pdf",
+			"/listedco/listconews/sehk/2024/0328/2024032800124.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4 " + r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LocalPath = t.TempDir()
+	cfg.MinRequestDelay = time.Millisecond
+	cfg.MaxRequestDelay = time.Millisecond
+	cfg.ExchangeConfig = &config.Config{}
+	d := New(cfg)
+
+	filing := &models.Filing{
+		ID:            "11223344",
+		SourceID:      "11223344",
+		Exchange:      "HKEX",
+		CompanyID:     "00005",
+		ReportDate:    time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC),
+		Title:         "Announcements and Notices",
+		SourceURL:     srv.URL + "/listedco/listconews/sehk/2024/0328/2024032800122.htm",
+		FileExtension: "htm",
+	}
+	result := d.Download(context.Background(), filing)
+	if !result.Success {
+		t.Fatalf("download failed: %v", result.Error)
+	}
+
+	// Same-site documents only, in page order, without duplicates
+	want := []string{"11223344_bd41e1ad852d1cd1", "11223344_d7658a19066b87fa", "11223344_96b982e729506e8c"}
+	if len(result.Attachments) != len(want) {
+		t.Fatalf("got %d attachments, want %d", len(result.Attachments), len(want))
+	}
+	for i, a := range result.Attachments {
+		if a.ID != want[i] || a.Seq != i+1 || a.FilingSourceID != "11223344" || a.Exchange != "HKEX" {
+			t.Errorf("attachment %d = %+v", i, a)
+		}
+	}
+	if a := result.Attachments[1]; a.Title != "Circular" || a.SourceURL != srv.URL+"/listedco/listconews/sehk/2024/0328/2024032800124.pdf" {
+		t.Errorf("unexpected attachment: %q %s", a.Title, a.SourceURL)
+	}
+
+	store := &memoryDocumentStore{docs: make(map[string]models.FilingDocument)}
+	results, err := d.ProcessAttachments(context.Background(), filing, result, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d attachment results, want 3", len(results))
+	}
+
+	doc := store.docs["11223344_bd41e1ad852d1cd1"]
+	if doc.ProcessingStatus != models.ProcessingStatusCompleted {
+		t.Fatalf("status = %s (%s)", doc.ProcessingStatus, doc.ProcessingError)
+	}
+	body, err := os.ReadFile(doc.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "%PDF-1.4 /listedco/listconews/sehk/2024/0328/2024032800123.pdf" {
+		t.Errorf("unexpected attachment body %q", body)
+	}
+	if got := store.docs["11223344_96b982e729506e8c"].ProcessingStatus; got != models.ProcessingStatusURLFailure {
+		t.Errorf("missing attachment status = %s, want URL_FAILURE", got)
+	}
+
+	// Downloaded and missing attachments are not fetched again
+	results, err = d.ProcessAttachments(context.Background(), filing, result, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("got %d attachment results on rerun, want 0", len(results))
+	}
+}
+
+func TestDownload_Archive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/listedco/listconews/sehk/2024/0328/2024032800123.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4 archived"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+	archive, err := NewArchive(t.TempDir(), blobs, "warc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MinRequestDelay = time.Millisecond
+	cfg.MaxRequestDelay = time.Millisecond
+	cfg.ExchangeConfig = &config.Config{}
+	d := New(cfg)
+	d.SetArchive(archive)
+
+	for _, id := range []string{"2024032800123", "2024032800999"} {
+		d.Download(ctx, &models.Filing{
+			ID:            id,
+			SourceID:      id,
+			Exchange:      "HKEX",
+			CompanyID:     "00005",
+			ReportDate:    time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC),
+			SourceURL:     srv.URL + "/listedco/listconews/sehk/2024/0328/" + id + ".pdf",
+			FileExtension: "pdf",
 		})
 	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var warcKey, cdxjKey string
+	blobs.List(ctx, "warc/", func(info *storage.Info) error {
+		if strings.HasSuffix(info.Key, ".cdxj") {
+			cdxjKey = info.Key
+		} else {
+			warcKey = info.Key
+		}
+		return nil
+	})
+	readKey := func(key string) []byte {
+		r, err := blobs.OpenKey(ctx, key)
+		if err != nil {
+			t.Fatalf("OpenKey(%q) error = %v", key, err)
+		}
+		defer r.Close()
+		data, _ := io.ReadAll(r)
+		return data
+	}
+
+	// Both the document and the 404 are captured
+	lines := strings.Split(strings.TrimSpace(string(readKey(cdxjKey))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("index has %d lines, want 2", len(lines))
+	}
+	entry, err := warc.ParseIndexLine(lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Fields["filing_id"] != "2024032800123" || entry.Status != "200" || entry.MIME != "application/pdf" {
+		t.Errorf("index entry = %+v", entry.Fields)
+	}
+
+	offset, _ := strconv.Atoi(entry.Offset)
+	rec, err := warc.ReadRecord(bytes.NewReader(readKey(warcKey)[offset:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, body, err := rec.Response(); err != nil || string(body) != "%PDF-1.4 archived" {
+		t.Errorf("archived response = %q, %v", body, err)
+	}
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && s[len(s)-len(substr):] == substr
+func TestAttachmentID(t *testing.T) {
+	a, _ := url.Parse("https://www1.hkexnews.hk/listedco/2024/0328/annex.pdf")
+	b, _ := url.Parse("https://www1.hkexnews.hk/listedco/2024/0329/annex.pdf")
+	c, _ := url.Parse("https://www1.hkexnews.hk/listedco/2024/0328/%E9%99%84%E4%BB%B6.pdf")
+
+	ida, idb, idc := attachmentID("11223344", a), attachmentID("11223344", b), attachmentID("11223344", c)
+	if ida == idb {
+		t.Errorf("links sharing a file name got one ID %q", ida)
+	}
+	for _, id := range []string{ida, idb, idc} {
+		if len(id) != len("11223344_")+16 || !strings.HasPrefix(id, "11223344_") {
+			t.Errorf("attachmentID() = %q, want 11223344_<16 hex digits>", id)
+		}
+	}
 }
@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// PostgresDocuments is the DocumentStore of the PostgreSQL-backed downloaders,
+// embedded in their database wrappers
+type PostgresDocuments struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDocuments creates a DocumentStore on a connection pool
+func NewPostgresDocuments(pool *pgxpool.Pool) *PostgresDocuments {
+	return &PostgresDocuments{pool: pool}
+}
+
+// RegisterDocuments records a filing's attachments and returns those not yet
+// downloaded. Known attachments keep their status.
+func (s *PostgresDocuments) RegisterDocuments(ctx context.Context, docs []models.FilingDocument) ([]models.FilingDocument, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, d := range docs {
+		batch.Queue(`INSERT INTO filing_documents (exchange, document_id, filing_source_id, seq, title, source_url, file_extension, processing_status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (exchange, document_id) DO UPDATE SET seq = EXCLUDED.seq, title = EXCLUDED.title,
+				source_url = EXCLUDED.source_url, updated_at = NOW()`,
+			d.Exchange, d.ID, d.FilingSourceID, d.Seq, d.Title, d.SourceURL, d.FileExtension, d.ProcessingStatus)
+	}
+
+	br := s.pool.SendBatch(ctx, batch)
+	for range docs {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return nil, err
+		}
+	}
+	if err := br.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT document_id, exchange, filing_source_id, seq, COALESCE(title, ''), source_url,
+		COALESCE(file_extension, ''), processing_status, created_at, updated_at
+		FROM filing_documents WHERE exchange = $1 AND filing_source_id = $2 AND processing_status NOT IN ($3, $4)
+		ORDER BY seq`, docs[0].Exchange, docs[0].FilingSourceID, models.ProcessingStatusCompleted, models.ProcessingStatusURLFailure)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []models.FilingDocument
+	for rows.Next() {
+		var d models.FilingDocument
+		if err := rows.Scan(&d.ID, &d.Exchange, &d.FilingSourceID, &d.Seq, &d.Title, &d.SourceURL,
+			&d.FileExtension, &d.ProcessingStatus, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		pending = append(pending, d)
+	}
+
+	return pending, rows.Err()
+}
+
+// UpdateDocumentDownload updates an attachment after a download attempt
+func (s *PostgresDocuments) UpdateDocumentDownload(ctx context.Context, exchange, documentID, localPath, s3Key string, fileSize int, status models.ProcessingStatus, errorMsg string) error {
+	query := `UPDATE filing_documents SET local_path = $1, pdf_s3_key = $2, file_size = $3, processing_status = $4,
+		processing_error = $5, updated_at = $6 WHERE exchange = $7 AND document_id = $8`
+
+	_, err := s.pool.Exec(ctx, query, localPath, s3Key, fileSize, status, errorMsg, time.Now(), exchange, documentID)
+	return err
+}
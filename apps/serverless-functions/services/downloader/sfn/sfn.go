@@ -0,0 +1,169 @@
+// Package sfn downloads a single filing for the Step Functions Map state.
+// It is the sfn-downloader Lambda's handler, also run by the local pipeline
+// runner.
+package sfn
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/claimcheck"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader"
+)
+
+// FilingPayload is the input from the Step Functions Map state.
+// Each field is populated by the scraper Lambda output.
+type FilingPayload struct {
+	SourceID      string `json:"source_id"`
+	SourceURL     string `json:"source_url"`
+	CompanyID     string `json:"company_id"`
+	FileExtension string `json:"file_extension"`
+	Exchange      string `json:"exchange"`
+	ReportDate    string `json:"report_date"` // RFC3339
+
+	// Set instead of the fields above when the scraper stored the filing
+	// list in S3: the claim check of this filing's line of the list
+	FilingRef *claimcheck.Ref `json:"filing_ref,omitempty"`
+}
+
+// DownloadResult is the output returned to Step Functions.
+// All fields are always present (no omitempty) so JSONPath selectors in the
+// state machine ResultSelector never fail on missing keys.
+type DownloadResult struct {
+	SourceID string `json:"source_id"`
+	Success  bool   `json:"success"`
+	S3Key    string `json:"s3_key"`
+	FileSize int64  `json:"file_size"`
+	Error    string `json:"error"`
+}
+
+// Handler processes a single filing payload from the Step Functions Map state.
+// It downloads the document to S3 and updates the filing status in the database.
+func Handler(ctx context.Context, payload FilingPayload) (*DownloadResult, error) {
+	s3Region := getEnvOrDefault("AWS_REGION", "ap-east-1")
+
+	if ref := payload.FilingRef; ref != nil {
+		refStore, err := storage.NewS3Storage(ctx, ref.Bucket, s3Region)
+		if err != nil {
+			return nil, fmt.Errorf("creating S3 client: %w", err)
+		}
+		var filing FilingPayload
+		if err := claimcheck.GetItem(ctx, refStore, ref, &filing); err != nil {
+			return nil, err
+		}
+		payload = filing
+	}
+
+	log.Printf("Downloading filing %s from %s", payload.SourceID, payload.SourceURL)
+
+	// Parse report date
+	reportDate, err := time.Parse(time.RFC3339, payload.ReportDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report_date %q: %w", payload.ReportDate, err)
+	}
+
+	// Construct Filing model from payload (no DB lookup needed)
+	filing := &models.Filing{
+		ID:            payload.SourceID,
+		SourceID:      payload.SourceID,
+		SourceURL:     payload.SourceURL,
+		CompanyID:     payload.CompanyID,
+		FileExtension: payload.FileExtension,
+		Exchange:      payload.Exchange,
+		ReportDate:    reportDate,
+	}
+
+	// Load config from environment
+	s3Bucket := os.Getenv("S3_BUCKET")
+	proxyBaseURL := os.Getenv("PROXY_BASE_URL")
+	databaseURL := os.Getenv("DATABASE_URL")
+	warcPrefix := os.Getenv("WARC_PREFIX")
+
+	// Create downloader (single filing, conservative rate limiting)
+	dlConfig := downloader.Config{
+		Concurrency:     1,
+		ProxyBaseURL:    proxyBaseURL,
+		Timeout:         30 * time.Second,
+		RetryAttempts:   3,
+		RetryDelay:      5 * time.Second,
+		MinRequestDelay: 500 * time.Millisecond,
+		MaxRequestDelay: 2 * time.Second,
+	}
+	dl := downloader.New(dlConfig)
+
+	// Create S3 client
+	if s3Bucket != "" {
+		s3Store, err := storage.NewS3Storage(ctx, s3Bucket, s3Region)
+		if err != nil {
+			return nil, fmt.Errorf("creating S3 client: %w", err)
+		}
+		dl.SetStorage(s3Store)
+
+		// Archive the invocation's fetches under WARC_PREFIX, if set
+		if warcPrefix != "" {
+			archive, err := downloader.NewArchive(filepath.Join(os.TempDir(), "warc"), s3Store, warcPrefix, 0)
+			if err != nil {
+				return nil, fmt.Errorf("creating archive: %w", err)
+			}
+			dl.SetArchive(archive)
+			defer func() {
+				if err := archive.Close(); err != nil {
+					log.Printf("Warning: failed to upload archive: %v", err)
+				}
+			}()
+		}
+	}
+
+	// Download the filing
+	result := dl.Download(ctx, filing)
+
+	// Determine processing status
+	status, errorMsg := downloader.ResultStatus(result)
+
+	// Update database status
+	if databaseURL != "" {
+		db, dbErr := NewPostgresDB(ctx, databaseURL)
+		if dbErr != nil {
+			log.Printf("Warning: failed to connect to database: %v", dbErr)
+		} else {
+			defer db.Close()
+			if updateErr := db.UpdateFilingDownloadFull(ctx, filing.Exchange, filing.SourceID, result.LocalPath, result.S3Key, status, errorMsg); updateErr != nil {
+				log.Printf("Warning: failed to update filing status: %v", updateErr)
+			}
+
+			// Register and download attachments of HTML filings
+			if _, attErr := dl.ProcessAttachments(ctx, filing, result, db); attErr != nil {
+				log.Printf("Warning: failed to process attachments: %v", attErr)
+			}
+		}
+	}
+
+	// Build output
+	output := &DownloadResult{
+		SourceID: payload.SourceID,
+		Success:  result.Success,
+		S3Key:    result.S3Key,
+		FileSize: result.FileSize,
+	}
+	if result.Error != nil {
+		output.Error = result.Error.Error()
+	}
+
+	log.Printf("Download complete: source_id=%s success=%v s3_key=%s duration=%s",
+		payload.SourceID, result.Success, result.S3Key, result.Duration)
+
+	return output, nil
+}
+
+func getEnvOrDefault(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
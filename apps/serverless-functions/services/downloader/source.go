@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// documentURL resolves the URL to download a filing from through its
+// exchange adapter. Filings from exchanges without a registered adapter are
+// downloaded from their SourceURL.
+func (d *Downloader) documentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	ex, err := d.exchange(filing.Exchange)
+	if err != nil {
+		return "", err
+	}
+	if ex == nil {
+		if filing.SourceURL == "" {
+			return "", fmt.Errorf("filing %s has no source URL", filing.SourceID)
+		}
+		return filing.SourceURL, nil
+	}
+
+	url, err := ex.DocumentURL(ctx, filing)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s document URL: %w", ex.Name(), err)
+	}
+	return url, nil
+}
+
+// exchange returns the cached adapter for an exchange, or nil if none is
+// registered
+func (d *Downloader) exchange(name string) (exchange.Exchange, error) {
+	if !exchange.Registered(name) {
+		return nil, nil
+	}
+
+	d.exchangesMu.Lock()
+	defer d.exchangesMu.Unlock()
+
+	if ex, ok := d.exchanges[name]; ok {
+		return ex, nil
+	}
+
+	cfg := d.config.ExchangeConfig
+	if cfg == nil {
+		cfg = config.Load()
+	}
+	ex, err := exchange.Open(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.exchanges == nil {
+		d.exchanges = make(map[string]exchange.Exchange)
+	}
+	d.exchanges[name] = ex
+	return ex, nil
+}
@@ -0,0 +1,175 @@
+package cninfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+)
+
+// PageSize is the largest page size the query endpoint accepts
+const PageSize = 30
+
+// Announcement is one result of the announcement query
+type Announcement struct {
+	SecCode          string `json:"secCode"`
+	SecName          string `json:"secName"`
+	OrgID            string `json:"orgId"`
+	AnnouncementID   string `json:"announcementId"`
+	Title            string `json:"announcementTitle"` // may contain <em> highlight tags
+	Time             int64  `json:"announcementTime"`  // Unix milliseconds
+	AdjunctURL       string `json:"adjunctUrl"`        // e.g. "finalpage/2024-03-29/1219012345.PDF"
+	AdjunctSize      int    `json:"adjunctSize"`       // KB
+	AdjunctType      string `json:"adjunctType"`       // "PDF"
+	PageColumn       string `json:"pageColumn"`
+	AnnouncementType string `json:"announcementType"` // "||"-separated category codes
+}
+
+// QueryResponse is one page of the announcement query
+type QueryResponse struct {
+	TotalAnnouncement int            `json:"totalAnnouncement"`
+	TotalPages        int            `json:"totalpages"`
+	HasMore           bool           `json:"hasMore"`
+	Announcements     []Announcement `json:"announcements"` // null when nothing matches
+}
+
+// QueryParams configures an announcement query
+type QueryParams struct {
+	Column  string // "sse" or "szse"
+	Plate   string // "sh", "sz" or a board such as "shkcp" (STAR)
+	From    time.Time
+	To      time.Time
+	PageNum int
+}
+
+// Stock is one entry of a cninfo stock list
+type Stock struct {
+	OrgID    string `json:"orgId"`
+	Category string `json:"category"` // "A股", "B股", "港股", ...
+	Code     string `json:"code"`
+	Pinyin   string `json:"pinyin"`
+	Name     string `json:"zwjc"` // Chinese short name
+}
+
+// Client calls cninfo's announcement search
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	limiter    *ratelimit.Limiter
+}
+
+// NewClient creates a cninfo client
+func NewClient(baseURL string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		limiter: limiter,
+	}
+}
+
+// Query fetches one page of announcements
+func (c *Client) Query(ctx context.Context, params QueryParams) (*QueryResponse, error) {
+	form := url.Values{}
+	form.Set("pageNum", strconv.Itoa(params.PageNum))
+	form.Set("pageSize", strconv.Itoa(PageSize))
+	form.Set("column", params.Column)
+	form.Set("tabName", "fulltext")
+	form.Set("plate", params.Plate)
+	form.Set("stock", "")
+	form.Set("searchkey", "")
+	form.Set("secid", "")
+	form.Set("category", "")
+	form.Set("trade", "")
+	form.Set("seDate", params.From.Format("2006-01-02")+"~"+params.To.Format("2006-01-02"))
+	form.Set("sortName", "")
+	form.Set("sortType", "")
+	form.Set("isHLtitle", "true")
+
+	body, err := c.do(ctx, http.MethodPost, "/new/hisAnnouncement/query", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp QueryResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding query response: %w", err)
+	}
+	return &resp, nil
+}
+
+// QueryAll fetches every page of a query
+func (c *Client) QueryAll(ctx context.Context, params QueryParams) ([]Announcement, error) {
+	var all []Announcement
+
+	for page := 1; ; page++ {
+		params.PageNum = page
+		resp, err := c.Query(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d: %w", page, err)
+		}
+
+		all = append(all, resp.Announcements...)
+
+		if !resp.HasMore || len(resp.Announcements) == 0 {
+			break
+		}
+	}
+
+	return all, nil
+}
+
+// Stocks fetches a cninfo stock list: "szse" lists all mainland A and B
+// shares (both exchanges), "hke" Hong Kong stocks
+func (c *Client) Stocks(ctx context.Context, list string) ([]Stock, error) {
+	body, err := c.do(ctx, http.MethodGet, "/new/data/"+list+"_stock.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		StockList []Stock `json:"stockList"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding %s stock list: %w", list, err)
+	}
+	return resp.StockList, nil
+}
+
+// do performs a rate-limited request
+func (c *Client) do(ctx context.Context, method, path string, form io.Reader) (io.ReadCloser, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, form)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %s: %d", path, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
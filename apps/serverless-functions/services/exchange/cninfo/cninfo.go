@@ -0,0 +1,258 @@
+// Package cninfo is the exchange adapter for Shanghai (SSE) and Shenzhen
+// (SZSE) announcements, fetched from cninfo (巨潮资讯), the CSRC-designated
+// disclosure site for both exchanges.
+//
+// One adapter is registered per exchange. Companies are keyed by their
+// six-digit security code, filings by cninfo's announcement ID.
+package cninfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// Exchange codes of mainland companies and filings
+const (
+	SSE  = "SSE"
+	SZSE = "SZSE"
+)
+
+// cst is China Standard Time (UTC+8), the zone of announcement dates
+var cst = time.FixedZone("CST", 8*3600)
+
+// venue holds the cninfo query parameters of one exchange
+type venue struct {
+	column string                       // query column
+	plate  string                       // default plate (the whole exchange)
+	boards map[models.MarketType]string // plates of individual boards
+}
+
+var venues = map[string]venue{
+	SSE: {
+		column: "sse",
+		plate:  "sh",
+		boards: map[models.MarketType]string{
+			models.MarketTypeSSEMain: "shmb",
+			models.MarketTypeSTAR:    "shkcp",
+		},
+	},
+	SZSE: {
+		column: "szse",
+		plate:  "sz",
+		boards: map[models.MarketType]string{
+			models.MarketTypeSZSEMain: "szmb",
+			models.MarketTypeChiNext:  "szcy",
+		},
+	},
+}
+
+func init() {
+	for name := range venues {
+		name := name
+		exchange.Register(name, func(cfg *config.Config) (exchange.Exchange, error) {
+			return New(name, cfg), nil
+		})
+	}
+}
+
+// Adapter implements exchange.Exchange for one mainland exchange
+type Adapter struct {
+	name      string
+	venue     venue
+	client    *Client
+	staticURL string
+}
+
+// New creates the adapter for SSE or SZSE
+func New(name string, cfg *config.Config) *Adapter {
+	return &Adapter{
+		name:      name,
+		venue:     venues[name],
+		client:    NewClient(cfg.CNInfoBaseURL, ratelimit.ForHost(cfg.CNInfoBaseURL, cfg.CNInfoRateLimit)),
+		staticURL: strings.TrimRight(cfg.CNInfoStaticURL, "/"),
+	}
+}
+
+// Name returns "SSE" or "SZSE"
+func (a *Adapter) Name() string {
+	return a.name
+}
+
+// ListSecurities returns the exchange's A and B shares from cninfo's stock list
+func (a *Adapter) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	stocks, err := a.client.Stocks(ctx, "szse")
+	if err != nil {
+		return nil, err
+	}
+
+	var secs []securities.Security
+	for _, s := range stocks {
+		if ExchangeOf(s.Code) != a.name {
+			continue
+		}
+		secs = append(secs, securities.Security{
+			StockCode: models.NormalizeStockCode(a.name, s.Code),
+			Name:      s.Name,
+			Category:  s.Category,
+		})
+	}
+	return secs, nil
+}
+
+// SearchFilings queries announcements one day at a time, since cninfo stops
+// paging deep into large result sets. opts.Market narrows the search to one
+// board, e.g. "STAR" or "CHINEXT".
+func (a *Adapter) SearchFilings(ctx context.Context, from, to time.Time, opts exchange.SearchOptions) ([]exchange.Filing, error) {
+	plate := a.venue.plate
+	if opts.Market != "" {
+		var ok bool
+		if plate, ok = a.venue.boards[models.MarketType(strings.ToUpper(opts.Market))]; !ok {
+			return nil, fmt.Errorf("unknown %s market %q", a.name, opts.Market)
+		}
+	}
+
+	var filings []exchange.Filing
+	from, to = from.In(cst), to.In(cst)
+	for day := dateOf(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		anns, err := a.client.QueryAll(ctx, QueryParams{
+			Column: a.venue.column,
+			Plate:  plate,
+			From:   day,
+			To:     day,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("searching %s %s: %w", a.name, day.Format("2006-01-02"), err)
+		}
+
+		for i := range anns {
+			ann := &anns[i]
+			if ann.SecCode == "" || ann.AdjunctURL == "" {
+				continue
+			}
+			filings = append(filings, a.ToFiling(ann))
+		}
+	}
+
+	return filings, nil
+}
+
+// DocumentURL returns the filing's source URL on cninfo's static host
+func (a *Adapter) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	if filing.SourceURL == "" {
+		return "", fmt.Errorf("filing %s has no source URL", filing.SourceID)
+	}
+	return filing.SourceURL, nil
+}
+
+// ToFiling maps an announcement to a filing and a template of its issuer
+func (a *Adapter) ToFiling(ann *Announcement) exchange.Filing {
+	title := stripHighlight(ann.Title)
+
+	filing := &models.Filing{
+		ID:               models.GenerateID("fil"),
+		CompanyID:        ann.SecCode,
+		SourceID:         ann.AnnouncementID,
+		Exchange:         a.name,
+		FilingType:       reportType(title),
+		ReportDate:       time.UnixMilli(ann.Time).In(cst),
+		Title:            title,
+		SourceURL:        a.staticURL + "/" + strings.TrimLeft(ann.AdjunctURL, "/"),
+		FileSize:         ann.AdjunctSize * 1024,
+		FileExtension:    strings.ToLower(ann.AdjunctType),
+		Language:         language(title),
+		ProcessingStatus: models.ProcessingStatusPending,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	company := &models.Company{
+		ID:          ann.SecCode,
+		StockCode:   models.NormalizeStockCode(a.name, ann.SecCode),
+		CompanyName: stripHighlight(ann.SecName),
+		MarketType:  MarketTypeOf(ann.SecCode),
+		Exchange:    a.name,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	return exchange.Filing{Filing: filing, Company: company}
+}
+
+// ExchangeOf returns the exchange of a mainland security code, or "" for
+// codes of neither exchange (e.g. Beijing Stock Exchange)
+func ExchangeOf(code string) string {
+	switch {
+	case strings.HasPrefix(code, "6"), strings.HasPrefix(code, "900"):
+		return SSE
+	case strings.HasPrefix(code, "0"), strings.HasPrefix(code, "200"), strings.HasPrefix(code, "30"):
+		return SZSE
+	}
+	return ""
+}
+
+// MarketTypeOf returns the board of a mainland security code
+func MarketTypeOf(code string) models.MarketType {
+	switch {
+	case strings.HasPrefix(code, "688"), strings.HasPrefix(code, "689"):
+		return models.MarketTypeSTAR
+	case strings.HasPrefix(code, "6"):
+		return models.MarketTypeSSEMain
+	case strings.HasPrefix(code, "30"):
+		return models.MarketTypeChiNext
+	case strings.HasPrefix(code, "900"), strings.HasPrefix(code, "200"):
+		return models.MarketTypeBShare
+	case strings.HasPrefix(code, "0"):
+		return models.MarketTypeSZSEMain
+	}
+	return models.MarketTypeOther
+}
+
+// periodicReports are the periodic report types, checked in order so that
+// "半年度报告" is not mistaken for "年度报告"
+var periodicReports = []string{
+	"半年度报告摘要",
+	"半年度报告",
+	"年度报告摘要",
+	"年度报告",
+	"第一季度报告",
+	"第三季度报告",
+}
+
+// reportType returns the periodic report type named in a title, or
+// "临时公告" (ad hoc announcement)
+func reportType(title string) string {
+	for _, t := range periodicReports {
+		if strings.Contains(title, t) {
+			return t
+		}
+	}
+	return "临时公告"
+}
+
+// language reports English translations, which cninfo titles mark "英文版"
+func language(title string) models.Language {
+	if strings.Contains(title, "英文版") || strings.Contains(title, "（英文）") || strings.Contains(title, "(英文)") {
+		return models.LanguageEN
+	}
+	return models.LanguageZH
+}
+
+// stripHighlight removes the <em> tags cninfo adds around search matches
+func stripHighlight(s string) string {
+	s = strings.ReplaceAll(s, "<em>", "")
+	s = strings.ReplaceAll(s, "</em>", "")
+	return strings.TrimSpace(s)
+}
+
+// dateOf truncates t to midnight in its zone
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,163 @@
+package cninfo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo/cninfotest"
+)
+
+// newTestAdapter opens an adapter against the fixture server
+func newTestAdapter(t *testing.T, name string) *Adapter {
+	t.Helper()
+
+	srv := cninfotest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ex, err := exchange.Open(name, &config.Config{CNInfoBaseURL: srv.URL, CNInfoStaticURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ex.(*Adapter)
+}
+
+func day(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, cst)
+	return t
+}
+
+func TestSearchFilings_SSE(t *testing.T) {
+	a := newTestAdapter(t, SSE)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-28"), day("2024-03-29"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 6 fixtures, less one exchange notice without a security code
+	if len(filings) != 5 {
+		t.Fatalf("got %d filings, want 5", len(filings))
+	}
+
+	bySource := make(map[string]exchange.Filing)
+	for _, f := range filings {
+		bySource[f.Filing.SourceID] = f
+	}
+
+	annual := bySource["1219456781"]
+	if annual.Filing == nil {
+		t.Fatal("annual report not found")
+	}
+	if annual.Filing.Exchange != SSE || annual.Filing.FilingType != "年度报告" || annual.Filing.Language != models.LanguageZH {
+		t.Errorf("unexpected annual report: %+v", annual.Filing)
+	}
+	if annual.Filing.SourceURL != a.staticURL+"/finalpage/2024-03-28/1219456781.PDF" || annual.Filing.FileExtension != "pdf" {
+		t.Errorf("SourceURL = %q, FileExtension = %q", annual.Filing.SourceURL, annual.Filing.FileExtension)
+	}
+	if annual.Company.ID != "600036" || annual.Company.MarketType != models.MarketTypeSSEMain {
+		t.Errorf("unexpected company: %+v", annual.Company)
+	}
+	if want := time.Date(2024, 3, 28, 18, 30, 0, 0, cst); !annual.Filing.ReportDate.Equal(want) {
+		t.Errorf("ReportDate = %v, want %v", annual.Filing.ReportDate, want)
+	}
+
+	if en := bySource["1219456782"]; en.Filing == nil || en.Filing.Language != models.LanguageEN {
+		t.Errorf("English annual report not detected")
+	}
+	if buyback := bySource["1219456790"]; buyback.Filing == nil || buyback.Filing.Title != "中国平安关于回购A股股份的进展公告" {
+		t.Errorf("highlight tags not stripped")
+	}
+	if star := bySource["1219456800"]; star.Filing == nil || star.Company.MarketType != models.MarketTypeSTAR || star.Filing.FilingType != "年度报告摘要" {
+		t.Errorf("unexpected STAR filing")
+	}
+}
+
+func TestSearchFilings_Board(t *testing.T) {
+	a := newTestAdapter(t, SSE)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-28"), day("2024-03-29"), exchange.SearchOptions{Market: "star"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 1 || filings[0].Company.ID != "688981" {
+		t.Fatalf("got %d filings, want only 中芯国际", len(filings))
+	}
+
+	if _, err := a.SearchFilings(context.Background(), day("2024-03-28"), day("2024-03-28"), exchange.SearchOptions{Market: "CHINEXT"}); err == nil {
+		t.Error("expected error for a Shenzhen board on SSE")
+	}
+}
+
+func TestSearchFilings_SZSE(t *testing.T) {
+	a := newTestAdapter(t, SZSE)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-29"), day("2024-03-29"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 1 {
+		t.Fatalf("got %d filings, want 1", len(filings))
+	}
+	f := filings[0]
+	if f.Filing.Exchange != SZSE || f.Company.MarketType != models.MarketTypeChiNext || f.Filing.FilingType != "临时公告" {
+		t.Errorf("unexpected filing: %+v / %+v", f.Filing, f.Company)
+	}
+}
+
+func TestListSecurities(t *testing.T) {
+	secs, err := newTestAdapter(t, SZSE).ListSecurities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secs) != 2 {
+		t.Errorf("got %d SZSE securities, want 2", len(secs))
+	}
+}
+
+func TestLinkAH(t *testing.T) {
+	a := newTestAdapter(t, SSE)
+
+	aShares, err := a.client.Stocks(context.Background(), "szse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hShares, err := a.client.Stocks(context.Background(), "hke")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, l := range LinkAH(aShares, hShares) {
+		got[l.ACode] = l.HCode.String()
+	}
+
+	want := map[string]string{
+		"600036": "03968", // by name
+		"601318": "02318",
+		"601398": "01398",
+		"688981": "00981", // by shared orgId
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d links, want %d: %v", len(got), len(want), got)
+	}
+	for a, h := range want {
+		if got[a] != h {
+			t.Errorf("%s linked to %q, want %s", a, got[a], h)
+		}
+	}
+}
+
+func TestLinkAH_AmbiguousName(t *testing.T) {
+	aShares := []Stock{{Code: "601766", Name: "中国中车", Category: "A股", OrgID: "gssh0601766"}}
+	hShares := []Stock{
+		{Code: "01766", Name: "中国中车", OrgID: "gshk0001766"},
+		{Code: "09999", Name: "中国中车 H股", OrgID: "gshk0009999"},
+	}
+	if links := LinkAH(aShares, hShares); len(links) != 0 {
+		t.Errorf("got %v, want no link for an ambiguous name", links)
+	}
+}
@@ -0,0 +1,177 @@
+// Package cninfotest serves the cninfo endpoints the SSE/SZSE adapters use
+// from recorded fixtures.
+//
+// The announcement query is filtered by column, plate and seDate and
+// paginated by pageNum and pageSize like the live site. The stock lists are
+// returned as recorded, and finalpage/ PDFs as a stub document for each
+// announcement in the fixtures. One server stands in for both the site and
+// its static host.
+package cninfotest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// PDF is the stub body served for announcement documents
+var PDF = []byte("%PDF-1.4\n% cninfotest stub\n%%EOF\n")
+
+var cst = time.FixedZone("CST", 8*3600)
+
+// recorded is an announcement from a query fixture, kept verbatim for replay
+type recorded struct {
+	raw        json.RawMessage
+	SecCode    string `json:"secCode"`
+	Time       int64  `json:"announcementTime"`
+	AdjunctURL string `json:"adjunctUrl"`
+}
+
+var (
+	loadOnce      sync.Once
+	announcements map[string][]recorded // by column
+	documents     map[string]bool       // adjunct paths
+)
+
+// load parses the embedded query fixtures
+func load() {
+	announcements = make(map[string][]recorded)
+	documents = make(map[string]bool)
+
+	for _, column := range []string{"sse", "szse"} {
+		data, err := fixtures.ReadFile("fixtures/query_" + column + ".json")
+		if err != nil {
+			panic(err)
+		}
+		var resp struct {
+			Announcements []json.RawMessage `json:"announcements"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			panic(fmt.Sprintf("cninfotest: parsing %s fixture: %v", column, err))
+		}
+		for _, raw := range resp.Announcements {
+			r := recorded{raw: raw}
+			if err := json.Unmarshal(raw, &r); err != nil {
+				panic(fmt.Sprintf("cninfotest: parsing announcement: %v", err))
+			}
+			announcements[column] = append(announcements[column], r)
+			if r.AdjunctURL != "" {
+				documents["/"+r.AdjunctURL] = true
+			}
+		}
+	}
+}
+
+// Handler serves the cninfo fixtures
+type Handler struct{}
+
+// NewServer starts a test server serving the fixtures
+func NewServer() *httptest.Server {
+	return httptest.NewServer(Handler{})
+}
+
+func (Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	loadOnce.Do(load)
+
+	switch {
+	case r.URL.Path == "/new/hisAnnouncement/query":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		serveQuery(w, r)
+	case r.URL.Path == "/new/data/szse_stock.json", r.URL.Path == "/new/data/hke_stock.json":
+		data, err := fixtures.ReadFile("fixtures/" + strings.TrimPrefix(r.URL.Path, "/new/data/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.Write(data)
+	case documents[r.URL.Path]:
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(PDF)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveQuery filters and paginates the query fixture of a column
+func serveQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, _ := strings.Cut(r.PostForm.Get("seDate"), "~")
+	plate := r.PostForm.Get("plate")
+
+	pageNum, _ := strconv.Atoi(r.PostForm.Get("pageNum"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	pageSize, _ := strconv.Atoi(r.PostForm.Get("pageSize"))
+	if pageSize < 1 || pageSize > 30 {
+		pageSize = 30
+	}
+
+	var matched []json.RawMessage
+	for _, a := range announcements[r.PostForm.Get("column")] {
+		date := time.UnixMilli(a.Time).In(cst).Format("2006-01-02")
+		if (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		if !onPlate(a.SecCode, plate) {
+			continue
+		}
+		matched = append(matched, a.raw)
+	}
+
+	totalPages := (len(matched) + pageSize - 1) / pageSize
+	start := (pageNum - 1) * pageSize
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	var page any = matched[start:end]
+	if len(matched) == 0 {
+		page = nil // cninfo returns null rather than an empty list
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]any{
+		"totalAnnouncement": len(matched),
+		"totalRecordNum":    len(matched),
+		"totalpages":        totalPages,
+		"hasMore":           end < len(matched),
+		"announcements":     page,
+	})
+}
+
+// onPlate reports whether a security code belongs to a plate
+func onPlate(code, plate string) bool {
+	switch plate {
+	case "shkcp":
+		return strings.HasPrefix(code, "688") || strings.HasPrefix(code, "689")
+	case "shmb":
+		return strings.HasPrefix(code, "6") && !onPlate(code, "shkcp")
+	case "szcy":
+		return strings.HasPrefix(code, "30")
+	case "szmb":
+		return strings.HasPrefix(code, "0")
+	}
+	return true // "sh", "sz" or empty: the whole column
+}
@@ -0,0 +1,72 @@
+package cninfo
+
+import (
+	"strings"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// DualListing pairs the A-share and H-share codes of one issuer
+type DualListing struct {
+	AExchange string // SSE or SZSE
+	ACode     string // six-digit A-share code
+	HCode     models.StockCode
+	Name      string
+}
+
+// LinkAH finds issuers listed both in mainland China and Hong Kong from
+// cninfo's A-share and Hong Kong stock lists. cninfo gives dual-listed
+// issuers one orgId across markets where it can; otherwise lines are
+// matched by Chinese short name. Names shared by several Hong Kong lines are
+// ambiguous and skipped.
+func LinkAH(aShares, hShares []Stock) []DualListing {
+	byOrg := make(map[string]Stock)
+	byName := make(map[string][]Stock)
+	for _, h := range hShares {
+		if h.OrgID != "" {
+			byOrg[h.OrgID] = h
+		}
+		if name := normalizeName(h.Name); name != "" {
+			byName[name] = append(byName[name], h)
+		}
+	}
+
+	var links []DualListing
+	for _, a := range aShares {
+		if a.Category != "A股" {
+			continue
+		}
+		ex := ExchangeOf(a.Code)
+		if ex == "" {
+			continue
+		}
+
+		h, ok := byOrg[a.OrgID]
+		if !ok {
+			matches := byName[normalizeName(a.Name)]
+			if len(matches) != 1 {
+				continue
+			}
+			h = matches[0]
+		}
+
+		links = append(links, DualListing{
+			AExchange: ex,
+			ACode:     a.Code,
+			HCode:     models.NormalizeStockCode("HKEX", h.Code),
+			Name:      a.Name,
+		})
+	}
+	return links
+}
+
+// normalizeName strips spacing and share-class suffixes from a short name,
+// e.g. "中国平安 H股" -> "中国平安"
+func normalizeName(name string) string {
+	name = strings.Join(strings.Fields(name), "")
+	name = strings.ReplaceAll(name, "Ｈ", "H")
+	for _, suffix := range []string{"H股", "-H", "H"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
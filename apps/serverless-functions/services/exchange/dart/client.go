@@ -0,0 +1,253 @@
+package dart
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+)
+
+// OpenDART response statuses
+const (
+	StatusOK        = "000"
+	StatusNoData    = "013"
+	StatusRateLimit = "020"
+)
+
+// MaxPageCount is the largest page size the list endpoint accepts
+const MaxPageCount = 100
+
+// APIError is a non-success status returned by OpenDART
+type APIError struct {
+	Status  string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("OpenDART status %s: %s", e.Status, e.Message)
+}
+
+// IsRateLimitError checks if an error is OpenDART's daily request limit
+func IsRateLimitError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == StatusRateLimit
+}
+
+// Disclosure is one entry of the OpenDART disclosure list (list.json)
+type Disclosure struct {
+	CorpCode    string `json:"corp_code"` // 8-digit DART issuer code
+	CorpName    string `json:"corp_name"`
+	StockCode   string `json:"stock_code"` // 6-digit KRX code, empty for unlisted issuers
+	CorpClass   string `json:"corp_cls"`   // Y: KOSPI, K: KOSDAQ, N: KONEX, E: other
+	ReportName  string `json:"report_nm"`  // e.g. "[기재정정]사업보고서 (2023.12)"
+	ReceiptNo   string `json:"rcept_no"`   // 14-digit receipt number
+	FilerName   string `json:"flr_nm"`
+	ReceiptDate string `json:"rcept_dt"` // YYYYMMDD
+	Remarks     string `json:"rm"`
+}
+
+// ListResponse is one page of list.json
+type ListResponse struct {
+	Status     string       `json:"status"`
+	Message    string       `json:"message"`
+	PageNo     int          `json:"page_no"`
+	PageCount  int          `json:"page_count"`
+	TotalCount int          `json:"total_count"`
+	TotalPage  int          `json:"total_page"`
+	List       []Disclosure `json:"list"`
+}
+
+// ListParams configures a list.json query
+type ListParams struct {
+	From      time.Time // bgn_de
+	To        time.Time // end_de
+	CorpClass string    // corp_cls, empty for all
+	PageNo    int
+	PageCount int
+}
+
+// Corp is one issuer from the corpCode.xml register
+type Corp struct {
+	CorpCode   string `xml:"corp_code"`
+	CorpName   string `xml:"corp_name"`
+	StockCode  string `xml:"stock_code"`
+	ModifyDate string `xml:"modify_date"`
+}
+
+// Client calls the OpenDART API
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	limiter    *ratelimit.Limiter
+	pageCount  int
+}
+
+// NewClient creates an OpenDART client
+func NewClient(baseURL, apiKey string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		limiter:   limiter,
+		pageCount: MaxPageCount,
+	}
+}
+
+// List fetches one page of the disclosure list
+func (c *Client) List(ctx context.Context, params ListParams) (*ListResponse, error) {
+	query := url.Values{}
+	query.Set("bgn_de", params.From.Format("20060102"))
+	query.Set("end_de", params.To.Format("20060102"))
+	query.Set("page_no", strconv.Itoa(params.PageNo))
+	query.Set("page_count", strconv.Itoa(params.PageCount))
+	if params.CorpClass != "" {
+		query.Set("corp_cls", params.CorpClass)
+	}
+
+	body, err := c.get(ctx, "/api/list.json", query)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp ListResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding list response: %w", err)
+	}
+
+	switch resp.Status {
+	case StatusOK:
+		return &resp, nil
+	case StatusNoData:
+		return &ListResponse{Status: resp.Status, PageNo: params.PageNo}, nil
+	}
+	return nil, &APIError{Status: resp.Status, Message: resp.Message}
+}
+
+// ListAll fetches every page of the disclosure list for a date range.
+// OpenDART limits searches without a corp_code to three months, so callers
+// should pass shorter ranges.
+func (c *Client) ListAll(ctx context.Context, from, to time.Time, corpClass string) ([]Disclosure, error) {
+	var all []Disclosure
+
+	for page := 1; ; page++ {
+		resp, err := c.List(ctx, ListParams{
+			From:      from,
+			To:        to,
+			CorpClass: corpClass,
+			PageNo:    page,
+			PageCount: c.pageCount,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d: %w", page, err)
+		}
+
+		all = append(all, resp.List...)
+
+		if len(resp.List) == 0 || page >= resp.TotalPage {
+			break
+		}
+	}
+
+	return all, nil
+}
+
+// CorpCodes downloads the register of DART issuers (a zipped CORPCODE.xml)
+func (c *Client) CorpCodes(ctx context.Context) ([]Corp, error) {
+	body, err := c.get(ctx, "/api/corpCode.xml", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading corp codes: %w", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		// Errors come back as an XML status document instead of a zip
+		return nil, statusError(data)
+	}
+	if len(zr.File) == 0 {
+		return nil, fmt.Errorf("corp code archive is empty")
+	}
+
+	f, err := zr.File[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", zr.File[0].Name, err)
+	}
+	defer f.Close()
+
+	var doc struct {
+		List []Corp `xml:"list"`
+	}
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding corp codes: %w", err)
+	}
+	return doc.List, nil
+}
+
+// DocumentURL returns the URL of a filing's original documents (a zip)
+func (c *Client) DocumentURL(receiptNo string) string {
+	query := url.Values{}
+	query.Set("crtfc_key", c.apiKey)
+	query.Set("rcept_no", receiptNo)
+	return c.baseURL + "/api/document.xml?" + query.Encode()
+}
+
+// get performs a rate-limited GET with the API key added
+func (c *Client) get(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("DART_API_KEY not set")
+	}
+	query.Set("crtfc_key", c.apiKey)
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "HKEXScraper/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The URL carries the API key; report the path only
+		return nil, fmt.Errorf("requesting %s: %w", path, errors.Unwrap(err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %s: %d", path, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// statusError decodes an OpenDART XML status document
+func statusError(data []byte) error {
+	var result struct {
+		Status  string `xml:"status"`
+		Message string `xml:"message"`
+	}
+	if err := xml.Unmarshal(data, &result); err != nil || result.Status == "" {
+		return fmt.Errorf("unexpected response: not a zip archive")
+	}
+	return &APIError{Status: result.Status, Message: result.Message}
+}
@@ -0,0 +1,210 @@
+// Package dart is the exchange adapter for Korean filings published on DART,
+// fetched through the OpenDART API (https://opendart.fss.or.kr).
+//
+// Companies are keyed by DART's 8-digit corp_code, which is permanent, and
+// filings by their 14-digit receipt number (rcept_no).
+package dart
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// Name is the exchange code of DART companies and filings
+const Name = "DART"
+
+// ViewerURL is the public DART viewer page for a filing
+const ViewerURL = "https://dart.fss.or.kr/dsaf001/main.do?rcpNo="
+
+// kst is Korea Standard Time (UTC+9), the zone of receipt dates
+var kst = time.FixedZone("KST", 9*3600)
+
+// corpClasses maps markets to OpenDART corp_cls values
+var corpClasses = map[string]string{
+	string(models.MarketTypeKOSPI):  "Y",
+	string(models.MarketTypeKOSDAQ): "K",
+	string(models.MarketTypeKONEX):  "N",
+}
+
+func init() {
+	exchange.Register(Name, New)
+}
+
+// Adapter implements exchange.Exchange for DART
+type Adapter struct {
+	client *Client
+}
+
+// New creates the DART adapter
+func New(cfg *config.Config) (exchange.Exchange, error) {
+	return &Adapter{
+		client: NewClient(cfg.DARTBaseURL, cfg.DARTAPIKey, ratelimit.ForHost(cfg.DARTBaseURL, cfg.DARTRateLimit)),
+	}, nil
+}
+
+// Name returns "DART"
+func (a *Adapter) Name() string {
+	return Name
+}
+
+// ListSecurities returns the listed issuers from the DART corp code register
+func (a *Adapter) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	corps, err := a.client.CorpCodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching corp codes: %w", err)
+	}
+
+	var secs []securities.Security
+	for _, c := range corps {
+		code := strings.TrimSpace(c.StockCode)
+		if code == "" {
+			continue // unlisted issuer
+		}
+		secs = append(secs, securities.Security{
+			StockCode: models.NormalizeStockCode(Name, code),
+			Name:      strings.TrimSpace(c.CorpName),
+		})
+	}
+	return secs, nil
+}
+
+// SearchFilings queries the disclosure list month by month, since OpenDART
+// caps searches across all issuers at three months. opts.Market selects
+// KOSPI, KOSDAQ or KONEX; by default filings of all listed issuers are
+// returned and those of unlisted issuers are dropped.
+func (a *Adapter) SearchFilings(ctx context.Context, from, to time.Time, opts exchange.SearchOptions) ([]exchange.Filing, error) {
+	corpClass := ""
+	if opts.Market != "" {
+		var ok bool
+		if corpClass, ok = corpClasses[strings.ToUpper(opts.Market)]; !ok {
+			return nil, fmt.Errorf("unknown DART market %q (want KOSPI, KOSDAQ or KONEX)", opts.Market)
+		}
+	}
+
+	var filings []exchange.Filing
+	current := from
+	for !current.After(to) {
+		chunkEnd := time.Date(current.Year(), current.Month()+1, 0, 23, 59, 59, 0, current.Location())
+		if chunkEnd.After(to) {
+			chunkEnd = to
+		}
+
+		list, err := a.client.ListAll(ctx, current, chunkEnd, corpClass)
+		if err != nil {
+			return nil, fmt.Errorf("searching DART %s to %s: %w",
+				current.Format("2006-01-02"), chunkEnd.Format("2006-01-02"), err)
+		}
+
+		for i := range list {
+			d := &list[i]
+			if d.CorpClass == "E" {
+				continue // unlisted issuer
+			}
+			filings = append(filings, ToFiling(d))
+		}
+
+		current = time.Date(current.Year(), current.Month()+1, 1, 0, 0, 0, 0, current.Location())
+	}
+
+	return filings, nil
+}
+
+// DocumentURL returns the OpenDART download URL of the filing's original
+// documents. The URL carries the API key, so it is resolved at download time
+// rather than stored as the filing's SourceURL.
+func (a *Adapter) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	if filing.SourceID == "" {
+		return "", fmt.Errorf("filing %s has no receipt number", filing.ID)
+	}
+	if a.client.apiKey == "" {
+		return "", fmt.Errorf("DART_API_KEY not set")
+	}
+	return a.client.DocumentURL(filing.SourceID), nil
+}
+
+// ToFiling maps a disclosure to a filing and a template of its issuer
+func ToFiling(d *Disclosure) exchange.Filing {
+	reportDate, _ := time.ParseInLocation("20060102", d.ReceiptDate, kst)
+	filingType, filingSubType := splitReportName(d.ReportName)
+
+	filing := &models.Filing{
+		ID:               models.GenerateID("fil"),
+		CompanyID:        d.CorpCode,
+		SourceID:         d.ReceiptNo,
+		Exchange:         Name,
+		FilingType:       filingType,
+		FilingSubType:    filingSubType,
+		ReportDate:       reportDate,
+		Title:            strings.TrimSpace(d.ReportName),
+		SourceURL:        ViewerURL + d.ReceiptNo,
+		FileExtension:    "zip", // document.xml returns a zip of the original files
+		Language:         models.LanguageKO,
+		ProcessingStatus: models.ProcessingStatusPending,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	company := &models.Company{
+		ID:          d.CorpCode,
+		StockCode:   models.NormalizeStockCode(Name, d.StockCode),
+		CompanyName: strings.TrimSpace(d.CorpName),
+		MarketType:  marketType(d.CorpClass),
+		Exchange:    Name,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	return exchange.Filing{Filing: filing, Company: company}
+}
+
+// marketType maps an OpenDART corp_cls to a market
+func marketType(corpClass string) models.MarketType {
+	switch corpClass {
+	case "Y":
+		return models.MarketTypeKOSPI
+	case "K":
+		return models.MarketTypeKOSDAQ
+	case "N":
+		return models.MarketTypeKONEX
+	}
+	return models.MarketTypeOther
+}
+
+// reportPeriod matches the period suffix of periodic reports, e.g. "2023.12"
+var reportPeriod = regexp.MustCompile(`^\d{4}\.\d{2}$`)
+
+// splitReportName splits a report name into its type and sub-type:
+// "[기재정정]사업보고서 (2023.12)" is type "사업보고서" and
+// "주요사항보고서(자기주식취득결정)" is type "주요사항보고서" with sub-type
+// "자기주식취득결정". Amendment markers and report periods are dropped.
+func splitReportName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	for strings.HasPrefix(name, "[") {
+		end := strings.Index(name, "]")
+		if end < 0 {
+			break
+		}
+		name = strings.TrimSpace(name[end+1:])
+	}
+
+	open := strings.Index(name, "(")
+	if open <= 0 || !strings.HasSuffix(name, ")") {
+		return name, ""
+	}
+
+	sub := strings.TrimSpace(name[open+1 : len(name)-1])
+	name = strings.TrimSpace(name[:open])
+	if reportPeriod.MatchString(sub) {
+		sub = ""
+	}
+	return name, sub
+}
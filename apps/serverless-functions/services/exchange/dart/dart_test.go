@@ -0,0 +1,174 @@
+package dart
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/dart/darttest"
+)
+
+// newTestAdapter returns an adapter against the fixture server with a small
+// page size to exercise paging
+func newTestAdapter(t *testing.T, apiKey string) *Adapter {
+	t.Helper()
+
+	srv := darttest.NewServer("test-key")
+	t.Cleanup(srv.Close)
+
+	ex, err := exchange.Open(Name, &config.Config{DARTBaseURL: srv.URL, DARTAPIKey: apiKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ex.(*Adapter)
+	a.client.pageCount = 2
+	return a
+}
+
+func day(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, kst)
+	return t
+}
+
+func TestSearchFilings(t *testing.T) {
+	a := newTestAdapter(t, "test-key")
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-01-01"), day("2024-02-29"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 7 fixtures across two months, less one unlisted issuer
+	if len(filings) != 6 {
+		t.Fatalf("got %d filings, want 6", len(filings))
+	}
+
+	var f *exchange.Filing
+	for i := range filings {
+		if filings[i].Filing.SourceID == "20240102000021" {
+			f = &filings[i]
+		}
+	}
+	if f == nil {
+		t.Fatal("filing 20240102000021 not found")
+	}
+
+	if f.Filing.Exchange != "DART" || f.Filing.FilingType != "사업보고서" || f.Filing.FilingSubType != "" {
+		t.Errorf("unexpected filing: %+v", f.Filing)
+	}
+	if f.Filing.Title != "[기재정정]사업보고서 (2022.12)" {
+		t.Errorf("Title = %q", f.Filing.Title)
+	}
+	if want := day("2024-01-02"); !f.Filing.ReportDate.Equal(want) {
+		t.Errorf("ReportDate = %v, want %v", f.Filing.ReportDate, want)
+	}
+	if f.Filing.SourceURL != ViewerURL+"20240102000021" || f.Filing.FileExtension != "zip" {
+		t.Errorf("SourceURL = %q, FileExtension = %q", f.Filing.SourceURL, f.Filing.FileExtension)
+	}
+	if f.Company.ID != "00126380" || f.Company.StockCode != "005930" || f.Company.MarketType != models.MarketTypeKOSPI {
+		t.Errorf("unexpected company: %+v", f.Company)
+	}
+}
+
+func TestSearchFilings_Market(t *testing.T) {
+	a := newTestAdapter(t, "test-key")
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-01-01"), day("2024-01-31"), exchange.SearchOptions{Market: "kosdaq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 1 || filings[0].Company.ID != "01160363" {
+		t.Fatalf("got %d filings, want only 에코프로비엠", len(filings))
+	}
+	if filings[0].Filing.FilingType != "단일판매ㆍ공급계약체결" {
+		t.Errorf("FilingType = %q", filings[0].Filing.FilingType)
+	}
+
+	if _, err := a.SearchFilings(context.Background(), day("2024-01-01"), day("2024-01-31"), exchange.SearchOptions{Market: "SEHK"}); err == nil {
+		t.Error("expected error for unknown market")
+	}
+}
+
+func TestSearchFilings_NoData(t *testing.T) {
+	a := newTestAdapter(t, "test-key")
+
+	filings, err := a.SearchFilings(context.Background(), day("2023-06-01"), day("2023-06-30"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 0 {
+		t.Errorf("got %d filings, want none", len(filings))
+	}
+}
+
+func TestSearchFilings_BadKey(t *testing.T) {
+	a := newTestAdapter(t, "wrong-key")
+
+	_, err := a.SearchFilings(context.Background(), day("2024-01-01"), day("2024-01-31"), exchange.SearchOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if IsRateLimitError(err) {
+		t.Errorf("bad key reported as rate limit: %v", err)
+	}
+}
+
+func TestListSecurities(t *testing.T) {
+	a := newTestAdapter(t, "test-key")
+
+	secs, err := a.ListSecurities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secs) != 5 {
+		t.Fatalf("got %d securities, want 5 listed issuers", len(secs))
+	}
+	if secs[0].StockCode != "005930" || secs[0].Name != "삼성전자" {
+		t.Errorf("unexpected first security: %+v", secs[0])
+	}
+}
+
+func TestDocumentURL(t *testing.T) {
+	a := newTestAdapter(t, "test-key")
+
+	got, err := a.DocumentURL(context.Background(), &models.Filing{SourceID: "20240115000456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/document.xml" || u.Query().Get("rcept_no") != "20240115000456" || u.Query().Get("crtfc_key") != "test-key" {
+		t.Errorf("unexpected document URL %q", got)
+	}
+
+	if _, err := newTestAdapter(t, "").DocumentURL(context.Background(), &models.Filing{SourceID: "20240115000456"}); err == nil {
+		t.Error("expected error without API key")
+	}
+}
+
+func TestSplitReportName(t *testing.T) {
+	tests := []struct {
+		name, typ, sub string
+	}{
+		{"사업보고서 (2023.12)", "사업보고서", ""},
+		{"[기재정정]사업보고서 (2022.12)", "사업보고서", ""},
+		{"[첨부추가][기재정정]반기보고서 (2023.06)", "반기보고서", ""},
+		{"주요사항보고서(자기주식취득결정)", "주요사항보고서", "자기주식취득결정"},
+		{"현금ㆍ현물배당결정", "현금ㆍ현물배당결정", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, sub := splitReportName(tt.name)
+			if typ != tt.typ || sub != tt.sub {
+				t.Errorf("splitReportName(%q) = %q, %q; want %q, %q", tt.name, typ, sub, tt.typ, tt.sub)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package darttest serves the OpenDART endpoints the DART adapter uses from
+// recorded fixtures, for tests and for local runs without an API key
+// (see tools/opendart-standin).
+//
+// list.json is filtered by bgn_de, end_de and corp_cls and paginated by
+// page_no and page_count like the live API. corpCode.xml returns the zipped
+// register and document.xml a zip holding a stub document for each receipt
+// number in the list fixture.
+package darttest
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// recorded is a disclosure from the list fixture, kept verbatim for replay
+type recorded struct {
+	raw         json.RawMessage
+	CorpClass   string `json:"corp_cls"`
+	ReceiptNo   string `json:"rcept_no"`
+	ReceiptDate string `json:"rcept_dt"`
+}
+
+var (
+	loadOnce    sync.Once
+	disclosures []recorded
+	corpCodes   []byte
+)
+
+// load parses the embedded fixtures
+func load() {
+	var list struct {
+		List []json.RawMessage `json:"list"`
+	}
+	data, err := fixtures.ReadFile("fixtures/list.json")
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		panic(fmt.Sprintf("darttest: parsing list fixture: %v", err))
+	}
+	for _, raw := range list.List {
+		r := recorded{raw: raw}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			panic(fmt.Sprintf("darttest: parsing disclosure: %v", err))
+		}
+		disclosures = append(disclosures, r)
+	}
+
+	xml, err := fixtures.ReadFile("fixtures/CORPCODE.xml")
+	if err != nil {
+		panic(err)
+	}
+	corpCodes = zipFile("CORPCODE.xml", xml)
+}
+
+// Handler serves the OpenDART API. An empty APIKey accepts any non-empty
+// crtfc_key.
+type Handler struct {
+	APIKey string
+}
+
+// NewServer starts a test server serving the fixtures to apiKey
+func NewServer(apiKey string) *httptest.Server {
+	return httptest.NewServer(&Handler{APIKey: apiKey})
+}
+
+// ReceiptNos returns the receipt numbers in the list fixture, newest first
+func ReceiptNos() []string {
+	loadOnce.Do(load)
+	nos := make([]string, len(disclosures))
+	for i, d := range disclosures {
+		nos[i] = d.ReceiptNo
+	}
+	return nos
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	loadOnce.Do(load)
+
+	q := r.URL.Query()
+	key := q.Get("crtfc_key")
+	if key == "" || (h.APIKey != "" && key != h.APIKey) {
+		writeStatus(w, r.URL.Path, "010", "등록되지 않은 키입니다.")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/list.json":
+		h.serveList(w, r)
+	case "/api/corpCode.xml":
+		w.Header().Set("Content-Type", "application/x-msdownload")
+		w.Write(corpCodes)
+	case "/api/document.xml":
+		h.serveDocument(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveList filters and paginates the list fixture
+func (h *Handler) serveList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, class := q.Get("bgn_de"), q.Get("end_de"), q.Get("corp_cls")
+
+	pageNo, _ := strconv.Atoi(q.Get("page_no"))
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	pageCount, _ := strconv.Atoi(q.Get("page_count"))
+	if pageCount < 1 || pageCount > 100 {
+		pageCount = 10
+	}
+
+	var matched []json.RawMessage
+	for _, d := range disclosures {
+		if (from != "" && d.ReceiptDate < from) || (to != "" && d.ReceiptDate > to) {
+			continue
+		}
+		if class != "" && d.CorpClass != class {
+			continue
+		}
+		matched = append(matched, d.raw)
+	}
+
+	if len(matched) == 0 {
+		writeStatus(w, r.URL.Path, "013", "조회된 데이타가 없습니다.")
+		return
+	}
+
+	totalPage := (len(matched) + pageCount - 1) / pageCount
+	start := (pageNo - 1) * pageCount
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + pageCount
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":      "000",
+		"message":     "정상",
+		"page_no":     pageNo,
+		"page_count":  pageCount,
+		"total_count": len(matched),
+		"total_page":  totalPage,
+		"list":        matched[start:end],
+	})
+}
+
+// serveDocument returns a zipped stub document for known receipt numbers
+func (h *Handler) serveDocument(w http.ResponseWriter, r *http.Request) {
+	no := r.URL.Query().Get("rcept_no")
+	for _, d := range disclosures {
+		if d.ReceiptNo == no {
+			doc := fmt.Sprintf("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<DOCUMENT><DOCUMENT-NAME>%s</DOCUMENT-NAME></DOCUMENT>\n", no)
+			w.Header().Set("Content-Type", "application/x-msdownload")
+			w.Write(zipFile(no+".xml", []byte(doc)))
+			return
+		}
+	}
+	writeStatus(w, r.URL.Path, "014", "파일이 존재하지 않습니다.")
+}
+
+// writeStatus writes an OpenDART error status as JSON or XML, matching the
+// endpoint's format
+func writeStatus(w http.ResponseWriter, path, status, message string) {
+	if path == "/api/list.json" {
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		json.NewEncoder(w).Encode(map[string]string{"status": status, "message": message})
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml;charset=UTF-8")
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<result><status>%s</status><message>%s</message></result>\n", status, message)
+}
+
+// zipFile builds a zip archive holding one file
+func zipFile(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(name)
+	if err != nil {
+		panic(err)
+	}
+	f.Write(data)
+	if err := zw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,107 @@
+// Package exchange defines the adapter interface for filing sources and a
+// registry of the adapters compiled into a binary.
+//
+// Each adapter registers itself from an init function, so binaries select
+// exchanges with blank imports:
+//
+//	import _ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+)
+
+// Exchange is a source of listed securities and their filings
+type Exchange interface {
+	// Name returns the code stored in Company.Exchange and Filing.Exchange, e.g. "HKEX"
+	Name() string
+
+	// ListSecurities returns the securities currently listed on the exchange
+	ListSecurities(ctx context.Context) ([]securities.Security, error)
+
+	// SearchFilings returns filings released between from and to (inclusive)
+	SearchFilings(ctx context.Context, from, to time.Time, opts SearchOptions) ([]Filing, error)
+
+	// DocumentURL resolves the URL a filing's document is downloaded from.
+	// Some sources need a request to find it.
+	DocumentURL(ctx context.Context, filing *models.Filing) (string, error)
+}
+
+// SearchOptions narrows a filing search
+type SearchOptions struct {
+	Market string // exchange-specific market or board, e.g. "SEHK" or "GEM"; empty for the default
+}
+
+// Filing is a filing found by SearchFilings together with its issuer
+type Filing struct {
+	// Filing has no CompanyID; callers resolve it from Company with identity.Resolve
+	Filing *models.Filing
+
+	// Company is a template of the issuing company built from the search result
+	Company *models.Company
+
+	// Class is the instrument classification of the issuer's security
+	Class securities.Classification
+}
+
+// Factory creates an adapter from configuration
+type Factory func(cfg *config.Config) (Exchange, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes an adapter available under name. It panics if an adapter is
+// already registered under that name.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, dup := factories[name]; dup {
+		panic("exchange: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Open creates the adapter registered under name
+func Open(name string, cfg *config.Config) (Exchange, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("exchange %q not registered (registered: %v)", name, Names())
+	}
+	return factory(cfg)
+}
+
+// Registered reports whether an adapter is registered under name
+func Registered(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := factories[name]
+	return ok
+}
+
+// Names returns the names of the registered adapters, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
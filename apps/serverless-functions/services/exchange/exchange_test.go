@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+)
+
+type fakeExchange struct{}
+
+func (fakeExchange) Name() string { return "FAKE" }
+
+func (fakeExchange) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	return nil, nil
+}
+
+func (fakeExchange) SearchFilings(ctx context.Context, from, to time.Time, opts SearchOptions) ([]Filing, error) {
+	return nil, nil
+}
+
+func (fakeExchange) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	return filing.SourceURL, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("FAKE", func(cfg *config.Config) (Exchange, error) { return fakeExchange{}, nil })
+
+	if !Registered("FAKE") {
+		t.Fatal("FAKE not registered")
+	}
+	if Registered("NOPE") {
+		t.Error("NOPE reported as registered")
+	}
+
+	ex, err := Open("FAKE", &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.Name() != "FAKE" {
+		t.Errorf("Name() = %q, want FAKE", ex.Name())
+	}
+
+	if _, err := Open("NOPE", &config.Config{}); err == nil {
+		t.Error("expected error opening unregistered exchange")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate Register")
+		}
+	}()
+	Register("FAKE", func(cfg *config.Config) (Exchange, error) { return fakeExchange{}, nil })
+}
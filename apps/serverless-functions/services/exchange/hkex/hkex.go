@@ -0,0 +1,119 @@
+// Package hkex is the exchange adapter for the Hong Kong Stock Exchange,
+// backed by the HKEXnews Title Search API and the HKEX List of Securities.
+package hkex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
+)
+
+// Name is the exchange code of HKEX companies and filings
+const Name = "HKEX"
+
+func init() {
+	exchange.Register(Name, New)
+}
+
+// Adapter implements exchange.Exchange for HKEX
+type Adapter struct {
+	config *config.Config
+	search *api.SearchClient
+
+	classifierOnce sync.Once
+	classifier     *securities.Classifier
+}
+
+// New creates the HKEX adapter
+func New(cfg *config.Config) (exchange.Exchange, error) {
+	return &Adapter{
+		config: cfg,
+		search: api.NewSearchClient(cfg, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit)),
+	}, nil
+}
+
+// Name returns "HKEX"
+func (a *Adapter) Name() string {
+	return Name
+}
+
+// ListSecurities downloads the HKEX List of Securities
+func (a *Adapter) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	list, err := securities.Fetch(ctx, a.config.SecuritiesListURL)
+	if err != nil {
+		return nil, err
+	}
+	return list.Securities, nil
+}
+
+// SearchFilings queries the Title Search API month by month. Market-wide
+// announcements without a stock code are dropped.
+func (a *Adapter) SearchFilings(ctx context.Context, from, to time.Time, opts exchange.SearchOptions) ([]exchange.Filing, error) {
+	market := opts.Market
+	if market == "" {
+		market = "SEHK"
+	}
+
+	results, err := a.search.SearchByDateRange(from, to, market)
+	if err != nil {
+		return nil, fmt.Errorf("searching HKEX: %w", err)
+	}
+
+	classifier := a.loadClassifier(ctx)
+
+	filings := make([]exchange.Filing, 0, len(results))
+	for i := range results {
+		r := &results[i]
+		if r.StockCode == "" {
+			continue
+		}
+		filings = append(filings, ToFiling(r, market, classifier))
+	}
+	return filings, nil
+}
+
+// DocumentURL returns the filing's source URL, which HKEX search results
+// carry in full
+func (a *Adapter) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	if filing.SourceURL == "" {
+		return "", fmt.Errorf("filing %s has no source URL", filing.SourceID)
+	}
+	return filing.SourceURL, nil
+}
+
+// ToFiling maps a search result to a filing and a template of its company
+func ToFiling(r *api.SearchResult, market string, classifier *securities.Classifier) exchange.Filing {
+	filing := models.SearchResultToFiling(
+		r.NewsID, r.Title, r.StockCode, r.StockName,
+		r.DateTime, r.FileType, r.FileInfo, r.FileLink,
+		r.LongText, "",
+	)
+
+	company := models.StockToCompany(&models.Stock{SC: r.StockCode, SN: r.StockName})
+	company.MarketType = models.MarketType(market)
+
+	class := classifier.Classify(r.StockCode)
+	class.Apply(company)
+
+	return exchange.Filing{Filing: filing, Company: company, Class: class}
+}
+
+// loadClassifier fetches the securities list on first use when list-based
+// classification is enabled. The list is shared by adapters in the process
+// and refreshed daily.
+func (a *Adapter) loadClassifier(ctx context.Context) *securities.Classifier {
+	a.classifierOnce.Do(func() {
+		if a.config.ClassifyFromList {
+			a.classifier = securities.CachedClassifier(ctx, a.config.SecuritiesListURL)
+		}
+	})
+	return a.classifier
+}
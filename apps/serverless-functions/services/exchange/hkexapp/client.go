@@ -0,0 +1,110 @@
+package hkexapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+)
+
+// Feed states: applications under review, lapsed, withdrawn, rejected or
+// returned applications, and applicants that have listed
+const (
+	StateActive   = "active"
+	StateInactive = "inactive"
+	StateListed   = "listed"
+)
+
+// Application is an applicant in a new listing feed
+type Application struct {
+	ID        int        `json:"id"`
+	Date      string     `json:"d"` // first submission, dd/mm/yyyy
+	Applicant string     `json:"a"`
+	StockCode string     `json:"sc,omitempty"` // listed applicants only
+	ListedOn  string     `json:"ld,omitempty"` // listed applicants only, dd/mm/yyyy
+	Documents []Document `json:"ls"`
+}
+
+// Document is a document posted for an applicant. Documents come as a full
+// version, a multi-file version, or both.
+type Document struct {
+	Date   string `json:"d"` // dd/mm/yyyy
+	Name   string `json:"nF"`
+	Label1 string `json:"nS1"`
+	URL1   string `json:"u1"`
+	Label2 string `json:"nS2"`
+	URL2   string `json:"u2"`
+}
+
+// feed is the envelope of a new listing feed
+type feed struct {
+	UpdatedAt    string        `json:"uDate"`
+	Applications []Application `json:"app"`
+}
+
+// Client fetches the new listing feeds of HKEXnews
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	limiter    *ratelimit.Limiter
+}
+
+// NewClient creates a new listing feed client
+func NewClient(baseURL string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		limiter: limiter,
+	}
+}
+
+// Applications returns the applications of a board ("sehk" or "gem") in one
+// feed state
+func (c *Client) Applications(ctx context.Context, board, state string) ([]Application, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	feedURL := c.FeedURL(board, state)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s %s applications: %w", board, state, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status for %s %s applications: %d", board, state, resp.StatusCode)
+	}
+
+	var f feed
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		return nil, fmt.Errorf("decoding %s %s applications: %w", board, state, err)
+	}
+	return f.Applications, nil
+}
+
+// FeedURL returns the URL of a board's feed in one state
+func (c *Client) FeedURL(board, state string) string {
+	return fmt.Sprintf("%s/ncms/json/eds/app%s_app_%s_e.json", c.baseURL, state, board)
+}
+
+// DocumentURL returns the full URL of a document path from a feed
+func (c *Client) DocumentURL(path string) string {
+	if strings.HasPrefix(path, "http") {
+		return path
+	}
+	return c.baseURL + "/app/" + strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,198 @@
+// Package hkexapp is the exchange adapter for HKEX new listing documents:
+// Application Proofs, Post Hearing Information Packs (PHIPs), prospectuses
+// and allotment results, published on HKEXnews's new listing pages.
+//
+// Applicants have no stock code until they list, so they are tracked as
+// companies of their own exchange, HKEX_APP, keyed by HKEXnews's application
+// ID. Each document is a filing of its applicant. Once an applicant lists,
+// the link-listings tool links it to the HKEX company holding its new code.
+package hkexapp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// Name is the exchange code of listing applicants and their documents
+const Name = "HKEX_APP"
+
+// Document types, derived from document names
+const (
+	TypeApplicationProof = "Application Proof"
+	TypePHIP             = "PHIP"
+	TypeProspectus       = "Prospectus"
+	TypeAllotmentResults = "Allotment Results"
+	TypeOCAnnouncement   = "OC Announcement"
+	TypeOther            = "Other"
+)
+
+// boards maps markets to the board names of the feeds
+var boards = map[models.MarketType]string{
+	models.MarketTypeSEHK: "sehk",
+	models.MarketTypeGEM:  "gem",
+}
+
+// states are the feeds read for each board
+var states = []string{StateActive, StateInactive, StateListed}
+
+// hkt is Hong Kong time, the zone of feed dates
+var hkt = models.HongKong
+
+func init() {
+	exchange.Register(Name, New)
+}
+
+// Adapter implements exchange.Exchange for new listing documents
+type Adapter struct {
+	client *Client
+}
+
+// New creates the new listing adapter, which reads the feeds from
+// HKEX_BASE_URL at the HKEX rate limit
+func New(cfg *config.Config) (exchange.Exchange, error) {
+	return &Adapter{client: NewClient(cfg.BaseURL, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit))}, nil
+}
+
+// Name returns "HKEX_APP"
+func (a *Adapter) Name() string {
+	return Name
+}
+
+// ListSecurities returns nothing: applicants have no securities until they
+// list, and are then in the HKEX securities list
+func (a *Adapter) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	return nil, nil
+}
+
+// SearchFilings returns the documents posted between from and to for
+// active, inactive and listed applicants. opts.Market narrows the search to
+// "SEHK" or "GEM"; by default both boards are searched.
+func (a *Adapter) SearchFilings(ctx context.Context, from, to time.Time, opts exchange.SearchOptions) ([]exchange.Filing, error) {
+	markets := []models.MarketType{models.MarketTypeSEHK, models.MarketTypeGEM}
+	if opts.Market != "" {
+		m := models.MarketType(strings.ToUpper(opts.Market))
+		if _, ok := boards[m]; !ok {
+			return nil, fmt.Errorf("unknown %s market %q", Name, opts.Market)
+		}
+		markets = []models.MarketType{m}
+	}
+
+	from, to = from.In(hkt), to.In(hkt)
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, hkt)
+
+	var filings []exchange.Filing
+	for _, market := range markets {
+		for _, state := range states {
+			apps, err := a.client.Applications(ctx, boards[market], state)
+			if err != nil {
+				return nil, fmt.Errorf("searching %s: %w", Name, err)
+			}
+
+			for i := range apps {
+				app := &apps[i]
+				for j := range app.Documents {
+					doc := &app.Documents[j]
+					date, err := parseDate(doc.Date)
+					if err != nil || date.Before(fromDay) || date.After(to) {
+						continue
+					}
+					if f, ok := a.ToFiling(app, doc, market); ok {
+						filings = append(filings, f)
+					}
+				}
+			}
+		}
+	}
+
+	return filings, nil
+}
+
+// DocumentURL returns the filing's source URL, which the feeds carry
+func (a *Adapter) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	if filing.SourceURL == "" {
+		return "", fmt.Errorf("filing %s has no source URL", filing.SourceID)
+	}
+	return filing.SourceURL, nil
+}
+
+// ToFiling maps a document to a filing and a template of its applicant. The
+// full version is preferred; documents posted only as multi-file versions
+// are stored as their index page. It reports false for documents without a
+// link or date.
+func (a *Adapter) ToFiling(app *Application, doc *Document, market models.MarketType) (exchange.Filing, bool) {
+	link := doc.URL1
+	if link == "" {
+		link = doc.URL2
+	}
+	date, err := parseDate(doc.Date)
+	if link == "" || err != nil {
+		return exchange.Filing{}, false
+	}
+
+	id := strconv.Itoa(app.ID)
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(link)), ".")
+	name := strings.TrimSuffix(path.Base(link), path.Ext(link))
+
+	filing := &models.Filing{
+		ID:               models.GenerateID("fil"),
+		CompanyID:        id,
+		SourceID:         id + "-" + name,
+		Exchange:         Name,
+		FilingType:       DocumentType(doc.Name),
+		ReportDate:       date,
+		Title:            doc.Name,
+		TitleEn:          doc.Name,
+		SourceURL:        a.client.DocumentURL(link),
+		FileExtension:    ext,
+		Language:         models.LanguageEN,
+		ProcessingStatus: models.ProcessingStatusPending,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	company := &models.Company{
+		ID:          id,
+		StockCode:   models.NormalizeStockCode("HKEX", app.StockCode),
+		CompanyName: app.Applicant,
+		MarketType:  market,
+		Exchange:    Name,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	return exchange.Filing{Filing: filing, Company: company}, true
+}
+
+// DocumentType classifies a document by its name, e.g.
+// "Application Proof (1st submission)" -> "Application Proof"
+func DocumentType(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case strings.Contains(n, "application proof"):
+		return TypeApplicationProof
+	case strings.Contains(n, "post hearing information pack"), strings.Contains(n, "phip"):
+		return TypePHIP
+	case strings.Contains(n, "allotment results"):
+		return TypeAllotmentResults
+	case strings.Contains(n, "prospectus"):
+		return TypeProspectus
+	case strings.Contains(n, "overall coordinator"), strings.HasPrefix(n, "oc announcement"):
+		return TypeOCAnnouncement
+	}
+	return TypeOther
+}
+
+// parseDate parses a feed date such as "28/03/2024"
+func parseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("02/01/2006", strings.TrimSpace(s), hkt)
+}
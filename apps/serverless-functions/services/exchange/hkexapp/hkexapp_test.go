@@ -0,0 +1,129 @@
+package hkexapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// newTestAdapter serves the feeds in testdata
+func newTestAdapter(t *testing.T) (*Adapter, *httptest.Server) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile("testdata/" + path.Base(r.URL.Path))
+		if path.Dir(r.URL.Path) != "/ncms/json/eds" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	ex, err := New(&config.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ex.(*Adapter), srv
+}
+
+func TestSearchFilings(t *testing.T) {
+	a, srv := newTestAdapter(t)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, hkt)
+	to := time.Date(2024, 3, 31, 23, 59, 59, 0, hkt)
+	filings, err := a.SearchFilings(context.Background(), from, to, exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bySource := make(map[string]exchange.Filing)
+	for _, f := range filings {
+		bySource[f.Filing.SourceID] = f
+	}
+
+	// Documents of active, inactive and listed applicants posted in March
+	want := map[string]string{
+		"106402-sehk24032800051": TypeApplicationProof,
+		"106188-sehk24031500033": TypeOCAnnouncement,
+		"105720-sehk24031400044": TypeApplicationProof,
+		"105901-sehk24031200088": TypePHIP,
+		"105901-sehk24032000150": TypeProspectus,
+		"105901-sehk24032700211": TypeAllotmentResults,
+	}
+	if len(filings) != len(want) {
+		t.Fatalf("got %d filings, want %d", len(filings), len(want))
+	}
+	for id, typ := range want {
+		f, ok := bySource[id]
+		if !ok {
+			t.Errorf("missing filing %s", id)
+			continue
+		}
+		if f.Filing.FilingType != typ {
+			t.Errorf("%s: FilingType = %q, want %q", id, f.Filing.FilingType, typ)
+		}
+	}
+
+	ap := bySource["106402-sehk24032800051"]
+	if ap.Filing.SourceURL != srv.URL+"/app/sehk/2024/106402/documents/sehk24032800051.pdf" || ap.Filing.FileExtension != "pdf" {
+		t.Errorf("unexpected document: %s (%s)", ap.Filing.SourceURL, ap.Filing.FileExtension)
+	}
+	if !ap.Filing.ReportDate.Equal(time.Date(2024, 3, 28, 0, 0, 0, 0, hkt)) {
+		t.Errorf("ReportDate = %v", ap.Filing.ReportDate)
+	}
+	if c := ap.Company; c.ID != "106402" || c.Exchange != Name || c.StockCode != "" || c.CompanyName != "Mixue Group" || c.MarketType != models.MarketTypeSEHK {
+		t.Errorf("unexpected applicant: %+v", c)
+	}
+
+	// Documents posted only as multi-file versions are stored as the index
+	if mf := bySource["105720-sehk24031400044"]; mf.Filing.FileExtension != "htm" {
+		t.Errorf("FileExtension = %q, want htm", mf.Filing.FileExtension)
+	}
+
+	// Listed applicants carry their stock code
+	if c := bySource["105901-sehk24032000150"].Company; c.StockCode != "02555" {
+		t.Errorf("StockCode = %q, want 02555", c.StockCode)
+	}
+}
+
+func TestSearchFilings_Market(t *testing.T) {
+	a, _ := newTestAdapter(t)
+
+	filings, err := a.SearchFilings(context.Background(), time.Now().AddDate(-1, 0, 0), time.Now(), exchange.SearchOptions{Market: "gem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 0 {
+		t.Errorf("got %d GEM filings, want 0", len(filings))
+	}
+
+	if _, err := a.SearchFilings(context.Background(), time.Now(), time.Now(), exchange.SearchOptions{Market: "STAR"}); err == nil {
+		t.Error("expected error for an unknown market")
+	}
+}
+
+func TestDocumentType(t *testing.T) {
+	tests := map[string]string{
+		"Application Proof (1st submission)":   TypeApplicationProof,
+		"Post Hearing Information Pack (PHIP)": TypePHIP,
+		"Supplemental Prospectus":              TypeProspectus,
+		"Allotment Results":                    TypeAllotmentResults,
+		"Overall Coordinators Announcement":    TypeOCAnnouncement,
+		"Formal Notice":                        TypeOther,
+	}
+	for name, want := range tests {
+		if got := DocumentType(name); got != want {
+			t.Errorf("DocumentType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
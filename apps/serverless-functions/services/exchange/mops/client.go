@@ -0,0 +1,364 @@
+package mops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/htmltable"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ErrTooFrequent is returned when MOPS keeps answering "查詢過於頻繁"
+// (queries too frequent) after retries
+var ErrTooFrequent = errors.New("mops: queries too frequent")
+
+// maxRetries is how often a throttled request is retried
+const maxRetries = 3
+
+// MaterialInfo is one row of the daily material information list (t05st02)
+type MaterialInfo struct {
+	CompanyCode string
+	CompanyName string
+	SpokeDate   string // YYYYMMDD
+	SpokeTime   string // HHMMSS
+	SeqNo       string
+	Subject     string
+}
+
+// Report is one electronic document of a company (t57sb01)
+type Report struct {
+	CompanyCode string
+	Kind        string // document kind queried, e.g. "A" (financial reports)
+	Year        int    // ROC data year
+	Season      string // "1".."4", empty for annual documents
+	Description string // e.g. "IFRSs合併財報"
+	Filename    string
+	Size        int // bytes
+	UploadedAt  time.Time
+}
+
+// Listing is one security of the ISIN securities list
+type Listing struct {
+	Code     string
+	Name     string
+	ISIN     string
+	Category string // section of the list, e.g. "股票" (shares)
+	Industry string
+}
+
+// Client fetches MOPS pages. MOPS itself serves UTF-8; the document and ISIN
+// sites serve Big5, declared as "big5", "MS950" or not at all.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	docURL     string
+	isinURL    string
+	limiter    *ratelimit.Limiter
+	retryWait  time.Duration // first pause after a throttled request
+}
+
+// NewClient creates a MOPS client
+func NewClient(baseURL, docURL, isinURL string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		docURL:    strings.TrimRight(docURL, "/"),
+		isinURL:   strings.TrimRight(isinURL, "/"),
+		limiter:   limiter,
+		retryWait: 10 * time.Second,
+	}
+}
+
+// MaterialInfo fetches the material information published on one day by
+// companies of a market ("sii" listed, "otc" OTC)
+func (c *Client) MaterialInfo(ctx context.Context, typek string, day time.Time) ([]MaterialInfo, error) {
+	form := url.Values{}
+	form.Set("encodeURIComponent", "1")
+	form.Set("step", "1")
+	form.Set("firstin", "1")
+	form.Set("off", "1")
+	form.Set("TYPEK", typek)
+	form.Set("year", strconv.Itoa(day.Year()-rocOffset))
+	form.Set("month", fmt.Sprintf("%02d", day.Month()))
+	form.Set("day", fmt.Sprintf("%02d", day.Day()))
+
+	doc, err := c.fetch(ctx, http.MethodPost, c.baseURL+"/mops/web/ajax_t05st02", form)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := htmltable.Find(doc, "公司代號", "發言日期", "主旨")
+	if !ok {
+		if noData(doc) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("material information table not found")
+	}
+
+	var infos []MaterialInfo
+	for _, r := range rows {
+		if r.Cells == nil {
+			continue
+		}
+		// The detail button carries the announcement key in its onclick
+		fields := onclickFields(r.Node)
+		infos = append(infos, MaterialInfo{
+			CompanyCode: r.Text("公司代號"),
+			CompanyName: r.Text("公司名稱"),
+			SpokeDate:   fields["spoke_date"],
+			SpokeTime:   fields["spoke_time"],
+			SeqNo:       fields["seq_no"],
+			Subject:     r.Text("主旨"),
+		})
+	}
+	return infos, nil
+}
+
+// MaterialInfoURL returns the detail page of an announcement
+func (c *Client) MaterialInfoURL(typek string, info *MaterialInfo) string {
+	q := url.Values{}
+	q.Set("encodeURIComponent", "1")
+	q.Set("step", "2")
+	q.Set("firstin", "1")
+	q.Set("off", "1")
+	q.Set("TYPEK", typek)
+	q.Set("co_id", info.CompanyCode)
+	q.Set("spoke_date", info.SpokeDate)
+	q.Set("spoke_time", info.SpokeTime)
+	q.Set("seq_no", info.SeqNo)
+	return c.baseURL + "/mops/web/ajax_t05st01?" + q.Encode()
+}
+
+// Reports fetches a company's documents of one kind for an ROC data year
+func (c *Client) Reports(ctx context.Context, coID string, year int, kind string) ([]Report, error) {
+	q := url.Values{}
+	q.Set("step", "1")
+	q.Set("colorchg", "1")
+	q.Set("co_id", coID)
+	q.Set("year", strconv.Itoa(year))
+	q.Set("mtype", kind)
+
+	doc, err := c.fetch(ctx, http.MethodGet, c.docURL+"/server-java/t57sb01?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := htmltable.Find(doc, "資料年度", "檔案說明", "電子檔案")
+	if !ok {
+		if noData(doc) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("document table of %s not found", coID)
+	}
+
+	var reports []Report
+	for _, r := range rows {
+		if r.Cells == nil {
+			continue
+		}
+		year, _ := strconv.Atoi(r.Text("資料年度"))
+		size, _ := strconv.Atoi(strings.ReplaceAll(r.Text("檔案大小"), ",", ""))
+		uploaded, _ := parseROCTime(r.Text("上傳日期"))
+		reports = append(reports, Report{
+			CompanyCode: coID,
+			Kind:        kind,
+			Year:        year,
+			Season:      r.Text("資料季別"),
+			Description: r.Text("檔案說明"),
+			Filename:    r.Text("電子檔案"),
+			Size:        size,
+			UploadedAt:  uploaded,
+		})
+	}
+	return reports, nil
+}
+
+// ReportPageURL returns the page that links a document's PDF. The PDF itself
+// is saved under a generated name, so the link is only known after fetching
+// this page.
+func (c *Client) ReportPageURL(kind, coID, filename string) string {
+	q := url.Values{}
+	q.Set("step", "9")
+	q.Set("kind", kind)
+	q.Set("co_id", coID)
+	q.Set("filename", filename)
+	return c.docURL + "/server-java/t57sb01?" + q.Encode()
+}
+
+// ReportURL fetches a document page and returns the PDF it links to
+func (c *Client) ReportURL(ctx context.Context, pageURL string) (string, error) {
+	doc, err := c.fetch(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	href := findLink(doc, ".pdf")
+	if href == "" {
+		return "", fmt.Errorf("no document link on %s", pageURL)
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("parsing document link: %w", err)
+	}
+	base, _ := url.Parse(pageURL)
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Securities fetches the ISIN securities list of a market: strMode "2" for
+// listed, "4" for OTC securities
+func (c *Client) Securities(ctx context.Context, strMode string) ([]Listing, error) {
+	doc, err := c.fetch(ctx, http.MethodGet, c.isinURL+"/isin/C_public.jsp?strMode="+url.QueryEscape(strMode), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := htmltable.Find(doc, "有價證券代號及名稱", "國際證券辨識號碼(ISIN Code)")
+	if !ok {
+		return nil, fmt.Errorf("securities table not found")
+	}
+
+	var listings []Listing
+	category := ""
+	for _, r := range rows {
+		if r.Cells == nil {
+			category = r.Section
+			continue
+		}
+		// "2330　台積電": code and name separated by an ideographic space
+		code, name, _ := strings.Cut(r.Text("有價證券代號及名稱"), " ")
+		listings = append(listings, Listing{
+			Code:     code,
+			Name:     name,
+			ISIN:     r.Text("國際證券辨識號碼(ISIN Code)"),
+			Category: category,
+			Industry: r.Text("產業別"),
+		})
+	}
+	return listings, nil
+}
+
+// fetch performs a rate-limited request and parses the decoded response,
+// retrying while MOPS reports queries as too frequent
+func (c *Client) fetch(ctx context.Context, method, rawURL string, form url.Values) (*html.Node, error) {
+	wait := c.retryWait
+	for attempt := 0; ; attempt++ {
+		page, err := c.do(ctx, method, rawURL, form)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(page, "查詢過於頻繁") {
+			doc, err := html.Parse(strings.NewReader(page))
+			if err != nil {
+				return nil, fmt.Errorf("parsing response: %w", err)
+			}
+			return doc, nil
+		}
+
+		if attempt == maxRetries {
+			return nil, ErrTooFrequent
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// do performs one rate-limited request and returns the body as UTF-8
+func (c *Client) do(ctx context.Context, method, rawURL string, form url.Values) (string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting %s: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %d", req.URL.Path, resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", req.URL.Path, err)
+	}
+	return decode(raw, resp.Header.Get("Content-Type"))
+}
+
+// metaCharset finds a charset declared in a page's first bytes
+var metaCharset = regexp.MustCompile(`(?i)charset\s*=\s*["']?([\w-]+)`)
+
+// decode converts a page to UTF-8. The charset comes from the Content-Type
+// header or a meta tag; MS950 and its aliases, which are not WHATWG labels,
+// are read as Big5. Undeclared pages are UTF-8 if valid and Big5 otherwise.
+func decode(raw []byte, contentType string) (string, error) {
+	name := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		name = params["charset"]
+	}
+	if name == "" {
+		head := raw
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if m := metaCharset.FindSubmatch(head); m != nil {
+			name = string(m[1])
+		}
+	}
+
+	var enc encoding.Encoding
+	switch strings.ToLower(name) {
+	case "":
+		if utf8.Valid(raw) {
+			return string(raw), nil
+		}
+		enc = traditionalchinese.Big5
+	case "ms950", "cp950", "windows-950", "x-windows-950":
+		enc = traditionalchinese.Big5
+	default:
+		enc, _ = charset.Lookup(name)
+		if enc == nil {
+			return "", fmt.Errorf("unsupported charset %q", name)
+		}
+	}
+
+	out, err := enc.NewDecoder().Bytes(raw)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return string(bytes.TrimPrefix(out, []byte("\ufeff"))), nil
+}
@@ -0,0 +1,276 @@
+// Package mops is the exchange adapter for Taiwan filings published on MOPS
+// (公開資訊觀測站, https://mops.twse.com.tw), the disclosure site of the
+// Taiwan Stock Exchange (TWSE) and the Taipei Exchange (TPEx).
+//
+// One adapter is registered per exchange. Filings are material information
+// announcements (重大訊息), searched day by day, and, when enabled, periodic
+// reports from the electronic document site. Companies are keyed by their
+// stock code.
+package mops
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+)
+
+// Exchange codes of Taiwan companies and filings
+const (
+	TWSE = "TWSE"
+	TPEx = "TPEx"
+)
+
+// FilingTypeMaterialInfo is the filing type of material information
+const FilingTypeMaterialInfo = "重大訊息"
+
+// shareCategory is the ISIN list section of ordinary shares, the only
+// securities scanned for periodic reports
+const shareCategory = "股票"
+
+// reportKinds are the document kinds scanned for periodic reports: financial
+// reports and annual reports with other shareholder meeting documents
+var reportKinds = []string{"A", "F"}
+
+// tst is Taiwan time (UTC+8), the zone of MOPS dates
+var tst = time.FixedZone("TST", 8*3600)
+
+// venue holds the MOPS query parameters of one exchange
+type venue struct {
+	typek    string // MOPS market: "sii" listed, "otc" OTC
+	isinMode string // ISIN list strMode
+	market   models.MarketType
+}
+
+var venues = map[string]venue{
+	TWSE: {typek: "sii", isinMode: "2", market: models.MarketTypeTWSE},
+	TPEx: {typek: "otc", isinMode: "4", market: models.MarketTypeTPEx},
+}
+
+func init() {
+	for name := range venues {
+		name := name
+		exchange.Register(name, func(cfg *config.Config) (exchange.Exchange, error) {
+			return New(name, cfg), nil
+		})
+	}
+}
+
+// Adapter implements exchange.Exchange for one Taiwan exchange
+type Adapter struct {
+	name    string
+	venue   venue
+	client  *Client
+	reports bool // scan periodic reports
+}
+
+// New creates the adapter for TWSE or TPEx
+func New(name string, cfg *config.Config) *Adapter {
+	return &Adapter{
+		name:    name,
+		venue:   venues[name],
+		client:  NewClient(cfg.MOPSBaseURL, cfg.MOPSDocURL, cfg.MOPSISINURL, ratelimit.ForHost(cfg.MOPSBaseURL, cfg.MOPSRateLimit)),
+		reports: cfg.MOPSReports,
+	}
+}
+
+// Name returns "TWSE" or "TPEx"
+func (a *Adapter) Name() string {
+	return a.name
+}
+
+// ListSecurities returns the exchange's securities from the ISIN list
+func (a *Adapter) ListSecurities(ctx context.Context) ([]securities.Security, error) {
+	listings, err := a.client.Securities(ctx, a.venue.isinMode)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s securities list: %w", a.name, err)
+	}
+
+	secs := make([]securities.Security, 0, len(listings))
+	for _, l := range listings {
+		secs = append(secs, securities.Security{
+			StockCode:   models.NormalizeStockCode(a.name, l.Code),
+			Name:        l.Name,
+			Category:    l.Category,
+			SubCategory: l.Industry,
+			ISIN:        l.ISIN,
+		})
+	}
+	return secs, nil
+}
+
+// SearchFilings returns the material information published between from and
+// to, queried one day at a time. With periodic reports enabled it also
+// returns the reports uploaded in the range, which takes a request per
+// company, document kind and data year.
+func (a *Adapter) SearchFilings(ctx context.Context, from, to time.Time, opts exchange.SearchOptions) ([]exchange.Filing, error) {
+	if opts.Market != "" && !strings.EqualFold(opts.Market, string(a.venue.market)) {
+		return nil, fmt.Errorf("unknown %s market %q", a.name, opts.Market)
+	}
+
+	var filings []exchange.Filing
+	from, to = dateOf(from.In(tst)), dateOf(to.In(tst))
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		infos, err := a.client.MaterialInfo(ctx, a.venue.typek, day)
+		if err != nil {
+			return nil, fmt.Errorf("searching %s material information %s: %w", a.name, day.Format("2006-01-02"), err)
+		}
+
+		for i := range infos {
+			info := &infos[i]
+			if info.CompanyCode == "" || info.SpokeDate == "" {
+				continue // no detail page to download
+			}
+			filings = append(filings, a.MaterialInfoFiling(info))
+		}
+	}
+
+	if a.reports {
+		reports, err := a.searchReports(ctx, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		filings = append(filings, reports...)
+	}
+
+	return filings, nil
+}
+
+// searchReports scans each listed company's documents for those uploaded in
+// [from, to). Reports uploaded in a year cover that and the previous data
+// year.
+func (a *Adapter) searchReports(ctx context.Context, from, to time.Time) ([]exchange.Filing, error) {
+	listings, err := a.client.Securities(ctx, a.venue.isinMode)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s securities list: %w", a.name, err)
+	}
+
+	var filings []exchange.Filing
+	scanned := 0
+	for _, l := range listings {
+		if l.Category != shareCategory {
+			continue
+		}
+
+		for year := from.Year() - 1 - rocOffset; year <= to.Year()-rocOffset; year++ {
+			for _, kind := range reportKinds {
+				reports, err := a.client.Reports(ctx, l.Code, year, kind)
+				if err != nil {
+					return nil, fmt.Errorf("searching %s reports of %s: %w", a.name, l.Code, err)
+				}
+				for i := range reports {
+					r := &reports[i]
+					if r.Filename == "" || r.UploadedAt.Before(from) || !r.UploadedAt.Before(to) {
+						continue
+					}
+					filings = append(filings, a.ReportFiling(r, l.Name))
+				}
+			}
+		}
+
+		if scanned++; scanned%100 == 0 {
+			log.Printf("%s: scanned reports of %d companies", a.name, scanned)
+		}
+	}
+
+	return filings, nil
+}
+
+// DocumentURL returns the document of a filing. Material information is
+// downloaded from its detail page; periodic reports from the PDF linked by
+// their document page.
+func (a *Adapter) DocumentURL(ctx context.Context, filing *models.Filing) (string, error) {
+	if filing.SourceURL == "" {
+		return "", fmt.Errorf("filing %s has no source URL", filing.SourceID)
+	}
+	if filing.FilingType == FilingTypeMaterialInfo {
+		return filing.SourceURL, nil
+	}
+	return a.client.ReportURL(ctx, filing.SourceURL)
+}
+
+// MaterialInfoFiling maps a material information announcement to a filing
+// and a template of its issuer
+func (a *Adapter) MaterialInfoFiling(info *MaterialInfo) exchange.Filing {
+	spokeTime := info.SpokeTime
+	if len(spokeTime) < 6 {
+		spokeTime = strings.Repeat("0", 6-len(spokeTime)) + spokeTime // e.g. "93501"
+	}
+	reportDate, _ := time.ParseInLocation("20060102150405", info.SpokeDate+spokeTime, tst)
+
+	filing := &models.Filing{
+		ID:               models.GenerateID("fil"),
+		CompanyID:        info.CompanyCode,
+		SourceID:         strings.Join([]string{info.CompanyCode, info.SpokeDate, spokeTime, info.SeqNo}, "-"),
+		Exchange:         a.name,
+		FilingType:       FilingTypeMaterialInfo,
+		ReportDate:       reportDate,
+		Title:            info.Subject,
+		SourceURL:        a.client.MaterialInfoURL(a.venue.typek, info),
+		FileExtension:    "html",
+		Language:         models.LanguageZH,
+		ProcessingStatus: models.ProcessingStatusPending,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	return exchange.Filing{Filing: filing, Company: a.company(info.CompanyCode, info.CompanyName)}
+}
+
+// ReportFiling maps a periodic report to a filing and a template of its issuer
+func (a *Adapter) ReportFiling(r *Report, companyName string) exchange.Filing {
+	period := fmt.Sprintf("%d", r.Year+rocOffset)
+	if r.Season != "" {
+		period += "Q" + r.Season
+	}
+
+	language := models.LanguageZH
+	if strings.Contains(r.Description, "英文") {
+		language = models.LanguageEN
+	}
+
+	filing := &models.Filing{
+		ID:               models.GenerateID("fil"),
+		CompanyID:        r.CompanyCode,
+		SourceID:         r.Filename,
+		Exchange:         a.name,
+		FilingType:       r.Description,
+		ReportDate:       r.UploadedAt,
+		Title:            r.Description + " " + period,
+		SourceURL:        a.client.ReportPageURL(r.Kind, r.CompanyCode, r.Filename),
+		FileSize:         r.Size,
+		FileExtension:    strings.ToLower(strings.TrimPrefix(path.Ext(r.Filename), ".")),
+		Language:         language,
+		ProcessingStatus: models.ProcessingStatusPending,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	return exchange.Filing{Filing: filing, Company: a.company(r.CompanyCode, companyName)}
+}
+
+// company builds a company template
+func (a *Adapter) company(code, name string) *models.Company {
+	return &models.Company{
+		ID:          code,
+		StockCode:   models.NormalizeStockCode(a.name, code),
+		CompanyName: name,
+		MarketType:  a.venue.market,
+		Exchange:    a.name,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
+
+// dateOf truncates t to midnight in its zone
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
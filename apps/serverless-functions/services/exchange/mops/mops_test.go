@@ -0,0 +1,214 @@
+package mops
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/mops/mopstest"
+)
+
+// newTestAdapter opens an adapter against the fixture server
+func newTestAdapter(t *testing.T, name string, reports bool) (*Adapter, string) {
+	t.Helper()
+
+	srv := mopstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ex, err := exchange.Open(name, &config.Config{
+		MOPSBaseURL: srv.URL,
+		MOPSDocURL:  srv.URL,
+		MOPSISINURL: srv.URL,
+		MOPSReports: reports,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ex.(*Adapter), srv.URL
+}
+
+func day(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, tst)
+	return t
+}
+
+func TestSearchFilings_MaterialInfo(t *testing.T) {
+	a, _ := newTestAdapter(t, TWSE, false)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-27"), day("2024-03-28"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 rows on the 28th, less one without a detail button; none on the 27th
+	if len(filings) != 3 {
+		t.Fatalf("got %d filings, want 3", len(filings))
+	}
+
+	f := filings[0]
+	if f.Filing.SourceID != "2330-20240328-173501-1" || f.Filing.Exchange != TWSE || f.Filing.FilingType != FilingTypeMaterialInfo {
+		t.Errorf("unexpected filing: %+v", f.Filing)
+	}
+	if f.Filing.Title != "本公司代子公司TSMC Global Ltd.公告取得固定收益證券" {
+		t.Errorf("Title = %q", f.Filing.Title)
+	}
+	if want := time.Date(2024, 3, 28, 17, 35, 1, 0, tst); !f.Filing.ReportDate.Equal(want) {
+		t.Errorf("ReportDate = %v, want %v", f.Filing.ReportDate, want)
+	}
+	if f.Company.ID != "2330" || f.Company.CompanyName != "台積電" || f.Company.MarketType != models.MarketTypeTWSE {
+		t.Errorf("unexpected company: %+v", f.Company)
+	}
+
+	url, err := a.DocumentURL(context.Background(), f.Filing)
+	if err != nil || url != f.Filing.SourceURL {
+		t.Errorf("DocumentURL = %q, %v; want the source URL", url, err)
+	}
+}
+
+func TestSearchFilings_TPEx(t *testing.T) {
+	a, _ := newTestAdapter(t, TPEx, false)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-28"), day("2024-03-28"), exchange.SearchOptions{Market: "tpex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filings) != 2 {
+		t.Fatalf("got %d filings, want 2", len(filings))
+	}
+	for _, f := range filings {
+		if f.Filing.Exchange != TPEx || f.Company.MarketType != models.MarketTypeTPEx {
+			t.Errorf("unexpected filing: %+v / %+v", f.Filing, f.Company)
+		}
+	}
+
+	if _, err := a.SearchFilings(context.Background(), day("2024-03-28"), day("2024-03-28"), exchange.SearchOptions{Market: "TWSE"}); err == nil {
+		t.Error("expected error for a TWSE market on TPEx")
+	}
+}
+
+func TestSearchFilings_Reports(t *testing.T) {
+	a, srvURL := newTestAdapter(t, TWSE, true)
+
+	filings, err := a.SearchFilings(context.Background(), day("2024-03-01"), day("2024-03-31"), exchange.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bySource := make(map[string]exchange.Filing)
+	for _, f := range filings {
+		bySource[f.Filing.SourceID] = f
+	}
+
+	// 3 material information filings, and the three 2023Q4 reports uploaded in March
+	if len(filings) != 6 {
+		t.Fatalf("got %d filings, want 6", len(filings))
+	}
+	if _, ok := bySource["202303_2330_AI1.pdf"]; ok {
+		t.Error("report uploaded in November included")
+	}
+
+	r := bySource["202304_2330_AI1.pdf"]
+	if r.Filing == nil {
+		t.Fatal("2023Q4 consolidated report not found")
+	}
+	if r.Filing.FilingType != "IFRSs合併財報" || r.Filing.Title != "IFRSs合併財報 2023Q4" || r.Filing.Language != models.LanguageZH {
+		t.Errorf("unexpected report: %+v", r.Filing)
+	}
+	if r.Filing.FileSize != 3456789 || r.Filing.FileExtension != "pdf" {
+		t.Errorf("FileSize = %d, FileExtension = %q", r.Filing.FileSize, r.Filing.FileExtension)
+	}
+	if want := time.Date(2024, 3, 12, 17, 21, 9, 0, tst); !r.Filing.ReportDate.Equal(want) {
+		t.Errorf("ReportDate = %v, want %v", r.Filing.ReportDate, want)
+	}
+	if r.Company.CompanyName != "台積電" {
+		t.Errorf("company name %q not decoded from the Big5 securities list", r.Company.CompanyName)
+	}
+
+	if en := bySource["202304_2330_AIA.pdf"]; en.Filing == nil || en.Filing.Language != models.LanguageEN {
+		t.Error("English report not detected")
+	}
+
+	url, err := a.DocumentURL(context.Background(), r.Filing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srvURL + "/pdf/202304_2330_AI1_20240328_150247.pdf"; url != want {
+		t.Errorf("DocumentURL = %q, want %q", url, want)
+	}
+}
+
+func TestListSecurities(t *testing.T) {
+	a, _ := newTestAdapter(t, TWSE, false)
+
+	secs, err := a.ListSecurities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secs) != 4 {
+		t.Fatalf("got %d securities, want 4", len(secs))
+	}
+
+	tsmc := secs[1]
+	if tsmc.StockCode != "2330" || tsmc.Name != "台積電" || tsmc.ISIN != "TW0002330008" || tsmc.Category != "股票" || tsmc.SubCategory != "半導體業" {
+		t.Errorf("unexpected security: %+v", tsmc)
+	}
+	if warrant := secs[3]; warrant.Category != "上市認購(售)權證" {
+		t.Errorf("warrant category = %q", warrant.Category)
+	}
+}
+
+func TestClient_TooFrequent(t *testing.T) {
+	srv := httptest.NewServer(&mopstest.Handler{Throttle: 2})
+	defer srv.Close()
+
+	c := NewClient(srv.URL, srv.URL, srv.URL, nil)
+	c.retryWait = time.Millisecond
+
+	infos, err := c.MaterialInfo(context.Background(), "otc", day("2024-03-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Errorf("got %d announcements after retrying, want 2", len(infos))
+	}
+
+	srv2 := httptest.NewServer(&mopstest.Handler{Throttle: maxRetries + 1})
+	defer srv2.Close()
+
+	c = NewClient(srv2.URL, srv2.URL, srv2.URL, nil)
+	c.retryWait = time.Millisecond
+	if _, err := c.MaterialInfo(context.Background(), "otc", day("2024-03-28")); !errors.Is(err, ErrTooFrequent) {
+		t.Errorf("err = %v, want ErrTooFrequent", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	big5 := []byte{0xa5, 0x78, 0xbf, 0x6e, 0xb9, 0x71} // 台積電
+
+	tests := []struct {
+		name        string
+		raw         []byte
+		contentType string
+	}{
+		{"undeclared Big5", big5, "text/html"},
+		{"MS950 header", big5, "text/html; charset=MS950"},
+		{"MS950 meta", append([]byte(`<meta http-equiv="Content-Type" content="text/html; charset=MS950">`), big5...), ""},
+		{"UTF-8", []byte("台積電"), "text/html; charset=UTF-8"},
+	}
+	for _, tt := range tests {
+		got, err := decode(tt.raw, tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !strings.HasSuffix(got, "台積電") {
+			t.Errorf("%s: got %q", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package mopstest serves the MOPS pages the TWSE/TPEx adapters use from
+// captured responses.
+//
+// Responses keep the encodings of the live sites: the material information
+// list is UTF-8, electronic document lists are Big5 with no declared
+// charset, and the ISIN lists are Big5 declared as MS950. Queries without a
+// captured response get each site's "no data" page. One server stands in for
+// MOPS, the document site and the ISIN site.
+package mopstest
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// PDF is the stub body served for periodic report documents
+var PDF = []byte("%PDF-1.4\n% mopstest stub\n%%EOF\n")
+
+// TooFrequent is the page MOPS serves when queried too often
+const TooFrequent = "<html><body><center><h3>查詢過於頻繁，請稍後再試!!</h3></center></body></html>"
+
+// Handler serves the MOPS fixtures
+type Handler struct {
+	// Throttle is the number of requests answered with TooFrequent before
+	// the fixtures are served
+	Throttle int
+
+	mu       sync.Mutex
+	requests int
+}
+
+// NewServer starts a test server serving the fixtures
+func NewServer() *httptest.Server {
+	return httptest.NewServer(&Handler{})
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests++
+	throttled := h.requests <= h.Throttle
+	h.mu.Unlock()
+
+	if throttled {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		fmt.Fprint(w, TooFrequent)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/mops/web/ajax_t05st02":
+		serveMaterialInfo(w, r)
+	case r.URL.Path == "/mops/web/ajax_t05st01":
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		fmt.Fprintf(w, "<html><body><table class='hasBorder'><tr><th>公司代號</th><td>%s</td></tr><tr><th>發言日期</th><td>%s</td></tr></table></body></html>",
+			r.URL.Query().Get("co_id"), r.URL.Query().Get("spoke_date"))
+	case r.URL.Path == "/server-java/t57sb01":
+		serveDocuments(w, r)
+	case strings.HasPrefix(r.URL.Path, "/pdf/"):
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(PDF)
+	case r.URL.Path == "/isin/C_public.jsp":
+		data, err := fixtures.ReadFile("fixtures/isin_" + r.URL.Query().Get("strMode") + ".html")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		// The live site declares MS950 only in a meta tag
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveMaterialInfo serves the material information list of a market and day
+func serveMaterialInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var year int
+	fmt.Sscanf(r.PostForm.Get("year"), "%d", &year)
+	name := fmt.Sprintf("fixtures/t05st02_%s_%04d%s%s.html",
+		r.PostForm.Get("TYPEK"), year+1911, r.PostForm.Get("month"), r.PostForm.Get("day"))
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		fmt.Fprint(w, "<html><body><center><h3>查詢無資料!</h3></center></body></html>")
+		return
+	}
+	w.Write(data)
+}
+
+// serveDocuments serves a company's document list (step 1) or the page
+// linking one document (step 9)
+func serveDocuments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	w.Header().Set("Content-Type", "text/html")
+
+	switch q.Get("step") {
+	case "1":
+		name := fmt.Sprintf("fixtures/t57sb01_%s_%s_%s.html", q.Get("co_id"), q.Get("year"), q.Get("mtype"))
+		data, err := fixtures.ReadFile(name)
+		if err != nil {
+			data, _ = fixtures.ReadFile("fixtures/t57sb01_nodata.html")
+		}
+		w.Write(data)
+	case "9":
+		// The live site copies the document under a timestamped name
+		saved := strings.TrimSuffix(q.Get("filename"), ".pdf") + "_20240328_150247.pdf"
+		fmt.Fprintf(w, "<HTML><BODY><CENTER><A HREF='/pdf/%s'>%s</A></CENTER></BODY></HTML>", saved, saved)
+	default:
+		http.Error(w, "unknown step", http.StatusBadRequest)
+	}
+}
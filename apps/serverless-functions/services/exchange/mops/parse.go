@@ -0,0 +1,83 @@
+package mops
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/htmltable"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// rocOffset converts Gregorian years to ROC (Minguo) years, which Taiwanese
+// pages use: 2024 is ROC 113
+const rocOffset = 1911
+
+// onclickAssign matches the form field assignments of MOPS detail buttons,
+// e.g. document.fm.seq_no.value='1'
+var onclickAssign = regexp.MustCompile(`(\w+)\.value\s*=\s*["']([^"']*)["']`)
+
+// onclickFields collects the form fields assigned by onclick handlers under n
+func onclickFields(n *html.Node) map[string]string {
+	fields := make(map[string]string)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, m := range onclickAssign.FindAllStringSubmatch(htmltable.Attr(n, "onclick"), -1) {
+				fields[m[1]] = m[2]
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return fields
+}
+
+// findLink returns the href of the first link ending in suffix
+func findLink(doc *html.Node, suffix string) string {
+	for _, a := range htmltable.FindAll(doc, atom.A) {
+		if href := htmltable.Attr(a, "href"); strings.HasSuffix(strings.ToLower(href), suffix) {
+			return href
+		}
+	}
+	return ""
+}
+
+// noData reports an empty result, which MOPS pages announce as "查詢無資料"
+// or "查無資料" instead of an empty table
+func noData(doc *html.Node) bool {
+	text := htmltable.Text(doc)
+	return strings.Contains(text, "查詢無資料") || strings.Contains(text, "查無資料") || strings.Contains(text, "無符合條件")
+}
+
+// parseROCDate parses an ROC date such as "113/03/28"
+func parseROCDate(s string) (time.Time, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid ROC date %q", s)
+	}
+	year, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ROC date %q", s)
+	}
+	return time.ParseInLocation("2006/01/02", fmt.Sprintf("%04d/%s/%s", year+rocOffset, parts[1], parts[2]), tst)
+}
+
+// parseROCTime parses an ROC timestamp such as "113/03/12 17:21:09"
+func parseROCTime(s string) (time.Time, error) {
+	date, clock, _ := strings.Cut(strings.TrimSpace(s), " ")
+	d, err := parseROCDate(date)
+	if err != nil || clock == "" {
+		return d, err
+	}
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return d.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second), nil
+}
@@ -3,15 +3,15 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/queue"
 )
 
 // TriggerInput is the input from Step Functions
@@ -33,19 +33,14 @@ type BatchJob struct {
 	FilingIDs []string `json:"filing_ids"`
 }
 
-func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
-	log.Println("Triggering downloads...")
-
-	// Set defaults
-	batchSize := input.BatchSize
-	if batchSize <= 0 {
-		batchSize = 100
-	}
-	limit := input.Limit
-	if limit <= 0 {
-		limit = 10000 // Default max
-	}
+// trigger queues filing IDs for the downloader Lambda
+type trigger struct {
+	queue queue.Queue
+	// pending returns the IDs of up to limit pending filings
+	pending func(ctx context.Context, limit int) ([]string, error)
+}
 
+func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 	databaseURL := os.Getenv("DATABASE_URL")
 	queueURL := os.Getenv("SQS_QUEUE_URL")
 
@@ -56,12 +51,32 @@ func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 		return nil, fmt.Errorf("SQS_QUEUE_URL not set")
 	}
 
-	// Connect to database
-	pool, err := pgxpool.New(ctx, databaseURL)
+	clients, err := awsclient.New(ctx, awsclient.FromEnv())
 	if err != nil {
-		return nil, fmt.Errorf("connecting to database: %w", err)
+		return nil, err
+	}
+
+	t := &trigger{
+		queue: queue.NewSQSQueue(clients.SQS(), queueURL),
+		pending: func(ctx context.Context, limit int) ([]string, error) {
+			return pendingFilings(ctx, databaseURL, limit)
+		},
+	}
+	return t.run(ctx, input)
+}
+
+func (t *trigger) run(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
+	log.Println("Triggering downloads...")
+
+	// Set defaults
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 10000 // Default max
 	}
-	defer pool.Close()
 
 	// Get filing IDs to process
 	var filingIDs []string
@@ -71,24 +86,10 @@ func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 		filingIDs = input.FilingIDs
 		log.Printf("Using %d provided filing IDs", len(filingIDs))
 	} else {
-		// Query pending filings from database
-		rows, err := pool.Query(ctx, `
-			SELECT source_id FROM filings
-			WHERE processing_status = 'PENDING'
-			ORDER BY report_date DESC
-			LIMIT $1
-		`, limit)
+		var err error
+		filingIDs, err = t.pending(ctx, limit)
 		if err != nil {
-			return nil, fmt.Errorf("querying pending filings: %w", err)
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var id string
-			if err := rows.Scan(&id); err != nil {
-				return nil, fmt.Errorf("scanning filing ID: %w", err)
-			}
-			filingIDs = append(filingIDs, id)
+			return nil, err
 		}
 		log.Printf("Found %d pending filings in database", len(filingIDs))
 	}
@@ -102,18 +103,9 @@ func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 		}, nil
 	}
 
-	// Create SQS client
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("loading AWS config: %w", err)
-	}
-	sqsClient := sqs.NewFromConfig(cfg)
-
-	// Send batches to SQS
-	output := &TriggerOutput{
-		TotalFilings: len(filingIDs),
-	}
-
+	// Build one message per batch
+	var batches [][]string
+	var bodies []string
 	for i := 0; i < len(filingIDs); i += batchSize {
 		end := i + batchSize
 		if end > len(filingIDs) {
@@ -128,16 +120,28 @@ func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 			log.Printf("Error marshaling batch: %v", err)
 			continue
 		}
+		batches = append(batches, batch)
+		bodies = append(bodies, string(jobJSON))
+	}
 
-		_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
-			QueueUrl:    aws.String(queueURL),
-			MessageBody: aws.String(string(jobJSON)),
-		})
-		if err != nil {
-			log.Printf("Error sending batch to SQS: %v", err)
-			continue
+	// Send batches, counting those that were queued
+	var failed map[int]bool
+	if err := t.queue.SendBatch(ctx, bodies); err != nil {
+		var batchErr *queue.BatchError
+		if !errors.As(err, &batchErr) {
+			return nil, fmt.Errorf("sending batches: %w", err)
 		}
+		log.Printf("Error sending batches to SQS: %v", err)
+		failed = batchErr.FailedIndexes()
+	}
 
+	output := &TriggerOutput{
+		TotalFilings: len(filingIDs),
+	}
+	for i, batch := range batches {
+		if failed[i] {
+			continue
+		}
 		output.BatchesSent++
 		output.FilingsQueued += len(batch)
 	}
@@ -147,6 +151,37 @@ func Handler(ctx context.Context, input TriggerInput) (*TriggerOutput, error) {
 	return output, nil
 }
 
+// pendingFilings queries the source IDs of up to limit pending filings,
+// newest first
+func pendingFilings(ctx context.Context, databaseURL string, limit int) ([]string, error) {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	defer pool.Close()
+
+	rows, err := pool.Query(ctx, `
+		SELECT source_id FROM filings
+		WHERE processing_status = 'PENDING'
+		ORDER BY report_date DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying pending filings: %w", err)
+	}
+	defer rows.Close()
+
+	var filingIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning filing ID: %w", err)
+		}
+		filingIDs = append(filingIDs, id)
+	}
+	return filingIDs, rows.Err()
+}
+
 func main() {
 	lambda.Start(Handler)
 }
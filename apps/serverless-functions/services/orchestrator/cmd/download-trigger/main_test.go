@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/queue"
+)
+
+// rejectingQueue fails the messages at the given batch indexes
+type rejectingQueue struct {
+	*queue.MemoryQueue
+	reject map[int]bool
+}
+
+func (q *rejectingQueue) SendBatch(ctx context.Context, bodies []string) error {
+	var sent []string
+	var failed []queue.BatchFailure
+	for i, body := range bodies {
+		if q.reject[i] {
+			failed = append(failed, queue.BatchFailure{Index: i, Err: errors.New("rejected")})
+			continue
+		}
+		sent = append(sent, body)
+	}
+	q.MemoryQueue.SendBatch(ctx, sent)
+	if failed != nil {
+		return &queue.BatchError{Failed: failed}
+	}
+	return nil
+}
+
+func TestTrigger(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryQueue(time.Minute)
+	tr := &trigger{
+		queue: q,
+		pending: func(ctx context.Context, limit int) ([]string, error) {
+			return []string{"1", "2", "3", "4", "5"}[:limit], nil
+		},
+	}
+
+	out, err := tr.run(ctx, TriggerInput{BatchSize: 2, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.TotalFilings != 5 || out.BatchesSent != 3 || out.FilingsQueued != 5 {
+		t.Errorf("run() = %+v", out)
+	}
+
+	msgs, _ := q.Receive(ctx, 10)
+	if len(msgs) != 3 {
+		t.Fatalf("queued %d messages, want 3", len(msgs))
+	}
+	var job BatchJob
+	if err := json.Unmarshal([]byte(msgs[2].Body), &job); err != nil || len(job.FilingIDs) != 1 || job.FilingIDs[0] != "5" {
+		t.Errorf("last message = %s", msgs[2].Body)
+	}
+}
+
+func TestTrigger_PartialFailure(t *testing.T) {
+	q := &rejectingQueue{MemoryQueue: queue.NewMemoryQueue(time.Minute), reject: map[int]bool{1: true}}
+	tr := &trigger{queue: q}
+
+	out, err := tr.run(context.Background(), TriggerInput{FilingIDs: []string{"1", "2", "3", "4", "5"}, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.BatchesSent != 2 || out.FilingsQueued != 3 || q.Len() != 2 {
+		t.Errorf("run() = %+v, queued %d", out, q.Len())
+	}
+}
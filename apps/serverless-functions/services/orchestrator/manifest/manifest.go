@@ -0,0 +1,128 @@
+// Package manifest writes the filing manifest the Batch download workers
+// read their chunks from (write-manifest Lambda).
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/claimcheck"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+// FilingPayload matches the scraper output format (same as sfn-downloader)
+type FilingPayload struct {
+	SourceID      string `json:"source_id"`
+	SourceURL     string `json:"source_url"`
+	CompanyID     string `json:"company_id"`
+	FileExtension string `json:"file_extension"`
+	Exchange      string `json:"exchange"`
+	ReportDate    string `json:"report_date"`
+}
+
+// Input is the Lambda event payload from Step Functions.
+// WriteManifest receives { "filings": [...], "filings_ref": ... } from the
+// Payload mapping; the filings are read from S3 when filings_ref is set.
+type Input struct {
+	Filings    []FilingPayload `json:"filings"`
+	FilingsRef *claimcheck.Ref `json:"filings_ref,omitempty"`
+}
+
+// Output is returned to Step Functions
+type Output struct {
+	ManifestBucket string `json:"manifest_bucket"`
+	ManifestKey    string `json:"manifest_key"`
+	ArraySize      int    `json:"array_size"`
+	TotalFilings   int    `json:"total_filings"`
+	ChunkSize      int    `json:"chunk_size"`
+}
+
+func Handler(ctx context.Context, input Input) (*Output, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET environment variable is required")
+	}
+
+	clients, err := awsclient.New(ctx, awsclient.FromEnv())
+	if err != nil {
+		return nil, err
+	}
+	if ref := input.FilingsRef; ref != nil {
+		if err := claimcheck.GetJSONL(ctx, storage.NewS3StorageFromClient(clients.S3(), ref.Bucket), ref, &input.Filings); err != nil {
+			return nil, err
+		}
+	}
+	return NewWriter(storage.NewS3StorageFromClient(clients.S3(), bucket), bucket, ChunkSize()).Write(ctx, input, time.Now())
+}
+
+// ChunkSize returns the number of filings per Batch array child, from
+// CHUNK_SIZE (default: 50)
+func ChunkSize() int {
+	if cs := os.Getenv("CHUNK_SIZE"); cs != "" {
+		if parsed, err := strconv.Atoi(cs); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 50
+}
+
+// Writer writes filing manifests for the Batch workers
+type Writer struct {
+	blobs     storage.Blobs
+	bucket    string // bucket of blobs, returned to the workers
+	chunkSize int
+}
+
+// NewWriter creates a Writer storing manifests in blobs, the store of bucket
+func NewWriter(blobs storage.Blobs, bucket string, chunkSize int) *Writer {
+	return &Writer{blobs: blobs, bucket: bucket, chunkSize: chunkSize}
+}
+
+// Write stores the manifest of a run started at now
+func (w *Writer) Write(ctx context.Context, input Input, now time.Time) (*Output, error) {
+	filings := input.Filings
+	totalFilings := len(filings)
+
+	if totalFilings == 0 {
+		return nil, fmt.Errorf("no filings to write")
+	}
+
+	log.Printf("Writing manifest for %d filings (chunk_size=%d)", totalFilings, w.chunkSize)
+
+	// Build JSONL content (one FilingPayload per line)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, f := range filings {
+		if err := encoder.Encode(f); err != nil {
+			return nil, fmt.Errorf("encoding filing %s: %w", f.SourceID, err)
+		}
+	}
+
+	// Generate manifest key with timestamp
+	manifestKey := fmt.Sprintf("manifests/%s.jsonl", now.UTC().Format("20060102T150405Z"))
+
+	// Upload to S3
+	if _, err := w.blobs.PutKey(ctx, manifestKey, bytes.NewReader(buf.Bytes()), "application/x-ndjson"); err != nil {
+		return nil, fmt.Errorf("uploading manifest to S3: %w", err)
+	}
+
+	arraySize := int(math.Ceil(float64(totalFilings) / float64(w.chunkSize)))
+
+	log.Printf("Manifest written: s3://%s/%s (%d filings, %d chunks)", w.bucket, manifestKey, totalFilings, arraySize)
+
+	return &Output{
+		ManifestBucket: w.bucket,
+		ManifestKey:    manifestKey,
+		ArraySize:      arraySize,
+		TotalFilings:   totalFilings,
+		ChunkSize:      w.chunkSize,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package manifest
+
+import (
+	"bufio"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+	m := NewWriter(blobs, "filings", 2)
+
+	input := Input{Filings: []FilingPayload{{SourceID: "1"}, {SourceID: "2"}, {SourceID: "3"}}}
+	out, err := m.Write(ctx, input, time.Date(2024, 3, 28, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ManifestBucket != "filings" || out.ManifestKey != "manifests/20240328T100000Z.jsonl" || out.ArraySize != 2 || out.TotalFilings != 3 {
+		t.Errorf("Write() = %+v", out)
+	}
+
+	r, err := blobs.OpenKey(ctx, out.ManifestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var lines int
+	for sc := bufio.NewScanner(r); sc.Scan(); {
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("manifest has %d lines, want 3", lines)
+	}
+
+	if _, err := m.Write(ctx, Input{}, time.Now()); err == nil {
+		t.Error("Write() of no filings succeeded")
+	}
+}
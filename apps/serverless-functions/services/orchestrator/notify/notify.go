@@ -0,0 +1,142 @@
+// Package notify sends the workflow summary notification (notify Lambda).
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/claimcheck"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/notifier"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+// NotifyInput is the workflow summary from Step Functions
+type NotifyInput struct {
+	// Scraper results
+	TotalAnnouncements int `json:"total_announcements"`
+	NewFilings         int `json:"new_filings"`
+	UpdatedFilings     int `json:"updated_filings"`
+	RevisedFilings     int `json:"revised_filings"`
+
+	// Download results
+	DownloadBatchesSent int `json:"download_batches_sent"`
+	DownloadsQueued     int `json:"downloads_queued"`
+
+	// Extraction results
+	ExtractionBatchesSent int `json:"extraction_batches_sent"`
+	ExtractionsQueued     int `json:"extractions_queued"`
+
+	// Final status
+	Status string `json:"status"` // SUCCESS, PARTIAL_FAILURE, FAILED
+	Error  string `json:"error,omitempty"`
+
+	// Set instead of the fields above for a summary stored in S3
+	PayloadRef *claimcheck.Ref `json:"payload_ref,omitempty"`
+}
+
+// NotifyOutput is the result of the notification
+type NotifyOutput struct {
+	MessageID string `json:"message_id"`
+	Sent      bool   `json:"sent"`
+}
+
+func Handler(ctx context.Context, input NotifyInput) (*NotifyOutput, error) {
+	log.Println("Sending workflow notification...")
+
+	input, err := Resolve(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	topicARN := os.Getenv("SNS_TOPIC_ARN")
+	if topicARN == "" {
+		log.Println("SNS_TOPIC_ARN not set, skipping notification")
+		return &NotifyOutput{Sent: false}, nil
+	}
+
+	clients, err := awsclient.New(ctx, awsclient.FromEnv())
+	if err != nil {
+		return nil, err
+	}
+	return Send(ctx, notifier.NewSNSNotifier(clients.SNS(), topicARN), input, time.Now())
+}
+
+// Resolve returns the summary stored in S3 if input is a claim check for
+// one, and input otherwise
+func Resolve(ctx context.Context, input NotifyInput) (NotifyInput, error) {
+	ref := input.PayloadRef
+	if ref == nil {
+		return input, nil
+	}
+	clients, err := awsclient.New(ctx, awsclient.FromEnv())
+	if err != nil {
+		return input, err
+	}
+	var stored NotifyInput
+	if err := claimcheck.GetJSON(ctx, storage.NewS3StorageFromClient(clients.S3(), ref.Bucket), ref, &stored); err != nil {
+		return input, err
+	}
+	return stored, nil
+}
+
+// Send sends the summary of a workflow run finished at now through n
+func Send(ctx context.Context, n notifier.Notifier, input NotifyInput, now time.Time) (*NotifyOutput, error) {
+	// Build notification message
+	status := input.Status
+	if status == "" {
+		status = "SUCCESS"
+	}
+
+	subject := fmt.Sprintf("HKEX Scraper Daily Run - %s", status)
+
+	message := fmt.Sprintf(`HKEX Scraper Daily Workflow Complete
+=====================================
+Date: %s
+Status: %s
+
+Scraper Results:
+  Total Announcements: %d
+  New Filings: %d
+  Updated Filings: %d
+  Revised Filings: %d
+
+Download Queue:
+  Batches Sent: %d
+  Filings Queued: %d
+
+Extraction Queue:
+  Batches Sent: %d
+  Filings Queued: %d
+`,
+		now.Format("2006-01-02 15:04:05 MST"),
+		status,
+		input.TotalAnnouncements,
+		input.NewFilings,
+		input.UpdatedFilings,
+		input.RevisedFilings,
+		input.DownloadBatchesSent,
+		input.DownloadsQueued,
+		input.ExtractionBatchesSent,
+		input.ExtractionsQueued,
+	)
+
+	if input.Error != "" {
+		message += fmt.Sprintf("\nError Details:\n%s\n", input.Error)
+	}
+
+	messageID, err := n.Notify(ctx, subject, message)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Notification sent: %s", messageID)
+
+	return &NotifyOutput{
+		MessageID: messageID,
+		Sent:      true,
+	}, nil
+}
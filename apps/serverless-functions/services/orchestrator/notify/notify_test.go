@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/notifier"
+)
+
+func TestSend(t *testing.T) {
+	n := notifier.NewMemoryNotifier()
+	out, err := Send(context.Background(), n, NotifyInput{
+		NewFilings:      12,
+		DownloadsQueued: 12,
+		Status:          "PARTIAL_FAILURE",
+		Error:           "2 downloads failed",
+	}, time.Date(2024, 3, 28, 18, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := n.Sent()
+	if !out.Sent || len(sent) != 1 || out.MessageID != sent[0].ID {
+		t.Fatalf("send() = %+v, sent %+v", out, sent)
+	}
+	if sent[0].Subject != "HKEX Scraper Daily Run - PARTIAL_FAILURE" {
+		t.Errorf("subject = %q", sent[0].Subject)
+	}
+	for _, want := range []string{"Date: 2024-03-28 18:00:00 UTC", "New Filings: 12", "Error Details:\n2 downloads failed"} {
+		if !strings.Contains(sent[0].Message, want) {
+			t.Errorf("message lacks %q:\n%s", want, sent[0].Message)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+// Package pipeline runs the ingestion workflow of the Step Functions state
+// machine (infra/modules/step-functions) in-process, so a production run can
+// be reproduced locally. Task states invoke the same handlers with the same
+// JSON payloads, Map states fan out with bounded concurrency and Batch array
+// jobs run one batch-worker per array index. Every task invocation is
+// recorded in a run log that Replay can run again.
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Task runs one workflow task, such as a Lambda handler, on a JSON payload
+type Task func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error)
+
+// Tasks are the handlers invoked by the workflow's task states
+type Tasks struct {
+	GenerateChunks Task // generate-chunks Lambda
+	Scrape         Task // scraper Lambda
+	DownloadFiling Task // sfn-downloader Lambda, once per Map item
+	WriteManifest  Task // write-manifest Lambda
+	BatchWorker    Task // one batch-worker array child; the payload is its container environment
+	CheckStatus    Task // check-status Lambda, run after downloads if set
+	Notify         Task // notify Lambda
+}
+
+// Options configure a run
+type Options struct {
+	Concurrency      int  // Map state MaxConcurrency (default: 5)
+	BatchConcurrency int  // Batch array children run at once (default: Concurrency)
+	BatchThreshold   int  // Filings above which downloads go through Batch (default: 1000)
+	FastRetries      bool // Retry failed tasks without waiting
+	Log              *RunLog
+}
+
+// TaskError is a failed state's error, as Step Functions reports it
+type TaskError struct {
+	Name  string `json:"Error"`
+	Cause string `json:"Cause"`
+}
+
+func (e *TaskError) Error() string {
+	return e.Name + ": " + e.Cause
+}
+
+// retrier is a task state's Retry policy
+type retrier struct {
+	Interval    time.Duration
+	MaxAttempts int // Retries after the first attempt
+	BackoffRate float64
+}
+
+var (
+	noRetry       = retrier{}
+	downloadRetry = retrier{Interval: 30 * time.Second, MaxAttempts: 2, BackoffRate: 2}
+	batchRetry    = retrier{Interval: 60 * time.Second, MaxAttempts: 3, BackoffRate: 2}
+)
+
+// route names the states of the daily path and of each backfill month
+type route struct {
+	Scrape          string
+	Download        string // Map state
+	DownloadItem    string // Map iteration task
+	DownloadByRef   string // Map state over the line spans of filings stored in S3
+	DownloadRefItem string
+	Manifest        string
+	Batch           string
+	JobName         string
+}
+
+var (
+	dailyRoute = route{
+		"Scrape", "DownloadFilings", "DownloadSingleFiling",
+		"DownloadFilingsByRef", "DownloadReferencedFiling",
+		"WriteManifest", "BatchDownload", "batch-download",
+	}
+	monthRoute = route{
+		"ScrapeMonth", "DownloadMonthFilings", "DownloadMonthFiling",
+		"DownloadMonthFilingsByRef", "DownloadReferencedMonthFiling",
+		"WriteMonthManifest", "BatchDownloadMonth", "backfill-download",
+	}
+)
+
+type runner struct {
+	tasks  Tasks
+	opts   Options
+	replay *replay // set when replaying a run log
+}
+
+func newRunner(tasks Tasks, opts Options) *runner {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 5
+	}
+	if opts.BatchConcurrency <= 0 {
+		opts.BatchConcurrency = opts.Concurrency
+	}
+	if opts.BatchThreshold <= 0 {
+		opts.BatchThreshold = 1000
+	}
+	return &runner{tasks: tasks, opts: opts}
+}
+
+// Run executes the workflow on an execution input and returns the output
+// of its final state
+func Run(ctx context.Context, tasks Tasks, input json.RawMessage, opts Options) (json.RawMessage, error) {
+	return newRunner(tasks, opts).execute(ctx, input)
+}
+
+func (r *runner) execute(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	state := map[string]interface{}{}
+	if err := json.Unmarshal(input, &state); err != nil {
+		return nil, fmt.Errorf("parsing execution input: %w", err)
+	}
+	r.record(Event{Type: ExecutionStarted, Input: input})
+
+	// IsBackfill: backfills provide start_date, daily runs omit it
+	var out interface{}
+	var err error
+	if _, ok := state["start_date"]; ok {
+		out, err = r.backfill(ctx, state)
+	} else {
+		out, err = r.daily(ctx, state)
+	}
+	if err != nil {
+		r.record(Event{Type: ExecutionFailed, Error: taskError(err)})
+		return nil, err
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("encoding execution output: %w", err)
+	}
+	r.record(Event{Type: ExecutionSucceeded, Output: data})
+	return data, nil
+}
+
+func (r *runner) daily(ctx context.Context, state map[string]interface{}) (interface{}, error) {
+	found, err := r.scrapeAndDownload(ctx, "", dailyRoute, state)
+	if err == nil && found && r.tasks.CheckStatus != nil {
+		state["statusResult"], err = r.invoke(ctx, "CheckStatus", "CheckStatus", r.tasks.CheckStatus,
+			map[string]interface{}{"check_downloads": true}, noRetry)
+	}
+	if err != nil {
+		if fatal(ctx, err) {
+			return nil, err
+		}
+		return r.notifyFailure(ctx, state, err)
+	}
+
+	scraper, _ := state["scraperResult"].(map[string]interface{})
+	payload := map[string]interface{}{
+		"status":              "SUCCESS",
+		"total_announcements": scraper["total_announcements"],
+		"new_filings":         scraper["new_filings"],
+		"updated_filings":     scraper["updated_filings"],
+		"revised_filings":     scraper["revised_filings"],
+	}
+	name := "NotifySuccess"
+	if !found {
+		name = "NotifyNoNewFilings"
+		payload["new_filings"] = 0
+	}
+	return r.invoke(ctx, name, name, r.tasks.Notify, payload, noRetry)
+}
+
+func (r *runner) notifyFailure(ctx context.Context, state map[string]interface{}, err error) (interface{}, error) {
+	te := taskError(err)
+	state["error"] = te
+	log.Printf("Workflow failed: %v", te)
+	return r.invoke(ctx, "NotifyFailure", "NotifyFailure", r.tasks.Notify,
+		map[string]interface{}{"status": "FAILED", "error": te.Cause}, noRetry)
+}
+
+func (r *runner) backfill(ctx context.Context, state map[string]interface{}) (interface{}, error) {
+	out, err := r.invoke(ctx, "GenerateChunks", "GenerateChunks", r.tasks.GenerateChunks, state, noRetry)
+	var chunks map[string]interface{}
+	if err == nil {
+		chunks, err = selectFields("GenerateChunks", out, "chunks")
+	}
+	if err != nil {
+		if fatal(ctx, err) {
+			return nil, err
+		}
+		return r.notifyFailure(ctx, state, err)
+	}
+	state["chunksResult"] = chunks
+
+	// Months run one at a time; each month catches its own errors
+	months, _ := chunks["chunks"].([]interface{})
+	r.enter("BackfillMonths", "BackfillMonths")
+	results, err := mapItems(ctx, len(months), 1, func(ctx context.Context, i int) (interface{}, error) {
+		return r.month(ctx, fmt.Sprintf("BackfillMonths[%d].", i), months[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	state["backfillResults"] = results
+
+	return r.invoke(ctx, "NotifyBackfillSuccess", "NotifyBackfillSuccess", r.tasks.Notify, map[string]interface{}{
+		"status":           "SUCCESS",
+		"message":          "Backfill completed",
+		"months_processed": results,
+	}, noRetry)
+}
+
+// month runs one BackfillMonths iteration and returns its summary
+func (r *runner) month(ctx context.Context, prefix string, item interface{}) (interface{}, error) {
+	chunk, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: chunk is not an object", prefix)
+	}
+	state := make(map[string]interface{}, len(chunk))
+	for k, v := range chunk {
+		state[k] = v
+	}
+
+	found, err := r.scrapeAndDownload(ctx, prefix, monthRoute, state)
+	switch {
+	case err != nil:
+		if fatal(ctx, err) {
+			return nil, err
+		}
+		te := taskError(err)
+		log.Printf("Month %v failed: %v", state["start_date"], te)
+		r.enter("MonthFailed", prefix+"MonthFailed")
+		return map[string]interface{}{"month": state["start_date"], "new_filings": 0, "status": "failed", "error": te}, nil
+	case !found:
+		r.enter("MonthNoFilings", prefix+"MonthNoFilings")
+		return map[string]interface{}{"month": state["start_date"], "new_filings": 0, "status": "skipped"}, nil
+	default:
+		scraper, _ := state["scraperResult"].(map[string]interface{})
+		r.enter("MonthDone", prefix+"MonthDone")
+		return map[string]interface{}{"month": state["start_date"], "new_filings": scraper["new_filings"], "status": "completed"}, nil
+	}
+}
+
+// scrapeAndDownload scrapes the state's date range and downloads the filings
+// found, through the downloader Map for small counts and a Batch array job
+// above the threshold. Filings the scraper stored in S3 are passed on by
+// reference. It reports whether there was anything to download.
+func (r *runner) scrapeAndDownload(ctx context.Context, prefix string, rt route, state map[string]interface{}) (bool, error) {
+	out, err := r.invoke(ctx, rt.Scrape, prefix+rt.Scrape, r.tasks.Scrape, state, noRetry)
+	if err != nil {
+		return false, err
+	}
+	scraper, err := selectFields(prefix+rt.Scrape, out,
+		"total_announcements", "new_filings", "updated_filings", "revised_filings", "download_filings", "filings", "filings_ref", "errors")
+	if err != nil {
+		return false, err
+	}
+	state["scraperResult"] = scraper
+
+	count, _ := scraper["download_filings"].(float64)
+	if count <= 0 {
+		return false, nil
+	}
+	filings, _ := scraper["filings"].([]interface{})
+
+	if count > float64(r.opts.BatchThreshold) {
+		out, err := r.invoke(ctx, rt.Manifest, prefix+rt.Manifest, r.tasks.WriteManifest,
+			map[string]interface{}{"filings": filings, "filings_ref": scraper["filings_ref"]}, noRetry)
+		if err != nil {
+			return true, err
+		}
+		manifest, err := selectFields(prefix+rt.Manifest, out,
+			"manifest_bucket", "manifest_key", "array_size", "total_filings", "chunk_size")
+		if err != nil {
+			return true, err
+		}
+		state["manifestResult"] = manifest
+
+		state["batchResult"], err = r.batchJob(ctx, rt, prefix+rt.Batch, manifest)
+		return true, err
+	}
+
+	items, download, item := filings, rt.Download, rt.DownloadItem
+	if ref, ok := scraper["filings_ref"].(map[string]interface{}); ok {
+		// As the Map's ItemSelector over the ref's lines: each item gets the
+		// claim check of its line
+		lines, _ := ref["lines"].([]interface{})
+		items = make([]interface{}, len(lines))
+		for i, l := range lines {
+			span, _ := l.(map[string]interface{})
+			items[i] = map[string]interface{}{"filing_ref": map[string]interface{}{
+				"bucket": ref["bucket"], "key": ref["key"], "offset": span["offset"], "length": span["length"],
+			}}
+		}
+		download, item = rt.DownloadByRef, rt.DownloadRefItem
+	}
+
+	r.enter(download, prefix+download)
+	results, err := mapItems(ctx, len(items), r.opts.Concurrency, func(ctx context.Context, i int) (interface{}, error) {
+		path := fmt.Sprintf("%s%s[%d]", prefix, download, i)
+		out, err := r.invoke(ctx, item, path, r.tasks.DownloadFiling, items[i], downloadRetry)
+		if err != nil {
+			return nil, err
+		}
+		return selectFields(path, out, "source_id", "success")
+	})
+	if err != nil {
+		return true, err
+	}
+	state["downloadResults"] = results
+	return true, nil
+}
+
+// batchJob emulates a batch:submitJob.sync task: it runs one batch-worker
+// per array index with the container environment the state machine sets,
+// and fails if any child fails. Like a retried submitJob, a retry runs the
+// whole array again.
+func (r *runner) batchJob(ctx context.Context, rt route, path string, manifest map[string]interface{}) (interface{}, error) {
+	size, _ := manifest["array_size"].(float64)
+	bucket, _ := manifest["manifest_bucket"].(string)
+	key, _ := manifest["manifest_key"].(string)
+
+	r.enter(rt.Batch, path)
+	return r.retry(ctx, path, batchRetry, func(attempt int) (interface{}, error) {
+		_, err := mapItems(ctx, int(size), r.opts.BatchConcurrency, func(ctx context.Context, i int) (interface{}, error) {
+			env, err := json.Marshal(map[string]string{
+				"MANIFEST_BUCKET":           bucket,
+				"MANIFEST_KEY":              key,
+				"AWS_BATCH_JOB_ARRAY_INDEX": strconv.Itoa(i),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return r.attempt(ctx, rt.Batch, fmt.Sprintf("%s[%d]", path, i), attempt, r.tasks.BatchWorker, env)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"JobName":         rt.JobName,
+			"Status":          "SUCCEEDED",
+			"ArrayProperties": map[string]interface{}{"Size": size},
+		}, nil
+	})
+}
+
+// invoke runs a task state, retrying it per policy, and returns its
+// decoded result
+func (r *runner) invoke(ctx context.Context, name, path string, task Task, payload interface{}, policy retrier) (interface{}, error) {
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s input: %w", path, err)
+	}
+
+	r.enter(name, path)
+	return r.retry(ctx, path, policy, func(attempt int) (interface{}, error) {
+		out, err := r.attempt(ctx, name, path, attempt, task, input)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if len(out) > 0 {
+			if err := json.Unmarshal(out, &v); err != nil {
+				return nil, &TaskError{Name: "States.Runtime", Cause: fmt.Sprintf("decoding %s output: %v", path, err)}
+			}
+		}
+		return v, nil
+	})
+}
+
+func (r *runner) retry(ctx context.Context, path string, policy retrier, fn func(attempt int) (interface{}, error)) (interface{}, error) {
+	delay := policy.Interval
+	for attempt := 1; ; attempt++ {
+		v, err := fn(attempt)
+		if err == nil || attempt > policy.MaxAttempts || fatal(ctx, err) {
+			return v, err
+		}
+		log.Printf("%s failed (attempt %d), retrying: %v", path, attempt, err)
+		if !r.opts.FastRetries && r.replay == nil {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		delay = time.Duration(float64(delay) * policy.BackoffRate)
+	}
+}
+
+// attempt runs one attempt of a task, or takes its result from the run log
+// being replayed
+func (r *runner) attempt(ctx context.Context, name, path string, n int, task Task, input json.RawMessage) (json.RawMessage, error) {
+	if r.replay != nil && !r.replay.live[name] {
+		recorded, err := r.replay.lookup(path, n, input)
+		if err != nil {
+			return nil, err
+		}
+		return recorded.result()
+	}
+	if task == nil {
+		return nil, fmt.Errorf("no task for state %s", name)
+	}
+
+	ev := Event{Type: TaskSucceeded, State: name, Path: path, Attempt: n, Input: input}
+	out, err := task(ctx, input)
+	if err != nil {
+		ev.Type = TaskFailed
+		ev.Error = &TaskError{Name: "States.TaskFailed", Cause: err.Error()}
+	} else {
+		ev.Output = out
+	}
+	r.record(ev)
+
+	if r.replay != nil {
+		// Live tasks are compared with the recording, which still drives
+		// the rest of the replay
+		recorded, err := r.replay.lookup(path, n, input)
+		if err != nil {
+			return nil, err
+		}
+		r.replay.compare(recorded, ev)
+		return recorded.result()
+	}
+	return ev.result()
+}
+
+func (r *runner) enter(name, path string) {
+	r.record(Event{Type: StateEntered, State: name, Path: path})
+}
+
+func (r *runner) record(ev Event) {
+	if r.opts.Log != nil {
+		r.opts.Log.Record(ev)
+	}
+}
+
+// mapItems runs fn for n items, at most limit at a time, and returns the
+// results in item order. Unlike a Map state, a failed item does not stop the
+// others, so a run log always records every item; the error is that of the
+// first failed item.
+func mapItems(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) (interface{}, error)) ([]interface{}, error) {
+	results := make([]interface{}, n)
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = fn(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// selectFields emulates a ResultSelector, failing like Step Functions when
+// the result lacks a selected field
+func selectFields(path string, v interface{}, keys ...string) (map[string]interface{}, error) {
+	obj, _ := v.(map[string]interface{})
+	selected := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		val, ok := obj[key]
+		if !ok {
+			return nil, &TaskError{Name: "States.Runtime", Cause: fmt.Sprintf("%s: result has no field %q", path, key)}
+		}
+		selected[key] = val
+	}
+	return selected, nil
+}
+
+// taskError converts err to the error object a Catch stores in the state
+func taskError(err error) *TaskError {
+	var te *TaskError
+	if errors.As(err, &te) {
+		return te
+	}
+	return &TaskError{Name: "States.Runtime", Cause: err.Error()}
+}
+
+// fatal reports whether err ends the run rather than being caught by a state
+func fatal(ctx context.Context, err error) bool {
+	var de *DivergenceError
+	return ctx.Err() != nil || errors.As(err, &de)
+}
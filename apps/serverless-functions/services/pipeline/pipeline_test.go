@@ -0,0 +1,279 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTasks records task payloads and returns canned results
+type fakeTasks struct {
+	mu    sync.Mutex
+	calls map[string][]string
+}
+
+func (f *fakeTasks) task(name string, fn func(payload map[string]interface{}, calls int) (interface{}, error)) Task {
+	return func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		f.mu.Lock()
+		if f.calls == nil {
+			f.calls = make(map[string][]string)
+		}
+		f.calls[name] = append(f.calls[name], string(payload))
+		calls := len(f.calls[name])
+		f.mu.Unlock()
+
+		var p map[string]interface{}
+		json.Unmarshal(payload, &p)
+		out, err := fn(p, calls)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(out)
+	}
+}
+
+func scraperOutput(n int) map[string]interface{} {
+	filings := make([]interface{}, n)
+	for i := range filings {
+		filings[i] = map[string]interface{}{"source_id": fmt.Sprint(i), "source_url": "https://example.com/" + fmt.Sprint(i)}
+	}
+	return map[string]interface{}{
+		"total_announcements": n + 1, "new_filings": n, "updated_filings": 1, "revised_filings": 0,
+		"download_filings": n, "filings": filings, "filings_ref": nil, "errors": 0, "revisions": nil,
+	}
+}
+
+func echo(payload map[string]interface{}, calls int) (interface{}, error) {
+	return payload, nil
+}
+
+func TestRun_Daily(t *testing.T) {
+	f := &fakeTasks{}
+	tasks := Tasks{
+		Scrape: f.task("scrape", func(map[string]interface{}, int) (interface{}, error) { return scraperOutput(3), nil }),
+		DownloadFiling: f.task("download", func(p map[string]interface{}, calls int) (interface{}, error) {
+			if calls == 2 {
+				return nil, errors.New("connection reset")
+			}
+			return map[string]interface{}{"source_id": p["source_id"], "success": true, "s3_key": "k"}, nil
+		}),
+		CheckStatus: f.task("status", func(map[string]interface{}, int) (interface{}, error) {
+			return map[string]interface{}{"pending_downloads": 0}, nil
+		}),
+		Notify: f.task("notify", echo),
+	}
+
+	var buf bytes.Buffer
+	out, err := Run(context.Background(), tasks, json.RawMessage(`{"exchange":"HKEX"}`),
+		Options{Concurrency: 1, FastRetries: true, Log: NewRunLog(&buf)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified map[string]interface{}
+	json.Unmarshal(out, &notified)
+	if notified["status"] != "SUCCESS" || notified["new_filings"] != 3.0 || notified["total_announcements"] != 4.0 {
+		t.Errorf("output = %s", out)
+	}
+	// Item 1 fails once and is retried
+	if n := len(f.calls["download"]); n != 4 {
+		t.Errorf("download called %d times, want 4", n)
+	}
+	if len(f.calls["status"]) != 1 {
+		t.Errorf("check-status called %d times, want 1", len(f.calls["status"]))
+	}
+	if !strings.Contains(f.calls["scrape"][0], `"exchange":"HKEX"`) {
+		t.Errorf("scrape payload = %s", f.calls["scrape"][0])
+	}
+
+	events, err := ReadLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed *Event
+	for i, ev := range events {
+		if ev.Type == TaskFailed {
+			failed = &events[i]
+		}
+	}
+	if failed == nil || failed.Path != "DownloadFilings[1]" || failed.Attempt != 1 || failed.State != "DownloadSingleFiling" {
+		t.Errorf("failed event = %+v", failed)
+	}
+
+	// A replay needs no tasks and reproduces the output
+	res, err := Replay(context.Background(), Tasks{}, events, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(res.Output, out) || len(res.Diffs) != 0 {
+		t.Errorf("replay = %s, %d diffs", res.Output, len(res.Diffs))
+	}
+
+	// Live tasks are compared with the recording
+	live := Tasks{DownloadFiling: f.task("live", func(p map[string]interface{}, calls int) (interface{}, error) {
+		return map[string]interface{}{"source_id": p["source_id"], "success": p["source_id"] != "2", "s3_key": "k"}, nil
+	})}
+	res, err = Replay(context.Background(), live, events, []string{"DownloadSingleFiling"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Item 1 succeeds on its first attempt now, and item 2 fails
+	if len(res.Diffs) != 2 || !jsonEqual(res.Output, out) {
+		t.Errorf("live replay = %s, diffs %+v", res.Output, res.Diffs)
+	}
+
+	// A task the run log has no result for diverges
+	var trimmed []Event
+	for _, ev := range events {
+		if ev.Path != "DownloadFilings[2]" {
+			trimmed = append(trimmed, ev)
+		}
+	}
+	_, err = Replay(context.Background(), Tasks{}, trimmed, nil, Options{})
+	var de *DivergenceError
+	if !errors.As(err, &de) || de.Path != "DownloadFilings[2]" {
+		t.Errorf("replay error = %v, want divergence at DownloadFilings[2]", err)
+	}
+}
+
+func TestRun_Batch(t *testing.T) {
+	f := &fakeTasks{}
+	tasks := Tasks{
+		Scrape: f.task("scrape", func(map[string]interface{}, int) (interface{}, error) { return scraperOutput(5), nil }),
+		WriteManifest: f.task("manifest", func(p map[string]interface{}, calls int) (interface{}, error) {
+			if n := len(p["filings"].([]interface{})); n != 5 {
+				t.Errorf("manifest got %d filings", n)
+			}
+			return map[string]interface{}{"manifest_bucket": "b", "manifest_key": "m.jsonl", "array_size": 3, "total_filings": 5, "chunk_size": 2}, nil
+		}),
+		BatchWorker: f.task("worker", func(p map[string]interface{}, calls int) (interface{}, error) {
+			if p["AWS_BATCH_JOB_ARRAY_INDEX"] == "1" && calls <= 3 {
+				return nil, errors.New("exit status 1")
+			}
+			return nil, nil
+		}),
+		Notify: f.task("notify", echo),
+	}
+
+	out, err := Run(context.Background(), tasks, nil, Options{BatchThreshold: 2, FastRetries: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"status":"SUCCESS"`) {
+		t.Errorf("output = %s", out)
+	}
+	// The failed array job is submitted again as a whole
+	if n := len(f.calls["worker"]); n != 6 {
+		t.Errorf("worker called %d times, want 6", n)
+	}
+	if !strings.Contains(f.calls["worker"][0], `"MANIFEST_KEY":"m.jsonl"`) {
+		t.Errorf("worker payload = %s", f.calls["worker"][0])
+	}
+}
+
+func TestRun_ClaimCheck(t *testing.T) {
+	f := &fakeTasks{}
+	ref := map[string]interface{}{"bucket": "b", "key": "claim-checks/filings/x.jsonl", "count": 3, "lines": []interface{}{
+		map[string]interface{}{"offset": 0, "length": 119},
+		map[string]interface{}{"offset": 120, "length": 115},
+		map[string]interface{}{"offset": 236, "length": 121},
+	}}
+	tasks := Tasks{
+		Scrape: f.task("scrape", func(map[string]interface{}, int) (interface{}, error) {
+			out := scraperOutput(3)
+			out["filings"], out["filings_ref"] = []interface{}{}, ref
+			return out, nil
+		}),
+		DownloadFiling: f.task("download", func(p map[string]interface{}, calls int) (interface{}, error) {
+			offset := p["filing_ref"].(map[string]interface{})["offset"]
+			return map[string]interface{}{"source_id": fmt.Sprint(offset), "success": true}, nil
+		}),
+		Notify: f.task("notify", echo),
+	}
+
+	var buf bytes.Buffer
+	if _, err := Run(context.Background(), tasks, nil, Options{Concurrency: 1, Log: NewRunLog(&buf)}); err != nil {
+		t.Fatal(err)
+	}
+	// Each downloader gets the claim check of its own line
+	if got := f.calls["download"]; len(got) != 3 || !jsonEqual(json.RawMessage(got[2]), json.RawMessage(`{"filing_ref":{"bucket":"b","key":"claim-checks/filings/x.jsonl","offset":236,"length":121}}`)) {
+		t.Errorf("download payloads = %v", got)
+	}
+	if !strings.Contains(buf.String(), `"path":"DownloadFilingsByRef[2]"`) {
+		t.Error("run log lacks DownloadFilingsByRef[2]")
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	f := &fakeTasks{}
+	tasks := Tasks{
+		Scrape: f.task("scrape", func(map[string]interface{}, int) (interface{}, error) { return nil, errors.New("HKEX unavailable") }),
+		Notify: f.task("notify", echo),
+	}
+
+	out, err := Run(context.Background(), tasks, json.RawMessage(`{}`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(out, json.RawMessage(`{"status":"FAILED","error":"HKEX unavailable"}`)) {
+		t.Errorf("output = %s", out)
+	}
+}
+
+func TestRun_Backfill(t *testing.T) {
+	f := &fakeTasks{}
+	tasks := Tasks{
+		GenerateChunks: f.task("chunks", func(map[string]interface{}, int) (interface{}, error) {
+			return map[string]interface{}{"chunks": []interface{}{
+				map[string]interface{}{"start_date": "2024-01-01", "end_date": "2024-01-31"},
+				map[string]interface{}{"start_date": "2024-02-01", "end_date": "2024-02-29"},
+				map[string]interface{}{"start_date": "2024-03-01", "end_date": "2024-03-31"},
+			}}, nil
+		}),
+		Scrape: f.task("scrape", func(p map[string]interface{}, calls int) (interface{}, error) {
+			switch p["start_date"] {
+			case "2024-01-01":
+				return nil, errors.New("timeout")
+			case "2024-02-01":
+				return scraperOutput(0), nil
+			}
+			return scraperOutput(2), nil
+		}),
+		DownloadFiling: f.task("download", func(p map[string]interface{}, calls int) (interface{}, error) {
+			return map[string]interface{}{"source_id": p["source_id"], "success": true}, nil
+		}),
+		Notify: f.task("notify", echo),
+	}
+
+	out, err := Run(context.Background(), tasks, json.RawMessage(`{"start_date":"2024-01-01","end_date":"2024-03-31"}`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified struct {
+		Message string `json:"message"`
+		Months  []struct {
+			Month  string     `json:"month"`
+			Status string     `json:"status"`
+			Error  *TaskError `json:"error"`
+		} `json:"months_processed"`
+	}
+	json.Unmarshal(out, &notified)
+	if notified.Message != "Backfill completed" || len(notified.Months) != 3 {
+		t.Fatalf("output = %s", out)
+	}
+	for i, want := range []string{"failed", "skipped", "completed"} {
+		if got := notified.Months[i].Status; got != want {
+			t.Errorf("month %d status = %q, want %q", i, got, want)
+		}
+	}
+	if e := notified.Months[0].Error; e == nil || e.Cause != "timeout" {
+		t.Errorf("month 0 error = %+v", e)
+	}
+	if len(f.calls["download"]) != 2 {
+		t.Errorf("download called %d times, want 2", len(f.calls["download"]))
+	}
+}
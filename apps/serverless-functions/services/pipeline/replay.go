@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// DivergenceError reports a replay reaching a task attempt the run log has
+// no matching result for
+type DivergenceError struct {
+	Path    string
+	Attempt int
+	Reason  string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("replay diverged at %s (attempt %d): %s", e.Path, e.Attempt, e.Reason)
+}
+
+// Diff is a live task result that differs from the recorded one
+type Diff struct {
+	Recorded Event
+	Live     Event
+}
+
+// ReplayResult is the outcome of a replay
+type ReplayResult struct {
+	Output json.RawMessage
+	Diffs  []Diff
+}
+
+type replay struct {
+	recorded map[string]Event // TaskSucceeded and TaskFailed events by path and attempt
+	live     map[string]bool
+
+	mu    sync.Mutex
+	diffs []Diff
+}
+
+// Replay runs the execution recorded in events again from its input. Task
+// results come from the recording, except for tasks of the states named in
+// live, which run with the current code and are compared with it; the
+// recorded results still drive the rest of the run. A task attempt the
+// recording has no result for fails the replay with a *DivergenceError.
+func Replay(ctx context.Context, tasks Tasks, events []Event, live []string, opts Options) (*ReplayResult, error) {
+	rp := &replay{recorded: make(map[string]Event), live: make(map[string]bool)}
+	var input json.RawMessage
+	started := false
+	for _, ev := range events {
+		switch ev.Type {
+		case ExecutionStarted:
+			input, started = ev.Input, true
+		case TaskSucceeded, TaskFailed:
+			rp.recorded[attemptKey(ev.Path, ev.Attempt)] = ev
+		}
+	}
+	if !started {
+		return nil, errors.New("run log has no ExecutionStarted event")
+	}
+	for _, name := range live {
+		rp.live[name] = true
+	}
+
+	r := newRunner(tasks, opts)
+	r.replay = rp
+	out, err := r.execute(ctx, input)
+	return &ReplayResult{Output: out, Diffs: rp.diffs}, err
+}
+
+// lookup returns the recorded result of a task attempt with the given input
+func (p *replay) lookup(path string, attempt int, input json.RawMessage) (Event, error) {
+	ev, ok := p.recorded[attemptKey(path, attempt)]
+	if !ok {
+		return ev, &DivergenceError{Path: path, Attempt: attempt, Reason: "not in the run log"}
+	}
+	if !jsonEqual(ev.Input, input) {
+		return ev, &DivergenceError{Path: path, Attempt: attempt, Reason: "input differs from the run log"}
+	}
+	return ev, nil
+}
+
+// compare records a Diff if a live attempt's result differs from the
+// recorded one
+func (p *replay) compare(recorded, live Event) {
+	same := recorded.Type == live.Type && jsonEqual(recorded.Output, live.Output)
+	if same && recorded.Error != nil && live.Error != nil {
+		same = *recorded.Error == *live.Error
+	}
+	if same {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.diffs = append(p.diffs, Diff{Recorded: recorded, Live: live})
+}
+
+func attemptKey(path string, attempt int) string {
+	return path + "#" + strconv.Itoa(attempt)
+}
+
+// jsonEqual reports whether two JSON documents have the same value,
+// ignoring formatting and key order
+func jsonEqual(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
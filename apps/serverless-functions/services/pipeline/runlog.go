@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Run log event types
+const (
+	ExecutionStarted   = "ExecutionStarted"
+	StateEntered       = "StateEntered"
+	TaskSucceeded      = "TaskSucceeded"
+	TaskFailed         = "TaskFailed"
+	ExecutionSucceeded = "ExecutionSucceeded"
+	ExecutionFailed    = "ExecutionFailed"
+)
+
+// Event is an entry of a run log. Path locates a state within Map
+// iterations and Batch array children, e.g. "DownloadFilings[3]" or
+// "BackfillMonths[2].ScrapeMonth".
+type Event struct {
+	Seq     int             `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Type    string          `json:"type"`
+	State   string          `json:"state,omitempty"`
+	Path    string          `json:"path,omitempty"`
+	Attempt int             `json:"attempt,omitempty"`
+	Input   json.RawMessage `json:"input,omitempty"`
+	Output  json.RawMessage `json:"output,omitempty"`
+	Error   *TaskError      `json:"error,omitempty"`
+}
+
+// result returns the task result recorded by a TaskSucceeded or TaskFailed
+// event
+func (ev Event) result() (json.RawMessage, error) {
+	if ev.Type == TaskFailed {
+		if ev.Error == nil {
+			return nil, &TaskError{Name: "States.TaskFailed"}
+		}
+		return nil, ev.Error
+	}
+	return ev.Output, nil
+}
+
+// RunLog writes events as JSON lines. It is safe for concurrent use.
+type RunLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+	err error
+}
+
+// NewRunLog creates a run log writing to w
+func NewRunLog(w io.Writer) *RunLog {
+	return &RunLog{w: w}
+}
+
+// Record numbers, timestamps and writes an event
+func (l *RunLog) Record(ev Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	ev.Seq = l.seq
+	ev.Time = time.Now().UTC()
+	if l.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		l.err = fmt.Errorf("encoding event %d: %w", ev.Seq, err)
+		return
+	}
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		l.err = fmt.Errorf("writing run log: %w", err)
+	}
+}
+
+// Err returns the first error recording an event
+func (l *RunLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// ReadLog reads the events of a run log
+func ReadLog(r io.Reader) ([]Event, error) {
+	dec := json.NewDecoder(r)
+	var events []Event
+	for {
+		var ev Event
+		err := dec.Decode(&ev)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading event %d: %w", len(events)+1, err)
+		}
+		events = append(events, ev)
+	}
+}
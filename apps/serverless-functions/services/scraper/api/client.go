@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,12 +10,13 @@ import (
 
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
 )
 
 // FetchByDateRange queries the HKEX Search API for announcements within a date range.
 // This replaces paginated fetching for date-scoped queries (daily runs and backfills).
 func (c *Client) FetchByDateRange(from, to time.Time, market string) ([]SearchResult, error) {
-	searchClient := NewSearchClient(c.config)
+	searchClient := NewSearchClient(c.config, c.limiter)
 	return searchClient.SearchByDateRange(from, to, market)
 }
 
@@ -22,22 +24,26 @@ func (c *Client) FetchByDateRange(from, to time.Time, market string) ([]SearchRe
 type Client struct {
 	httpClient *http.Client
 	config     *config.Config
-	lastReq    time.Time
+	limiter    *ratelimit.Limiter
 }
 
-// NewClient creates a new HKEX API client
-func NewClient(cfg *config.Config) *Client {
+// NewClient creates a new HKEX API client. limiter is shared with the other
+// clients of the same site (see ratelimit.ForHost).
+func NewClient(cfg *config.Config, limiter *ratelimit.Limiter) *Client {
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		config: cfg,
+		config:  cfg,
+		limiter: limiter,
 	}
 }
 
 // FetchAnnouncements retrieves announcements from a specific page
 func (c *Client) FetchAnnouncements(page int) (*models.AnnouncementResponse, error) {
-	c.rateLimit()
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
 
 	url := c.config.AnnouncementListURL(page)
 
@@ -69,7 +75,9 @@ func (c *Client) FetchAnnouncements(page int) (*models.AnnouncementResponse, err
 
 // DownloadDocument downloads a document and returns its content
 func (c *Client) DownloadDocument(announcement *models.Announcement) ([]byte, error) {
-	c.rateLimit()
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
 
 	url := announcement.DocumentURL()
 
@@ -92,19 +100,3 @@ func (c *Client) DownloadDocument(announcement *models.Announcement) ([]byte, er
 
 	return io.ReadAll(resp.Body)
 }
-
-// rateLimit ensures we don't exceed the configured rate limit
-func (c *Client) rateLimit() {
-	if c.config.RateLimit <= 0 {
-		return
-	}
-
-	minInterval := time.Second / time.Duration(c.config.RateLimit)
-	elapsed := time.Since(c.lastReq)
-
-	if elapsed < minInterval {
-		time.Sleep(minInterval - elapsed)
-	}
-
-	c.lastReq = time.Now()
-}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
 )
 
 // SearchClient handles requests to the HKEX Title Search API
@@ -17,7 +20,7 @@ import (
 type SearchClient struct {
 	httpClient *http.Client
 	config     *config.Config
-	lastReq    time.Time
+	limiter    *ratelimit.Limiter
 }
 
 // SearchResult represents a single result from the Search API
@@ -69,20 +72,24 @@ func DefaultSearchParams() SearchParams {
 	}
 }
 
-// NewSearchClient creates a new Search API client
-func NewSearchClient(cfg *config.Config) *SearchClient {
+// NewSearchClient creates a new Search API client. limiter is shared with the
+// other clients of the same site (see ratelimit.ForHost).
+func NewSearchClient(cfg *config.Config, limiter *ratelimit.Limiter) *SearchClient {
 	return &SearchClient{
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
-		config: cfg,
+		config:  cfg,
+		limiter: limiter,
 	}
 }
 
 // Search performs a single search query and returns results, total count, and
 // whether more rows are available (for pagination).
 func (c *SearchClient) Search(params SearchParams) ([]SearchResult, int, bool, error) {
-	c.rateLimit()
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, 0, false, err
+	}
 
 	// Build query URL
 	baseURL := c.config.BaseURL + "/search/titleSearchServlet.do"
@@ -236,23 +243,8 @@ func (r *SearchResult) DocumentURL() string {
 	return "https://www1.hkexnews.hk" + r.FileLink
 }
 
-// ParseDateTime parses the DateTime field into a time.Time
+// ParseDateTime parses the DateTime field, which is Hong Kong time, into a
+// UTC instant
 func (r *SearchResult) ParseDateTime() (time.Time, error) {
-	return time.Parse("02/01/2006 15:04", r.DateTime)
-}
-
-// rateLimit ensures we don't exceed the configured rate limit
-func (c *SearchClient) rateLimit() {
-	if c.config.RateLimit <= 0 {
-		return
-	}
-
-	minInterval := time.Second / time.Duration(c.config.RateLimit)
-	elapsed := time.Since(c.lastReq)
-
-	if elapsed < minInterval {
-		time.Sleep(minInterval - elapsed)
-	}
-
-	c.lastReq = time.Now()
+	return models.ParseHKEXTime(r.DateTime)
 }
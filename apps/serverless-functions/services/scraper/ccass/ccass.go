@@ -0,0 +1,335 @@
+// Package ccass fetches participant shareholdings from the HKEXnews CCASS
+// shareholding search (https://www3.hkexnews.hk/sdw/search/searchsdw.aspx).
+//
+// The search is an ASP.NET form: each query posts the page's hidden state
+// fields back with the stock code and shareholding date. The result page
+// carries fresh state, so after the first GET consecutive searches need one
+// request each. Holdings are only kept for the past twelve months.
+package ccass
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/htmltable"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const searchPath = "/sdw/search/searchsdw.aspx"
+
+// Column headers of the participant list
+const (
+	colParticipantID = "Participant ID"
+	colName          = "Name of CCASS Participant (* for Consenting Investor Participants )"
+	colAddress       = "Address"
+	colShareholding  = "Shareholding"
+	colPercent       = "% of the total number of Issued Shares/ Warrants/ Units"
+)
+
+// Snapshot is the participant list of a stock on one day
+type Snapshot struct {
+	StockCode    models.StockCode
+	HoldingDate  time.Time
+	IssuedShares int64 // last updated figure shown with the list
+	Holdings     []models.CCASSHolding
+}
+
+// Client calls the CCASS shareholding search
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	limiter    *ratelimit.Limiter
+
+	mu    sync.Mutex
+	state url.Values // hidden fields of the last page served
+}
+
+// NewClient creates a CCASS search client
+func NewClient(baseURL string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		limiter: limiter,
+	}
+}
+
+// Holdings returns the participant shareholdings of a stock at the end of
+// date. A stock not held in CCASS on that date returns a snapshot without
+// holdings.
+func (c *Client) Holdings(ctx context.Context, stockCode string, date time.Time) (*Snapshot, error) {
+	code := models.NormalizeStockCode("HKEX", stockCode)
+
+	c.mu.Lock()
+	state := c.state
+	c.mu.Unlock()
+
+	if state == nil {
+		doc, err := c.do(ctx, http.MethodGet, nil)
+		if err != nil {
+			return nil, fmt.Errorf("loading search form: %w", err)
+		}
+		state = hiddenFields(doc)
+	}
+
+	form := url.Values{}
+	for k, v := range state {
+		form[k] = v
+	}
+	form.Set("__EVENTTARGET", "btnSearch")
+	form.Set("__EVENTARGUMENT", "")
+	form.Set("today", time.Now().Format("20060102"))
+	form.Set("sortBy", "shareholding")
+	form.Set("sortDirection", "desc")
+	form.Set("alertMsg", "")
+	form.Set("txtShareholdingDate", date.Format("2006/01/02"))
+	form.Set("txtStockCode", code.String())
+	form.Set("txtStockName", "")
+	form.Set("txtParticipantID", "")
+	form.Set("txtParticipantName", "")
+	form.Set("txtSelPartID", "")
+
+	doc, err := c.do(ctx, http.MethodPost, form)
+	if err != nil {
+		// The state may have expired; load the form again next time
+		c.mu.Lock()
+		c.state = nil
+		c.mu.Unlock()
+		return nil, fmt.Errorf("searching %s on %s: %w", code, date.Format("2006-01-02"), err)
+	}
+
+	c.mu.Lock()
+	c.state = hiddenFields(doc)
+	c.mu.Unlock()
+
+	return parseSnapshot(doc, code, date)
+}
+
+// parseSnapshot reads the participant list of a result page
+func parseSnapshot(doc *html.Node, stockCode models.StockCode, date time.Time) (*Snapshot, error) {
+	if msg := alert(doc); msg != "" {
+		return nil, fmt.Errorf("search rejected: %s", msg)
+	}
+
+	// The page echoes the date searched; a mismatch means the form was
+	// not accepted
+	if shown := inputValue(doc, "txtShareholdingDate"); shown != "" && shown != date.Format("2006/01/02") {
+		return nil, fmt.Errorf("search returned holdings of %s, not %s", shown, date.Format("2006/01/02"))
+	}
+
+	s := &Snapshot{
+		StockCode:    stockCode,
+		HoldingDate:  date,
+		IssuedShares: parseShares(summaryTotal(doc)),
+	}
+
+	rows, ok := htmltable.Find(doc, colParticipantID, colName, colShareholding)
+	if !ok {
+		return s, nil // no holdings, e.g. before listing
+	}
+	for _, r := range rows {
+		if r.Cells == nil {
+			continue
+		}
+		h := models.CCASSHolding{
+			Exchange:        "HKEX",
+			StockCode:       stockCode,
+			HoldingDate:     date,
+			ParticipantID:   cellValue(r, colParticipantID),
+			ParticipantName: cellValue(r, colName),
+			Address:         cellValue(r, colAddress),
+			Shareholding:    parseShares(cellValue(r, colShareholding)),
+			Percent:         parsePercent(cellValue(r, colPercent)),
+		}
+		if h.ParticipantID == "" {
+			h.ParticipantID = h.ParticipantName
+		}
+		s.Holdings = append(s.Holdings, h)
+	}
+	return s, nil
+}
+
+// Changes compares two snapshots of a stock and returns the change of each
+// participant whose shareholding moved, largest moves first. Participants
+// missing from one of the snapshots are treated as holding nothing.
+func Changes(prev, cur *Snapshot) []models.CCASSChange {
+	before := make(map[string]models.CCASSHolding, len(prev.Holdings))
+	for _, h := range prev.Holdings {
+		before[h.ParticipantID] = h
+	}
+
+	var changes []models.CCASSChange
+	add := func(b, a models.CCASSHolding, id, name string) {
+		if a.Shareholding == b.Shareholding {
+			return
+		}
+		changes = append(changes, models.CCASSChange{
+			Exchange:        "HKEX",
+			StockCode:       cur.StockCode,
+			HoldingDate:     cur.HoldingDate,
+			PreviousDate:    prev.HoldingDate,
+			ParticipantID:   id,
+			ParticipantName: name,
+			SharesBefore:    b.Shareholding,
+			SharesAfter:     a.Shareholding,
+			Change:          a.Shareholding - b.Shareholding,
+			PercentBefore:   b.Percent,
+			PercentAfter:    a.Percent,
+		})
+	}
+
+	for _, a := range cur.Holdings {
+		b := before[a.ParticipantID]
+		delete(before, a.ParticipantID)
+		add(b, a, a.ParticipantID, a.ParticipantName)
+	}
+	for id, b := range before {
+		add(b, models.CCASSHolding{}, id, b.ParticipantName)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		ai, aj := abs(changes[i].Change), abs(changes[j].Change)
+		if ai != aj {
+			return ai > aj
+		}
+		return changes[i].ParticipantID < changes[j].ParticipantID
+	})
+	return changes
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// cellValue returns the value of a column. Cells repeat their header for
+// the mobile layout, so only the value element is read when present.
+func cellValue(r htmltable.Row, header string) string {
+	n, ok := r.Cells[header]
+	if !ok {
+		return ""
+	}
+	if v := findClass(n, "mobile-list-body"); v != nil {
+		return htmltable.Text(v)
+	}
+	return htmltable.Text(n)
+}
+
+// summaryTotal returns the issued shares figure of the summary section
+func summaryTotal(doc *html.Node) string {
+	total := findClass(doc, "ccass-search-total")
+	if total == nil {
+		return ""
+	}
+	if v := findClass(total, "value"); v != nil {
+		return htmltable.Text(v)
+	}
+	return ""
+}
+
+// alert returns the message the page raises for a rejected search, such as
+// a date outside the past twelve months
+func alert(doc *html.Node) string {
+	return inputValue(doc, "alertMsg")
+}
+
+// hiddenFields collects the page's hidden inputs, which must be posted back
+func hiddenFields(doc *html.Node) url.Values {
+	fields := url.Values{}
+	for _, in := range htmltable.FindAll(doc, atom.Input) {
+		if strings.EqualFold(htmltable.Attr(in, "type"), "hidden") && htmltable.Attr(in, "name") != "" {
+			fields.Set(htmltable.Attr(in, "name"), htmltable.Attr(in, "value"))
+		}
+	}
+	return fields
+}
+
+// inputValue returns the value of the input with the given name
+func inputValue(doc *html.Node, name string) string {
+	for _, in := range htmltable.FindAll(doc, atom.Input) {
+		if htmltable.Attr(in, "name") == name {
+			return strings.TrimSpace(htmltable.Attr(in, "value"))
+		}
+	}
+	return ""
+}
+
+// findClass returns the first element under n with the given class
+func findClass(n *html.Node, class string) *html.Node {
+	if n.Type == html.ElementNode {
+		for _, c := range strings.Fields(htmltable.Attr(n, "class")) {
+			if c == class {
+				return n
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findClass(c, class); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// parseShares parses a share count such as "1,234,567"
+func parseShares(s string) int64 {
+	v, _ := strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+	return v
+}
+
+// parsePercent parses a percentage such as "6.32%"
+func parsePercent(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return v
+}
+
+// do performs a rate-limited request to the search page and parses it. A
+// nil form is sent as a GET.
+func (c *Client) do(ctx context.Context, method string, form url.Values) (*html.Node, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+searchPath, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting search page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from search page: %d", resp.StatusCode)
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing search page: %w", err)
+	}
+	return doc, nil
+}
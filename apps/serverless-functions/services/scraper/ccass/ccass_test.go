@@ -0,0 +1,164 @@
+package ccass
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the captured pages in testdata: the empty search
+// form on GET, and on POST the holdings of the code and date searched.
+// Searches must post back the state of the last page served.
+func newTestServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+
+	requests := 0
+	lastState := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		name := "search_form.html"
+		if r.Method == http.MethodPost {
+			r.ParseForm()
+			if r.PostForm.Get("__VIEWSTATE") != lastState || r.PostForm.Get("__EVENTTARGET") != "btnSearch" {
+				http.Error(w, "invalid postback", http.StatusInternalServerError)
+				return
+			}
+			date := strings.ReplaceAll(r.PostForm.Get("txtShareholdingDate"), "/", "")
+			name = "holdings_" + r.PostForm.Get("txtStockCode") + "_" + date + ".html"
+			switch {
+			case date < "20230401":
+				name = "alert.html"
+			case r.PostForm.Get("txtStockCode") == "09999":
+				name = "nodata.html"
+			}
+		}
+
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if i := strings.Index(string(data), `name="__VIEWSTATE" id="__VIEWSTATE" value="`); i >= 0 {
+			rest := string(data)[i+len(`name="__VIEWSTATE" id="__VIEWSTATE" value="`):]
+			lastState = rest[:strings.Index(rest, `"`)]
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestHoldings(t *testing.T) {
+	srv, requests := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	date := time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)
+	s, err := c.Holdings(context.Background(), "5", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.StockCode != "00005" || s.IssuedShares != 18666228411 {
+		t.Errorf("unexpected snapshot: %s, %d issued shares", s.StockCode, s.IssuedShares)
+	}
+	if len(s.Holdings) != 5 {
+		t.Fatalf("got %d holdings, want 5", len(s.Holdings))
+	}
+
+	h := s.Holdings[1]
+	if h.ParticipantID != "C00019" || h.ParticipantName != "THE HONGKONG AND SHANGHAI BANKING" || h.Shareholding != 1706608912 || h.Percent != 9.14 {
+		t.Errorf("unexpected holding: %+v", h)
+	}
+	if !strings.HasPrefix(h.Address, "HSBC WEALTH BUSINESS SERVICES 8/F TOWER 2 & 3") {
+		t.Errorf("Address = %q", h.Address)
+	}
+	if !h.HoldingDate.Equal(date) {
+		t.Errorf("HoldingDate = %v", h.HoldingDate)
+	}
+
+	// Consenting investor participants are listed without an ID
+	if investor := s.Holdings[4]; investor.ParticipantID != "WONG TAI MAN*" || investor.Shareholding != 12000 {
+		t.Errorf("unexpected investor holding: %+v", investor)
+	}
+
+	// The next search posts back the result page's state without loading
+	// the form again
+	if _, err := c.Holdings(context.Background(), "00005", date.AddDate(0, 0, -1)); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 3 {
+		t.Errorf("got %d requests, want 3", *requests)
+	}
+}
+
+func TestHoldings_NoData(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	s, err := c.Holdings(context.Background(), "09999", time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Holdings) != 0 {
+		t.Errorf("got %d holdings, want 0", len(s.Holdings))
+	}
+}
+
+func TestHoldings_Rejected(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	_, err := c.Holdings(context.Background(), "00005", time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC))
+	if err == nil || !strings.Contains(err.Error(), "past 12 months") {
+		t.Errorf("expected rejected search, got %v", err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	prev, err := c.Holdings(context.Background(), "00005", time.Date(2024, 3, 27, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := c.Holdings(context.Background(), "00005", time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Changes(prev, cur)
+
+	// Citibank is unchanged; UBS left the list and two participants joined
+	want := []struct {
+		id     string
+		change int64
+	}{
+		{"B01161", -15300000},
+		{"A00003", 3600000},
+		{"C00019", -3600000},
+		{"C00100", 2400000},
+		{"WONG TAI MAN*", 12000},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].ParticipantID != w.id || changes[i].Change != w.change {
+			t.Errorf("change %d = %s %d, want %s %d", i, changes[i].ParticipantID, changes[i].Change, w.id, w.change)
+		}
+	}
+
+	ubs := changes[0]
+	if ubs.SharesBefore != 15300000 || ubs.SharesAfter != 0 || ubs.PercentBefore != 0.08 || ubs.ParticipantName != "UBS SECURITIES HONG KONG LTD" {
+		t.Errorf("unexpected exit: %+v", ubs)
+	}
+	if !ubs.PreviousDate.Equal(prev.HoldingDate) || !ubs.HoldingDate.Equal(cur.HoldingDate) {
+		t.Errorf("dates = %v -> %v", ubs.PreviousDate, ubs.HoldingDate)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
 	"github.com/nicholaszhao/hkex-scraper/services/scraper"
 	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
 )
@@ -29,7 +31,7 @@ func main() {
 	}()
 
 	cfg := config.Load()
-	client := api.NewClient(cfg)
+	client := api.NewClient(cfg, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit))
 
 	// Initialize database
 	var db *database.DB
@@ -43,8 +45,14 @@ func main() {
 	defer db.Close()
 	log.Println("Database initialized successfully")
 
+	// Classify instruments from the latest securities list
+	var classifier *securities.Classifier
+	if cfg.ClassifyFromList {
+		classifier = securities.LoadClassifierWithFallback(ctx, cfg.SecuritiesListURL)
+	}
+
 	// Run the scraper (metadata only, no downloads)
-	s := scraper.New(cfg, client, nil, db)
+	s := scraper.New(cfg, client, nil, db, classifier)
 	result, err := s.Run(ctx)
 	if err != nil {
 		log.Fatalf("Scraper error: %v", err)
@@ -56,6 +64,8 @@ func main() {
 	fmt.Printf("Total announcements: %d\n", result.TotalAnnouncements)
 	fmt.Printf("New filings:         %d\n", result.NewFilings)
 	fmt.Printf("Updated filings:     %d\n", result.UpdatedFilings)
+	fmt.Printf("Revised filings:     %d\n", result.RevisedFilings)
+	fmt.Printf("Translations paired: %d\n", result.TranslationsPaired)
 	fmt.Printf("Errors:              %d\n", result.Errors)
 
 	// Show database stats
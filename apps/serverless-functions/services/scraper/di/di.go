@@ -0,0 +1,303 @@
+// Package di fetches notices from HKEX's Disclosure of Interests service
+// (https://di.hkex.com.hk), where substantial shareholders, directors and
+// chief executives report dealings in a listed company's shares.
+//
+// Notices are searched per issuer and date range: the stock code search
+// finds the issuer, whose notice list gives the filer, reason, event date
+// and resulting holding of each notice. The holding before the event and the
+// filer's capacity are only on the notice's form, fetched with FillForm.
+package di
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/htmltable"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Column headers and labels of the search results, notice lists and forms
+const (
+	colSerial     = "Form Serial Number"
+	colFiler      = "Name of substantial shareholder / director / chief executive"
+	colReason     = "Reason for disclosure"
+	colInvolved   = "Number of shares bought / sold / involved"
+	colPrice      = "Average price per share"
+	colShares     = "Number of shares interested (See *Notes above)"
+	colPercent    = "% of issued voting shares (See *Notes above)"
+	colEventDate  = "Date of relevant event (dd/mm/yyyy)"
+	colStockCode  = "Stock Code"
+	colCorpName   = "Name of listed corporation"
+	colCapCode    = "Code describing capacity"
+	colCapacity   = "Capacity"
+	colPosition   = "Position"
+	colBefore     = "Number of shares immediately before the relevant event"
+	colBeforePct  = "% immediately before the relevant event"
+	colAfter      = "Number of shares immediately after the relevant event"
+	colAfterPct   = "% immediately after the relevant event"
+	longPosition  = "Long position"
+	nextPageLabel = "Next Page"
+)
+
+// formType extracts the form type from a form link, e.g. NSForm3A.aspx
+var formType = regexp.MustCompile(`(?i)NSForm(\w+)\.aspx`)
+
+// Client calls the Disclosure of Interests service
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	limiter    *ratelimit.Limiter
+}
+
+// NewClient creates a Disclosure of Interests client
+func NewClient(baseURL string, limiter *ratelimit.Limiter) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		limiter: limiter,
+	}
+}
+
+// Notices returns the notices filed for an issuer with relevant events
+// between from and to. The notices have no company ID, capacity or holding
+// before the event; see FillForm.
+func (c *Client) Notices(ctx context.Context, stockCode string, from, to time.Time) ([]models.InterestNotice, error) {
+	code := models.NormalizeStockCode("HKEX", stockCode)
+
+	q := url.Values{}
+	q.Set("sa1", "cl")
+	q.Set("scsd", from.Format("02/01/2006"))
+	q.Set("sced", to.Format("02/01/2006"))
+	q.Set("sc", code.String())
+	q.Set("src", "MAIN")
+	q.Set("lang", "EN")
+	searchURL := c.baseURL + "/di/NSSrchCorpList.aspx?" + q.Encode()
+
+	doc, err := c.get(ctx, searchURL)
+	if err != nil {
+		return nil, fmt.Errorf("searching %s: %w", code, err)
+	}
+
+	rows, ok := htmltable.Find(doc, colStockCode, colCorpName)
+	if !ok {
+		return nil, nil // no notices in the range
+	}
+
+	var notices []models.InterestNotice
+	for _, r := range rows {
+		if r.Cells == nil || models.NormalizeStockCode("HKEX", r.Text(colStockCode)) != code {
+			continue
+		}
+		listURL := link(r.Node, "NSAllFormList.aspx")
+		if listURL == "" {
+			continue
+		}
+
+		// The search can return several corporations for a code, e.g. the
+		// company and a former holder of the code
+		list, err := c.listNotices(ctx, resolve(searchURL, listURL), code)
+		if err != nil {
+			return nil, fmt.Errorf("listing notices of %s: %w", code, err)
+		}
+		notices = append(notices, list...)
+	}
+	return notices, nil
+}
+
+// listNotices reads every page of a corporation's notice list
+func (c *Client) listNotices(ctx context.Context, pageURL string, stockCode models.StockCode) ([]models.InterestNotice, error) {
+	var notices []models.InterestNotice
+
+	for pageURL != "" {
+		doc, err := c.get(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, ok := htmltable.Find(doc, colSerial, colFiler, colEventDate)
+		if !ok {
+			return nil, fmt.Errorf("notice table not found")
+		}
+		for _, r := range rows {
+			if r.Cells == nil {
+				continue
+			}
+			n, err := parseNotice(r, pageURL, stockCode)
+			if err != nil {
+				return nil, err
+			}
+			notices = append(notices, n)
+		}
+
+		next := ""
+		for _, a := range htmltable.FindAll(doc, atom.A) {
+			if htmltable.Text(a) == nextPageLabel {
+				next = resolve(pageURL, htmltable.Attr(a, "href"))
+			}
+		}
+		pageURL = next
+	}
+
+	return notices, nil
+}
+
+// parseNotice reads a row of the notice list
+func parseNotice(r htmltable.Row, pageURL string, stockCode models.StockCode) (models.InterestNotice, error) {
+	serial := r.Text(colSerial)
+	eventDate, err := time.Parse("02/01/2006", r.Text(colEventDate))
+	if err != nil {
+		return models.InterestNotice{}, fmt.Errorf("notice %s: invalid event date %q", serial, r.Text(colEventDate))
+	}
+
+	n := models.InterestNotice{
+		SerialNumber:   serial,
+		Exchange:       "HKEX",
+		StockCode:      stockCode,
+		FilerName:      r.Text(colFiler),
+		ReasonCode:     long(r.Text(colReason)),
+		SharesInvolved: parseShares(long(r.Text(colInvolved))),
+		AveragePrice:   r.Text(colPrice),
+		SharesAfter:    parseShares(long(r.Text(colShares))),
+		PercentAfter:   parsePercent(long(r.Text(colPercent))),
+		EventDate:      eventDate,
+	}
+
+	if form := link(r.Cells[colSerial], "NSForm"); form != "" {
+		n.SourceURL = resolve(pageURL, form)
+		if m := formType.FindStringSubmatch(form); m != nil {
+			n.FormType = strings.ToUpper(m[1])
+		}
+	}
+	return n, nil
+}
+
+// FillForm fetches a notice's form and fills in the filer's capacity and
+// the holding before the event. Forms state the holding after the event
+// too; it replaces the list's figures, which are rounded.
+func (c *Client) FillForm(ctx context.Context, n *models.InterestNotice) error {
+	if n.SourceURL == "" {
+		return fmt.Errorf("notice %s has no form link", n.SerialNumber)
+	}
+
+	doc, err := c.get(ctx, n.SourceURL)
+	if err != nil {
+		return fmt.Errorf("fetching form %s: %w", n.SerialNumber, err)
+	}
+
+	holdings, ok := htmltable.Find(doc, colPosition, colBefore, colAfter)
+	if !ok {
+		return fmt.Errorf("form %s: holding table not found", n.SerialNumber)
+	}
+	for _, r := range holdings {
+		if r.Text(colPosition) != longPosition {
+			continue
+		}
+		n.SharesBefore = parseShares(r.Text(colBefore))
+		n.PercentBefore = parsePercent(r.Text(colBeforePct))
+		n.SharesAfter = parseShares(r.Text(colAfter))
+		n.PercentAfter = parsePercent(r.Text(colAfterPct))
+	}
+
+	var capacities []string
+	if rows, ok := htmltable.Find(doc, colCapCode, colCapacity); ok {
+		for _, r := range rows {
+			if r.Cells == nil || (r.Text(colPosition) != "" && r.Text(colPosition) != longPosition) {
+				continue
+			}
+			capacities = append(capacities, strings.TrimSpace(r.Text(colCapCode)+" "+r.Text(colCapacity)))
+		}
+	}
+	n.Capacity = strings.Join(capacities, "; ")
+
+	return nil
+}
+
+// long returns the long position of a figure listing several positions,
+// e.g. "1,234,567(L) 100(S)" -> "1,234,567". Figures without a position
+// are returned as is.
+func long(s string) string {
+	for _, part := range strings.Fields(s) {
+		if v, ok := strings.CutSuffix(part, "(L)"); ok {
+			return v
+		}
+	}
+	if strings.Contains(s, "(") {
+		return "" // only short or lending pool positions
+	}
+	return s
+}
+
+// parseShares parses a share count such as "1,234,567"
+func parseShares(s string) int64 {
+	v, _ := strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+	return v
+}
+
+// parsePercent parses a percentage such as "6.32" or "6.32%"
+func parsePercent(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return v
+}
+
+// link returns the href of the first link under n containing substr
+func link(n *html.Node, substr string) string {
+	for _, a := range htmltable.FindAll(n, atom.A) {
+		if href := htmltable.Attr(a, "href"); strings.Contains(href, substr) {
+			return href
+		}
+	}
+	return ""
+}
+
+// resolve resolves a link against the page it was found on
+func resolve(pageURL, href string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// get performs a rate-limited GET and parses the page
+func (c *Client) get(ctx context.Context, pageURL string) (*html.Node, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %d", req.URL.Path, resp.StatusCode)
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", req.URL.Path, err)
+	}
+	return doc, nil
+}
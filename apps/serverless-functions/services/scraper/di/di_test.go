@@ -0,0 +1,140 @@
+package di
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the captured pages in testdata: the search for 00005,
+// HSBC's notice list over two pages and the form of one notice
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		name := ""
+		switch {
+		case r.URL.Path == "/di/NSSrchCorpList.aspx" && q.Get("sc") == "00005":
+			name = "search.html"
+		case r.URL.Path == "/di/NSAllFormList.aspx" && q.Get("sid") == "2233":
+			name = "notices_1.html"
+			if q.Get("pg") == "2" {
+				name = "notices_2.html"
+			}
+		case strings.HasPrefix(r.URL.Path, "/di/NSForm"):
+			name = "form_" + q.Get("fn") + ".html"
+		}
+
+		data, err := os.ReadFile("testdata/" + name)
+		if name == "" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNotices(t *testing.T) {
+	srv := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	notices, err := c.Notices(context.Background(), "5", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 notices on the first page and 1 on the second; the other
+	// corporation in the search results is not listed
+	if len(notices) != 4 {
+		t.Fatalf("got %d notices, want 4", len(notices))
+	}
+
+	n := notices[0]
+	if n.SerialNumber != "CS20240315E00265" || n.StockCode != "00005" || n.FormType != "2" || n.FilerName != "BlackRock, Inc." {
+		t.Errorf("unexpected notice: %+v", n)
+	}
+	if n.ReasonCode != "1201" || n.SharesInvolved != 3210000 || n.AveragePrice != "HKD 64.8000" {
+		t.Errorf("unexpected dealing: reason %q, involved %d, price %q", n.ReasonCode, n.SharesInvolved, n.AveragePrice)
+	}
+	if n.SharesAfter != 1401234567 || n.PercentAfter != 7.02 {
+		t.Errorf("long position = %d (%v%%), want 1401234567 (7.02%%)", n.SharesAfter, n.PercentAfter)
+	}
+	if !n.EventDate.Equal(time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("EventDate = %v", n.EventDate)
+	}
+	if !strings.HasPrefix(n.SourceURL, srv.URL+"/di/NSForm2.aspx?fn=CS20240315E00265") {
+		t.Errorf("SourceURL = %q", n.SourceURL)
+	}
+
+	if director := notices[2]; director.FormType != "3A" {
+		t.Errorf("FormType = %q, want 3A", director.FormType)
+	}
+
+	// Long position among short and lending pool positions
+	jpm := notices[3]
+	if jpm.FilerName != "JPMorgan Chase & Co." || jpm.ReasonCode != "1101" || jpm.SharesAfter != 1020304050 || jpm.PercentAfter != 5.11 {
+		t.Errorf("unexpected notice: %+v", jpm)
+	}
+}
+
+func TestNotices_SearchError(t *testing.T) {
+	c := NewClient(newTestServer(t).URL, nil)
+
+	_, err := c.Notices(context.Background(), "00700", time.Now(), time.Now())
+	if err == nil {
+		t.Error("expected error for a failed search")
+	}
+}
+
+func TestFillForm(t *testing.T) {
+	srv := newTestServer(t)
+	c := NewClient(srv.URL, nil)
+
+	notices, err := c.Notices(context.Background(), "00005", time.Now(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := notices[0]
+	if err := c.FillForm(context.Background(), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.SharesBefore != 1404444567 || n.PercentBefore != 7.0362 {
+		t.Errorf("before = %d (%v%%), want 1404444567 (7.0362%%)", n.SharesBefore, n.PercentBefore)
+	}
+	if n.SharesAfter != 1401234567 || n.PercentAfter != 7.0201 {
+		t.Errorf("after = %d (%v%%), want 1401234567 (7.0201%%)", n.SharesAfter, n.PercentAfter)
+	}
+	if want := "2201 Interest of corporation controlled by you; 2102 Investment manager"; n.Capacity != want {
+		t.Errorf("Capacity = %q, want %q", n.Capacity, want)
+	}
+
+	// Forms not captured are served as 404s
+	if err := c.FillForm(context.Background(), &notices[1]); err == nil {
+		t.Error("expected error for a missing form")
+	}
+}
+
+func TestLong(t *testing.T) {
+	tests := map[string]string{
+		"1,234,567(L) 100(S)": "1,234,567",
+		"1101(L) 1201(S)":     "1101",
+		"40,000,000(S)":       "",
+		"HKD 64.8000":         "HKD 64.8000",
+	}
+	for in, want := range tests {
+		if got := long(in); got != want {
+			t.Errorf("long(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
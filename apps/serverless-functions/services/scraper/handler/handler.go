@@ -0,0 +1,282 @@
+// Package handler scrapes announcements and stores new filings. It is the
+// scraper Lambda's handler, also run by the local pipeline runner.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/claimcheck"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/identity"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/translation"
+)
+
+// ScraperInput is the input for the scraper Lambda.
+// For daily scheduled runs, omit dates to query the last 24 hours.
+// For historical backfills, provide start_date and end_date in YYYY-MM-DD format.
+type ScraperInput struct {
+	Exchange  string `json:"exchange,omitempty"`   // Registered exchange adapter (default: HKEX)
+	StartDate string `json:"start_date,omitempty"` // YYYY-MM-DD (default: 24 hours ago)
+	EndDate   string `json:"end_date,omitempty"`   // YYYY-MM-DD (default: now)
+	Market    string `json:"market,omitempty"`     // Exchange-specific market, e.g. SEHK or GEM for HKEX
+}
+
+// FilingPayload is the metadata passed to the downloader via Step Functions Map state.
+// Contains everything the downloader needs to download a filing without querying the DB.
+type FilingPayload struct {
+	SourceID      string `json:"source_id"`
+	SourceURL     string `json:"source_url"`
+	CompanyID     string `json:"company_id"`
+	FileExtension string `json:"file_extension"`
+	Exchange      string `json:"exchange"`
+	ReportDate    string `json:"report_date"` // RFC3339
+}
+
+// ScraperOutput is the output for Step Functions
+type ScraperOutput struct {
+	TotalAnnouncements int                     `json:"total_announcements"`
+	NewFilings         int                     `json:"new_filings"`
+	UpdatedFilings     int                     `json:"updated_filings"`
+	RevisedFilings     int                     `json:"revised_filings"`  // Updated filings with changed fields
+	DownloadFilings    int                     `json:"download_filings"` // len(Filings)
+	Errors             int                     `json:"errors"`
+	Filings            []FilingPayload         `json:"filings"`     // New and re-linked filings for downstream Map state
+	FilingsRef         *claimcheck.Ref         `json:"filings_ref"` // Filings stored in S3 instead, when too large; null otherwise
+	Revisions          []models.FilingRevision `json:"revisions"`
+	RevisionsRef       *claimcheck.Ref         `json:"revisions_ref"` // Revisions stored in S3 instead, when too large; null otherwise
+}
+
+// Handler is the Lambda handler function
+func Handler(ctx context.Context, input ScraperInput) (*ScraperOutput, error) {
+	exchangeName := input.Exchange
+	if exchangeName == "" {
+		exchangeName = "HKEX"
+	}
+
+	log.Printf("Starting %s Scraper Lambda...", exchangeName)
+
+	// Load config
+	cfg := config.Load()
+
+	ex, err := exchange.Open(exchangeName, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine date range in Hong Kong time
+	hkt := models.HongKong
+	now := time.Now().In(hkt)
+
+	var startDate, endDate time.Time
+
+	if input.StartDate != "" {
+		var err error
+		startDate, err = time.ParseInLocation("2006-01-02", input.StartDate, hkt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_date %q: %w", input.StartDate, err)
+		}
+	} else {
+		// Default: last 24 hours
+		startDate = now.Add(-24 * time.Hour)
+	}
+
+	if input.EndDate != "" {
+		var err error
+		endDate, err = time.ParseInLocation("2006-01-02", input.EndDate, hkt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date %q: %w", input.EndDate, err)
+		}
+		// Set to end of day
+		endDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	} else {
+		endDate = now
+	}
+
+	log.Printf("Searching %s filings: %s to %s (market: %s)", ex.Name(),
+		startDate.Format("2006-01-02 15:04"), endDate.Format("2006-01-02 15:04"), input.Market)
+
+	// Connect to PostgreSQL
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
+	}
+
+	db, err := NewPostgresDB(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	log.Println("Database connected")
+
+	filter := securities.NewFilter(cfg.SkipCategories)
+
+	// Fetch filings by date range through the exchange adapter
+	results, err := ex.SearchFilings(ctx, startDate, endDate, exchange.SearchOptions{Market: input.Market})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Found %d announcements in date range", len(results))
+
+	// Process results and collect new filing payloads for the Map state
+	output := &ScraperOutput{
+		TotalAnnouncements: len(results),
+		Filings:            make([]FilingPayload, 0),
+	}
+
+	for _, r := range results {
+		// The company is resolved as of the filing's report date
+		filing := r.Filing
+
+		// Skip excluded instrument categories (by default DWs, CBBCs, Inline
+		// Warrants), by the company's stored category if the list lacks the code
+		class, err := identity.Classify(ctx, db, r.Company, filing.ReportDate, r.Class)
+		if err != nil {
+			log.Printf("Error classifying %s: %v", r.Company.StockCode, err)
+		}
+		if filter.Skip(class) {
+			continue
+		}
+
+		// Get or create the company that held this stock code at the time
+		company, created, err := identity.Resolve(ctx, db, r.Company, filing.ReportDate)
+		if err != nil {
+			log.Printf("Error resolving company %s: %v", r.Company.StockCode, err)
+			output.Errors++
+			continue
+		}
+		if created {
+			log.Printf("Created company: %s (%s)", company.ID, company.CompanyName)
+		}
+		filing.CompanyID = company.ID
+
+		// Check if filing already exists
+		existing, err := db.GetFilingBySourceID(ctx, filing.Exchange, filing.SourceID)
+		if err != nil {
+			log.Printf("Error checking filing %s: %v", filing.SourceID, err)
+			output.Errors++
+			continue
+		}
+
+		var revisions []models.FilingRevision
+		if existing != nil {
+			output.UpdatedFilings++
+			revisions = models.Revise(existing, filing)
+		}
+
+		if existing == nil || models.DocumentChanged(revisions) {
+			if existing == nil {
+				output.NewFilings++
+			}
+			// Add to filings array for downstream Map state processing;
+			// Revise left a changed document pending
+			output.Filings = append(output.Filings, FilingPayload{
+				SourceID:      filing.SourceID,
+				SourceURL:     filing.SourceURL,
+				CompanyID:     company.ID,
+				FileExtension: filing.FileExtension,
+				Exchange:      filing.Exchange,
+				ReportDate:    filing.ReportDate.Format(time.RFC3339),
+			})
+		}
+
+		// Save filing
+		if err := db.UpsertFiling(ctx, filing); err != nil {
+			log.Printf("Error saving filing %s: %v", filing.SourceID, err)
+			output.Errors++
+			continue
+		}
+
+		if len(revisions) > 0 {
+			if err := db.InsertRevisions(ctx, revisions); err != nil {
+				log.Printf("Error recording revisions of %s: %v", filing.SourceID, err)
+				output.Errors++
+				continue
+			}
+			for _, rev := range revisions {
+				log.Printf("Filing %s changed %s: %q -> %q", rev.SourceID, rev.Field, rev.OldValue, rev.NewValue)
+			}
+			output.RevisedFilings++
+			output.Revisions = append(output.Revisions, revisions...)
+		}
+	}
+
+	output.DownloadFilings = len(output.Filings)
+
+	// Pair the language versions of the announcements just stored
+	paired, err := translation.Link(ctx, db, ex.Name(), startDate, endDate)
+	if err != nil {
+		log.Printf("Error pairing translations: %v", err)
+		output.Errors++
+	}
+
+	log.Printf("Scraper complete: %d total, %d new, %d updated (%d revised), %d translations paired, %d errors",
+		output.TotalAnnouncements, output.NewFilings, output.UpdatedFilings, output.RevisedFilings, paired, output.Errors)
+
+	// Store the lists in S3 if the output would exceed the state limit
+	if bucket := os.Getenv("S3_BUCKET"); bucket != "" {
+		clients, err := awsclient.New(ctx, awsclient.FromEnv())
+		if err != nil {
+			return nil, err
+		}
+		blobs := storage.NewS3StorageFromClient(clients.S3(), bucket)
+		if err := offload(ctx, output, blobs, bucket, claimcheck.Threshold()); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// offload replaces the output's filings and revisions with claim checks in
+// bucket when the encoded output is larger than threshold. It fails if the
+// output is still too large without them.
+func offload(ctx context.Context, output *ScraperOutput, blobs storage.Blobs, bucket string, threshold int) error {
+	size, err := encodedSize(output)
+	if err != nil || size <= threshold {
+		return err
+	}
+
+	ref, err := claimcheck.PutJSONL(ctx, blobs, bucket, "filings", output.Filings)
+	if err != nil {
+		return err
+	}
+	log.Printf("Output is %d bytes; stored %d filings at s3://%s/%s", size, ref.Count, bucket, ref.Key)
+	output.Filings = make([]FilingPayload, 0)
+	output.FilingsRef = ref
+
+	if len(output.Revisions) > 0 {
+		ref, err := claimcheck.PutJSONL(ctx, blobs, bucket, "revisions", output.Revisions)
+		if err != nil {
+			return err
+		}
+		log.Printf("Stored %d revisions at s3://%s/%s", ref.Count, bucket, ref.Key)
+		output.Revisions = nil
+		output.RevisionsRef = ref
+	}
+
+	if size, err = encodedSize(output); err == nil && size > threshold {
+		err = fmt.Errorf("output is %d bytes after storing its lists in S3, over the %d byte limit", size, threshold)
+	}
+	return err
+}
+
+// encodedSize returns the size of the output encoded as JSON
+func encodedSize(output *ScraperOutput) (int, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return 0, fmt.Errorf("encoding output: %w", err)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/claimcheck"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+func TestOffload(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewMemoryStorage()
+	output := &ScraperOutput{}
+	for i := 0; i < 10; i++ {
+		output.Filings = append(output.Filings, FilingPayload{SourceID: fmt.Sprint(i), SourceURL: "https://example.com/doc.pdf"})
+	}
+	output.DownloadFilings = len(output.Filings)
+	for i := 0; i < 10; i++ {
+		output.Revisions = append(output.Revisions, models.FilingRevision{SourceID: fmt.Sprint(i), Field: "title", OldValue: "Old title", NewValue: "New title"})
+	}
+
+	// Small outputs stay inline
+	if err := offload(ctx, output, blobs, "bucket", 64*1024); err != nil {
+		t.Fatal(err)
+	}
+	if output.FilingsRef != nil || len(output.Filings) != 10 {
+		t.Fatalf("small output offloaded: %+v", output.FilingsRef)
+	}
+
+	if err := offload(ctx, output, blobs, "bucket", 1024); err != nil {
+		t.Fatal(err)
+	}
+	if output.FilingsRef == nil || output.FilingsRef.Count != 10 || len(output.Filings) != 0 || output.DownloadFilings != 10 ||
+		output.RevisionsRef == nil || output.RevisionsRef.Count != 10 || output.Revisions != nil {
+		t.Fatalf("offloaded output = %+v", output)
+	}
+	var stored []FilingPayload
+	if err := claimcheck.GetJSONL(ctx, blobs, output.FilingsRef, &stored); err != nil || stored[9].SourceID != "9" {
+		t.Errorf("stored filings = %+v, %v", stored, err)
+	}
+
+	var revisions []models.FilingRevision
+	if err := claimcheck.GetJSONL(ctx, blobs, output.RevisionsRef, &revisions); err != nil || revisions[9].SourceID != "9" {
+		t.Errorf("stored revisions = %+v, %v", revisions, err)
+	}
+
+	// The rest of the output must fit on its own
+	if err := offload(ctx, &ScraperOutput{Filings: output.Filings}, blobs, "bucket", 16); err == nil {
+		t.Error("offload() succeeded with the output still over the threshold")
+	}
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// PostgresDB wraps a PostgreSQL connection pool for the scraper
+type PostgresDB struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDB creates a new PostgreSQL connection
+func NewPostgresDB(ctx context.Context, dsn string) (*PostgresDB, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection pool: %w", err)
+	}
+
+	// Test connection
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	return &PostgresDB{pool: pool}, nil
+}
+
+// Close closes the database connection
+func (db *PostgresDB) Close() {
+	db.pool.Close()
+}
+
+// GetCompanyByID retrieves a company by its (exchange, company_id) key
+func (db *PostgresDB) GetCompanyByID(ctx context.Context, exchange, id string) (*models.Company, error) {
+	query := `SELECT company_id, COALESCE(stock_code, ''), name, exchange,
+			  COALESCE(instrument_category, ''), COALESCE(instrument_sub_category, ''), updated_at
+			  FROM companies WHERE exchange = $1 AND company_id = $2`
+
+	var c models.Company
+	var code string
+	err := db.pool.QueryRow(ctx, query, exchange, models.NormalizeCompanyID(exchange, id)).Scan(
+		&c.ID, &code, &c.CompanyName, &c.Exchange,
+		&c.InstrumentCategory, &c.InstrumentSubCategory, &c.UpdatedAt,
+	)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	c.StockCode = models.NormalizeStockCode(c.Exchange, code)
+	return &c, nil
+}
+
+// GetListings retrieves all listing periods for a stock code, oldest first
+func (db *PostgresDB) GetListings(ctx context.Context, exchange, stockCode string) ([]models.CompanyListing, error) {
+	query := `SELECT id, company_id, exchange, stock_code, listed_from, listed_to, created_at, updated_at
+			  FROM company_listings WHERE exchange = $1 AND stock_code = $2
+			  ORDER BY listed_from NULLS FIRST`
+
+	rows, err := db.pool.Query(ctx, query, exchange, models.NormalizeStockCode(exchange, stockCode))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listings []models.CompanyListing
+	for rows.Next() {
+		var l models.CompanyListing
+		var code string
+		if err := rows.Scan(&l.ID, &l.CompanyID, &l.Exchange, &code,
+			&l.ListedFrom, &l.ListedTo, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		l.StockCode = models.NormalizeStockCode(l.Exchange, code)
+		listings = append(listings, l)
+	}
+
+	return listings, rows.Err()
+}
+
+// UpsertListing creates or updates a company listing period
+func (db *PostgresDB) UpsertListing(ctx context.Context, listing *models.CompanyListing) error {
+	query := `
+		INSERT INTO company_listings (id, company_id, exchange, stock_code, listed_from, listed_to, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT(id) DO UPDATE SET
+			company_id = EXCLUDED.company_id,
+			listed_from = EXCLUDED.listed_from,
+			listed_to = EXCLUDED.listed_to,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	listing.CompanyID = models.NormalizeCompanyID(listing.Exchange, listing.CompanyID)
+	listing.StockCode = models.NormalizeStockCode(listing.Exchange, listing.StockCode.String())
+
+	now := time.Now()
+	if listing.CreatedAt.IsZero() {
+		listing.CreatedAt = now
+	}
+	listing.UpdatedAt = now
+
+	_, err := db.pool.Exec(ctx, query,
+		listing.ID,
+		listing.CompanyID,
+		listing.Exchange,
+		listing.StockCode,
+		listing.ListedFrom,
+		listing.ListedTo,
+		listing.CreatedAt,
+		listing.UpdatedAt,
+	)
+	return err
+}
+
+// UpsertCompany creates or updates a company.
+// Schema: companies(company_id, name, stock_code, exchange, instrument_category,
+//
+//	instrument_sub_category, updated_at)
+//
+// PK: (exchange, company_id)
+func (db *PostgresDB) UpsertCompany(ctx context.Context, company *models.Company) error {
+	query := `
+		INSERT INTO companies (company_id, name, stock_code, exchange, instrument_category, instrument_sub_category, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+		ON CONFLICT(exchange, company_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			stock_code = EXCLUDED.stock_code,
+			instrument_category = COALESCE(EXCLUDED.instrument_category, companies.instrument_category),
+			instrument_sub_category = COALESCE(EXCLUDED.instrument_sub_category, companies.instrument_sub_category),
+			updated_at = EXCLUDED.updated_at
+	`
+
+	company.ID = models.NormalizeCompanyID(company.Exchange, company.ID)
+	company.StockCode = models.NormalizeStockCode(company.Exchange, company.StockCode.String())
+	company.UpdatedAt = time.Now()
+
+	_, err := db.pool.Exec(ctx, query,
+		company.ID,
+		company.CompanyName,
+		company.StockCode,
+		company.Exchange,
+		company.InstrumentCategory,
+		company.InstrumentSubCategory,
+		company.UpdatedAt,
+	)
+	return err
+}
+
+// GetFilingBySourceID retrieves a filing by its source ID.
+// Schema: filings(exchange, source_id, company_id, title, title_en, source_url, pdf_s3_key,
+//
+//	filing_type, filing_sub_type, local_path, file_extension, page_count, file_size,
+//	language, processing_status, processing_error, ingested_at, report_date, created_at, updated_at, ...)
+//
+// PK: (exchange, source_id)
+func (db *PostgresDB) GetFilingBySourceID(ctx context.Context, exchangeType, sourceID string) (*models.Filing, error) {
+	query := `SELECT source_id, exchange, COALESCE(company_id, ''), COALESCE(filing_type, ''), COALESCE(filing_sub_type, ''),
+			  report_date, COALESCE(title, ''), COALESCE(title_en, ''), COALESCE(source_url, ''), COALESCE(pdf_s3_key, ''), COALESCE(local_path, ''),
+			  COALESCE(page_count, 0), COALESCE(file_size, 0), COALESCE(file_extension, ''), COALESCE(language, 'ZH'), COALESCE(processing_status, 'PENDING'),
+			  COALESCE(processing_error, ''), ingested_at, released_at, created_at, COALESCE(updated_at, created_at)
+			  FROM filings WHERE exchange = $1 AND source_id = $2`
+
+	var f models.Filing
+	var reportDate *time.Time
+	err := db.pool.QueryRow(ctx, query, exchangeType, sourceID).Scan(
+		&f.SourceID, &f.Exchange, &f.CompanyID, &f.FilingType, &f.FilingSubType,
+		&reportDate, &f.Title, &f.TitleEn, &f.SourceURL, &f.PDFS3Key, &f.LocalPath,
+		&f.PageCount, &f.FileSize, &f.FileExtension, &f.Language, &f.ProcessingStatus,
+		&f.ProcessingError, &f.IngestedAt, &f.ReleasedAt, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if reportDate != nil {
+		f.ReportDate = *reportDate
+	}
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Use composite key as ID for internal reference
+	f.ID = f.Exchange + ":" + f.SourceID
+	return &f, nil
+}
+
+// UpsertFiling creates or updates a filing.
+// Schema PK: (exchange, source_id) — no separate id column.
+// An update writes the download fields as given; models.Revise keeps or
+// resets them before the filing is saved again.
+func (db *PostgresDB) UpsertFiling(ctx context.Context, filing *models.Filing) error {
+	query := `
+		INSERT INTO filings (source_id, exchange, company_id, filing_type, filing_sub_type,
+			report_date, title, title_en, source_url, pdf_s3_key, local_path, page_count, file_size,
+			file_extension, language, processing_status, processing_error, ingested_at, released_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT(exchange, source_id) DO UPDATE SET
+			company_id = EXCLUDED.company_id,
+			filing_type = EXCLUDED.filing_type,
+			filing_sub_type = EXCLUDED.filing_sub_type,
+			title = EXCLUDED.title,
+			title_en = EXCLUDED.title_en,
+			source_url = EXCLUDED.source_url,
+			file_extension = EXCLUDED.file_extension,
+			file_size = EXCLUDED.file_size,
+			language = EXCLUDED.language,
+			pdf_s3_key = NULLIF(EXCLUDED.pdf_s3_key, ''),
+			local_path = NULLIF(EXCLUDED.local_path, ''),
+			page_count = EXCLUDED.page_count,
+			processing_status = EXCLUDED.processing_status,
+			processing_error = NULLIF(EXCLUDED.processing_error, ''),
+			ingested_at = EXCLUDED.ingested_at,
+			released_at = COALESCE(EXCLUDED.released_at, filings.released_at),
+			updated_at = EXCLUDED.updated_at
+	`
+
+	filing.CompanyID = models.NormalizeCompanyID(filing.Exchange, filing.CompanyID)
+
+	now := time.Now()
+	if filing.CreatedAt.IsZero() {
+		filing.CreatedAt = now
+	}
+	filing.UpdatedAt = now
+
+	_, err := db.pool.Exec(ctx, query,
+		filing.SourceID,
+		filing.Exchange,
+		filing.CompanyID,
+		filing.FilingType,
+		filing.FilingSubType,
+		filing.ReportDate,
+		filing.Title,
+		filing.TitleEn,
+		filing.SourceURL,
+		filing.PDFS3Key,
+		filing.LocalPath,
+		filing.PageCount,
+		filing.FileSize,
+		filing.FileExtension,
+		filing.Language,
+		filing.ProcessingStatus,
+		filing.ProcessingError,
+		filing.IngestedAt,
+		filing.ReleasedAt,
+		filing.CreatedAt,
+		filing.UpdatedAt,
+	)
+	return err
+}
+
+// InsertRevisions records changes to the fields of stored filings
+func (db *PostgresDB) InsertRevisions(ctx context.Context, revisions []models.FilingRevision) error {
+	batch := &pgx.Batch{}
+	for _, r := range revisions {
+		batch.Queue(`
+			INSERT INTO filing_revisions (exchange, source_id, field, old_value, new_value, created_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+		`, r.Exchange, r.SourceID, r.Field, r.OldValue, r.NewValue, r.CreatedAt)
+	}
+	return db.pool.SendBatch(ctx, batch).Close()
+}
+
+// GetUnpairedFilings returns the filings of an exchange released between
+// from and to that have no other-language version recorded
+func (db *PostgresDB) GetUnpairedFilings(ctx context.Context, exchange string, from, to time.Time) ([]models.Filing, error) {
+	query := `SELECT source_id, exchange, COALESCE(company_id, ''), COALESCE(filing_type, ''), COALESCE(filing_sub_type, ''),
+			  report_date, released_at, COALESCE(title, ''), COALESCE(source_url, ''), COALESCE(file_size, 0), COALESCE(language, 'ZH')
+			  FROM filings WHERE exchange = $1 AND COALESCE(released_at, report_date) BETWEEN $2 AND $3
+			  AND COALESCE(translation_of, '') = '' ORDER BY COALESCE(released_at, report_date)`
+
+	rows, err := db.pool.Query(ctx, query, exchange, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filings []models.Filing
+	for rows.Next() {
+		var f models.Filing
+		if err := rows.Scan(
+			&f.SourceID, &f.Exchange, &f.CompanyID, &f.FilingType, &f.FilingSubType,
+			&f.ReportDate, &f.ReleasedAt, &f.Title, &f.SourceURL, &f.FileSize, &f.Language,
+		); err != nil {
+			return nil, err
+		}
+		f.ID = f.Exchange + ":" + f.SourceID
+		filings = append(filings, f)
+	}
+
+	return filings, rows.Err()
+}
+
+// SetTranslation records two filings as language versions of each other
+func (db *PostgresDB) SetTranslation(ctx context.Context, exchange, sourceID, translationSourceID string) error {
+	query := `UPDATE filings SET translation_of = CASE source_id WHEN $2 THEN $3 ELSE $2 END, updated_at = NOW()
+			  WHERE exchange = $1 AND source_id IN ($2, $3)`
+	_, err := db.pool.Exec(ctx, query, exchange, sourceID, translationSourceID)
+	return err
+}
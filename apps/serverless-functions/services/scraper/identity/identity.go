@@ -0,0 +1,276 @@
+// Package identity maps exchange stock codes to company identities.
+//
+// HKEX reassigns stock codes after delistings, so a code alone does not
+// identify an issuer. Each company has one or more listing periods and a
+// filing belongs to the company whose listing covered the filing's report
+// date.
+//
+// Exchanges that do not recycle codes, or whose adapters key companies by a
+// permanent issuer identifier such as DART's corp_code, skip listing periods
+// and resolve companies by the template's ID.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+)
+
+// Store defines the database methods needed to resolve company identities.
+// Implemented by the SQLite database and the Lambda's PostgreSQL wrapper.
+type Store interface {
+	GetCompanyByID(ctx context.Context, exchange, id string) (*models.Company, error)
+	GetListings(ctx context.Context, exchange, stockCode string) ([]models.CompanyListing, error)
+	UpsertCompany(ctx context.Context, company *models.Company) error
+	UpsertListing(ctx context.Context, listing *models.CompanyListing) error
+}
+
+// recyclesCodes lists the exchanges whose stock codes are reassigned to new
+// issuers after delistings
+var recyclesCodes = map[string]bool{
+	"HKEX": true,
+}
+
+// Resolve returns the company that held template.StockCode on asOf.
+//
+// On exchanges that do not recycle codes the company is looked up by
+// template.ID and created from template if missing.
+//
+// template's instrument category, when set, describes the code's current
+// holder and is only stored on the company with an open-ended listing.
+// If no listing covers asOf, a company is created from template:
+//   - a code with no listings at all gets an open-ended listing, reusing a
+//     legacy company row keyed by the bare code if one exists
+//   - a code whose known listings do not cover asOf was reused by another
+//     issuer, so a new company is created whose listing spans the gap
+//     between the neighbouring listings
+//
+// The returned bool reports whether a company was created.
+func Resolve(ctx context.Context, store Store, template *models.Company, asOf time.Time) (*models.Company, bool, error) {
+	if !recyclesCodes[template.Exchange] {
+		return resolveByID(ctx, store, template)
+	}
+
+	listings, err := store.GetListings(ctx, template.Exchange, template.StockCode.String())
+	if err != nil {
+		return nil, false, fmt.Errorf("getting listings: %w", err)
+	}
+
+	asOfDate := models.ListingDate(asOf)
+	for i := range listings {
+		if listings[i].ActiveAt(asOfDate) {
+			company, err := store.GetCompanyByID(ctx, template.Exchange, listings[i].CompanyID)
+			if err != nil {
+				return nil, false, fmt.Errorf("getting company %s: %w", listings[i].CompanyID, err)
+			}
+			if company != nil {
+				if listings[i].ListedTo == nil {
+					if err := refreshCategory(ctx, store, company, template); err != nil {
+						return nil, false, err
+					}
+				}
+				return company, false, nil
+			}
+		}
+	}
+
+	if len(listings) == 0 {
+		return adoptOrCreate(ctx, store, template)
+	}
+
+	listedFrom, listedTo := gapAround(listings, asOfDate)
+	start := listedFrom
+	if start == nil {
+		start = &asOfDate
+	}
+
+	company := *template
+	company.ID = models.CompanyIDForListing(template.StockCode, start)
+	if listedTo != nil {
+		// The template's category describes the code's current holder
+		company.InstrumentCategory = ""
+		company.InstrumentSubCategory = ""
+	}
+	if err := store.UpsertCompany(ctx, &company); err != nil {
+		return nil, false, fmt.Errorf("creating company: %w", err)
+	}
+
+	listing := &models.CompanyListing{
+		ID:         listingID(company.Exchange, company.ID),
+		CompanyID:  company.ID,
+		Exchange:   company.Exchange,
+		StockCode:  company.StockCode,
+		ListedFrom: listedFrom,
+		ListedTo:   listedTo,
+	}
+	if err := store.UpsertListing(ctx, listing); err != nil {
+		return nil, false, fmt.Errorf("creating listing: %w", err)
+	}
+
+	log.Printf("Stock code %s reused: created company %s for %s", company.StockCode, company.ID, asOfDate.Format("2006-01-02"))
+	return &company, true, nil
+}
+
+// resolveByID returns the company keyed by template.ID, creating it if needed
+func resolveByID(ctx context.Context, store Store, template *models.Company) (*models.Company, bool, error) {
+	if template.ID == "" {
+		return nil, false, fmt.Errorf("%s company %q has no ID", template.Exchange, template.CompanyName)
+	}
+
+	company, err := store.GetCompanyByID(ctx, template.Exchange, template.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting company %s: %w", template.ID, err)
+	}
+	if company != nil {
+		if err := refreshCategory(ctx, store, company, template); err != nil {
+			return nil, false, err
+		}
+		return company, false, nil
+	}
+
+	company = new(models.Company)
+	*company = *template
+	if err := store.UpsertCompany(ctx, company); err != nil {
+		return nil, false, fmt.Errorf("creating company: %w", err)
+	}
+	return company, true, nil
+}
+
+// adoptOrCreate handles a stock code with no listings. A company keyed by the
+// bare code predates listing tracking and is adopted as the holder, with an
+// open-ended listing that discover-codes closes when the code is delisted.
+// Reuses of the code before listing tracking began leave several issuers'
+// filings on the adopted company; those are split with split-companies.
+func adoptOrCreate(ctx context.Context, store Store, template *models.Company) (*models.Company, bool, error) {
+	id := models.CompanyIDForListing(template.StockCode, nil)
+
+	company, err := store.GetCompanyByID(ctx, template.Exchange, id)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting company %s: %w", id, err)
+	}
+
+	created := false
+	if company != nil {
+		if err := refreshCategory(ctx, store, company, template); err != nil {
+			return nil, false, err
+		}
+	} else {
+		company = new(models.Company)
+		*company = *template
+		company.ID = id
+		if err := store.UpsertCompany(ctx, company); err != nil {
+			return nil, false, fmt.Errorf("creating company: %w", err)
+		}
+		created = true
+	}
+
+	listing := &models.CompanyListing{
+		ID:        listingID(company.Exchange, company.ID),
+		CompanyID: company.ID,
+		Exchange:  company.Exchange,
+		StockCode: template.StockCode,
+	}
+	if err := store.UpsertListing(ctx, listing); err != nil {
+		return nil, false, fmt.Errorf("creating listing: %w", err)
+	}
+
+	return company, created, nil
+}
+
+// Classify returns the classification used to filter a filing of template's
+// stock code on asOf. A classification from the securities list is returned
+// as is; otherwise the category stored on the company that held the code,
+// from an earlier list, takes precedence over cls's stock code range guess.
+func Classify(ctx context.Context, store Store, template *models.Company, asOf time.Time, cls securities.Classification) (securities.Classification, error) {
+	if cls.FromList {
+		return cls, nil
+	}
+
+	company, err := holder(ctx, store, template, asOf)
+	if err != nil {
+		return cls, err
+	}
+	if company == nil || company.InstrumentCategory == "" {
+		return cls, nil
+	}
+	return securities.Classification{
+		Category:    company.InstrumentCategory,
+		SubCategory: company.InstrumentSubCategory,
+	}, nil
+}
+
+// holder returns the existing company Resolve would return for template on
+// asOf, without creating one; nil if there is none yet
+func holder(ctx context.Context, store Store, template *models.Company, asOf time.Time) (*models.Company, error) {
+	if !recyclesCodes[template.Exchange] {
+		if template.ID == "" {
+			return nil, nil
+		}
+		return store.GetCompanyByID(ctx, template.Exchange, template.ID)
+	}
+
+	listings, err := store.GetListings(ctx, template.Exchange, template.StockCode.String())
+	if err != nil {
+		return nil, fmt.Errorf("getting listings: %w", err)
+	}
+	if len(listings) == 0 {
+		return store.GetCompanyByID(ctx, template.Exchange, models.CompanyIDForListing(template.StockCode, nil))
+	}
+
+	asOfDate := models.ListingDate(asOf)
+	for i := range listings {
+		if listings[i].ActiveAt(asOfDate) {
+			return store.GetCompanyByID(ctx, template.Exchange, listings[i].CompanyID)
+		}
+	}
+	return nil, nil
+}
+
+// refreshCategory updates an existing company's instrument category when the
+// template carries a different one from the latest securities list
+func refreshCategory(ctx context.Context, store Store, company, template *models.Company) error {
+	if template.InstrumentCategory == "" {
+		return nil
+	}
+	if company.InstrumentCategory == template.InstrumentCategory &&
+		company.InstrumentSubCategory == template.InstrumentSubCategory {
+		return nil
+	}
+
+	company.InstrumentCategory = template.InstrumentCategory
+	company.InstrumentSubCategory = template.InstrumentSubCategory
+	if err := store.UpsertCompany(ctx, company); err != nil {
+		return fmt.Errorf("updating category of %s: %w", company.ID, err)
+	}
+	return nil
+}
+
+// gapAround returns the uncovered period around date: from the end of the
+// latest listing before it to the start of the earliest listing after it.
+// Either bound is nil when there is no neighbouring listing on that side.
+func gapAround(listings []models.CompanyListing, date time.Time) (from, to *time.Time) {
+	for i := range listings {
+		l := &listings[i]
+		if l.ListedTo != nil && !l.ListedTo.After(date) {
+			if from == nil || l.ListedTo.After(*from) {
+				from = l.ListedTo
+			}
+		}
+		if l.ListedFrom != nil && l.ListedFrom.After(date) {
+			if to == nil || l.ListedFrom.Before(*to) {
+				to = l.ListedFrom
+			}
+		}
+	}
+	return from, to
+}
+
+// listingID builds a deterministic listing ID so repeated runs upsert rather
+// than duplicate
+func listingID(exchange, companyID string) string {
+	return "lst_" + exchange + "_" + companyID
+}
@@ -0,0 +1,178 @@
+package identity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+)
+
+// memStore is an in-memory Store for tests
+type memStore struct {
+	companies map[string]*models.Company
+	listings  map[string]*models.CompanyListing
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		companies: make(map[string]*models.Company),
+		listings:  make(map[string]*models.CompanyListing),
+	}
+}
+
+func (m *memStore) GetCompanyByID(ctx context.Context, exchange, id string) (*models.Company, error) {
+	return m.companies[id], nil
+}
+
+func (m *memStore) GetListings(ctx context.Context, exchange, stockCode string) ([]models.CompanyListing, error) {
+	var out []models.CompanyListing
+	for _, l := range m.listings {
+		if l.Exchange == exchange && l.StockCode.String() == stockCode {
+			out = append(out, *l)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) UpsertCompany(ctx context.Context, company *models.Company) error {
+	c := *company
+	m.companies[c.ID] = &c
+	return nil
+}
+
+func (m *memStore) UpsertListing(ctx context.Context, listing *models.CompanyListing) error {
+	l := *listing
+	m.listings[l.ID] = &l
+	return nil
+}
+
+func date(s string) *time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return &t
+}
+
+func template(name string) *models.Company {
+	return &models.Company{StockCode: "01234", CompanyName: name, Exchange: "HKEX"}
+}
+
+func TestResolve_NewCodeCreatesOpenListing(t *testing.T) {
+	store := newMemStore()
+
+	c, created, err := Resolve(context.Background(), store, template("Alpha"), *date("2024-03-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || c.ID != "01234" {
+		t.Fatalf("got %q created=%v, want 01234 created", c.ID, created)
+	}
+
+	// Any later date resolves to the same company
+	c2, created, _ := Resolve(context.Background(), store, template("Alpha"), *date("2008-01-01"))
+	if created || c2.ID != "01234" {
+		t.Errorf("got %q created=%v, want existing 01234", c2.ID, created)
+	}
+}
+
+func TestResolve_AdoptsLegacyCompany(t *testing.T) {
+	store := newMemStore()
+	store.companies["01234"] = &models.Company{ID: "01234", StockCode: "01234", CompanyName: "Legacy", Exchange: "HKEX"}
+
+	c, created, err := Resolve(context.Background(), store, template("New Name"), *date("2024-03-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || c.CompanyName != "Legacy" {
+		t.Errorf("got %q created=%v, want legacy company adopted", c.CompanyName, created)
+	}
+	if len(store.listings) != 1 {
+		t.Errorf("got %d listings, want 1", len(store.listings))
+	}
+}
+
+func TestResolve_RecycledCode(t *testing.T) {
+	store := newMemStore()
+	store.companies["01234"] = &models.Company{ID: "01234", StockCode: "01234", CompanyName: "Old Co", Exchange: "HKEX"}
+	store.listings["lst_HKEX_01234"] = &models.CompanyListing{
+		ID: "lst_HKEX_01234", CompanyID: "01234", Exchange: "HKEX", StockCode: "01234",
+		ListedTo: date("2012-06-30"),
+	}
+
+	old, _, err := Resolve(context.Background(), store, template("New Co"), *date("2008-05-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.ID != "01234" {
+		t.Errorf("2008 filing resolved to %q, want 01234", old.ID)
+	}
+
+	newer, created, err := Resolve(context.Background(), store, template("New Co"), *date("2024-01-05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || newer.ID != "01234_20120630" {
+		t.Fatalf("2024 filing resolved to %q created=%v, want new 01234_20120630", newer.ID, created)
+	}
+
+	// A second filing in the same gap reuses the new company
+	again, created, _ := Resolve(context.Background(), store, template("New Co"), *date("2025-02-01"))
+	if created || again.ID != newer.ID {
+		t.Errorf("got %q created=%v, want existing %q", again.ID, created, newer.ID)
+	}
+}
+
+func TestResolve_PermanentIssuerID(t *testing.T) {
+	store := newMemStore()
+	tmpl := &models.Company{ID: "00126380", StockCode: "005930", CompanyName: "삼성전자", Exchange: "DART"}
+
+	c, created, err := Resolve(context.Background(), store, tmpl, *date("2024-03-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || c.ID != "00126380" {
+		t.Fatalf("got %q created=%v, want 00126380 created", c.ID, created)
+	}
+	if len(store.listings) != 0 {
+		t.Errorf("got %d listings, want none", len(store.listings))
+	}
+
+	c2, created, _ := Resolve(context.Background(), store, tmpl, *date("2001-01-01"))
+	if created || c2.ID != "00126380" {
+		t.Errorf("got %q created=%v, want existing 00126380", c2.ID, created)
+	}
+}
+
+func TestClassify_StoredCategory(t *testing.T) {
+	store := newMemStore()
+	store.companies["01234"] = &models.Company{ID: "01234", StockCode: "01234", Exchange: "HKEX",
+		InstrumentCategory: securities.CategoryETP, InstrumentSubCategory: "Exchange Traded Funds"}
+	ctx := context.Background()
+	asOf := *date("2024-03-01")
+
+	// Missing from the list: the stored category beats the range guess
+	got, err := Classify(ctx, store, template("Tracker"), asOf, securities.Classification{Category: securities.CategoryEquity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Category != securities.CategoryETP || got.SubCategory != "Exchange Traded Funds" {
+		t.Errorf("Classify() = %+v, want stored ETP", got)
+	}
+
+	// In the list: the list wins
+	fromList := securities.Classification{Category: securities.CategoryEquity, FromList: true}
+	if got, _ := Classify(ctx, store, template("Tracker"), asOf, fromList); got != fromList {
+		t.Errorf("Classify() = %+v, want %+v", got, fromList)
+	}
+
+	// No company yet: the range guess stands
+	other := template("New")
+	other.StockCode = "05678"
+	guess := securities.Classification{Category: securities.CategoryEquity}
+	if got, _ := Classify(ctx, store, other, asOf, guess); got != guess {
+		t.Errorf("Classify() = %+v, want %+v", got, guess)
+	}
+	if len(store.companies) != 1 {
+		t.Errorf("Classify() created companies: %d", len(store.companies))
+	}
+}
@@ -0,0 +1,82 @@
+// Package reconcile compares stored filings with a fresh search of their
+// source.
+//
+// Exchanges withdraw announcements and replace documents after we have
+// stored them. Re-searching a recent date range and diffing the results
+// against the stored filings finds filings that are no longer listed, and
+// listed filings whose title or document link has changed.
+package reconcile
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// Change is a difference between a stored filing and its source
+type Change struct {
+	Filing   models.Filing // as stored
+	Event    models.FilingEventType
+	OldValue string
+	NewValue string
+}
+
+// Diff returns the changes between stored filings and the filings found at
+// the source for the same date range, ordered by source ID.
+//
+// A stored filing missing from the source is withdrawn, but only if the
+// source returned filings for its release day; a day without results is
+// more likely a failed search than a day of withdrawals. A withdrawn filing
+// listed again is restored.
+func Diff(stored, source []models.Filing) []Change {
+	bySource := make(map[string]*models.Filing, len(source))
+	days := make(map[time.Time]bool)
+	for i := range source {
+		f := &source[i]
+		bySource[key(f)] = f
+		days[day(f.ReportDate)] = true
+	}
+
+	var changes []Change
+	for _, s := range stored {
+		cur, ok := bySource[key(&s)]
+		if !ok {
+			if s.ProcessingStatus != models.ProcessingStatusWithdrawn && days[day(s.ReportDate)] {
+				changes = append(changes, Change{Filing: s, Event: models.FilingEventWithdrawn})
+			}
+			continue
+		}
+
+		if s.ProcessingStatus == models.ProcessingStatusWithdrawn {
+			changes = append(changes, Change{Filing: s, Event: models.FilingEventRestored})
+		}
+		if normalize(s.Title) != normalize(cur.Title) {
+			changes = append(changes, Change{Filing: s, Event: models.FilingEventTitleChanged, OldValue: s.Title, NewValue: cur.Title})
+		}
+		if cur.SourceURL != "" && s.SourceURL != cur.SourceURL {
+			changes = append(changes, Change{Filing: s, Event: models.FilingEventURLChanged, OldValue: s.SourceURL, NewValue: cur.SourceURL})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Filing.SourceID < changes[j].Filing.SourceID
+	})
+	return changes
+}
+
+// key identifies a filing across stored and source records
+func key(f *models.Filing) string {
+	return f.Exchange + ":" + f.SourceID
+}
+
+// day truncates t to its calendar day, in t's location
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// normalize collapses whitespace so reformatted titles compare equal
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,67 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+func filing(sourceID, title, link string, at time.Time) models.Filing {
+	return models.Filing{
+		SourceID:         sourceID,
+		Exchange:         "HKEX",
+		ReportDate:       at,
+		Title:            title,
+		SourceURL:        "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/" + link,
+		ProcessingStatus: models.ProcessingStatusCompleted,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	mar28 := time.Date(2024, 3, 28, 16, 30, 0, 0, time.UTC)
+	mar29 := time.Date(2024, 3, 29, 9, 0, 0, 0, time.UTC)
+
+	withdrawn := filing("105", "Voluntary Announcement", "2024032800105.pdf", mar28)
+	withdrawn.ProcessingStatus = models.ProcessingStatusWithdrawn
+
+	stored := []models.Filing{
+		filing("101", "Final Results", "2024032800101.pdf", mar28),
+		filing("102", "Date of Board Meeting", "2024032800102.pdf", mar28),
+		filing("103", "Poll Results", "2024032800103.pdf", mar28),
+		filing("104", "Monthly Return", "2024032800104.pdf", mar28),
+		withdrawn,
+		// The search returned nothing for the 29th, so this is not withdrawn
+		filing("201", "Next Day Disclosure Return", "2024032900201.pdf", mar29),
+	}
+	source := []models.Filing{
+		filing("101", "Final  Results", "2024032800101.pdf", mar28),
+		filing("103", "Poll Results (Revised)", "2024032800103.pdf", mar28),
+		filing("104", "Monthly Return", "2024032800199.pdf", mar28),
+		filing("105", "Voluntary Announcement", "2024032800105.pdf", mar28),
+		filing("106", "New Listing", "2024032800106.pdf", mar28),
+	}
+
+	changes := Diff(stored, source)
+
+	want := []struct {
+		sourceID string
+		event    models.FilingEventType
+	}{
+		{"102", models.FilingEventWithdrawn},
+		{"103", models.FilingEventTitleChanged},
+		{"104", models.FilingEventURLChanged},
+		{"105", models.FilingEventRestored},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Filing.SourceID != w.sourceID || changes[i].Event != w.event {
+			t.Errorf("change %d = %s %s, want %s %s", i, changes[i].Filing.SourceID, changes[i].Event, w.sourceID, w.event)
+		}
+	}
+	if c := changes[2]; c.NewValue != "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800199.pdf" {
+		t.Errorf("NewValue = %q", c.NewValue)
+	}
+}
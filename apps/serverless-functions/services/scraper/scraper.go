@@ -1,7 +1,9 @@
 package scraper
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -10,8 +12,12 @@ import (
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
 	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/identity"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/translation"
 )
 
 // Result holds the results of scraping
@@ -22,23 +28,31 @@ type Result struct {
 	Errors             int
 	NewFilings         int
 	UpdatedFilings     int
+	RevisedFilings     int // updated filings with changed fields
+	TranslationsPaired int
+	Revisions          []models.FilingRevision
 }
 
 // Scraper orchestrates the scraping workflow
 type Scraper struct {
-	client  *api.Client
-	config  *config.Config
-	storage storage.Storage
-	db      *database.DB
+	client     *api.Client
+	config     *config.Config
+	storage    storage.Storage
+	db         *database.DB
+	classifier *securities.Classifier
+	filter     *securities.Filter
 }
 
-// New creates a new Scraper instance
-func New(cfg *config.Config, client *api.Client, store storage.Storage, db *database.DB) *Scraper {
+// New creates a new Scraper instance. classifier may be nil to classify
+// stock codes by range only.
+func New(cfg *config.Config, client *api.Client, store storage.Storage, db *database.DB, classifier *securities.Classifier) *Scraper {
 	return &Scraper{
-		client:  client,
-		config:  cfg,
-		storage: store,
-		db:      db,
+		client:     client,
+		config:     cfg,
+		storage:    store,
+		db:         db,
+		classifier: classifier,
+		filter:     securities.NewFilter(cfg.SkipCategories),
 	}
 }
 
@@ -91,6 +105,11 @@ func (s *Scraper) Run(ctx context.Context) (*Result, error) {
 		}
 	}
 
+	if s.db != nil && len(allAnnouncements) > 0 {
+		from, to := releaseRange(allAnnouncements)
+		s.pairTranslations(ctx, "HKEX", from, to, result)
+	}
+
 	return result, nil
 }
 
@@ -104,26 +123,27 @@ func (s *Scraper) persistAnnouncement(ctx context.Context, ann *models.Announcem
 	// Use the first stock as the primary company
 	stock := &ann.Stock[0]
 
-	// Skip structured products (DWs, CBBCs, Inline Warrants)
-	if models.IsStructuredProduct(stock.SC) {
+	// Convert announcement to filing; the company is resolved as of its report date
+	filing := models.AnnouncementToFiling(ann, "")
+	template := models.StockToCompany(stock)
+	cls := s.classifier.Classify(stock.SC)
+	cls.Apply(template)
+
+	// Skip excluded instrument categories (by default DWs, CBBCs, Inline Warrants)
+	if s.skip(ctx, template, filing.ReportDate, cls) {
 		result.Skipped++
 		return nil
 	}
 
-	// Get or create company
-	company, err := s.db.GetCompanyByStockCode(ctx, stock.SC)
+	// Get or create the company that held this stock code at the time
+	company, created, err := identity.Resolve(ctx, s.db, template, filing.ReportDate)
 	if err != nil {
-		return fmt.Errorf("getting company: %w", err)
+		return fmt.Errorf("resolving company: %w", err)
 	}
-
-	if company == nil {
-		// Create new company
-		company = models.StockToCompany(stock)
-		if err := s.db.UpsertCompany(ctx, company); err != nil {
-			return fmt.Errorf("creating company: %w", err)
-		}
-		log.Printf("Created company: %s (%s)", company.StockCode, company.CompanyName)
+	if created {
+		log.Printf("Created company: %s (%s)", company.ID, company.CompanyName)
 	}
+	filing.CompanyID = company.ID
 
 	// Check if filing already exists
 	existing, err := s.db.GetFilingBySourceID(ctx, "HKEX", strconv.Itoa(ann.NewsID))
@@ -131,13 +151,10 @@ func (s *Scraper) persistAnnouncement(ctx context.Context, ann *models.Announcem
 		return fmt.Errorf("checking existing filing: %w", err)
 	}
 
-	// Convert announcement to filing
-	filing := models.AnnouncementToFiling(ann, company.ID)
-
+	var revisions []models.FilingRevision
 	if existing != nil {
 		// Update existing filing
-		filing.ID = existing.ID
-		filing.CreatedAt = existing.CreatedAt
+		revisions = models.Revise(existing, filing)
 		result.UpdatedFilings++
 	} else {
 		result.NewFilings++
@@ -148,21 +165,23 @@ func (s *Scraper) persistAnnouncement(ctx context.Context, ann *models.Announcem
 		return fmt.Errorf("saving filing: %w", err)
 	}
 
-	return nil
+	return s.recordRevisions(ctx, revisions, result)
 }
 
 // downloadDocument downloads and stores a single document
 func (s *Scraper) downloadDocument(ctx context.Context, ann *models.Announcement, result *Result) error {
+	doc := storage.AnnouncementFiling(ann)
+
 	// Check if already exists
-	exists, err := s.storage.Exists(ctx, ann)
-	if err != nil {
-		result.Errors++
-		return fmt.Errorf("checking existence: %w", err)
-	}
-	if exists {
+	_, err := s.storage.Stat(ctx, doc)
+	if err == nil {
 		result.Skipped++
 		return nil
 	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		result.Errors++
+		return fmt.Errorf("checking existence: %w", err)
+	}
 
 	// Download the document
 	data, err := s.client.DownloadDocument(ann)
@@ -172,11 +191,12 @@ func (s *Scraper) downloadDocument(ctx context.Context, ann *models.Announcement
 	}
 
 	// Save to storage
-	path, err := s.storage.Save(ctx, ann, data)
+	info, err := s.storage.Put(ctx, doc, bytes.NewReader(data), contentType(ann))
 	if err != nil {
 		result.Errors++
 		return fmt.Errorf("saving: %w", err)
 	}
+	path := s.storage.Location(info.Key)
 
 	result.Downloaded++
 	log.Printf("Downloaded: %s -> %s", ann.DocumentURL(), path)
@@ -195,87 +215,122 @@ func (s *Scraper) downloadDocument(ctx context.Context, ann *models.Announcement
 	return nil
 }
 
+// contentType returns the content type of an announcement's document
+func contentType(ann *models.Announcement) string {
+	switch {
+	case ann.IsPDF():
+		return "application/pdf"
+	case ann.IsHTML():
+		return "text/html"
+	}
+	return "application/octet-stream"
+}
+
 // RunByDateRange executes the scraping workflow for a specific date range,
-// using the HKEX Search API instead of paginated News API. This replaces the
-// MaxPages loop for both daily runs and historical backfills.
-func (s *Scraper) RunByDateRange(ctx context.Context, from, to time.Time, market string) (*Result, error) {
+// searching filings through an exchange adapter instead of the paginated News
+// API. This replaces the MaxPages loop for both daily runs and historical
+// backfills.
+func (s *Scraper) RunByDateRange(ctx context.Context, ex exchange.Exchange, from, to time.Time, opts exchange.SearchOptions) (*Result, error) {
 	result := &Result{}
 
-	log.Printf("Searching HKEX: %s to %s (market: %s)",
-		from.Format("2006-01-02"), to.Format("2006-01-02"), market)
+	log.Printf("Searching %s: %s to %s (market: %s)", ex.Name(),
+		from.Format("2006-01-02"), to.Format("2006-01-02"), opts.Market)
 
-	searchResults, err := s.client.FetchByDateRange(from, to, market)
+	filings, err := ex.SearchFilings(ctx, from, to, opts)
 	if err != nil {
-		return nil, fmt.Errorf("searching HKEX: %w", err)
+		return nil, err
 	}
 
-	result.TotalAnnouncements = len(searchResults)
+	result.TotalAnnouncements = len(filings)
 	log.Printf("Found %d announcements in date range", result.TotalAnnouncements)
 
-	for i := range searchResults {
-		sr := &searchResults[i]
-
-		if sr.StockCode == "" {
-			continue
-		}
-
-		// Skip structured products (DWs, CBBCs, Inline Warrants)
-		if models.IsStructuredProduct(sr.StockCode) {
+	for _, f := range filings {
+		// Skip excluded instrument categories (by default DWs, CBBCs, Inline Warrants)
+		if s.skip(ctx, f.Company, f.Filing.ReportDate, f.Class) {
 			result.Skipped++
 			continue
 		}
 
 		if s.db != nil {
-			if err := s.persistSearchResult(ctx, sr, result); err != nil {
-				log.Printf("Error persisting search result %s: %v", sr.NewsID, err)
+			if err := s.persistFiling(ctx, f, result); err != nil {
+				log.Printf("Error persisting filing %s: %v", f.Filing.SourceID, err)
 			}
 		}
 	}
 
+	if s.db != nil {
+		s.pairTranslations(ctx, ex.Name(), from, to, result)
+	}
+
 	return result, nil
 }
 
-// persistSearchResult saves a SearchResult and its company to the database
-func (s *Scraper) persistSearchResult(ctx context.Context, sr *api.SearchResult, result *Result) error {
-	// Get or create company
-	company, err := s.db.GetCompanyByStockCode(ctx, sr.StockCode)
+// skip reports whether filings of template's stock code on asOf are excluded
+// by instrument category. Codes missing from the securities list fall back to
+// the category stored on their company before the stock code range.
+func (s *Scraper) skip(ctx context.Context, template *models.Company, asOf time.Time, cls securities.Classification) bool {
+	if s.db != nil {
+		var err error
+		if cls, err = identity.Classify(ctx, s.db, template, asOf, cls); err != nil {
+			log.Printf("Error classifying %s: %v", template.StockCode, err)
+		}
+	}
+	return s.filter.Skip(cls)
+}
+
+// pairTranslations pairs the language versions of the filings released
+// between from and to
+func (s *Scraper) pairTranslations(ctx context.Context, exchangeName string, from, to time.Time, result *Result) {
+	paired, err := translation.Link(ctx, s.db, exchangeName, from, to)
 	if err != nil {
-		return fmt.Errorf("getting company: %w", err)
+		log.Printf("Error pairing translations: %v", err)
+		result.Errors++
 	}
+	result.TranslationsPaired += paired
+}
 
-	if company == nil {
-		company = &models.Company{
-			ID:          sr.StockCode,
-			StockCode:   sr.StockCode,
-			CompanyName: sr.StockName,
-			MarketType:  models.MarketTypeSEHK,
-			Exchange:    "HKEX",
+// releaseRange returns the earliest and latest release times of announcements
+func releaseRange(anns []models.Announcement) (time.Time, time.Time) {
+	var from, to time.Time
+	for i := range anns {
+		f := models.AnnouncementToFiling(&anns[i], "")
+		t := f.ReportDate
+		if f.ReleasedAt != nil {
+			t = *f.ReleasedAt
 		}
-		if models.IsEnglishText(sr.StockName) {
-			company.CompanyNameEn = sr.StockName
+		if from.IsZero() || t.Before(from) {
+			from = t
 		}
-		if err := s.db.UpsertCompany(ctx, company); err != nil {
-			return fmt.Errorf("creating company: %w", err)
+		if t.After(to) {
+			to = t
 		}
-		log.Printf("Created company: %s (%s)", company.StockCode, company.CompanyName)
 	}
+	return from, to
+}
+
+// persistFiling saves an adapter filing and its company to the database
+func (s *Scraper) persistFiling(ctx context.Context, f exchange.Filing, result *Result) error {
+	filing := f.Filing
+
+	// Get or create the company that held this stock code at the time
+	company, created, err := identity.Resolve(ctx, s.db, f.Company, filing.ReportDate)
+	if err != nil {
+		return fmt.Errorf("resolving company: %w", err)
+	}
+	if created {
+		log.Printf("Created company: %s (%s)", company.ID, company.CompanyName)
+	}
+	filing.CompanyID = company.ID
 
 	// Check if filing already exists
-	existing, err := s.db.GetFilingBySourceID(ctx, "HKEX", sr.NewsID)
+	existing, err := s.db.GetFilingBySourceID(ctx, filing.Exchange, filing.SourceID)
 	if err != nil {
 		return fmt.Errorf("checking existing filing: %w", err)
 	}
 
-	// Convert search result to filing
-	filing := models.SearchResultToFiling(
-		sr.NewsID, sr.Title, sr.StockCode, sr.StockName,
-		sr.DateTime, sr.FileType, sr.FileInfo, sr.FileLink,
-		sr.LongText, company.ID,
-	)
-
+	var revisions []models.FilingRevision
 	if existing != nil {
-		filing.ID = existing.ID
-		filing.CreatedAt = existing.CreatedAt
+		revisions = models.Revise(existing, filing)
 		result.UpdatedFilings++
 	} else {
 		result.NewFilings++
@@ -285,6 +340,22 @@ func (s *Scraper) persistSearchResult(ctx context.Context, sr *api.SearchResult,
 		return fmt.Errorf("saving filing: %w", err)
 	}
 
+	return s.recordRevisions(ctx, revisions, result)
+}
+
+// recordRevisions saves the changed fields of an updated filing
+func (s *Scraper) recordRevisions(ctx context.Context, revisions []models.FilingRevision, result *Result) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	if err := s.db.InsertRevisions(ctx, revisions); err != nil {
+		return fmt.Errorf("recording revisions: %w", err)
+	}
+	for _, r := range revisions {
+		log.Printf("Filing %s changed %s: %q -> %q", r.SourceID, r.Field, r.OldValue, r.NewValue)
+	}
+	result.RevisedFilings++
+	result.Revisions = append(result.Revisions, revisions...)
 	return nil
 }
 
@@ -0,0 +1,182 @@
+// Package translation pairs the English and Chinese versions of the same
+// announcement.
+//
+// HKEX publishes each language version of an announcement under its own
+// NEWS_ID, so the scraper stores them as separate filings. Versions of one
+// announcement share the company, release minute and category; Chinese
+// documents are named with a "_c" suffix. When a company releases several
+// announcements of one category in the same minute, versions are matched
+// by file size. Paired filings record each other's source ID in
+// translation_of, so downstream processing can skip one of them.
+package translation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// Store defines the database methods needed to pair filings. Implemented by
+// the SQLite database and the Lambda's PostgreSQL wrapper.
+type Store interface {
+	GetUnpairedFilings(ctx context.Context, exchange string, from, to time.Time) ([]models.Filing, error)
+	SetTranslation(ctx context.Context, exchange, sourceID, translationSourceID string) error
+}
+
+// separateVersions lists the exchanges that publish language versions as
+// separate filings
+var separateVersions = map[string]bool{
+	"HKEX": true,
+}
+
+// maxSizeRatio is the largest ratio between the file sizes of two versions
+// of one announcement
+const maxSizeRatio = 3.0
+
+// Pair is the English and Chinese version of an announcement
+type Pair struct {
+	EN *models.Filing
+	ZH *models.Filing
+}
+
+// Link pairs the unpaired filings of an exchange released between from and
+// to and records the pairs. It returns the number of pairs recorded.
+func Link(ctx context.Context, store Store, exchange string, from, to time.Time) (int, error) {
+	if !separateVersions[exchange] {
+		return 0, nil
+	}
+
+	// Versions share their release minute, so include the start of it
+	filings, err := store.GetUnpairedFilings(ctx, exchange, from.Truncate(time.Minute), to)
+	if err != nil {
+		return 0, fmt.Errorf("getting unpaired filings: %w", err)
+	}
+
+	linked := 0
+	for _, p := range Match(filings) {
+		if err := store.SetTranslation(ctx, exchange, p.EN.SourceID, p.ZH.SourceID); err != nil {
+			return linked, fmt.Errorf("pairing %s and %s: %w", p.EN.SourceID, p.ZH.SourceID, err)
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// Match returns the English and Chinese versions among filings. Filings are
+// grouped by exchange, company, release minute and category; a group with
+// one version of each language is paired unless their sizes differ too
+// much, and larger groups are paired by closest file size.
+func Match(filings []models.Filing) []Pair {
+	type key struct {
+		exchange, company string
+		minute            int64
+		typ, subType      string
+	}
+	type group struct {
+		en, zh []*models.Filing
+	}
+
+	groups := make(map[key]*group)
+	var keys []key
+	for i := range filings {
+		f := &filings[i]
+		k := key{f.Exchange, f.CompanyID, releaseTime(f).Truncate(time.Minute).Unix(), f.FilingType, f.FilingSubType}
+		g, ok := groups[k]
+		if !ok {
+			g = &group{}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+		switch Language(f) {
+		case models.LanguageEN:
+			g.en = append(g.en, f)
+		case models.LanguageZH:
+			g.zh = append(g.zh, f)
+		}
+	}
+
+	var pairs []Pair
+	for _, k := range keys {
+		g := groups[k]
+		if len(g.en) == 0 || len(g.zh) == 0 {
+			continue
+		}
+
+		// A lone pair only needs plausible sizes, which may be unknown
+		if len(g.en) == 1 && len(g.zh) == 1 {
+			if r, ok := sizeRatio(g.en[0], g.zh[0]); !ok || r <= maxSizeRatio {
+				pairs = append(pairs, Pair{EN: g.en[0], ZH: g.zh[0]})
+			}
+			continue
+		}
+
+		// Otherwise pair the closest sizes first
+		type candidate struct {
+			en, zh *models.Filing
+			ratio  float64
+		}
+		var cands []candidate
+		for _, en := range g.en {
+			for _, zh := range g.zh {
+				if r, ok := sizeRatio(en, zh); ok && r <= maxSizeRatio {
+					cands = append(cands, candidate{en, zh, r})
+				}
+			}
+		}
+		sort.Slice(cands, func(i, j int) bool {
+			if cands[i].ratio != cands[j].ratio {
+				return cands[i].ratio < cands[j].ratio
+			}
+			if cands[i].en.SourceID != cands[j].en.SourceID {
+				return cands[i].en.SourceID < cands[j].en.SourceID
+			}
+			return cands[i].zh.SourceID < cands[j].zh.SourceID
+		})
+
+		used := make(map[*models.Filing]bool)
+		for _, c := range cands {
+			if used[c.en] || used[c.zh] {
+				continue
+			}
+			used[c.en], used[c.zh] = true, true
+			pairs = append(pairs, Pair{EN: c.en, ZH: c.zh})
+		}
+	}
+	return pairs
+}
+
+// Language returns the language of a filing's document. HKEX names Chinese
+// documents with a "_c" suffix, e.g. "2024032800124_c.pdf"; otherwise the
+// language guessed from the title is used.
+func Language(f *models.Filing) models.Language {
+	name := path.Base(f.SourceURL)
+	name = strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	if strings.HasSuffix(name, "_c") {
+		return models.LanguageZH
+	}
+	return f.Language
+}
+
+// releaseTime returns the release time of a filing, or its report date if the
+// release time is unknown
+func releaseTime(f *models.Filing) time.Time {
+	if f.ReleasedAt != nil {
+		return *f.ReleasedAt
+	}
+	return f.ReportDate
+}
+
+// sizeRatio returns the ratio of the larger to the smaller file size of two
+// filings, and false if either size is unknown
+func sizeRatio(a, b *models.Filing) (float64, bool) {
+	if a.FileSize <= 0 || b.FileSize <= 0 {
+		return 0, false
+	}
+	return math.Max(float64(a.FileSize), float64(b.FileSize)) / math.Min(float64(a.FileSize), float64(b.FileSize)), true
+}
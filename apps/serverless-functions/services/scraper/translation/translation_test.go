@@ -0,0 +1,112 @@
+package translation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// memStore is an in-memory Store for tests
+type memStore struct {
+	filings map[string]*models.Filing
+}
+
+func (m *memStore) GetUnpairedFilings(ctx context.Context, exchange string, from, to time.Time) ([]models.Filing, error) {
+	var out []models.Filing
+	for _, f := range m.filings {
+		if f.Exchange == exchange && f.TranslationOf == "" && !f.ReportDate.Before(from) && !f.ReportDate.After(to) {
+			out = append(out, *f)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) SetTranslation(ctx context.Context, exchange, sourceID, translationSourceID string) error {
+	m.filings[sourceID].TranslationOf = translationSourceID
+	m.filings[translationSourceID].TranslationOf = sourceID
+	return nil
+}
+
+var released = time.Date(2024, 3, 28, 16, 30, 0, 0, time.UTC)
+
+func filing(sourceID, company, file string, size int, at time.Time) models.Filing {
+	return models.Filing{
+		SourceID:      sourceID,
+		Exchange:      "HKEX",
+		CompanyID:     company,
+		FilingType:    "Announcements and Notices",
+		FilingSubType: "13300",
+		ReportDate:    at,
+		SourceURL:     "https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/" + file,
+		FileSize:      size,
+		Language:      models.LanguageEN,
+	}
+}
+
+func TestMatch(t *testing.T) {
+	filings := []models.Filing{
+		// One announcement, released a few seconds apart
+		filing("100", "00005", "2024032800100.pdf", 200000, released),
+		filing("101", "00005", "2024032800101_c.pdf", 150000, released.Add(20*time.Second)),
+
+		// Two announcements of one company in the same minute, told apart by size
+		filing("200", "00700", "2024032800200.pdf", 900000, released),
+		filing("201", "00700", "2024032800201.pdf", 60000, released),
+		filing("202", "00700", "2024032800202_c.pdf", 50000, released),
+		filing("203", "00700", "2024032800203_c.pdf", 700000, released),
+
+		// Another company, and another minute, are not versions
+		filing("300", "00939", "2024032800300_c.pdf", 150000, released),
+		filing("400", "00005", "2024032800400_c.pdf", 150000, released.Add(time.Minute)),
+	}
+
+	want := map[string]string{"100": "101", "200": "203", "201": "202"}
+	pairs := Match(filings)
+	if len(pairs) != len(want) {
+		t.Fatalf("got %d pairs, want %d", len(pairs), len(want))
+	}
+	for _, p := range pairs {
+		if want[p.EN.SourceID] != p.ZH.SourceID {
+			t.Errorf("paired %s with %s", p.EN.SourceID, p.ZH.SourceID)
+		}
+	}
+}
+
+func TestMatch_SizeMismatch(t *testing.T) {
+	filings := []models.Filing{
+		filing("100", "00005", "2024032800100.pdf", 2000000, released),
+		filing("101", "00005", "2024032800101_c.pdf", 100000, released),
+	}
+	if pairs := Match(filings); len(pairs) != 0 {
+		t.Errorf("got %d pairs, want 0", len(pairs))
+	}
+}
+
+func TestLink(t *testing.T) {
+	store := &memStore{filings: make(map[string]*models.Filing)}
+	for _, f := range []models.Filing{
+		filing("100", "00005", "2024032800100.pdf", 200000, released),
+		filing("101", "00005", "2024032800101_c.pdf", 0, released),
+	} {
+		f := f
+		store.filings[f.SourceID] = &f
+	}
+
+	n, err := Link(context.Background(), store, "HKEX", released.Add(30*time.Second), released.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("linked %d pairs, want 1", n)
+	}
+	if store.filings["100"].TranslationOf != "101" || store.filings["101"].TranslationOf != "100" {
+		t.Errorf("translation_of not recorded both ways")
+	}
+
+	// Paired filings are not paired again
+	if n, _ := Link(context.Background(), store, "HKEX", released, released.Add(time.Hour)); n != 0 {
+		t.Errorf("linked %d pairs on rerun, want 0", n)
+	}
+}
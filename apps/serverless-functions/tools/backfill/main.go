@@ -7,30 +7,41 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
-	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/dart"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkexapp"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/mops"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/identity"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/translation"
 )
 
 func main() {
 	// Parse command line flags
 	fromDate := flag.String("from", "", "Start date (YYYY-MM-DD)")
 	toDate := flag.String("to", "", "End date (YYYY-MM-DD)")
-	market := flag.String("market", "SEHK", "Market: SEHK or GEM")
+	exchangeName := flag.String("exchange", "HKEX", "Exchange adapter to backfill")
+	market := flag.String("market", "", "Exchange-specific market, e.g. SEHK or GEM for HKEX (default: exchange default)")
 	dryRun := flag.Bool("dry-run", false, "Don't save to database, just show what would be fetched")
 	flag.Parse()
 
 	// Validate dates
 	if *fromDate == "" || *toDate == "" {
-		fmt.Println("Usage: backfill -from YYYY-MM-DD -to YYYY-MM-DD [-market SEHK|GEM] [-dry-run]")
+		fmt.Println("Usage: backfill -from YYYY-MM-DD -to YYYY-MM-DD [-exchange NAME] [-market MARKET] [-dry-run]")
 		fmt.Println("\nOptions:")
 		fmt.Println("  -from       Start date (required)")
 		fmt.Println("  -to         End date (required)")
-		fmt.Println("  -market     Market: SEHK or GEM (default: SEHK)")
+		fmt.Printf("  -exchange   Exchange: %s (default: HKEX)\n", strings.Join(exchange.Names(), ", "))
+		fmt.Println("  -market     Exchange-specific market, e.g. SEHK or GEM for HKEX")
 		fmt.Println("  -dry-run    Preview only, don't save to database")
 		fmt.Println("\nExamples:")
 		fmt.Println("  backfill -from 2020-01-01 -to 2020-12-31")
@@ -66,7 +77,10 @@ func main() {
 	}()
 
 	cfg := config.Load()
-	searchClient := api.NewSearchClient(cfg)
+	ex, err := exchange.Open(*exchangeName, cfg)
+	if err != nil {
+		log.Fatalf("Failed to open exchange: %v", err)
+	}
 
 	// Initialize database
 	var db *database.DB
@@ -80,8 +94,12 @@ func main() {
 		log.Println("Database initialized successfully")
 	}
 
+	// The adapter classifies instruments; historical codes missing from
+	// today's securities list fall back to stock code ranges.
+	filter := securities.NewFilter(cfg.SkipCategories)
+
 	// Run backfill
-	result, err := runBackfill(ctx, searchClient, db, from, to, *market, *dryRun)
+	result, err := runBackfill(ctx, ex, db, filter, from, to, *market, *dryRun)
 	if err != nil {
 		log.Fatalf("Backfill error: %v", err)
 	}
@@ -90,11 +108,16 @@ func main() {
 	fmt.Println()
 	fmt.Println("=== Backfill Complete ===")
 	fmt.Printf("Date range:      %s to %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
-	fmt.Printf("Market:          %s\n", *market)
+	fmt.Printf("Exchange:        %s\n", ex.Name())
+	if *market != "" {
+		fmt.Printf("Market:          %s\n", *market)
+	}
 	fmt.Printf("Total fetched:   %d\n", result.TotalFetched)
 	fmt.Printf("New filings:     %d\n", result.NewFilings)
 	fmt.Printf("Updated filings: %d\n", result.UpdatedFilings)
+	fmt.Printf("Revised filings: %d\n", result.RevisedFilings)
 	fmt.Printf("New companies:   %d\n", result.NewCompanies)
+	fmt.Printf("Translations:    %d\n", result.Paired)
 	fmt.Printf("Errors:          %d\n", result.Errors)
 
 	if db != nil {
@@ -117,15 +140,17 @@ type BackfillResult struct {
 	TotalFetched   int
 	NewFilings     int
 	UpdatedFilings int
+	RevisedFilings int
 	NewCompanies   int
+	Paired         int
 	Errors         int
 }
 
-func runBackfill(ctx context.Context, client *api.SearchClient, db *database.DB, from, to time.Time, market string, dryRun bool) (*BackfillResult, error) {
+func runBackfill(ctx context.Context, ex exchange.Exchange, db *database.DB, filter *securities.Filter, from, to time.Time, market string, dryRun bool) (*BackfillResult, error) {
 	result := &BackfillResult{}
 
-	log.Printf("Starting backfill from %s to %s for market %s",
-		from.Format("2006-01-02"), to.Format("2006-01-02"), market)
+	log.Printf("Starting %s backfill from %s to %s", ex.Name(),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
 
 	// Process month by month with large rowRange to get all results
 	current := from
@@ -144,18 +169,9 @@ func runBackfill(ctx context.Context, client *api.SearchClient, db *database.DB,
 		}
 
 		log.Printf("Fetching %s %s to %s...",
-			market, current.Format("2006-01"), chunkEnd.Format("2006-01-02"))
-
-		params := api.SearchParams{
-			FromDate: current,
-			ToDate:   chunkEnd,
-			Market:   market,
-			Category: -2,
-			RowRange: 50000, // Increased to handle high-volume months
-			SortDir:  0,
-		}
+			ex.Name(), current.Format("2006-01"), chunkEnd.Format("2006-01-02"))
 
-		results, err := client.SearchAll(params)
+		results, err := ex.SearchFilings(ctx, current, chunkEnd, exchange.SearchOptions{Market: market})
 		if err != nil {
 			log.Printf("Error fetching %s: %v", current.Format("2006-01"), err)
 			result.Errors++
@@ -170,11 +186,19 @@ func runBackfill(ctx context.Context, client *api.SearchClient, db *database.DB,
 		if !dryRun && db != nil {
 			// Process and save results
 			for _, r := range results {
-				if err := processSearchResult(ctx, db, &r, result); err != nil {
-					log.Printf("  Error processing %s: %v", r.NewsID, err)
+				if err := processFiling(ctx, db, filter, r, result); err != nil {
+					log.Printf("  Error processing %s: %v", r.Filing.SourceID, err)
 					result.Errors++
 				}
 			}
+
+			// Pair the language versions of the month's announcements
+			paired, err := translation.Link(ctx, db, ex.Name(), current, chunkEnd)
+			if err != nil {
+				log.Printf("  Error pairing translations: %v", err)
+				result.Errors++
+			}
+			result.Paired += paired
 		}
 
 		// Move to next month
@@ -184,74 +208,49 @@ func runBackfill(ctx context.Context, client *api.SearchClient, db *database.DB,
 	return result, nil
 }
 
-func processSearchResult(ctx context.Context, db *database.DB, r *api.SearchResult, result *BackfillResult) error {
-	// Skip if no stock code (some announcements are market-wide)
-	if r.StockCode == "" {
-		return nil
-	}
+func processFiling(ctx context.Context, db *database.DB, filter *securities.Filter, r exchange.Filing, result *BackfillResult) error {
+	// The filing carries its SourceURL for later download
+	filing := r.Filing
 
-	// Skip structured products (DWs, CBBCs, Inline Warrants)
-	if models.IsStructuredProduct(r.StockCode) {
+	// Skip excluded instrument categories (by default DWs, CBBCs, Inline
+	// Warrants), by the company's stored category if the list lacks the code
+	class, err := identity.Classify(ctx, db, r.Company, filing.ReportDate, r.Class)
+	if err != nil {
+		return fmt.Errorf("classifying: %w", err)
+	}
+	if filter.Skip(class) {
 		return nil
 	}
 
-	// Get or create company
-	company, err := db.GetCompanyByStockCode(ctx, r.StockCode)
+	// Get or create the company that held this stock code on the report date.
+	// Stock codes are recycled after delistings, so the code alone is ambiguous.
+	company, created, err := identity.Resolve(ctx, db, r.Company, filing.ReportDate)
 	if err != nil {
-		return fmt.Errorf("getting company: %w", err)
+		return fmt.Errorf("resolving company: %w", err)
 	}
-
-	if company == nil {
-		// Create new company - use stock code as ID (not timestamp-based IDs)
-		company = &models.Company{
-			ID:          r.StockCode,
-			StockCode:   r.StockCode,
-			CompanyName: r.StockName,
-			MarketType:  models.MarketTypeSEHK,
-			Exchange:    "HKEX",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
-
-		// Check if name is English
-		if models.IsEnglishText(r.StockName) {
-			company.CompanyNameEn = r.StockName
-		}
-
-		if err := db.UpsertCompany(ctx, company); err != nil {
-			return fmt.Errorf("creating company: %w", err)
-		}
+	if created {
 		result.NewCompanies++
 	}
+	filing.CompanyID = company.ID
 
 	// Check if filing already exists
-	existing, err := db.GetFilingBySourceID(ctx, "HKEX", r.NewsID)
+	existing, err := db.GetFilingBySourceID(ctx, filing.Exchange, filing.SourceID)
 	if err != nil {
 		return fmt.Errorf("checking existing filing: %w", err)
 	}
 
-	// Create filing from search result (with SourceURL for later download)
-	filing := models.SearchResultToFiling(
-		r.NewsID,
-		r.Title,
-		r.StockCode,
-		r.StockName,
-		r.DateTime,
-		r.FileType,
-		r.FileInfo,
-		r.FileLink,
-		r.LongText,
-		company.ID,
-	)
-
+	var revisions []models.FilingRevision
 	if existing != nil {
 		// Update existing filing
 		filing.ID = existing.ID
 		filing.CreatedAt = existing.CreatedAt
-		// Preserve download status if already downloaded
-		if existing.ProcessingStatus == models.ProcessingStatusCompleted {
+		revisions = models.Revisions(existing, filing)
+		// Preserve download status if already downloaded, unless the
+		// document changed and has to be downloaded again
+		if existing.ProcessingStatus == models.ProcessingStatusCompleted && !models.DocumentChanged(revisions) {
 			filing.ProcessingStatus = existing.ProcessingStatus
 			filing.LocalPath = existing.LocalPath
+			filing.PDFS3Key = existing.PDFS3Key
 		}
 		result.UpdatedFilings++
 	} else {
@@ -263,5 +262,15 @@ func processSearchResult(ctx context.Context, db *database.DB, r *api.SearchResu
 		return fmt.Errorf("saving filing: %w", err)
 	}
 
+	if len(revisions) > 0 {
+		if err := db.InsertRevisions(ctx, revisions); err != nil {
+			return fmt.Errorf("recording revisions: %w", err)
+		}
+		for _, r := range revisions {
+			log.Printf("Filing %s changed %s: %q -> %q", r.SourceID, r.Field, r.OldValue, r.NewValue)
+		}
+		result.RevisedFilings++
+	}
+
 	return nil
 }
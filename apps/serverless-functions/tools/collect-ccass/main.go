@@ -0,0 +1,348 @@
+// collect-ccass records daily CCASS participant shareholdings of HKEX
+// stocks and the day-over-day change of each participant's holding.
+//
+// Each stock code and date is one search, made at the scraper's rate limit
+// (HKEX_RATE_LIMIT). Snapshots already collected are skipped, so a failed
+// run can be repeated. Dates are collected in order and each snapshot is
+// compared with the stock's latest earlier snapshot in ccass_snapshots.
+//
+// Usage:
+//
+//	go run ./tools/collect-ccass                                  # previous weekday
+//	go run ./tools/collect-ccass -from 2024-03-01 -to 2024-03-28
+//	go run ./tools/collect-ccass -codes 00005,00700 -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/ccass"
+)
+
+// target is a stock code to collect
+type target struct {
+	companyID string
+	stockCode models.StockCode
+}
+
+func main() {
+	fromFlag := flag.String("from", "", "First shareholding date, YYYY-MM-DD (default: previous weekday)")
+	toFlag := flag.String("to", "", "Last shareholding date, YYYY-MM-DD (default: -from)")
+	codesFlag := flag.String("codes", "", "Comma-separated stock codes to collect (default: all HKEX companies)")
+	dryRun := flag.Bool("dry-run", false, "Fetch and print changes without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	from, to, err := parseRange(*fromFlag, *toFlag)
+	if err != nil {
+		log.Fatalf("Invalid date range: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	cfg := config.Load()
+	targets, err := loadTargets(ctx, pool, securities.SkipCategories(cfg.SkipCategories), parseCodes(*codesFlag))
+	if err != nil {
+		log.Fatalf("Failed to load companies: %v", err)
+	}
+
+	client := ccass.NewClient(cfg.CCASSBaseURL, ratelimit.ForHost(cfg.CCASSBaseURL, cfg.RateLimit))
+
+	dates, snapshots, holdings, changes, failed := 0, 0, 0, 0, 0
+	for date := from; !date.After(to) && ctx.Err() == nil; date = date.AddDate(0, 0, 1) {
+		if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		dates++
+
+		collected, err := collectedCodes(ctx, pool, date)
+		if err != nil {
+			log.Fatalf("Failed to load collected snapshots: %v", err)
+		}
+		log.Printf("%s: collecting %d stocks (%d already collected)", date.Format("2006-01-02"), len(targets)-len(collected), len(collected))
+
+		for _, t := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+			if collected[t.stockCode] {
+				continue
+			}
+
+			s, err := client.Holdings(ctx, t.stockCode.String(), date)
+			if err != nil {
+				log.Printf("Error collecting %s: %v", t.stockCode, err)
+				failed++
+				continue
+			}
+
+			prev, err := loadPrevious(ctx, pool, t.stockCode, date)
+			if err != nil {
+				log.Printf("Error loading previous snapshot of %s: %v", t.stockCode, err)
+				failed++
+				continue
+			}
+			var diff []models.CCASSChange
+			if prev != nil {
+				diff = ccass.Changes(prev, s)
+			}
+
+			if *dryRun {
+				for _, c := range diff {
+					fmt.Printf("  %s %s  %-10s %-40s %+d\n", t.stockCode, date.Format("2006-01-02"), c.ParticipantID, c.ParticipantName, c.Change)
+				}
+			} else if err := saveSnapshot(ctx, pool, t.companyID, s, diff); err != nil {
+				log.Printf("Error saving %s: %v", t.stockCode, err)
+				failed++
+				continue
+			}
+
+			snapshots++
+			holdings += len(s.Holdings)
+			changes += len(diff)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== CCASS Collection Complete ===")
+	fmt.Printf("Dates:           %d\n", dates)
+	fmt.Printf("Stocks:          %d\n", len(targets))
+	fmt.Printf("Snapshots:       %d\n", snapshots)
+	fmt.Printf("Holdings:        %d\n", holdings)
+	fmt.Printf("Changes:         %d\n", changes)
+	fmt.Printf("Errors:          %d\n", failed)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// loadTargets returns the current stock codes of HKEX companies, leaving
+// out the instrument categories the scraper skips
+func loadTargets(ctx context.Context, pool *pgxpool.Pool, skip []string, codes map[models.StockCode]bool) ([]target, error) {
+	var listings bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('company_listings') IS NOT NULL`).Scan(&listings); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.company_id, c.stock_code
+		FROM companies c
+		WHERE c.exchange = 'HKEX'
+			AND NOT (COALESCE(c.instrument_category, '') = ANY($1) OR COALESCE(c.instrument_sub_category, '') = ANY($1))
+		ORDER BY c.stock_code
+	`
+	if listings {
+		// Companies whose listings have all ended no longer own their code
+		query = `
+			SELECT c.company_id, COALESCE(l.stock_code, c.stock_code)
+			FROM companies c
+			LEFT JOIN company_listings l
+				ON l.exchange = c.exchange AND l.company_id = c.company_id AND l.listed_to IS NULL
+			WHERE c.exchange = 'HKEX'
+				AND NOT (COALESCE(c.instrument_category, '') = ANY($1) OR COALESCE(c.instrument_sub_category, '') = ANY($1))
+				AND (l.company_id IS NOT NULL OR NOT EXISTS (
+					SELECT 1 FROM company_listings e WHERE e.exchange = c.exchange AND e.company_id = c.company_id
+				))
+			ORDER BY 2
+		`
+	}
+
+	rows, err := pool.Query(ctx, query, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []target
+	for rows.Next() {
+		var t target
+		var code string
+		if err := rows.Scan(&t.companyID, &code); err != nil {
+			return nil, err
+		}
+		t.stockCode = models.NormalizeStockCode("HKEX", code)
+		if len(codes) > 0 && !codes[t.stockCode] {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+// collectedCodes returns the stock codes with a snapshot on date
+func collectedCodes(ctx context.Context, pool *pgxpool.Pool, date time.Time) (map[models.StockCode]bool, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT stock_code FROM ccass_snapshots WHERE exchange = 'HKEX' AND holding_date = $1
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collected := make(map[models.StockCode]bool)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		collected[models.NormalizeStockCode("HKEX", code)] = true
+	}
+	return collected, rows.Err()
+}
+
+// loadPrevious returns the latest snapshot of a stock before date, or nil
+// if there is none
+func loadPrevious(ctx context.Context, pool *pgxpool.Pool, stockCode models.StockCode, date time.Time) (*ccass.Snapshot, error) {
+	s := &ccass.Snapshot{StockCode: stockCode}
+	err := pool.QueryRow(ctx, `
+		SELECT holding_date, COALESCE(issued_shares, 0) FROM ccass_snapshots
+		WHERE exchange = 'HKEX' AND stock_code = $1 AND holding_date < $2
+		ORDER BY holding_date DESC LIMIT 1
+	`, stockCode, date).Scan(&s.HoldingDate, &s.IssuedShares)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT participant_id, participant_name, COALESCE(address, ''), shareholding, COALESCE(percent, 0)
+		FROM ccass_holdings
+		WHERE exchange = 'HKEX' AND stock_code = $1 AND holding_date = $2
+	`, stockCode, s.HoldingDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h := models.CCASSHolding{Exchange: "HKEX", StockCode: stockCode, HoldingDate: s.HoldingDate}
+		if err := rows.Scan(&h.ParticipantID, &h.ParticipantName, &h.Address, &h.Shareholding, &h.Percent); err != nil {
+			return nil, err
+		}
+		s.Holdings = append(s.Holdings, h)
+	}
+	return s, rows.Err()
+}
+
+// saveSnapshot writes a snapshot with its holdings and changes
+func saveSnapshot(ctx context.Context, pool *pgxpool.Pool, companyID string, s *ccass.Snapshot, changes []models.CCASSChange) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ccass_snapshots (exchange, stock_code, holding_date, company_id, issued_shares, participants)
+		VALUES ('HKEX', $1, $2, $3, $4, $5)
+	`, s.StockCode, s.HoldingDate, companyID, s.IssuedShares, len(s.Holdings)); err != nil {
+		return fmt.Errorf("inserting snapshot: %w", err)
+	}
+
+	holdingRows := make([][]any, len(s.Holdings))
+	for i, h := range s.Holdings {
+		holdingRows[i] = []any{"HKEX", h.StockCode, h.HoldingDate, h.ParticipantID, h.ParticipantName, h.Address, h.Shareholding, h.Percent}
+	}
+	if _, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"ccass_holdings"},
+		[]string{"exchange", "stock_code", "holding_date", "participant_id", "participant_name", "address", "shareholding", "percent"},
+		pgx.CopyFromRows(holdingRows),
+	); err != nil {
+		return fmt.Errorf("copying holdings: %w", err)
+	}
+
+	changeRows := make([][]any, len(changes))
+	for i, c := range changes {
+		changeRows[i] = []any{"HKEX", c.StockCode, c.HoldingDate, c.PreviousDate, c.ParticipantID, c.ParticipantName,
+			c.SharesBefore, c.SharesAfter, c.Change, c.PercentBefore, c.PercentAfter}
+	}
+	if _, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"ccass_changes"},
+		[]string{"exchange", "stock_code", "holding_date", "previous_date", "participant_id", "participant_name",
+			"shares_before", "shares_after", "change", "percent_before", "percent_after"},
+		pgx.CopyFromRows(changeRows),
+	); err != nil {
+		return fmt.Errorf("copying changes: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// parseRange parses the -from and -to flags. Holdings are published after
+// the close, so the default is the weekday before today in Hong Kong.
+func parseRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	var from time.Time
+	if fromStr == "" {
+		from = time.Now().UTC().Add(8*time.Hour).Truncate(24*time.Hour).AddDate(0, 0, -1)
+		for from.Weekday() == time.Saturday || from.Weekday() == time.Sunday {
+			from = from.AddDate(0, 0, -1)
+		}
+	} else {
+		var err error
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return from, from, err
+		}
+	}
+
+	to := from
+	if toStr != "" {
+		var err error
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return from, to, err
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("-to is before -from")
+	}
+	return from, to, nil
+}
+
+// parseCodes parses the -codes flag into normalized stock codes
+func parseCodes(s string) map[models.StockCode]bool {
+	codes := make(map[models.StockCode]bool)
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			codes[models.NormalizeStockCode("HKEX", c)] = true
+		}
+	}
+	return codes
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
+)
+
+// recordHistory stores the list as a dated snapshot, diffs it against the
+// previous snapshot and prints a report of the resulting security events.
+// When write is false the snapshot and events are only reported.
+func recordHistory(ctx context.Context, pool *pgxpool.Pool, list *securities.List, write bool, reportPath string) error {
+	date := list.UpdatedAt
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	prevDate, prev, err := loadPreviousSnapshot(ctx, pool, date)
+	if err != nil {
+		return fmt.Errorf("loading previous snapshot: %w", err)
+	}
+
+	var events []securities.Event
+	if prevDate.IsZero() {
+		log.Printf("No snapshot before %s; recording baseline", date.Format("2006-01-02"))
+	} else {
+		events = securities.Diff(prev, list.Securities)
+		log.Printf("Diffed %s against %s: %d events", date.Format("2006-01-02"), prevDate.Format("2006-01-02"), len(events))
+	}
+
+	fmt.Println()
+	fmt.Printf("=== Security Events %s ===\n\n", date.Format("2006-01-02"))
+	if err := securities.WriteReport(os.Stdout, events); err != nil {
+		return err
+	}
+	if reportPath != "" {
+		f, err := os.Create(reportPath)
+		if err != nil {
+			return fmt.Errorf("creating report: %w", err)
+		}
+		defer f.Close()
+		if err := securities.WriteReport(f, events); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	}
+
+	if !write {
+		return nil
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := saveSnapshot(ctx, tx, date, list.Securities); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	for _, e := range events {
+		if err := applyEvent(ctx, tx, date, e); err != nil {
+			return fmt.Errorf("applying %s event for %s: %w", e.Type, e.StockCode, err)
+		}
+	}
+	if prevDate.IsZero() {
+		if err := seedNameHistory(ctx, tx, list.Securities); err != nil {
+			return fmt.Errorf("seeding name history: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// loadPreviousSnapshot returns the latest snapshot taken before date, or a
+// zero date if there is none
+func loadPreviousSnapshot(ctx context.Context, pool *pgxpool.Pool, date time.Time) (time.Time, []Security, error) {
+	// Dry runs don't create the table
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('security_snapshots') IS NOT NULL`).Scan(&exists); err != nil || !exists {
+		return time.Time{}, nil, err
+	}
+
+	var prevDate *time.Time
+	err := pool.QueryRow(ctx, `
+		SELECT MAX(snapshot_date) FROM security_snapshots
+		WHERE exchange = 'HKEX' AND snapshot_date < $1
+	`, date).Scan(&prevDate)
+	if err != nil || prevDate == nil {
+		return time.Time{}, nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT stock_code, name, COALESCE(category, ''), COALESCE(sub_category, ''),
+			COALESCE(board_lot, 0), COALESCE(isin, '')
+		FROM security_snapshots
+		WHERE exchange = 'HKEX' AND snapshot_date = $1
+	`, *prevDate)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer rows.Close()
+
+	var secs []Security
+	for rows.Next() {
+		var s Security
+		var code string
+		if err := rows.Scan(&code, &s.Name, &s.Category, &s.SubCategory, &s.BoardLot, &s.ISIN); err != nil {
+			return time.Time{}, nil, err
+		}
+		s.StockCode = models.NormalizeStockCode("HKEX", code)
+		secs = append(secs, s)
+	}
+	return *prevDate, secs, rows.Err()
+}
+
+// saveSnapshot replaces the snapshot for date
+func saveSnapshot(ctx context.Context, tx pgx.Tx, date time.Time, secs []Security) error {
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM security_snapshots WHERE exchange = 'HKEX' AND snapshot_date = $1
+	`, date); err != nil {
+		return err
+	}
+
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"security_snapshots"},
+		[]string{"exchange", "snapshot_date", "stock_code", "name", "category", "sub_category", "board_lot", "isin"},
+		pgx.CopyFromSlice(len(secs), func(i int) ([]any, error) {
+			s := secs[i]
+			return []any{"HKEX", date, s.StockCode, s.Name, s.Category, s.SubCategory, s.BoardLot, s.ISIN}, nil
+		}),
+	)
+	return err
+}
+
+// applyEvent records an event and updates the company it affects: renames
+// update the company name and its history, delistings close the code's open
+// listing so a later reuse of the code becomes a new company
+func applyEvent(ctx context.Context, tx pgx.Tx, date time.Time, e securities.Event) error {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO security_events (exchange, stock_code, event_date, event_type, old_value, new_value)
+		VALUES ('HKEX', $1, $2, $3, $4, $5)
+		ON CONFLICT (exchange, stock_code, event_date, event_type) DO NOTHING
+	`, e.StockCode, date, string(e.Type), e.OldValue, e.NewValue)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil // already applied by an earlier run
+	}
+
+	companyID, err := currentCompanyID(ctx, tx, e.StockCode)
+	if err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case securities.EventListed:
+		return openName(ctx, tx, companyID, e.StockCode, e.NewValue, &date)
+
+	case securities.EventRenamed:
+		if err := closeName(ctx, tx, companyID, date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE companies SET name = $2, updated_at = NOW()
+			WHERE exchange = 'HKEX' AND company_id = $1
+		`, companyID, e.NewValue); err != nil {
+			return err
+		}
+		return openName(ctx, tx, companyID, e.StockCode, e.NewValue, &date)
+
+	case securities.EventDelisted:
+		if err := closeName(ctx, tx, companyID, date); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, `
+			UPDATE company_listings SET listed_to = $2, updated_at = NOW()
+			WHERE exchange = 'HKEX' AND stock_code = $1 AND listed_to IS NULL
+		`, e.StockCode, date)
+		if err != nil || tag.RowsAffected() > 0 {
+			return err
+		}
+
+		// Companies never seen by the scraper have no listing yet
+		_, err = tx.Exec(ctx, `
+			INSERT INTO company_listings (id, company_id, exchange, stock_code, listed_to)
+			SELECT 'lst_HKEX_' || company_id, company_id, 'HKEX', $1, $2
+			FROM companies WHERE exchange = 'HKEX' AND company_id = $3
+			ON CONFLICT (id) DO NOTHING
+		`, e.StockCode, date, companyID)
+		return err
+	}
+
+	return nil
+}
+
+// currentCompanyID returns the company holding a stock code: the one with an
+// open listing, else the company identity.Resolve will create for the gap
+// after the code's last closed listing, else the company keyed by the code
+func currentCompanyID(ctx context.Context, tx pgx.Tx, code models.StockCode) (string, error) {
+	var id string
+	var listedTo *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT company_id, listed_to FROM company_listings
+		WHERE exchange = 'HKEX' AND stock_code = $1
+		ORDER BY listed_to DESC NULLS FIRST
+		LIMIT 1
+	`, code).Scan(&id, &listedTo)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return models.CompanyIDForListing(code, nil), nil
+		}
+		return "", err
+	}
+	if listedTo != nil {
+		return models.CompanyIDForListing(code, listedTo), nil
+	}
+	return id, nil
+}
+
+// openName starts a name period for a company unless one is already open
+func openName(ctx context.Context, tx pgx.Tx, companyID string, code models.StockCode, name string, from *time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO company_name_history (exchange, company_id, stock_code, name, valid_from)
+		SELECT 'HKEX', $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM company_name_history
+			WHERE exchange = 'HKEX' AND company_id = $1 AND valid_to IS NULL
+		)
+	`, companyID, code, name, from)
+	return err
+}
+
+// closeName ends a company's open name period
+func closeName(ctx context.Context, tx pgx.Tx, companyID string, to time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE company_name_history SET valid_to = $2
+		WHERE exchange = 'HKEX' AND company_id = $1 AND valid_to IS NULL
+	`, companyID, to)
+	return err
+}
+
+// seedNameHistory opens a name period of unknown start for every security in
+// the baseline snapshot
+func seedNameHistory(ctx context.Context, tx pgx.Tx, secs []Security) error {
+	for _, s := range secs {
+		companyID, err := currentCompanyID(ctx, tx, s.StockCode)
+		if err != nil {
+			return err
+		}
+		if err := openName(ctx, tx, companyID, s.StockCode, s.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,45 +4,24 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
-	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/xuri/excelize/v2"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/securities"
 )
 
-const (
-	securitiesURL = "https://www.hkex.com.hk/eng/services/trading/securities/securitieslists/ListOfSecurities.xlsx"
-	userAgent     = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
-)
-
-// excludedCategories are structured products we skip entirely.
-// DWs, CBBCs, and Inline Warrants are thousands of short-lived leveraged
-// products with repetitive boilerplate filings — they bloat storage and
-// clutter search results for fundamental researchers.
-var excludedCategories = map[string]bool{
-	"Derivative Warrants":          true,
-	"Callable Bull/Bear Contracts": true,
-	"Inline Warrants":              true,
-}
-
 // Security represents a row from the HKEX securities list.
-type Security struct {
-	StockCode   string
-	Name        string
-	Category    string // e.g. "Equity", "Debt Securities", "Real Estate Investment Trusts"
-	SubCategory string // e.g. "Equity Securities (Main Board)", "Exchange Traded Funds"
-}
+type Security = securities.Security
 
 func main() {
 	dryRun := flag.Bool("dry-run", false, "Don't write to database, just show new codes")
 	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	reportPath := flag.String("report", "", "Also write the security events report to this file")
 	flag.Parse()
 
 	dsn := *dbURL
@@ -66,20 +45,20 @@ func main() {
 
 	// 1. Download and parse the securities list
 	log.Println("Downloading HKEX List of Securities...")
-	securities, err := fetchSecuritiesList(ctx)
+	list, err := fetchSecuritiesList(ctx)
 	if err != nil {
 		log.Fatalf("Failed to fetch securities list: %v", err)
 	}
-	log.Printf("Parsed %d securities from HKEX (excluding DWs/CBBCs)", len(securities))
+	log.Printf("Parsed %d securities from HKEX (excluding DWs/CBBCs)", len(list.Securities))
 
 	// Build set of HKEX stock codes
-	hkexCodes := make(map[string]Security, len(securities))
-	for _, s := range securities {
+	hkexCodes := make(map[models.StockCode]Security, len(list.Securities))
+	for _, s := range list.Securities {
 		hkexCodes[s.StockCode] = s
 	}
 
 	// 2. Get existing codes from the database
-	var existingCodes map[string]bool
+	var existingCodes map[models.StockCode]bool
 	var pool *pgxpool.Pool
 
 	if dsn != "" {
@@ -97,30 +76,38 @@ func main() {
 	} else {
 		// Dry-run without DB — use an empty set so everything looks "new"
 		log.Println("No database connection (dry-run mode)")
-		existingCodes = make(map[string]bool)
+		existingCodes = make(map[models.StockCode]bool)
 	}
 
 	// 3. Diff: find codes in HKEX list that are not in our database
-	var newSecurities []Security
+	var newSecurities, existingSecurities []Security
 	for code, sec := range hkexCodes {
-		if !existingCodes[code] {
+		if existingCodes[code] {
+			existingSecurities = append(existingSecurities, sec)
+		} else {
 			newSecurities = append(newSecurities, sec)
 		}
 	}
 
 	if len(newSecurities) == 0 {
 		log.Println("No new stock codes found")
-		return
+	} else {
+		log.Printf("Discovered %d new stock codes", len(newSecurities))
+		fmt.Println()
+		fmt.Println("=== New Stock Codes ===")
+		for _, s := range newSecurities {
+			fmt.Printf("  %s  %-40s  [%s]\n", s.StockCode, s.Name, s.Category+": "+s.SubCategory)
+		}
 	}
 
-	log.Printf("Discovered %d new stock codes", len(newSecurities))
-	fmt.Println()
-	fmt.Println("=== New Stock Codes ===")
-	for _, s := range newSecurities {
-		fmt.Printf("  %s  %-40s  [%s]\n", s.StockCode, s.Name, s.Category+": "+s.SubCategory)
+	// 4. Snapshot the list and record changes since the previous snapshot
+	if pool != nil {
+		if err := recordHistory(ctx, pool, list, !*dryRun, *reportPath); err != nil {
+			log.Fatalf("Failed to record security history: %v", err)
+		}
 	}
 
-	// 4. Upsert new companies into the database
+	// 5. Upsert new companies into the database
 	if *dryRun || pool == nil {
 		log.Println("Dry-run mode — skipping database writes")
 		return
@@ -137,121 +124,44 @@ func main() {
 
 	fmt.Println()
 	log.Printf("Upserted %d/%d new companies into database", inserted, len(newSecurities))
-}
 
-// fetchSecuritiesList downloads the HKEX xlsx and returns filtered equity securities.
-func fetchSecuritiesList(ctx context.Context) ([]Security, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, securitiesURL, nil)
+	// 6. Refresh instrument categories of existing companies
+	updated, err := updateCategories(ctx, pool, existingSecurities)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		log.Fatalf("Failed to update categories: %v", err)
 	}
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("downloading file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
-	}
-
-	// Write to temp file (excelize needs a reader, not streaming)
-	tmp, err := os.CreateTemp("", "hkex-securities-*.xlsx")
-	if err != nil {
-		return nil, fmt.Errorf("creating temp file: %w", err)
-	}
-	defer os.Remove(tmp.Name())
-	defer tmp.Close()
-
-	if _, err := io.Copy(tmp, resp.Body); err != nil {
-		return nil, fmt.Errorf("writing temp file: %w", err)
-	}
-
-	// Parse the Excel file
-	f, err := excelize.OpenFile(tmp.Name())
-	if err != nil {
-		return nil, fmt.Errorf("opening xlsx: %w", err)
-	}
-	defer f.Close()
+	log.Printf("Updated instrument categories of %d existing companies", updated)
+}
 
-	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
+// fetchSecuritiesList downloads the HKEX securities list, dropping
+// structured products.
+func fetchSecuritiesList(ctx context.Context) (*securities.List, error) {
+	list, err := securities.Fetch(ctx, securities.ListURL)
 	if err != nil {
-		return nil, fmt.Errorf("reading rows: %w", err)
-	}
-
-	if len(rows) < 4 {
-		return nil, fmt.Errorf("unexpected spreadsheet format: only %d rows", len(rows))
+		return nil, err
 	}
 
-	// The HKEX xlsx layout has:
-	//   Row 0: "List of Securities" (title)
-	//   Row 1: "Updated as at DD/MM/YYYY" (date)
-	//   Row 2: Merged header row (not machine-parseable)
-	//   Row 3+: Data with fixed columns:
-	//     [0] Stock Code  [1] Name of Securities  [2] Category  [3] Sub-Category
-	//     [4] Board Lot   [5] ISIN                [6] Expiry Date ...
-	const (
-		colStockCode   = 0
-		colName        = 1
-		colCategory    = 2 // "Equity", "Debt Securities", "Derivative Warrants", etc.
-		colSubCategory = 3 // "Equity Securities (Main Board)", "Exchange Traded Funds", etc.
-	)
+	// DWs, CBBCs, and Inline Warrants are thousands of short-lived leveraged
+	// products with repetitive boilerplate filings — skip them entirely
+	filter := securities.NewFilter(securities.DefaultSkipCategories)
 
-	// Skip title (row 0), date (row 1), and header (row 2)
-	var securities []Security
+	var kept []Security
 	skipped := 0
-	for _, row := range rows[3:] {
-		if len(row) <= colCategory {
-			continue
-		}
-
-		category := strings.TrimSpace(row[colCategory])
-		if excludedCategories[category] {
+	for _, s := range list.Securities {
+		if filter.Skip(securities.Classification{Category: s.Category, SubCategory: s.SubCategory}) {
 			skipped++
 			continue
 		}
-
-		code := strings.TrimSpace(row[colStockCode])
-		if code == "" {
-			continue
-		}
-
-		// Pad to 5 digits (HKEX convention)
-		code = padStockCode(code)
-
-		name := strings.TrimSpace(row[colName])
-		subCategory := ""
-		if len(row) > colSubCategory {
-			subCategory = strings.TrimSpace(row[colSubCategory])
-		}
-
-		securities = append(securities, Security{
-			StockCode:   code,
-			Name:        name,
-			Category:    category,
-			SubCategory: subCategory,
-		})
+		kept = append(kept, s)
 	}
 
 	log.Printf("Skipped %d structured products (DWs/CBBCs)", skipped)
-	return securities, nil
-}
-
-// padStockCode zero-pads a stock code to 5 characters.
-func padStockCode(code string) string {
-	for len(code) < 5 {
-		code = "0" + code
-	}
-	return code
+	list.Securities = kept
+	return list, nil
 }
 
 // getExistingStockCodes queries all HKEX stock codes from the companies table.
-func getExistingStockCodes(ctx context.Context, pool *pgxpool.Pool) (map[string]bool, error) {
+func getExistingStockCodes(ctx context.Context, pool *pgxpool.Pool) (map[models.StockCode]bool, error) {
 	rows, err := pool.Query(ctx,
 		`SELECT COALESCE(stock_code, '') FROM companies WHERE exchange = 'HKEX'`)
 	if err != nil {
@@ -259,14 +169,14 @@ func getExistingStockCodes(ctx context.Context, pool *pgxpool.Pool) (map[string]
 	}
 	defer rows.Close()
 
-	codes := make(map[string]bool)
+	codes := make(map[models.StockCode]bool)
 	for rows.Next() {
 		var code string
 		if err := rows.Scan(&code); err != nil {
 			return nil, err
 		}
 		if code != "" {
-			codes[padStockCode(code)] = true
+			codes[models.NormalizeStockCode("HKEX", code)] = true
 		}
 	}
 	return codes, rows.Err()
@@ -275,12 +185,48 @@ func getExistingStockCodes(ctx context.Context, pool *pgxpool.Pool) (map[string]
 // upsertCompany inserts a new company or updates the name if it already exists.
 func upsertCompany(ctx context.Context, pool *pgxpool.Pool, s Security) error {
 	_, err := pool.Exec(ctx, `
-		INSERT INTO companies (company_id, name, stock_code, exchange, updated_at)
-		VALUES ($1, $2, $3, 'HKEX', NOW())
+		INSERT INTO companies (company_id, name, stock_code, exchange, instrument_category, instrument_sub_category, updated_at)
+		VALUES ($1, $2, $3, 'HKEX', $4, $5, NOW())
 		ON CONFLICT(exchange, company_id) DO UPDATE SET
 			name = EXCLUDED.name,
 			stock_code = EXCLUDED.stock_code,
+			instrument_category = EXCLUDED.instrument_category,
+			instrument_sub_category = EXCLUDED.instrument_sub_category,
 			updated_at = NOW()
-	`, s.StockCode, s.Name, s.StockCode)
+	`, s.StockCode, s.Name, s.StockCode, s.Category, s.SubCategory)
 	return err
 }
+
+// updateCategories sets the instrument category of the companies currently
+// listed under each security's stock code. Companies on a recycled code whose
+// listing has ended keep their category. Returns the number of rows changed.
+func updateCategories(ctx context.Context, pool *pgxpool.Pool, secs []Security) (int64, error) {
+	batch := &pgx.Batch{}
+	for _, s := range secs {
+		batch.Queue(`
+			UPDATE companies c SET
+				instrument_category = $2,
+				instrument_sub_category = $3,
+				updated_at = NOW()
+			WHERE c.exchange = 'HKEX' AND c.stock_code = $1
+			AND (c.instrument_category IS DISTINCT FROM $2 OR c.instrument_sub_category IS DISTINCT FROM $3)
+			AND NOT EXISTS (
+				SELECT 1 FROM company_listings l
+				WHERE l.exchange = c.exchange AND l.company_id = c.company_id AND l.listed_to IS NOT NULL
+			)
+		`, s.StockCode, s.Category, s.SubCategory)
+	}
+
+	results := pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var updated int64
+	for range secs {
+		tag, err := results.Exec()
+		if err != nil {
+			return updated, err
+		}
+		updated += tag.RowsAffected()
+	}
+	return updated, nil
+}
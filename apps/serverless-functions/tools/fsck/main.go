@@ -0,0 +1,114 @@
+// fsck checks a local download directory for damaged documents.
+//
+// Documents written by LocalStorage carry a SHA-256 checksum file and are
+// verified against it. Documents written before checksums existed were
+// written in place, so an interrupted download could leave a truncated file
+// that later runs took for complete; those that look truncated (a PDF without
+// %%EOF, a ZIP without its central directory, HTML without </html>, an empty
+// file) are quarantined, and the rest are given a checksum. Quarantined
+// documents are moved under .quarantine in the directory, keeping their keys,
+// and their filings are marked PENDING so local-downloader fetches them
+// again. Temporary files of interrupted writes are removed.
+//
+// Usage:
+//
+//	go run ./tools/fsck -local ./downloads -dry-run
+//	go run ./tools/fsck -local ./downloads
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+func main() {
+	localRoot := flag.String("local", "./downloads", "Local download directory to check")
+	sqlitePath := flag.String("sqlite", "", "SQLite database path (default: DATABASE_URL from config)")
+	verbose := flag.Bool("v", false, "List every file checked")
+	dryRun := flag.Bool("dry-run", false, "Report problems without changing anything")
+	flag.Parse()
+
+	if _, err := os.Stat(*localRoot); err != nil {
+		log.Fatalf("Cannot read %s: %v", *localRoot, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	path := *sqlitePath
+	if path == "" {
+		path = config.Load().DatabaseURL
+	}
+	db, err := database.New(path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	store, err := storage.NewLocalStorage(*localRoot)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *localRoot, err)
+	}
+
+	counts := make(map[storage.FsckStatus]int)
+	var reset int64
+	err = store.Fsck(ctx, *dryRun, func(r *storage.FsckResult) {
+		counts[r.Status]++
+		switch r.Status {
+		case storage.FsckOK, storage.FsckAdopted:
+			if *verbose {
+				fmt.Printf("  %-10s %s\n", r.Status, r.Key)
+			}
+			return
+		}
+		if r.Reason != "" {
+			fmt.Printf("  %-10s %s (%s)\n", r.Status, r.Key, r.Reason)
+		} else {
+			fmt.Printf("  %-10s %s\n", r.Status, r.Key)
+		}
+
+		if *dryRun || (r.Status != storage.FsckPartial && r.Status != storage.FsckCorrupt) {
+			return
+		}
+		n, err := db.ResetDownload(ctx, store.Location(r.Key))
+		if err != nil {
+			log.Printf("Error resetting filing of %s: %v", r.Key, err)
+			return
+		}
+		reset += n
+	})
+	if err != nil {
+		log.Fatalf("Failed to check %s: %v", *localRoot, err)
+	}
+
+	fmt.Println()
+	fmt.Println("=== Fsck Complete ===")
+	fmt.Printf("Verified:        %d\n", counts[storage.FsckOK])
+	fmt.Printf("Adopted:         %d\n", counts[storage.FsckAdopted])
+	fmt.Printf("Partial:         %d\n", counts[storage.FsckPartial])
+	fmt.Printf("Corrupt:         %d\n", counts[storage.FsckCorrupt])
+	fmt.Printf("Temp files:      %d\n", counts[storage.FsckTemp])
+	fmt.Printf("Orphans:         %d\n", counts[storage.FsckOrphan])
+	fmt.Printf("Unreadable:      %d\n", counts[storage.FsckUnreadable])
+	fmt.Printf("Filings reset:   %d\n", reset)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - nothing was moved or written)")
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -10,10 +11,9 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/awsclient"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/queue"
 )
 
 // BatchJob represents a batch of filing IDs to process
@@ -219,29 +219,22 @@ func main() {
 
 	log.Printf("Found %d filings to process", len(filingIDs))
 
-	// Create SQS client
-	var sqsClient *sqs.Client
+	// Create SQS queue
+	var q queue.Queue
 	if !*dryRun {
-		cfg, err := config.LoadDefaultConfig(ctx)
+		clients, err := awsclient.New(ctx, awsclient.FromEnv())
 		if err != nil {
-			log.Fatalf("Failed to load AWS config: %v", err)
+			log.Fatalf("Failed to create AWS clients: %v", err)
 		}
-		sqsClient = sqs.NewFromConfig(cfg)
+		q = queue.NewSQSQueue(clients.SQS(), *queueURL)
 	}
 
 	// Group into batches and push to SQS
 	totalBatches := (len(filingIDs) + *batchSize - 1) / *batchSize
 	log.Printf("Creating %d batches of %d filings each", totalBatches, *batchSize)
 
-	sentMessages := 0
+	var bodies []string
 	for i := 0; i < len(filingIDs); i += *batchSize {
-		select {
-		case <-ctx.Done():
-			log.Printf("Interrupted after %d messages", sentMessages)
-			return
-		default:
-		}
-
 		end := i + *batchSize
 		if end > len(filingIDs) {
 			end = len(filingIDs)
@@ -258,19 +251,24 @@ func main() {
 
 		if *dryRun {
 			log.Printf("Batch %d: %d filings (dry-run)", i / *batchSize + 1, len(batch))
-		} else {
-			_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
-				QueueUrl:    queueURL,
-				MessageBody: aws.String(string(jobJSON)),
-			})
-			if err != nil {
-				log.Printf("Error sending batch %d: %v", i / *batchSize + 1, err)
-				continue
-			}
-			log.Printf("Batch %d: %d filings sent", i / *batchSize + 1, len(batch))
 		}
+		bodies = append(bodies, string(jobJSON))
+	}
 
-		sentMessages++
+	// Send in SQS batches; messages that failed are reported and skipped
+	sentMessages := len(bodies)
+	if !*dryRun {
+		if err := q.SendBatch(ctx, bodies); err != nil {
+			var batchErr *queue.BatchError
+			if !errors.As(err, &batchErr) {
+				log.Fatalf("Failed to send batches: %v", err)
+			}
+			for _, f := range batchErr.Failed {
+				log.Printf("Error sending batch %d: %v", f.Index+1, f.Err)
+			}
+			sentMessages -= len(batchErr.Failed)
+		}
+		log.Printf("%d batches sent", sentMessages)
 	}
 
 	// Print summary
@@ -0,0 +1,150 @@
+// link-ah links the A-share (SSE/SZSE) and H-share (HKEX) companies of
+// issuers listed in both markets.
+//
+// Dual listings are found with cninfo.LinkAH from cninfo's A-share and Hong
+// Kong stock lists. A link is written to company_links in both directions
+// when both companies exist; the H-share side is the company currently
+// holding the HKEX code. Rerun after scraping new exchanges to pick up
+// companies created since the last run.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Show links without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	cfg := config.Load()
+	client := cninfo.NewClient(cfg.CNInfoBaseURL, ratelimit.ForHost(cfg.CNInfoBaseURL, cfg.CNInfoRateLimit))
+
+	// Step 1: find dual listings
+	aShares, err := client.Stocks(ctx, "szse")
+	if err != nil {
+		log.Fatalf("Failed to fetch A-share list: %v", err)
+	}
+	hShares, err := client.Stocks(ctx, "hke")
+	if err != nil {
+		log.Fatalf("Failed to fetch Hong Kong list: %v", err)
+	}
+	dual := cninfo.LinkAH(aShares, hShares)
+	log.Printf("Found %d A+H issuers among %d mainland and %d Hong Kong securities", len(dual), len(aShares), len(hShares))
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	// Step 2: link the companies we have
+	linked, missing := 0, 0
+	for _, d := range dual {
+		hID, err := hkexCompanyID(ctx, pool, d.HCode)
+		if err != nil {
+			log.Fatalf("Failed to resolve HKEX %s: %v", d.HCode, err)
+		}
+		aExists, err := companyExists(ctx, pool, d.AExchange, d.ACode)
+		if err != nil {
+			log.Fatalf("Failed to look up %s %s: %v", d.AExchange, d.ACode, err)
+		}
+		if hID == "" || !aExists {
+			missing++
+			continue
+		}
+
+		fmt.Printf("  %s:%s <-> HKEX:%s  %s\n", d.AExchange, d.ACode, hID, d.Name)
+		if !*dryRun {
+			if err := saveLink(ctx, pool, d.AExchange, d.ACode, hID); err != nil {
+				log.Printf("Error linking %s: %v", d.ACode, err)
+				continue
+			}
+		}
+		linked++
+	}
+
+	fmt.Println()
+	fmt.Println("=== A+H Linking Complete ===")
+	fmt.Printf("Dual-listed issuers: %d\n", len(dual))
+	fmt.Printf("Linked:              %d\n", linked)
+	fmt.Printf("Companies missing:   %d\n", missing)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// hkexCompanyID returns the company currently holding an HKEX code, or ""
+// if we have none
+func hkexCompanyID(ctx context.Context, pool *pgxpool.Pool, code models.StockCode) (string, error) {
+	var id string
+	err := pool.QueryRow(ctx, `
+		SELECT company_id FROM company_listings
+		WHERE exchange = 'HKEX' AND stock_code = $1 AND listed_to IS NULL
+		LIMIT 1
+	`, code).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err.Error() != "no rows in result set" {
+		return "", err
+	}
+
+	// Companies predating listing tracking are keyed by the bare code
+	id = models.CompanyIDForListing(code, nil)
+	exists, err := companyExists(ctx, pool, "HKEX", id)
+	if err != nil || !exists {
+		return "", err
+	}
+	return id, nil
+}
+
+// companyExists reports whether a company row exists
+func companyExists(ctx context.Context, pool *pgxpool.Pool, exchange, id string) (bool, error) {
+	var exists bool
+	err := pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM companies WHERE exchange = $1 AND company_id = $2)
+	`, exchange, id).Scan(&exists)
+	return exists, err
+}
+
+// saveLink writes an A+H link in both directions
+func saveLink(ctx context.Context, pool *pgxpool.Pool, aExchange, aID, hID string) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO company_links (exchange, company_id, linked_exchange, linked_company_id, link_type)
+		VALUES ($1, $2, 'HKEX', $3, $4), ('HKEX', $3, $1, $2, $4)
+		ON CONFLICT DO NOTHING
+	`, aExchange, aID, hID, string(models.CompanyLinkAH))
+	return err
+}
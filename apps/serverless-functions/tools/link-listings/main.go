@@ -0,0 +1,179 @@
+// link-listings links listing applicants (HKEX_APP) to the HKEX companies
+// they listed as, so Application Proofs, PHIPs and prospectuses stay
+// attached to the listed company.
+//
+// Listed applicants and their stock codes come from HKEXnews's listed
+// applications feeds. The applicant's stock code is recorded and an IPO link
+// is written to company_links in both directions once both companies exist;
+// the HKEX side is the company currently holding the code. Rerun after the
+// daily scrape to pick up new listings.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/hkexapp"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Show links without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	cfg := config.Load()
+	client := hkexapp.NewClient(cfg.BaseURL, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit))
+
+	// Step 1: fetch listed applicants of both boards
+	var listed []hkexapp.Application
+	for _, board := range []string{"sehk", "gem"} {
+		apps, err := client.Applications(ctx, board, hkexapp.StateListed)
+		if err != nil {
+			log.Fatalf("Failed to fetch listed applicants: %v", err)
+		}
+		listed = append(listed, apps...)
+	}
+	log.Printf("Found %d listed applicants", len(listed))
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	// Step 2: link the applicants we have
+	linked, missing, noCode := 0, 0, 0
+	for _, app := range listed {
+		if ctx.Err() != nil {
+			break
+		}
+		if app.StockCode == "" {
+			noCode++
+			continue
+		}
+		code := models.NormalizeStockCode("HKEX", app.StockCode)
+		appID := strconv.Itoa(app.ID)
+
+		appExists, err := companyExists(ctx, pool, hkexapp.Name, appID)
+		if err != nil {
+			log.Fatalf("Failed to look up applicant %s: %v", appID, err)
+		}
+		hID, err := hkexCompanyID(ctx, pool, code)
+		if err != nil {
+			log.Fatalf("Failed to resolve HKEX %s: %v", code, err)
+		}
+		if !appExists || hID == "" {
+			missing++
+			continue
+		}
+
+		fmt.Printf("  %s:%s <-> HKEX:%s  %s\n", hkexapp.Name, appID, hID, app.Applicant)
+		if !*dryRun {
+			if err := saveLink(ctx, pool, appID, code, hID); err != nil {
+				log.Printf("Error linking %s: %v", appID, err)
+				continue
+			}
+		}
+		linked++
+	}
+
+	fmt.Println()
+	fmt.Println("=== Listing Linking Complete ===")
+	fmt.Printf("Listed applicants:   %d\n", len(listed))
+	fmt.Printf("Linked:              %d\n", linked)
+	fmt.Printf("Companies missing:   %d\n", missing)
+	fmt.Printf("No stock code:       %d\n", noCode)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// hkexCompanyID returns the company currently holding an HKEX code, or ""
+// if we have none
+func hkexCompanyID(ctx context.Context, pool *pgxpool.Pool, code models.StockCode) (string, error) {
+	var id string
+	err := pool.QueryRow(ctx, `
+		SELECT company_id FROM company_listings
+		WHERE exchange = 'HKEX' AND stock_code = $1 AND listed_to IS NULL
+		LIMIT 1
+	`, code).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err.Error() != "no rows in result set" {
+		return "", err
+	}
+
+	// Companies predating listing tracking are keyed by the bare code
+	id = models.CompanyIDForListing(code, nil)
+	exists, err := companyExists(ctx, pool, "HKEX", id)
+	if err != nil || !exists {
+		return "", err
+	}
+	return id, nil
+}
+
+// companyExists reports whether a company row exists
+func companyExists(ctx context.Context, pool *pgxpool.Pool, exchange, id string) (bool, error) {
+	var exists bool
+	err := pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM companies WHERE exchange = $1 AND company_id = $2)
+	`, exchange, id).Scan(&exists)
+	return exists, err
+}
+
+// saveLink records an applicant's stock code and writes an IPO link in both
+// directions
+func saveLink(ctx context.Context, pool *pgxpool.Pool, appID string, code models.StockCode, hID string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE companies SET stock_code = $2, updated_at = NOW()
+		WHERE exchange = $1 AND company_id = $3 AND COALESCE(stock_code, '') <> $2
+	`, hkexapp.Name, code, appID); err != nil {
+		return fmt.Errorf("recording stock code: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO company_links (exchange, company_id, linked_exchange, linked_company_id, link_type)
+		VALUES ($1, $2, 'HKEX', $3, $4), ('HKEX', $3, $1, $2, $4)
+		ON CONFLICT DO NOTHING
+	`, hkexapp.Name, appID, hID, string(models.CompanyLinkIPO)); err != nil {
+		return fmt.Errorf("saving link: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
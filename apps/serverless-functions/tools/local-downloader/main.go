@@ -13,7 +13,13 @@ import (
 	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
 	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
 	"github.com/nicholaszhao/hkex-scraper/services/downloader"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/dart"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkexapp"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/mops"
 )
 
 func main() {
@@ -24,6 +30,7 @@ func main() {
 	s3Bucket := flag.String("s3-bucket", "", "S3 bucket for uploads (optional)")
 	dryRun := flag.Bool("dry-run", false, "Preview only, don't download files")
 	proxyURL := flag.String("proxy", "", "FireProx proxy URL (optional)")
+	warcDir := flag.String("warc", "", "Directory to archive fetches to as WARC files (optional)")
 	timeout := flag.Duration("timeout", 30*time.Second, "HTTP request timeout")
 	flag.Parse()
 
@@ -59,7 +66,6 @@ func main() {
 	dlConfig := downloader.Config{
 		Concurrency:   *concurrency,
 		LocalPath:     *outputDir,
-		S3Bucket:      *s3Bucket,
 		DryRun:        *dryRun,
 		ProxyBaseURL:  *proxyURL,
 		Timeout:       *timeout,
@@ -72,14 +78,28 @@ func main() {
 
 	// Set up S3 client if bucket specified
 	if *s3Bucket != "" && !*dryRun {
-		s3Client, err := downloader.NewS3Client(ctx)
+		s3Store, err := storage.NewS3Storage(ctx, *s3Bucket, "")
 		if err != nil {
 			log.Fatalf("Failed to create S3 client: %v", err)
 		}
-		dl.SetS3Client(s3Client)
+		dl.SetStorage(s3Store)
 		log.Printf("S3 uploads enabled: %s", *s3Bucket)
 	}
 
+	// Archive fetches if requested
+	if *warcDir != "" && !*dryRun {
+		archive, err := downloader.NewArchive(*warcDir, nil, "", 0)
+		if err != nil {
+			log.Fatalf("Failed to create archive: %v", err)
+		}
+		dl.SetArchive(archive)
+		defer func() {
+			if err := archive.Close(); err != nil {
+				log.Printf("Warning: failed to close archive: %v", err)
+			}
+		}()
+	}
+
 	// Create store and batch downloader
 	store := downloader.NewDBStore(db)
 	batchDl := downloader.NewBatchDownloader(dl, store)
@@ -93,6 +113,9 @@ func main() {
 	if *proxyURL != "" {
 		fmt.Printf("  Proxy URL:        %s\n", *proxyURL)
 	}
+	if *warcDir != "" {
+		fmt.Printf("  WARC directory:   %s\n", *warcDir)
+	}
 	fmt.Println()
 
 	// Get pending filings
@@ -0,0 +1,379 @@
+// merge-companies merges HKEX company rows that are the same issuer under
+// differently formatted stock codes ("1", "00001", "com_00001").
+//
+// Each company ID is normalised with models.NormalizeCompanyID. Companies
+// whose IDs normalise to the same value are merged into one row keyed by the
+// normalised ID: filings, listings and every other reference in
+// models.CompanyReferences are moved to it, stock codes are normalised, and
+// the duplicate rows are deleted. Recycled-code companies ("01234_20240105")
+// keep their date suffix. The tool refuses to run if the database has a
+// foreign key to companies that the list lacks.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// Company is one row of the companies table
+type Company struct {
+	ID        string
+	Name      string
+	StockCode string
+	UpdatedAt time.Time
+}
+
+// Group is a set of company rows that normalise to the same company ID
+type Group struct {
+	CanonicalID string
+	StockCode   models.StockCode
+	Members     []Company // most recently updated first
+}
+
+// NeedsMerge reports whether the group has rows to merge or rename
+func (g *Group) NeedsMerge() bool {
+	if len(g.Members) > 1 {
+		return true
+	}
+	m := g.Members[0]
+	return m.ID != g.CanonicalID || m.StockCode != g.StockCode.String()
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Show what would change without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	if err := checkReferences(ctx, pool); err != nil {
+		log.Fatalf("Reference check failed: %v", err)
+	}
+
+	companies, err := loadCompanies(ctx, pool)
+	if err != nil {
+		log.Fatalf("Failed to load companies: %v", err)
+	}
+	log.Printf("Loaded %d HKEX companies", len(companies))
+
+	groups := groupCompanies(companies)
+
+	merged, deleted, moved := 0, 0, int64(0)
+	for _, g := range groups {
+		if !g.NeedsMerge() {
+			continue
+		}
+		n, err := mergeGroup(ctx, pool, g, *dryRun)
+		if err != nil {
+			log.Printf("Error merging %s: %v", g.CanonicalID, err)
+			continue
+		}
+		merged++
+		deleted += len(g.Members) - 1
+		moved += n
+	}
+
+	fmt.Println()
+	fmt.Println("=== Merge Complete ===")
+	fmt.Printf("Companies fixed:   %d\n", merged)
+	fmt.Printf("Duplicates merged: %d\n", deleted)
+	fmt.Printf("Filings moved:     %d\n", moved)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	} else if moved > 0 {
+		fmt.Println("\nNote: pdf_s3_key values are unchanged; moved filings keep their existing S3 objects")
+	}
+}
+
+// loadCompanies reads all HKEX companies
+func loadCompanies(ctx context.Context, pool *pgxpool.Pool) ([]Company, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT company_id, COALESCE(name, ''), COALESCE(stock_code, ''), COALESCE(updated_at, NOW())
+		FROM companies WHERE exchange = 'HKEX'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var companies []Company
+	for rows.Next() {
+		var c Company
+		if err := rows.Scan(&c.ID, &c.Name, &c.StockCode, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		companies = append(companies, c)
+	}
+	return companies, rows.Err()
+}
+
+// groupCompanies groups companies by normalised ID, sorted by that ID
+func groupCompanies(companies []Company) []*Group {
+	byID := make(map[string]*Group)
+	for _, c := range companies {
+		id := canonicalID(c.ID)
+		g, ok := byID[id]
+		if !ok {
+			g = &Group{CanonicalID: id, StockCode: canonicalStockCode(c, id)}
+			byID[id] = g
+		}
+		g.Members = append(g.Members, c)
+	}
+
+	groups := make([]*Group, 0, len(byID))
+	for _, g := range byID {
+		sort.Slice(g.Members, func(i, j int) bool {
+			return g.Members[i].UpdatedAt.After(g.Members[j].UpdatedAt)
+		})
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].CanonicalID < groups[j].CanonicalID })
+	return groups
+}
+
+// canonicalID normalises a company ID, including the stock code part of a
+// recycled-code ID ("1234_20240105" -> "01234_20240105")
+func canonicalID(id string) string {
+	base, suffix, ok := strings.Cut(strings.TrimPrefix(id, "com_"), "_")
+	if ok && len(suffix) == len("20060102") {
+		if _, err := time.Parse("20060102", suffix); err == nil {
+			return models.NormalizeCompanyID("HKEX", base) + "_" + suffix
+		}
+	}
+	return models.NormalizeCompanyID("HKEX", id)
+}
+
+// canonicalStockCode returns the normalised stock code for a company, falling
+// back to the code part of its ID when the stock_code column is empty
+func canonicalStockCode(c Company, id string) models.StockCode {
+	if c.StockCode != "" {
+		return models.NormalizeStockCode("HKEX", c.StockCode)
+	}
+	code, _, _ := strings.Cut(id, "_")
+	return models.NormalizeStockCode("HKEX", code)
+}
+
+// mergeGroup merges the group's rows into the canonical company and returns
+// the number of filings moved
+func mergeGroup(ctx context.Context, pool *pgxpool.Pool, g *Group, dryRun bool) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var dupes []string
+	for _, m := range g.Members {
+		if m.ID != g.CanonicalID {
+			dupes = append(dupes, m.ID)
+		}
+	}
+
+	// Canonical row first so the moved rows can reference it
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO companies (company_id, name, stock_code, exchange, updated_at)
+		VALUES ($1, $2, $3, 'HKEX', NOW())
+		ON CONFLICT(exchange, company_id) DO UPDATE SET
+			stock_code = EXCLUDED.stock_code,
+			updated_at = NOW()
+	`, g.CanonicalID, g.Members[0].Name, g.StockCode); err != nil {
+		return 0, fmt.Errorf("upserting company: %w", err)
+	}
+
+	if err := mergeListings(ctx, tx, g, dupes); err != nil {
+		return 0, fmt.Errorf("merging listings: %w", err)
+	}
+
+	var moved int64
+	for _, ref := range models.CompanyReferences {
+		for _, dupe := range dupes {
+			n, err := repoint(ctx, tx, ref, dupe, g.CanonicalID)
+			if err != nil {
+				return 0, fmt.Errorf("moving %s.%s: %w", ref.Table, ref.CompanyColumn, err)
+			}
+			if ref.Table == "filings" {
+				moved += n
+			}
+		}
+	}
+
+	if len(dupes) > 0 {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM companies WHERE exchange = 'HKEX' AND company_id = ANY($1)
+		`, dupes); err != nil {
+			return 0, fmt.Errorf("deleting duplicates: %w", err)
+		}
+	}
+
+	log.Printf("%s: merged %v (stock code %s, %d filings moved)", g.CanonicalID, dupes, g.StockCode, moved)
+
+	if dryRun {
+		return moved, nil
+	}
+	return moved, tx.Commit(ctx)
+}
+
+// repoint moves the rows of one reference from company from to company to.
+// Rows that would duplicate a unique key the target already has are
+// deleted. It returns the number of rows moved.
+func repoint(ctx context.Context, tx pgx.Tx, ref models.CompanyReference, from, to string) (int64, error) {
+	set := ref.CompanyColumn + " = $1"
+	if ref.Touch {
+		set += ", updated_at = NOW()"
+	}
+	where := fmt.Sprintf("%s = 'HKEX' AND %s = $2", ref.ExchangeColumn, ref.CompanyColumn)
+
+	var conflict string
+	if keep := ref.KeepsKey("$1"); keep != "" {
+		conflict = " AND " + keep
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf("UPDATE %s t SET %s WHERE %s%s", ref.Table, set, where, conflict), to, from)
+	if err != nil {
+		return 0, err
+	}
+	if conflict != "" {
+		del := fmt.Sprintf("DELETE FROM %s WHERE %s = 'HKEX' AND %s = $1", ref.Table, ref.ExchangeColumn, ref.CompanyColumn)
+		if _, err := tx.Exec(ctx, del, from); err != nil {
+			return 0, fmt.Errorf("dropping duplicates: %w", err)
+		}
+	}
+	return tag.RowsAffected(), nil
+}
+
+// checkReferences fails if a foreign key to companies is missing from
+// models.CompanyReferences, so that merges never orphan its rows
+func checkReferences(ctx context.Context, pool *pgxpool.Pool) error {
+	rows, err := pool.Query(ctx, `
+		SELECT c.conrelid::regclass::text,
+			ARRAY(SELECT a.attname::text FROM unnest(c.conkey) WITH ORDINALITY k(n, i)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.n ORDER BY k.i),
+			ARRAY(SELECT a.attname::text FROM unnest(c.confkey) WITH ORDINALITY k(n, i)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.n ORDER BY k.i)
+		FROM pg_constraint c
+		WHERE c.contype = 'f' AND c.confrelid = 'companies'::regclass
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	known := make(map[[3]string]bool)
+	for _, ref := range models.CompanyReferences {
+		known[[3]string{ref.Table, ref.ExchangeColumn, ref.CompanyColumn}] = true
+	}
+
+	var missing []string
+	for rows.Next() {
+		var table string
+		var cols, refCols []string
+		if err := rows.Scan(&table, &cols, &refCols); err != nil {
+			return err
+		}
+		var exchangeCol, companyCol string
+		for i, col := range refCols {
+			switch col {
+			case "exchange":
+				exchangeCol = cols[i]
+			case "company_id":
+				companyCol = cols[i]
+			}
+		}
+		if !known[[3]string{table, exchangeCol, companyCol}] {
+			missing = append(missing, fmt.Sprintf("%s(%s, %s)", table, exchangeCol, companyCol))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("references to companies missing from models.CompanyReferences: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// mergeListings normalises the group's listing stock codes and moves the
+// duplicates' listings to the canonical company. If the canonical company
+// already has a listing the duplicates' listings are dropped; otherwise the
+// earliest one is re-keyed to it.
+func mergeListings(ctx context.Context, tx pgx.Tx, g *Group, dupes []string) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass('company_listings') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	ids := append([]string{g.CanonicalID}, dupes...)
+	if _, err := tx.Exec(ctx, `
+		UPDATE company_listings SET stock_code = $1, updated_at = NOW()
+		WHERE exchange = 'HKEX' AND company_id = ANY($2) AND stock_code <> $1
+	`, g.StockCode, ids); err != nil {
+		return fmt.Errorf("normalising stock codes: %w", err)
+	}
+	if len(dupes) == 0 {
+		return nil
+	}
+
+	var canonical int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM company_listings WHERE exchange = 'HKEX' AND company_id = $1
+	`, g.CanonicalID).Scan(&canonical); err != nil {
+		return err
+	}
+
+	if canonical == 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE company_listings SET id = $1, company_id = $2, updated_at = NOW()
+			WHERE id = (
+				SELECT id FROM company_listings
+				WHERE exchange = 'HKEX' AND company_id = ANY($3)
+				ORDER BY listed_from NULLS FIRST
+				LIMIT 1
+			)
+		`, "lst_HKEX_"+g.CanonicalID, g.CanonicalID, dupes); err != nil {
+			return fmt.Errorf("moving listing: %w", err)
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		DELETE FROM company_listings WHERE exchange = 'HKEX' AND company_id = ANY($1)
+	`, dupes)
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// index is the database recording where documents are stored
+type index interface {
+	// documents returns the recorded documents of filings and attachments
+	documents(ctx context.Context) ([]document, error)
+	// unrecordedFilings returns the HKEX filings of the given source IDs that
+	// have no recorded document
+	unrecordedFilings(ctx context.Context, sourceIDs []string) ([]models.Filing, error)
+	// setKey records the key of a document
+	setKey(ctx context.Context, d document, key string) error
+}
+
+// postgresIndex records S3 keys in pdf_s3_key
+type postgresIndex struct {
+	pool   *pgxpool.Pool
+	prefix string
+}
+
+func newPostgresIndex(ctx context.Context, dsn, prefix string) (*postgresIndex, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &postgresIndex{pool: pool, prefix: prefix}, nil
+}
+
+func (x *postgresIndex) close() {
+	x.pool.Close()
+}
+
+func (x *postgresIndex) documents(ctx context.Context) ([]document, error) {
+	pattern := "%"
+	if x.prefix != "" {
+		pattern = x.prefix + "/%"
+	}
+
+	rows, err := x.pool.Query(ctx, `
+		SELECT source_id, exchange, company_id, report_date, source_url,
+			COALESCE(file_extension, ''), pdf_s3_key
+		FROM filings
+		WHERE COALESCE(pdf_s3_key, '') <> '' AND pdf_s3_key LIKE $1
+	`, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("querying filings: %w", err)
+	}
+	var docs []document
+	for rows.Next() {
+		d := document{recorded: true}
+		f := &d.filing
+		if err := rows.Scan(&f.SourceID, &f.Exchange, &f.CompanyID, &f.ReportDate, &f.SourceURL, &f.FileExtension, &d.key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Attachments are keyed like their parent filing, under their own ID
+	rows, err = x.pool.Query(ctx, `
+		SELECT d.document_id, f.exchange, f.company_id, f.report_date, d.source_url,
+			COALESCE(d.file_extension, ''), d.pdf_s3_key
+		FROM filing_documents d
+		JOIN filings f ON f.exchange = d.exchange AND f.source_id = d.filing_source_id
+		WHERE COALESCE(d.pdf_s3_key, '') <> '' AND d.pdf_s3_key LIKE $1
+	`, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("querying attachments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := document{attachment: true, recorded: true}
+		f := &d.filing
+		if err := rows.Scan(&f.SourceID, &f.Exchange, &f.CompanyID, &f.ReportDate, &f.SourceURL, &f.FileExtension, &d.key); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+func (x *postgresIndex) unrecordedFilings(ctx context.Context, sourceIDs []string) ([]models.Filing, error) {
+	if len(sourceIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := x.pool.Query(ctx, `
+		SELECT source_id, exchange, company_id, report_date, source_url, COALESCE(file_extension, '')
+		FROM filings
+		WHERE exchange = 'HKEX' AND source_id = ANY($1) AND COALESCE(pdf_s3_key, '') = ''
+	`, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filings []models.Filing
+	for rows.Next() {
+		var f models.Filing
+		if err := rows.Scan(&f.SourceID, &f.Exchange, &f.CompanyID, &f.ReportDate, &f.SourceURL, &f.FileExtension); err != nil {
+			return nil, err
+		}
+		filings = append(filings, f)
+	}
+	return filings, rows.Err()
+}
+
+// setKey records the key; a filing whose feed-layout document had not been
+// recorded is marked downloaded
+func (x *postgresIndex) setKey(ctx context.Context, d document, key string) error {
+	if d.attachment {
+		_, err := x.pool.Exec(ctx, `
+			UPDATE filing_documents SET pdf_s3_key = $1, updated_at = NOW()
+			WHERE exchange = $2 AND document_id = $3
+		`, key, d.filing.Exchange, d.filing.SourceID)
+		return err
+	}
+	_, err := x.pool.Exec(ctx, `
+		UPDATE filings SET pdf_s3_key = $1,
+			processing_status = CASE WHEN $4 OR processing_status <> $5 THEN processing_status ELSE $6 END,
+			updated_at = NOW()
+		WHERE exchange = $2 AND source_id = $3
+	`, key, d.filing.Exchange, d.filing.SourceID, d.recorded,
+		models.ProcessingStatusPending, models.ProcessingStatusCompleted)
+	return err
+}
+
+// sqliteIndex records local paths in local_path; document keys are paths
+// relative to root
+type sqliteIndex struct {
+	db   *database.DB
+	root string
+}
+
+// pageSize is the number of filings read per query
+const pageSize = 1000
+
+func (x *sqliteIndex) documents(ctx context.Context) ([]document, error) {
+	root, err := filepath.Abs(x.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document
+	for offset := 0; ; offset += pageSize {
+		filings, err := x.db.ListFilings(ctx, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range filings {
+			if f.LocalPath == "" {
+				continue
+			}
+			abs, err := filepath.Abs(f.LocalPath)
+			if err != nil {
+				return nil, err
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				log.Printf("Skipping %s: %s is outside %s", f.SourceID, f.LocalPath, x.root)
+				continue
+			}
+			docs = append(docs, document{filing: f, key: filepath.ToSlash(rel), recorded: true})
+		}
+		if len(filings) < pageSize {
+			return docs, nil
+		}
+	}
+}
+
+func (x *sqliteIndex) unrecordedFilings(ctx context.Context, sourceIDs []string) ([]models.Filing, error) {
+	var filings []models.Filing
+	for _, id := range sourceIDs {
+		f, err := x.db.GetFilingBySourceID(ctx, "HKEX", id)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil && f.LocalPath == "" {
+			filings = append(filings, *f)
+		}
+	}
+	return filings, nil
+}
+
+func (x *sqliteIndex) setKey(ctx context.Context, d document, key string) error {
+	path := filepath.Join(x.root, filepath.FromSlash(key))
+	if d.recorded {
+		return x.db.SetLocalPath(ctx, d.filing.ID, path)
+	}
+	return x.db.UpdateFilingDownload(ctx, d.filing.ID, path)
+}
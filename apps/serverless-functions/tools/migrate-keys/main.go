@@ -0,0 +1,378 @@
+// migrate-keys moves stored documents to the current key layout
+// (storage.Key) and updates the keys recorded for them.
+//
+// Documents were written under three layouts: the feed scraper's
+// {year}/{stock_code}/{news_id}.{ext}, the local downloader's file names with
+// the title appended, and the current {exchange}/{company_id}/YYYY/MM/DD/
+// {source_id}.{ext}. The tool takes the keys recorded for filings and
+// attachments (pdf_s3_key in PostgreSQL with -bucket, local_path in SQLite
+// with -local) and scans the bucket or tree for feed-layout documents not
+// recorded anywhere. Each document is copied to its current key, the copy is
+// verified, and the new key is recorded. Originals are kept unless -delete
+// is given.
+//
+// An interrupted run resumes when run again: documents whose new key is
+// recorded are not planned again, and an existing copy that matches its
+// original is recorded without copying. Completed moves are appended to a
+// journal, from which a resumed -delete run removes the originals it had not
+// yet deleted.
+//
+// Usage:
+//
+//	go run ./tools/migrate-keys -bucket my-bucket -dry-run
+//	go run ./tools/migrate-keys -bucket my-bucket -delete
+//	go run ./tools/migrate-keys -local ./downloads -sqlite ./data/hkex.db
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/database"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+// document is a stored document and the filing it belongs to
+type document struct {
+	filing     models.Filing // for attachments, the parent with the document's ID, URL and extension
+	attachment bool
+	key        string // object key, or path relative to the local root
+	recorded   bool   // key is recorded in the database
+}
+
+// move is a document to be copied to its current key
+type move struct {
+	doc  document
+	from string
+	to   string
+}
+
+// stats counts the outcomes of a run
+type stats struct {
+	documents, feed, planned                 int
+	moved, adopted, missing, conflicts, errs int
+	deleted                                  int
+}
+
+func main() {
+	bucket := flag.String("bucket", "", "S3 bucket to migrate (keys recorded in PostgreSQL)")
+	prefix := flag.String("prefix", "", "Key prefix within the bucket")
+	region := flag.String("region", "", "AWS region (default: AWS_REGION env or ap-east-1)")
+	localRoot := flag.String("local", "", "Local download directory to migrate (paths recorded in SQLite)")
+	sqlitePath := flag.String("sqlite", "", "SQLite database path for -local (default: DATABASE_URL from config)")
+	dbURL := flag.String("db", "", "PostgreSQL connection string for -bucket (default: DATABASE_URL env)")
+	journalPath := flag.String("journal", "migrate-keys.journal", "File recording completed moves")
+	del := flag.Bool("delete", false, "Delete originals after they are copied and recorded")
+	dryRun := flag.Bool("dry-run", false, "Show moves without copying or writing")
+	flag.Parse()
+
+	if (*bucket == "") == (*localRoot == "") {
+		log.Fatal("Pass exactly one of -bucket or -local")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	var idx index
+	var objs storage.Blobs
+	if *bucket != "" {
+		dsn := *dbURL
+		if dsn == "" {
+			dsn = os.Getenv("DATABASE_URL")
+		}
+		if dsn == "" {
+			log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+		}
+		r := *region
+		if r == "" {
+			r = os.Getenv("AWS_REGION")
+		}
+		if r == "" {
+			r = "ap-east-1"
+		}
+
+		pg, err := newPostgresIndex(ctx, dsn, *prefix)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer pg.close()
+		idx = pg
+
+		if objs, err = storage.NewS3Storage(ctx, *bucket, r); err != nil {
+			log.Fatalf("Failed to create S3 client: %v", err)
+		}
+	} else {
+		path := *sqlitePath
+		if path == "" {
+			path = config.Load().DatabaseURL
+		}
+		db, err := database.New(path)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		idx = &sqliteIndex{db: db, root: *localRoot}
+		if objs, err = storage.NewLocalStorage(*localRoot); err != nil {
+			log.Fatalf("Failed to open %s: %v", *localRoot, err)
+		}
+	}
+
+	done, err := loadJournal(*journalPath)
+	if err != nil {
+		log.Fatalf("Failed to read journal: %v", err)
+	}
+
+	// Step 1: plan moves from recorded keys and unrecorded feed-layout objects
+	var st stats
+	moves, err := plan(ctx, idx, objs, *prefix, &st)
+	if err != nil {
+		log.Fatalf("Failed to plan moves: %v", err)
+	}
+	log.Printf("Planned %d moves (%d recorded documents, %d unrecorded feed-layout documents)", len(moves), st.documents, st.feed)
+
+	var journal *os.File
+	if !*dryRun {
+		journal, err = os.OpenFile(*journalPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open journal: %v", err)
+		}
+		defer journal.Close()
+	}
+
+	// Step 2: delete originals an earlier run moved but did not delete
+	if *del && !*dryRun {
+		for from, to := range done {
+			if ctx.Err() != nil {
+				break
+			}
+			removed, err := removeMoved(ctx, objs, from, to)
+			if err != nil {
+				log.Printf("Error deleting %s: %v", from, err)
+				st.errs++
+			} else if removed {
+				st.deleted++
+			}
+		}
+	}
+
+	// Step 3: copy, verify, record
+	for _, m := range moves {
+		if ctx.Err() != nil {
+			break
+		}
+		if *dryRun {
+			fmt.Printf("  %s -> %s\n", m.from, m.to)
+			continue
+		}
+
+		recorded, err := migrate(ctx, idx, objs, m, &st)
+		if err != nil {
+			log.Printf("Error moving %s: %v", m.from, err)
+			st.errs++
+			continue
+		}
+		if !recorded {
+			continue
+		}
+		if _, err := fmt.Fprintf(journal, "%s\t%s\n", m.from, m.to); err != nil {
+			log.Fatalf("Failed to write journal: %v", err)
+		}
+		if err := journal.Sync(); err != nil {
+			log.Fatalf("Failed to write journal: %v", err)
+		}
+
+		if *del {
+			if err := objs.DeleteKey(ctx, m.from); err != nil {
+				log.Printf("Error deleting %s: %v", m.from, err)
+				st.errs++
+				continue
+			}
+			st.deleted++
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== Key Migration Complete ===")
+	fmt.Printf("Layout version:  %d\n", storage.LayoutVersion)
+	fmt.Printf("Documents:       %d\n", st.documents)
+	fmt.Printf("Feed layout:     %d\n", st.feed)
+	fmt.Printf("Moves planned:   %d\n", st.planned)
+	fmt.Printf("Moved:           %d\n", st.moved)
+	fmt.Printf("Adopted:         %d\n", st.adopted)
+	fmt.Printf("Missing:         %d\n", st.missing)
+	fmt.Printf("Conflicts:       %d\n", st.conflicts)
+	fmt.Printf("Deleted:         %d\n", st.deleted)
+	fmt.Printf("Errors:          %d\n", st.errs)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - nothing was copied or written)")
+	}
+}
+
+// plan returns the moves of documents not stored under their current key
+func plan(ctx context.Context, idx index, objs storage.Blobs, prefix string, st *stats) ([]move, error) {
+	docs, err := idx.documents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading documents: %w", err)
+	}
+	st.documents = len(docs)
+
+	recorded := make(map[string]bool, len(docs))
+	for _, d := range docs {
+		recorded[d.key] = true
+	}
+
+	// Feed-layout objects whose filing has no recorded document
+	listPrefix := ""
+	if prefix != "" {
+		listPrefix = prefix + "/"
+	}
+	feedKeys := make(map[string]string)
+	err = objs.List(ctx, listPrefix, func(info *storage.Info) error {
+		if recorded[info.Key] {
+			return nil
+		}
+		if id, ok := storage.ParseFeedKey(strings.TrimPrefix(info.Key, listPrefix)); ok {
+			feedKeys[id] = info.Key
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+
+	ids := make([]string, 0, len(feedKeys))
+	for id := range feedKeys {
+		ids = append(ids, id)
+	}
+	unrecorded, err := idx.unrecordedFilings(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("loading filings: %w", err)
+	}
+	for _, f := range unrecorded {
+		docs = append(docs, document{filing: f, key: feedKeys[f.SourceID]})
+		st.feed++
+	}
+
+	var moves []move
+	for _, d := range docs {
+		to := storage.Key(&d.filing)
+		if prefix != "" {
+			to = prefix + "/" + to
+		}
+		if d.key != to {
+			moves = append(moves, move{doc: d, from: d.key, to: to})
+		}
+	}
+	st.planned = len(moves)
+	return moves, nil
+}
+
+// migrate copies a document to its current key unless an identical copy is
+// there, and records the new key. It reports false if the document is
+// missing or its current key holds a different document.
+func migrate(ctx context.Context, idx index, objs storage.Blobs, m move, st *stats) (bool, error) {
+	fromExists, err := exists(ctx, objs, m.from)
+	if err != nil {
+		return false, err
+	}
+	toExists, err := exists(ctx, objs, m.to)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !fromExists && !toExists:
+		log.Printf("Missing %s (%s)", m.from, m.doc.filing.SourceID)
+		st.missing++
+		return false, nil
+	case !fromExists:
+		// Copied by an earlier run that stopped before recording the key
+		st.adopted++
+	case toExists:
+		ok, err := same(ctx, objs, m.from, m.to)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			log.Printf("Conflict: %s differs from existing %s", m.from, m.to)
+			st.conflicts++
+			return false, nil
+		}
+		st.adopted++
+	default:
+		if err := objs.CopyKey(ctx, m.from, m.to); err != nil {
+			return false, fmt.Errorf("copying: %w", err)
+		}
+		ok, err := same(ctx, objs, m.from, m.to)
+		if err != nil {
+			return false, fmt.Errorf("verifying: %w", err)
+		}
+		if !ok {
+			return false, errors.New("copy does not match original")
+		}
+		st.moved++
+	}
+
+	if err := idx.setKey(ctx, m.doc, m.to); err != nil {
+		return false, fmt.Errorf("recording key: %w", err)
+	}
+	return true, nil
+}
+
+// removeMoved deletes the original of a journaled move if it is still there
+// and its copy exists, reporting whether it was deleted
+func removeMoved(ctx context.Context, objs storage.Blobs, from, to string) (bool, error) {
+	fromExists, err := exists(ctx, objs, from)
+	if err != nil || !fromExists {
+		return false, err
+	}
+	toExists, err := exists(ctx, objs, to)
+	if err != nil {
+		return false, err
+	}
+	if !toExists {
+		return false, fmt.Errorf("copy %s is missing", to)
+	}
+	return true, objs.DeleteKey(ctx, from)
+}
+
+// loadJournal returns the completed moves recorded in a journal, by
+// original key
+func loadJournal(path string) (map[string]string, error) {
+	done := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// A torn last line from a crash has no tab and is ignored
+		if from, to, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			done[from] = to
+		}
+	}
+	return done, scanner.Err()
+}
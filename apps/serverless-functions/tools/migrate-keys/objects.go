@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+)
+
+// exists reports whether a key is stored
+func exists(ctx context.Context, objs storage.Blobs, key string) (bool, error) {
+	_, err := objs.StatKey(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// same reports whether two keys hold the same content. It compares sizes,
+// then hashes, then S3 ETags unless either object was a multipart upload,
+// whose ETag depends on the part size. Objects with neither are read and
+// hashed.
+func same(ctx context.Context, objs storage.Blobs, a, b string) (bool, error) {
+	ia, err := objs.StatKey(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := objs.StatKey(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	if ia.Size != ib.Size {
+		return false, nil
+	}
+	if ia.Hash != "" && ib.Hash != "" {
+		return ia.Hash == ib.Hash, nil
+	}
+	if ia.ETag != "" && ib.ETag != "" && !strings.Contains(ia.ETag+ib.ETag, "-") {
+		return ia.ETag == ib.ETag, nil
+	}
+
+	ha, err := hashKey(ctx, objs, a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := hashKey(ctx, objs, b)
+	if err != nil {
+		return false, err
+	}
+	return ha == hb, nil
+}
+
+// hashKey reads a stored document and returns its hex SHA-256
+func hashKey(ctx context.Context, objs storage.Blobs, key string) (string, error) {
+	r, err := objs.OpenKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/dart/darttest"
+)
+
+// Serves the recorded OpenDART fixtures so the DART adapter can be run
+// locally without an API key:
+//
+//	go run ./tools/opendart-standin -addr :8089
+//	DART_BASE_URL=http://localhost:8089 DART_API_KEY=local \
+//	  go run ./tools/backfill -exchange DART -from 2024-01-01 -to 2024-02-29
+func main() {
+	addr := flag.String("addr", ":8089", "Listen address")
+	apiKey := flag.String("key", "", "API key to require (default: accept any)")
+	flag.Parse()
+
+	fmt.Println("=== OpenDART Stand-in ===")
+	fmt.Printf("Listening on %s\n", *addr)
+	fmt.Println("Endpoints: /api/list.json, /api/corpCode.xml, /api/document.xml")
+	fmt.Printf("Receipts:  %d recorded filings\n", len(darttest.ReceiptNos()))
+
+	handler := &darttest.Handler{APIKey: *apiKey}
+	logged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		handler.ServeHTTP(w, r)
+	})
+
+	if err := http.ListenAndServe(*addr, logged); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// pipeline runs the Step Functions ingestion workflow locally: the scraper,
+// downloader, write-manifest, batch-worker, check-status and notify handlers
+// run in-process in the state machine's order, with the same JSON payloads
+// between them. Each run is recorded in a JSONL run log that "replay" runs
+// again, taking task results from the log except for the states named with
+// -live, which run with the current code and are compared with the log.
+//
+// The handlers read the same environment as in AWS (DATABASE_URL, S3_BUCKET,
+// PROXY_BASE_URL, ...); point AWS_ENDPOINT_URL at a local stand-in to keep
+// everything off AWS. Without SNS_TOPIC_ARN notifications are printed.
+//
+// Usage:
+//
+//	go run ./tools/pipeline run
+//	go run ./tools/pipeline run -input '{"start_date":"2024-01-01","end_date":"2024-03-31"}' -concurrency 2
+//	go run ./tools/pipeline replay -log pipeline-20240328T090000.jsonl
+//	go run ./tools/pipeline replay -log pipeline-20240328T090000.jsonl -live DownloadSingleFiling
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/notifier"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader/batchworker"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader/sfn"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/cninfo"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/dart"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/hkexapp"
+	_ "github.com/nicholaszhao/hkex-scraper/services/exchange/mops"
+	"github.com/nicholaszhao/hkex-scraper/services/orchestrator/chunks"
+	"github.com/nicholaszhao/hkex-scraper/services/orchestrator/manifest"
+	"github.com/nicholaszhao/hkex-scraper/services/orchestrator/notify"
+	"github.com/nicholaszhao/hkex-scraper/services/orchestrator/status"
+	"github.com/nicholaszhao/hkex-scraper/services/pipeline"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/handler"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: pipeline run|replay [flags]")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("\nReceived shutdown signal, stopping...")
+		cancel()
+	}()
+
+	switch os.Args[1] {
+	case "run":
+		run(ctx, os.Args[2:])
+	case "replay":
+		replay(ctx, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q; use run or replay\n", os.Args[1])
+		os.Exit(2)
+	}
+}
+
+func run(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	input := fs.String("input", "{}", "Execution input JSON; start_date and end_date select the backfill path")
+	logPath := fs.String("log", "", "Run log to write (default: pipeline-<time>.jsonl)")
+	concurrency := fs.Int("concurrency", 5, "Download Map state concurrency")
+	batchConcurrency := fs.Int("batch-concurrency", 0, "Batch array children run at once (default: -concurrency)")
+	batchThreshold := fs.Int("batch-threshold", 1000, "Filings above which downloads go through the Batch array job")
+	fastRetries := fs.Bool("fast-retries", false, "Retry failed tasks without the state machine's intervals")
+	checkStatus := fs.Bool("check-status", true, "Run check-status after downloads")
+	fs.Parse(args)
+
+	if *logPath == "" {
+		*logPath = fmt.Sprintf("pipeline-%s.jsonl", time.Now().UTC().Format("20060102T150405"))
+	}
+	f, err := os.Create(*logPath)
+	if err != nil {
+		log.Fatalf("Failed to create run log: %v", err)
+	}
+	defer f.Close()
+	runLog := pipeline.NewRunLog(f)
+
+	t := tasks()
+	if !*checkStatus {
+		t.CheckStatus = nil
+	}
+
+	start := time.Now()
+	out, err := pipeline.Run(ctx, t, json.RawMessage(*input), pipeline.Options{
+		Concurrency:      *concurrency,
+		BatchConcurrency: *batchConcurrency,
+		BatchThreshold:   *batchThreshold,
+		FastRetries:      *fastRetries,
+		Log:              runLog,
+	})
+	if logErr := runLog.Err(); logErr != nil {
+		log.Printf("Warning: %v", logErr)
+	}
+
+	fmt.Println()
+	fmt.Println("=== Pipeline Complete ===")
+	fmt.Printf("Run log:  %s\n", *logPath)
+	fmt.Printf("Duration: %s\n", time.Since(start).Round(time.Second))
+	if err != nil {
+		log.Fatalf("Execution failed: %v", err)
+	}
+	fmt.Printf("Output:   %s\n", out)
+}
+
+func replay(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	logPath := fs.String("log", "", "Run log to replay (required)")
+	live := fs.String("live", "", "Comma-separated states to run live, e.g. Scrape,DownloadSingleFiling")
+	record := fs.String("record", "", "Write the replay's own run log to this file")
+	fs.Parse(args)
+
+	if *logPath == "" {
+		log.Fatal("-log is required")
+	}
+	f, err := os.Open(*logPath)
+	if err != nil {
+		log.Fatalf("Failed to open run log: %v", err)
+	}
+	events, err := pipeline.ReadLog(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to read run log: %v", err)
+	}
+
+	var liveStates []string
+	if *live != "" {
+		liveStates = strings.Split(*live, ",")
+	}
+
+	opts := pipeline.Options{}
+	if *record != "" {
+		out, err := os.Create(*record)
+		if err != nil {
+			log.Fatalf("Failed to create run log: %v", err)
+		}
+		defer out.Close()
+		opts.Log = pipeline.NewRunLog(out)
+	}
+
+	result, err := pipeline.Replay(ctx, tasks(), events, liveStates, opts)
+	if result != nil {
+		for _, d := range result.Diffs {
+			fmt.Printf("DIFF %s (attempt %d)\n", d.Live.Path, d.Live.Attempt)
+			fmt.Printf("  recorded: %s\n", describe(d.Recorded))
+			fmt.Printf("  live:     %s\n", describe(d.Live))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== Replay Complete ===")
+	fmt.Printf("Events:  %d\n", len(events))
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+	fmt.Printf("Diffs:   %d\n", len(result.Diffs))
+	fmt.Printf("Output:  %s\n", result.Output)
+	if len(result.Diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func describe(ev pipeline.Event) string {
+	if ev.Error != nil {
+		return "error " + ev.Error.Error()
+	}
+	return string(ev.Output)
+}
+
+// tasks returns the workflow's handlers. Lambda handlers are invoked the
+// way the Lambda runtime invokes them, decoding and encoding the same JSON.
+func tasks() pipeline.Tasks {
+	return pipeline.Tasks{
+		GenerateChunks: lambdaTask(chunks.Handler),
+		Scrape:         lambdaTask(handler.Handler),
+		DownloadFiling: lambdaTask(sfn.Handler),
+		WriteManifest:  lambdaTask(manifest.Handler),
+		BatchWorker:    batchWorker,
+		CheckStatus:    lambdaTask(status.Handler),
+		Notify:         notifyTask,
+	}
+}
+
+func lambdaTask(handlerFunc interface{}) pipeline.Task {
+	h := lambda.NewHandler(handlerFunc)
+	return func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		return h.Invoke(ctx, payload)
+	}
+}
+
+// batchWorker runs one array child with the state machine's container
+// environment overrides on top of this process's environment
+func batchWorker(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var overrides map[string]string
+	if err := json.Unmarshal(payload, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing container environment: %w", err)
+	}
+	job, err := batchworker.NewJob(func(key string) string {
+		if val, ok := overrides[key]; ok {
+			return val
+		}
+		return os.Getenv(key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := batchworker.Run(ctx, job); err != nil {
+		return nil, err
+	}
+	return json.RawMessage("null"), nil
+}
+
+// notifyTask publishes through SNS when SNS_TOPIC_ARN is set and otherwise
+// prints the notification
+func notifyTask(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if os.Getenv("SNS_TOPIC_ARN") != "" {
+		return lambdaTask(notify.Handler)(ctx, payload)
+	}
+	var input notify.NotifyInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		return nil, fmt.Errorf("parsing notification: %w", err)
+	}
+	input, err := notify.Resolve(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	out, err := notify.Send(ctx, notifier.NewWriterNotifier(os.Stdout), input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,293 @@
+// reconcile re-searches recent HKEX announcements and flags stored filings
+// that HKEX has since withdrawn or changed.
+//
+// The Title Search API is queried for the date range on both boards and the
+// results are diffed against the stored filings (see services/scraper/
+// reconcile). Filings no longer listed are marked WITHDRAWN, and filings
+// whose document link changed are marked REPLACED and point at the new
+// link. Title changes update the title. Every change is recorded in
+// filing_events. With -redownload, replaced documents are downloaded again
+// to S3_BUCKET.
+//
+// Usage:
+//
+//	go run ./tools/reconcile                                   # last 7 days
+//	go run ./tools/reconcile -from 2024-03-01 -to 2024-03-31
+//	go run ./tools/reconcile -days 3 -redownload
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/storage"
+	"github.com/nicholaszhao/hkex-scraper/services/downloader"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/reconcile"
+)
+
+// markets are the boards searched
+var markets = []string{"SEHK", "GEM"}
+
+func main() {
+	days := flag.Int("days", 7, "Reconcile announcements released in the last N days")
+	fromFlag := flag.String("from", "", "Start date, YYYY-MM-DD (overrides -days)")
+	toFlag := flag.String("to", "", "End date, YYYY-MM-DD (default: today)")
+	redownload := flag.Bool("redownload", false, "Download replaced documents again (requires S3_BUCKET)")
+	dryRun := flag.Bool("dry-run", false, "Show changes without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	// Report dates are Hong Kong calendar dates
+	now := time.Now().In(models.HongKong)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -*days)
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse("2006-01-02", *fromFlag); err != nil {
+			log.Fatalf("Invalid -from date: %v", err)
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse("2006-01-02", *toFlag); err != nil {
+			log.Fatalf("Invalid -to date: %v", err)
+		}
+	}
+	if from.After(to) {
+		log.Fatal("-from must not be after -to")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	cfg := config.Load()
+
+	// Step 1: search the source again
+	search := api.NewSearchClient(cfg, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit))
+	var source []models.Filing
+	for _, market := range markets {
+		results, err := search.SearchByDateRange(from, to.Add(24*time.Hour-time.Second), market)
+		if err != nil {
+			log.Fatalf("Failed to search %s: %v", market, err)
+		}
+		for i := range results {
+			if results[i].StockCode == "" {
+				continue
+			}
+			source = append(source, *hkex.ToFiling(&results[i], market, nil).Filing)
+		}
+	}
+	log.Printf("Found %d announcements from %s to %s", len(source), from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	// Step 2: diff against the stored filings
+	stored, err := loadFilings(ctx, pool, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Fatalf("Failed to load filings: %v", err)
+	}
+	changes := reconcile.Diff(stored, source)
+	log.Printf("Compared %d stored filings: %d changes", len(stored), len(changes))
+
+	// Step 3: record the changes
+	counts := make(map[models.FilingEventType]int)
+	var replaced []models.Filing
+	for _, c := range changes {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("  %s %-13s %s\n", c.Filing.SourceID, c.Event, describe(c))
+		if !*dryRun {
+			if err := applyChange(ctx, pool, c); err != nil {
+				log.Printf("Error recording %s of %s: %v", c.Event, c.Filing.SourceID, err)
+				continue
+			}
+		}
+		counts[c.Event]++
+		if c.Event == models.FilingEventURLChanged {
+			f := c.Filing
+			f.SourceURL = c.NewValue
+			f.FileExtension = strings.TrimPrefix(strings.ToLower(path.Ext(c.NewValue)), ".")
+			replaced = append(replaced, f)
+		}
+	}
+
+	// Step 4: optionally download replaced documents again
+	redownloaded := 0
+	if *redownload && !*dryRun && len(replaced) > 0 {
+		redownloaded = redownloadAll(ctx, pool, replaced)
+	}
+
+	fmt.Println()
+	fmt.Println("=== Reconciliation Complete ===")
+	fmt.Printf("Date range:      %s to %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	fmt.Printf("Source results:  %d\n", len(source))
+	fmt.Printf("Stored filings:  %d\n", len(stored))
+	fmt.Printf("Withdrawn:       %d\n", counts[models.FilingEventWithdrawn])
+	fmt.Printf("Restored:        %d\n", counts[models.FilingEventRestored])
+	fmt.Printf("Title changed:   %d\n", counts[models.FilingEventTitleChanged])
+	fmt.Printf("URL changed:     %d\n", counts[models.FilingEventURLChanged])
+	if *redownload {
+		fmt.Printf("Re-downloaded:   %d\n", redownloaded)
+	}
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// loadFilings returns the HKEX filings released in [from, to)
+func loadFilings(ctx context.Context, pool *pgxpool.Pool, from, to time.Time) ([]models.Filing, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT source_id, exchange, COALESCE(company_id, ''), report_date, COALESCE(title, ''),
+			COALESCE(source_url, ''), COALESCE(file_extension, ''), COALESCE(processing_status, 'PENDING')
+		FROM filings
+		WHERE exchange = 'HKEX' AND report_date >= $1 AND report_date < $2
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filings []models.Filing
+	for rows.Next() {
+		var f models.Filing
+		if err := rows.Scan(&f.SourceID, &f.Exchange, &f.CompanyID, &f.ReportDate, &f.Title,
+			&f.SourceURL, &f.FileExtension, &f.ProcessingStatus); err != nil {
+			return nil, err
+		}
+		f.ID = f.SourceID
+		filings = append(filings, f)
+	}
+	return filings, rows.Err()
+}
+
+// applyChange updates a filing for a change and records the event
+func applyChange(ctx context.Context, pool *pgxpool.Pool, c reconcile.Change) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var update string
+	var args []interface{}
+	switch c.Event {
+	case models.FilingEventWithdrawn:
+		update = `processing_status = $3`
+		args = []interface{}{models.ProcessingStatusWithdrawn}
+	case models.FilingEventRestored:
+		update = `processing_status = CASE WHEN COALESCE(pdf_s3_key, '') <> '' THEN $3 ELSE $4 END`
+		args = []interface{}{models.ProcessingStatusCompleted, models.ProcessingStatusPending}
+	case models.FilingEventTitleChanged:
+		update = `title = $3`
+		args = []interface{}{c.NewValue}
+	case models.FilingEventURLChanged:
+		update = `source_url = $3, processing_status = $4`
+		args = []interface{}{c.NewValue, models.ProcessingStatusReplaced}
+	default:
+		return fmt.Errorf("unknown event %s", c.Event)
+	}
+
+	args = append([]interface{}{c.Filing.Exchange, c.Filing.SourceID}, args...)
+	if _, err := tx.Exec(ctx, `UPDATE filings SET `+update+`, updated_at = NOW() WHERE exchange = $1 AND source_id = $2`, args...); err != nil {
+		return fmt.Errorf("updating filing: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO filing_events (exchange, source_id, event_type, old_value, new_value)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+	`, c.Filing.Exchange, c.Filing.SourceID, string(c.Event), c.OldValue, c.NewValue); err != nil {
+		return fmt.Errorf("recording event: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// redownloadAll downloads replaced documents to S3_BUCKET and records the
+// outcome. It returns the number downloaded.
+func redownloadAll(ctx context.Context, pool *pgxpool.Pool, filings []models.Filing) int {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		log.Printf("S3_BUCKET is not set; skipping %d re-downloads", len(filings))
+		return 0
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "ap-east-1"
+	}
+	s3Store, err := storage.NewS3Storage(ctx, bucket, region)
+	if err != nil {
+		log.Printf("Failed to create S3 client: %v", err)
+		return 0
+	}
+
+	dl := downloader.New(downloader.DefaultConfig())
+	dl.SetStorage(s3Store)
+
+	downloaded := 0
+	for i := range filings {
+		if ctx.Err() != nil {
+			break
+		}
+		f := &filings[i]
+		r := dl.Download(ctx, f)
+		status, errorMsg := downloader.ResultStatus(r)
+		if _, err := pool.Exec(ctx, `
+			UPDATE filings SET pdf_s3_key = COALESCE(NULLIF($3, ''), pdf_s3_key), file_extension = $4,
+				processing_status = $5, processing_error = $6, updated_at = NOW()
+			WHERE exchange = $1 AND source_id = $2
+		`, f.Exchange, f.SourceID, r.S3Key, f.FileExtension, status, errorMsg); err != nil {
+			log.Printf("Error updating %s: %v", f.SourceID, err)
+			continue
+		}
+		if r.Success {
+			downloaded++
+		} else {
+			log.Printf("Error re-downloading %s: %v", f.SourceID, r.Error)
+		}
+	}
+	return downloaded
+}
+
+// describe summarises a change for the log
+func describe(c reconcile.Change) string {
+	switch c.Event {
+	case models.FilingEventTitleChanged, models.FilingEventURLChanged:
+		return fmt.Sprintf("%q -> %q", c.OldValue, c.NewValue)
+	}
+	return c.Filing.Title
+}
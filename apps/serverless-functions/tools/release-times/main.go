@@ -0,0 +1,217 @@
+// release-times sets the release time of stored HKEX filings and corrects
+// their report dates.
+//
+// HKEX release times used to be parsed without a zone, so filings stored
+// before released_at existed have no release time, and report dates derived
+// from a UTC instant can be a day off. The tool searches the Title Search API
+// again for the date range on both boards, sets released_at to each
+// announcement's UTC release time and report_date to its Hong Kong calendar
+// date. Stored documents whose key (pdf_s3_key) is filed under another date
+// are listed; they are not moved.
+//
+// Usage:
+//
+//	go run ./tools/release-times -from 2024-01-01 -to 2024-03-31 -dry-run
+//	go run ./tools/release-times -from 2024-01-01
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/services/exchange/hkex"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/api"
+)
+
+// markets are the boards searched
+var markets = []string{"SEHK", "GEM"}
+
+// storedFiling is the stored state of a filing's dates
+type storedFiling struct {
+	reportDate time.Time
+	releasedAt *time.Time
+	s3Key      string
+}
+
+func main() {
+	fromFlag := flag.String("from", "", "Start date, YYYY-MM-DD (required)")
+	toFlag := flag.String("to", "", "End date, YYYY-MM-DD (default: today)")
+	dryRun := flag.Bool("dry-run", false, "Show changes without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	if *fromFlag == "" {
+		log.Fatal("-from is required")
+	}
+	from, err := time.ParseInLocation("2006-01-02", *fromFlag, models.HongKong)
+	if err != nil {
+		log.Fatalf("Invalid -from date: %v", err)
+	}
+	now := time.Now().In(models.HongKong)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, models.HongKong)
+	if *toFlag != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *toFlag, models.HongKong); err != nil {
+			log.Fatalf("Invalid -to date: %v", err)
+		}
+	}
+	if from.After(to) {
+		log.Fatal("-from must not be after -to")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	cfg := config.Load()
+
+	// Step 1: search the source again
+	search := api.NewSearchClient(cfg, ratelimit.ForHost(cfg.BaseURL, cfg.RateLimit))
+	var source []*models.Filing
+	for _, market := range markets {
+		results, err := search.SearchByDateRange(from, to.Add(24*time.Hour-time.Second), market)
+		if err != nil {
+			log.Fatalf("Failed to search %s: %v", market, err)
+		}
+		for i := range results {
+			if results[i].StockCode == "" {
+				continue
+			}
+			if f := hkex.ToFiling(&results[i], market, nil).Filing; f.ReleasedAt != nil {
+				source = append(source, f)
+			}
+		}
+	}
+	log.Printf("Found %d announcements from %s to %s", len(source), from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	// Step 2: load stored filings, a day either side to catch misdated rows
+	stored, err := loadFilings(ctx, pool, models.HKEXDate(from).AddDate(0, 0, -1), models.HKEXDate(to).AddDate(0, 0, 2))
+	if err != nil {
+		log.Fatalf("Failed to load filings: %v", err)
+	}
+
+	// Step 3: compare and fix
+	var matched, released, redated, misfiled int
+	batch := &pgx.Batch{}
+	for _, f := range source {
+		s, ok := stored[f.SourceID]
+		if !ok {
+			continue
+		}
+		matched++
+
+		if key := s.s3Key; key != "" {
+			if d, ok := keyDate(key); ok && !d.Equal(f.ReportDate) {
+				fmt.Printf("  %s misfiled: %s (released %s)\n", f.SourceID, key, f.ReportDate.Format("2006/01/02"))
+				misfiled++
+			}
+		}
+
+		dateChanged := !models.ListingDate(s.reportDate).Equal(f.ReportDate)
+		releaseChanged := s.releasedAt == nil || !s.releasedAt.Equal(*f.ReleasedAt)
+		if !dateChanged && !releaseChanged {
+			continue
+		}
+		if dateChanged {
+			log.Printf("%s report date %s -> %s", f.SourceID, s.reportDate.Format("2006-01-02"), f.ReportDate.Format("2006-01-02"))
+			redated++
+		}
+		if releaseChanged {
+			released++
+		}
+		batch.Queue(`
+			UPDATE filings SET released_at = $2, report_date = $3, updated_at = NOW()
+			WHERE exchange = 'HKEX' AND source_id = $1
+		`, f.SourceID, *f.ReleasedAt, f.ReportDate)
+	}
+
+	if !*dryRun && batch.Len() > 0 {
+		if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+			log.Fatalf("Failed to update filings: %v", err)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== Release Times Complete ===")
+	fmt.Printf("Date range:      %s to %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	fmt.Printf("Source results:  %d\n", len(source))
+	fmt.Printf("Stored filings:  %d\n", matched)
+	fmt.Printf("Release times:   %d\n", released)
+	fmt.Printf("Report dates:    %d\n", redated)
+	fmt.Printf("Misfiled keys:   %d\n", misfiled)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// loadFilings returns the HKEX filings with report dates in [from, to), by
+// source ID
+func loadFilings(ctx context.Context, pool *pgxpool.Pool, from, to time.Time) (map[string]storedFiling, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT source_id, report_date, released_at, COALESCE(pdf_s3_key, '')
+		FROM filings
+		WHERE exchange = 'HKEX' AND report_date >= $1 AND report_date < $2
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filings := make(map[string]storedFiling)
+	for rows.Next() {
+		var id string
+		var f storedFiling
+		if err := rows.Scan(&id, &f.reportDate, &f.releasedAt, &f.s3Key); err != nil {
+			return nil, err
+		}
+		filings[id] = f
+	}
+	return filings, rows.Err()
+}
+
+// keyDate returns the date segment of a document key laid out as
+// exchange/company_id/YYYY/MM/DD/file
+func keyDate(key string) (time.Time, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) < 6 {
+		return time.Time{}, false
+	}
+	n := len(parts)
+	d, err := time.Parse("2006/01/02", strings.Join(parts[n-4:n-1], "/"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d, true
+}
@@ -0,0 +1,394 @@
+// split-companies repairs companies that were conflated because HKEX
+// reassigned their stock code after a delisting.
+//
+// Detect mode scans filing history for long gaps under one stock code and
+// prints suggested listing periods as CSV. Apply mode reads listing periods
+// (stock_code,listed_from,listed_to[,company_name]) and splits each code into
+// one company per period. Filings and every other dated row referencing the
+// code's companies (models.CompanyReferences) move to the company whose period
+// covers their date; undated rows, such as A+H links, move to the latest.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+)
+
+// Period is one listing of a stock code, as read from the apply CSV
+type Period struct {
+	StockCode   models.StockCode
+	ListedFrom  *time.Time // nil = open start
+	ListedTo    *time.Time // exclusive; nil = still listed
+	CompanyName string
+}
+
+func main() {
+	detect := flag.Bool("detect", false, "Print suggested listing periods for codes with long filing gaps")
+	gapDays := flag.Int("gap", 365, "Minimum gap in days between filings that suggests a code was reused (detect mode)")
+	apply := flag.String("apply", "", "CSV of listing periods to apply (stock_code,listed_from,listed_to[,company_name])")
+	dryRun := flag.Bool("dry-run", false, "Show what would change without writing (apply mode)")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	if *detect == (*apply != "") {
+		fmt.Println("Usage: split-companies -detect [-gap 365] > periods.csv")
+		fmt.Println("       split-companies -apply periods.csv [-dry-run]")
+		os.Exit(1)
+	}
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	if *detect {
+		if err := detectGaps(ctx, pool, *gapDays, os.Stdout); err != nil {
+			log.Fatalf("Detection failed: %v", err)
+		}
+		return
+	}
+
+	f, err := os.Open(*apply)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *apply, err)
+	}
+	defer f.Close()
+
+	periods, err := readPeriods(f)
+	if err != nil {
+		log.Fatalf("Failed to read periods: %v", err)
+	}
+
+	byCode := make(map[models.StockCode][]Period)
+	for _, p := range periods {
+		byCode[p.StockCode] = append(byCode[p.StockCode], p)
+	}
+
+	codes := make([]models.StockCode, 0, len(byCode))
+	for code := range byCode {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	split, moved := 0, int64(0)
+	for _, code := range codes {
+		n, err := splitCode(ctx, pool, code, byCode[code], *dryRun)
+		if err != nil {
+			log.Printf("Error splitting %s: %v", code, err)
+			continue
+		}
+		split++
+		moved += n
+	}
+
+	fmt.Println()
+	fmt.Println("=== Split Complete ===")
+	fmt.Printf("Stock codes:    %d/%d\n", split, len(codes))
+	fmt.Printf("Filings moved:  %d\n", moved)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	} else if moved > 0 {
+		fmt.Println("\nNote: pdf_s3_key values are unchanged; moved filings keep their existing S3 objects")
+	}
+}
+
+// detectGaps writes suggested periods for every stock code whose filing
+// history has a gap of at least gapDays. Each gap closes one period on the
+// first filing date after the gap and opens the next.
+func detectGaps(ctx context.Context, pool *pgxpool.Pool, gapDays int, out io.Writer) error {
+	rows, err := pool.Query(ctx, `
+		SELECT stock_code, prev_date, report_date FROM (
+			SELECT c.stock_code, f.report_date,
+				LAG(f.report_date) OVER (PARTITION BY c.stock_code ORDER BY f.report_date) AS prev_date
+			FROM filings f
+			JOIN companies c ON c.exchange = f.exchange AND c.company_id = f.company_id
+			WHERE f.exchange = 'HKEX' AND c.stock_code IS NOT NULL
+		) g
+		WHERE prev_date IS NOT NULL AND report_date - prev_date >= $1 * INTERVAL '1 day'
+		ORDER BY stock_code, report_date
+	`, gapDays)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"stock_code", "listed_from", "listed_to", "company_name"})
+
+	var lastCode, lastBoundary string
+	flush := func() {
+		if lastCode != "" {
+			w.Write([]string{lastCode, lastBoundary, "", ""})
+		}
+	}
+
+	gaps := 0
+	for rows.Next() {
+		var code string
+		var prev, next time.Time
+		if err := rows.Scan(&code, &prev, &next); err != nil {
+			return err
+		}
+		if code != lastCode {
+			flush()
+			lastCode, lastBoundary = code, ""
+		}
+		boundary := next.Format("2006-01-02")
+		w.Write([]string{code, lastBoundary, boundary, ""})
+		lastBoundary = boundary
+		gaps++
+
+		log.Printf("%s: no filings between %s and %s", code, prev.Format("2006-01-02"), next.Format("2006-01-02"))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	flush()
+
+	w.Flush()
+	log.Printf("Found %d gaps of %d+ days; review the periods and fill in company names before applying", gaps, gapDays)
+	return w.Error()
+}
+
+// readPeriods parses the apply CSV. A header row starting with "stock_code"
+// is skipped. Dates are YYYY-MM-DD; empty dates are open bounds.
+func readPeriods(r io.Reader) ([]Period, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var periods []Period
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 columns, got %d", line, len(rec))
+		}
+		if line == 1 && strings.TrimSpace(rec[0]) == "stock_code" {
+			continue
+		}
+
+		code, err := models.ParseStockCode(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		p := Period{StockCode: code}
+		if p.ListedFrom, err = parseDate(rec[1]); err != nil {
+			return nil, fmt.Errorf("line %d: listed_from: %w", line, err)
+		}
+		if p.ListedTo, err = parseDate(rec[2]); err != nil {
+			return nil, fmt.Errorf("line %d: listed_to: %w", line, err)
+		}
+		if len(rec) > 3 {
+			p.CompanyName = strings.TrimSpace(rec[3])
+		}
+		periods = append(periods, p)
+	}
+
+	return periods, nil
+}
+
+func parseDate(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// splitCode replaces the listings of one stock code with the given periods
+// and moves the rows of models.CompanyReferences to the company of the period
+// covering their date, or of the latest period if they are undated. The
+// earliest period keeps the bare stock code as its company ID. It returns the
+// number of filings moved.
+func splitCode(ctx context.Context, pool *pgxpool.Pool, code models.StockCode, periods []Period, dryRun bool) (int64, error) {
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].ListedFrom == nil {
+			return periods[j].ListedFrom != nil
+		}
+		return periods[j].ListedFrom != nil && periods[i].ListedFrom.Before(*periods[j].ListedFrom)
+	})
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Every company currently holding this code, so rows can be moved between them
+	companyIDs, fallbackName, err := companiesForCode(ctx, tx, code)
+	if err != nil {
+		return 0, fmt.Errorf("listing companies: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM company_listings WHERE exchange = 'HKEX' AND stock_code = $1`, code); err != nil {
+		return 0, fmt.Errorf("clearing listings: %w", err)
+	}
+
+	var moved int64
+	for i, p := range periods {
+		var companyID string
+		if i == 0 {
+			companyID = models.CompanyIDForListing(code, nil)
+		} else {
+			companyID = models.CompanyIDForListing(code, p.ListedFrom)
+		}
+
+		name := p.CompanyName
+		if name == "" {
+			name = fallbackName
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO companies (company_id, name, stock_code, exchange, updated_at)
+			VALUES ($1, $2, $3, 'HKEX', NOW())
+			ON CONFLICT(exchange, company_id) DO UPDATE SET
+				name = CASE WHEN $4 THEN EXCLUDED.name ELSE companies.name END,
+				stock_code = EXCLUDED.stock_code,
+				updated_at = NOW()
+		`, companyID, name, code, p.CompanyName != ""); err != nil {
+			return 0, fmt.Errorf("upserting company %s: %w", companyID, err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO company_listings (id, company_id, exchange, stock_code, listed_from, listed_to)
+			VALUES ($1, $2, 'HKEX', $3, $4, $5)
+		`, "lst_HKEX_"+companyID, companyID, code, p.ListedFrom, p.ListedTo); err != nil {
+			return 0, fmt.Errorf("inserting listing for %s: %w", companyID, err)
+		}
+
+		var filings int64
+		for _, ref := range models.CompanyReferences {
+			if ref.Table == "company_listings" || (ref.DateColumn == "" && i < len(periods)-1) {
+				continue
+			}
+			n, err := moveRows(ctx, tx, ref, companyIDs, companyID, p)
+			if err != nil {
+				return 0, fmt.Errorf("moving %s.%s to %s: %w", ref.Table, ref.CompanyColumn, companyID, err)
+			}
+			if ref.Table == "filings" {
+				filings = n
+			} else if n > 0 {
+				log.Printf("%s: %s (%d %s rows moved)", code, companyID, n, ref.Table)
+			}
+		}
+		moved += filings
+
+		log.Printf("%s: %s %s..%s (%d filings moved)", code, companyID,
+			formatBound(p.ListedFrom), formatBound(p.ListedTo), filings)
+	}
+
+	if dryRun {
+		return moved, nil
+	}
+	return moved, tx.Commit(ctx)
+}
+
+// moveRows moves the rows of one reference held by any of the companies ids to
+// company to. Dated rows move only if their date falls in the period; rows
+// that would duplicate a unique key of the target stay where they are.
+func moveRows(ctx context.Context, tx pgx.Tx, ref models.CompanyReference, ids []string, to string, p Period) (int64, error) {
+	set := ref.CompanyColumn + " = $1"
+	if ref.Touch {
+		set += ", updated_at = NOW()"
+	}
+	conds := []string{
+		ref.ExchangeColumn + " = 'HKEX'",
+		ref.CompanyColumn + " = ANY($2)",
+		ref.CompanyColumn + " <> $1",
+	}
+	args := []interface{}{to, ids}
+	if ref.DateColumn != "" {
+		conds = append(conds,
+			fmt.Sprintf("($3::date IS NULL OR %s >= $3)", ref.DateColumn),
+			fmt.Sprintf("($4::date IS NULL OR %s < $4)", ref.DateColumn))
+		args = append(args, p.ListedFrom, p.ListedTo)
+	}
+	if keep := ref.KeepsKey("$1"); keep != "" {
+		conds = append(conds, keep)
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf("UPDATE %s t SET %s WHERE %s", ref.Table, set, strings.Join(conds, " AND ")), args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// companiesForCode returns the IDs of all companies that hold the stock code
+// and the name of the most recently updated one
+func companiesForCode(ctx context.Context, tx pgx.Tx, code models.StockCode) ([]string, string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT company_id, name FROM companies
+		WHERE exchange = 'HKEX' AND stock_code = $1
+		ORDER BY updated_at DESC
+	`, code)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var ids []string
+	var name string
+	for rows.Next() {
+		var id, n string
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, "", err
+		}
+		if name == "" {
+			name = n
+		}
+		ids = append(ids, id)
+	}
+	return ids, name, rows.Err()
+}
+
+func formatBound(t *time.Time) string {
+	if t == nil {
+		return "open"
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,299 @@
+// sync-interests loads HKEX Disclosure of Interests notices into
+// interest_notices.
+//
+// Sync is incremental per company: interest_sync records the date each
+// company was synced to, and the next run searches from that date less an
+// overlap for late filings. Companies never synced are searched from -from.
+// Forms are only fetched for notices not yet stored.
+//
+// Usage:
+//
+//	go run ./tools/sync-interests                      # all HKEX equities
+//	go run ./tools/sync-interests -codes 00005,00700   # selected stock codes
+//	go run ./tools/sync-interests -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/config"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/models"
+	"github.com/nicholaszhao/hkex-scraper/packages/go/ratelimit"
+	"github.com/nicholaszhao/hkex-scraper/services/scraper/di"
+)
+
+// target is a company to sync
+type target struct {
+	companyID string
+	stockCode models.StockCode
+	syncedTo  *time.Time
+}
+
+func main() {
+	codesFlag := flag.String("codes", "", "Comma-separated stock codes to sync (default: all HKEX equities)")
+	fromFlag := flag.String("from", "", "Start date for companies never synced, YYYY-MM-DD (default: one year ago)")
+	overlap := flag.Int("overlap", 14, "Days before the last sync date to search again for late filings")
+	dryRun := flag.Bool("dry-run", false, "Fetch and print notices without writing")
+	dbURL := flag.String("db", "", "PostgreSQL connection string (default: DATABASE_URL env)")
+	flag.Parse()
+
+	dsn := *dbURL
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+	}
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is required (set env or pass -db flag)")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	initialFrom := today.AddDate(-1, 0, 0)
+	if *fromFlag != "" {
+		var err error
+		if initialFrom, err = time.Parse("2006-01-02", *fromFlag); err != nil {
+			log.Fatalf("Invalid -from date: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down...")
+		cancel()
+	}()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	targets, err := loadTargets(ctx, pool, parseCodes(*codesFlag))
+	if err != nil {
+		log.Fatalf("Failed to load companies: %v", err)
+	}
+	log.Printf("Syncing interest notices of %d companies", len(targets))
+
+	cfg := config.Load()
+	client := di.NewClient(cfg.DIBaseURL, ratelimit.ForHost(cfg.DIBaseURL, cfg.RateLimit))
+
+	found, saved, failed := 0, 0, 0
+	for i, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		from := initialFrom
+		if t.syncedTo != nil {
+			from = t.syncedTo.AddDate(0, 0, -*overlap)
+		}
+
+		n, s, err := syncCompany(ctx, pool, client, t, from, today, *dryRun)
+		found += n
+		saved += s
+		if err != nil {
+			log.Printf("Error syncing %s: %v", t.stockCode, err)
+			failed++
+			continue
+		}
+
+		if (i+1)%50 == 0 {
+			log.Printf("Progress: %d/%d companies, %d new notices", i+1, len(targets), saved)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== Interest Sync Complete ===")
+	fmt.Printf("Companies:       %d\n", len(targets))
+	fmt.Printf("Notices found:   %d\n", found)
+	fmt.Printf("New notices:     %d\n", saved)
+	fmt.Printf("Errors:          %d\n", failed)
+	if *dryRun {
+		fmt.Println("\n(dry-run mode - no changes were written)")
+	}
+}
+
+// syncCompany fetches a company's notices between from and to, stores the
+// new ones and advances its sync date. A company whose forms fail is not
+// advanced, so the next run retries them.
+func syncCompany(ctx context.Context, pool *pgxpool.Pool, client *di.Client, t target, from, to time.Time, dryRun bool) (found, saved int, err error) {
+	notices, err := client.Notices(ctx, t.stockCode.String(), from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stored, err := storedSerials(ctx, pool, notices)
+	if err != nil {
+		return len(notices), 0, fmt.Errorf("checking stored notices: %w", err)
+	}
+
+	formErrors := 0
+	for i := range notices {
+		n := &notices[i]
+		if stored[n.SerialNumber] {
+			continue
+		}
+		if err := client.FillForm(ctx, n); err != nil {
+			log.Printf("  %s: %v", n.SerialNumber, err)
+			formErrors++
+			continue
+		}
+		n.CompanyID = t.companyID
+
+		if dryRun {
+			fmt.Printf("  %s %s  %-40s %s  %d -> %d (%.2f%%)\n", t.stockCode, n.EventDate.Format("2006-01-02"),
+				n.FilerName, n.ReasonCode, n.SharesBefore, n.SharesAfter, n.PercentAfter)
+			saved++
+			continue
+		}
+		if err := saveNotice(ctx, pool, n); err != nil {
+			return len(notices), saved, fmt.Errorf("saving %s: %w", n.SerialNumber, err)
+		}
+		saved++
+	}
+
+	if formErrors > 0 {
+		return len(notices), saved, fmt.Errorf("%d forms failed", formErrors)
+	}
+	if !dryRun {
+		if err := markSynced(ctx, pool, t.companyID, to); err != nil {
+			return len(notices), saved, fmt.Errorf("updating sync date: %w", err)
+		}
+	}
+	return len(notices), saved, nil
+}
+
+// loadTargets returns the HKEX equities to sync with their current stock
+// codes. Companies whose listings have all ended are skipped, since their
+// codes may since have been reassigned.
+func loadTargets(ctx context.Context, pool *pgxpool.Pool, codes map[models.StockCode]bool) ([]target, error) {
+	var listings bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('company_listings') IS NOT NULL`).Scan(&listings); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.company_id, c.stock_code, s.synced_to
+		FROM companies c
+		LEFT JOIN interest_sync s ON s.exchange = c.exchange AND s.company_id = c.company_id
+		WHERE c.exchange = 'HKEX' AND COALESCE(c.instrument_category, '') IN ('', 'Equity')
+		ORDER BY c.stock_code
+	`
+	if listings {
+		query = `
+			SELECT c.company_id, COALESCE(l.stock_code, c.stock_code), s.synced_to
+			FROM companies c
+			LEFT JOIN company_listings l
+				ON l.exchange = c.exchange AND l.company_id = c.company_id AND l.listed_to IS NULL
+			LEFT JOIN interest_sync s ON s.exchange = c.exchange AND s.company_id = c.company_id
+			WHERE c.exchange = 'HKEX' AND COALESCE(c.instrument_category, '') IN ('', 'Equity')
+				AND (l.company_id IS NOT NULL OR NOT EXISTS (
+					SELECT 1 FROM company_listings e WHERE e.exchange = c.exchange AND e.company_id = c.company_id
+				))
+			ORDER BY 2
+		`
+	}
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []target
+	for rows.Next() {
+		var t target
+		var code string
+		if err := rows.Scan(&t.companyID, &code, &t.syncedTo); err != nil {
+			return nil, err
+		}
+		t.stockCode = models.NormalizeStockCode("HKEX", code)
+		if len(codes) > 0 && !codes[t.stockCode] {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+// storedSerials returns the serial numbers among notices already stored
+func storedSerials(ctx context.Context, pool *pgxpool.Pool, notices []models.InterestNotice) (map[string]bool, error) {
+	serials := make([]string, len(notices))
+	for i, n := range notices {
+		serials[i] = n.SerialNumber
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT serial_number FROM interest_notices
+		WHERE exchange = 'HKEX' AND serial_number = ANY($1)
+	`, serials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]bool)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		stored[s] = true
+	}
+	return stored, rows.Err()
+}
+
+// saveNotice upserts a notice
+func saveNotice(ctx context.Context, pool *pgxpool.Pool, n *models.InterestNotice) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO interest_notices (exchange, serial_number, company_id, stock_code, form_type,
+			filer_name, reason_code, capacity, shares_involved, average_price,
+			shares_before, percent_before, shares_after, percent_after, event_date, source_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (exchange, serial_number) DO UPDATE SET
+			company_id = EXCLUDED.company_id,
+			capacity = EXCLUDED.capacity,
+			shares_before = EXCLUDED.shares_before,
+			percent_before = EXCLUDED.percent_before,
+			shares_after = EXCLUDED.shares_after,
+			percent_after = EXCLUDED.percent_after,
+			source_url = EXCLUDED.source_url,
+			updated_at = NOW()
+	`, n.Exchange, n.SerialNumber, n.CompanyID, n.StockCode, n.FormType,
+		n.FilerName, n.ReasonCode, n.Capacity, n.SharesInvolved, n.AveragePrice,
+		n.SharesBefore, n.PercentBefore, n.SharesAfter, n.PercentAfter, n.EventDate, n.SourceURL)
+	return err
+}
+
+// markSynced records the date a company was synced to
+func markSynced(ctx context.Context, pool *pgxpool.Pool, companyID string, to time.Time) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO interest_sync (exchange, company_id, synced_to)
+		VALUES ('HKEX', $1, $2)
+		ON CONFLICT (exchange, company_id) DO UPDATE SET synced_to = EXCLUDED.synced_to, updated_at = NOW()
+	`, companyID, to)
+	return err
+}
+
+// parseCodes parses the -codes flag into normalized stock codes
+func parseCodes(s string) map[models.StockCode]bool {
+	codes := make(map[models.StockCode]bool)
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			codes[models.NormalizeStockCode("HKEX", c)] = true
+		}
+	}
+	return codes
+}
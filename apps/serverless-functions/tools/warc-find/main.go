@@ -0,0 +1,136 @@
+// warc-find locates the WARC captures of a filing or URL through the CDXJ
+// indexes written next to the archives, and extracts a captured document.
+//
+// Archives uploaded by the downloaders can be fetched first with
+// "aws s3 sync s3://<bucket>/<WARC_PREFIX> ./warc". The directory can also be
+// served by any CDXJ-aware replay tool such as pywb.
+//
+// Usage:
+//
+//	go run ./tools/warc-find -dir ./warc -filing 11223344
+//	go run ./tools/warc-find -dir ./warc -url https://www1.hkexnews.hk/listedco/listconews/sehk/2024/0328/2024032800122.pdf
+//	go run ./tools/warc-find -dir ./warc -filing 11223344 -extract ./11223344.pdf
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/nicholaszhao/hkex-scraper/packages/go/warc"
+)
+
+func main() {
+	dir := flag.String("dir", "./warc", "Directory of WARC files and their CDXJ indexes")
+	filingID := flag.String("filing", "", "Filing ID to look up")
+	targetURL := flag.String("url", "", "Source URL to look up")
+	extract := flag.String("extract", "", "Write the body of the latest capture to this file")
+	flag.Parse()
+
+	if *filingID == "" && *targetURL == "" {
+		log.Fatal("One of -filing or -url is required")
+	}
+
+	var surt string
+	if *targetURL != "" {
+		surt = warc.SURT(*targetURL)
+	}
+
+	indexes, err := filepath.Glob(filepath.Join(*dir, "*.cdxj"))
+	if err != nil {
+		log.Fatalf("Failed to list indexes: %v", err)
+	}
+
+	var matches []*warc.IndexEntry
+	for _, path := range indexes {
+		err := scan(path, func(e *warc.IndexEntry) {
+			if surt != "" && e.SURT != surt {
+				return
+			}
+			if *filingID != "" && e.Fields["filing_id"] != *filingID {
+				return
+			}
+			matches = append(matches, e)
+		})
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No captures found.")
+		os.Exit(1)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Timestamp < matches[j].Timestamp
+	})
+	for _, e := range matches {
+		fmt.Printf("%s  %-3s  %s  %s@%s\n", e.Timestamp, e.Status, e.URL, e.Filename, e.Offset)
+		if id, ok := e.Fields["filing_id"].(string); ok {
+			fmt.Printf("    filing %s, sha256 %v\n", id, e.Fields["sha256"])
+		}
+	}
+
+	if *extract != "" {
+		latest := matches[len(matches)-1]
+		n, err := extractBody(filepath.Join(*dir, latest.Filename), latest.Offset, *extract)
+		if err != nil {
+			log.Fatalf("Failed to extract %s: %v", latest.URL, err)
+		}
+		fmt.Printf("\nExtracted %d bytes to %s\n", n, *extract)
+	}
+}
+
+// scan calls fn with each entry of a CDXJ index
+func scan(path string, fn func(*warc.IndexEntry)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, err := warc.ParseIndexLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		fn(entry)
+	}
+	return scanner.Err()
+}
+
+// extractBody writes the HTTP response body of the record at offset in a
+// WARC file to dest
+func extractBody(warcPath, offset, dest string) (int, error) {
+	off, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing offset: %w", err)
+	}
+
+	f, err := os.Open(warcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(off, 0); err != nil {
+		return 0, err
+	}
+
+	rec, err := warc.ReadRecord(f)
+	if err != nil {
+		return 0, err
+	}
+	_, body, err := rec.Response()
+	if err != nil {
+		return 0, err
+	}
+	return len(body), os.WriteFile(dest, body, 0644)
+}
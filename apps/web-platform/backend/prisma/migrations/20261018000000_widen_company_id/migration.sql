@@ -0,0 +1,4 @@
+-- AlterTable
+-- A recycled HKEX stock code gives the later issuer an ID with its listing
+-- date appended (e.g. "01234_20240105"), which needs more than 8 characters
+ALTER TABLE "companies" ALTER COLUMN "company_id" SET DATA TYPE VARCHAR(32);
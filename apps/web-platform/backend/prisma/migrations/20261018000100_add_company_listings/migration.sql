@@ -0,0 +1,19 @@
+-- CreateTable
+CREATE TABLE "company_listings" (
+    "id" TEXT NOT NULL,
+    "exchange" TEXT NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "listed_from" DATE,
+    "listed_to" DATE,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "company_listings_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_company_listings_stock_code" ON "company_listings"("exchange", "stock_code");
+
+-- AddForeignKey
+ALTER TABLE "company_listings" ADD CONSTRAINT "company_listings_exchange_company_id_fkey" FOREIGN KEY ("exchange", "company_id") REFERENCES "companies"("exchange", "company_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
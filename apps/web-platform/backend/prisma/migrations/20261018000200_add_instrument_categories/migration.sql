@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "companies" ADD COLUMN "instrument_category" TEXT,
+ADD COLUMN "instrument_sub_category" TEXT;
@@ -0,0 +1,49 @@
+-- CreateTable
+CREATE TABLE "company_name_history" (
+    "id" BIGSERIAL NOT NULL,
+    "exchange" TEXT NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "name" TEXT NOT NULL,
+    "valid_from" DATE,
+    "valid_to" DATE,
+
+    CONSTRAINT "company_name_history_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "security_snapshots" (
+    "exchange" TEXT NOT NULL,
+    "snapshot_date" DATE NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "name" TEXT NOT NULL,
+    "category" TEXT,
+    "sub_category" TEXT,
+    "board_lot" INTEGER,
+    "isin" TEXT,
+
+    CONSTRAINT "security_snapshots_pkey" PRIMARY KEY ("exchange","snapshot_date","stock_code")
+);
+
+-- CreateTable
+CREATE TABLE "security_events" (
+    "id" BIGSERIAL NOT NULL,
+    "exchange" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "event_date" DATE NOT NULL,
+    "event_type" TEXT NOT NULL,
+    "old_value" TEXT,
+    "new_value" TEXT,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "security_events_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_company_name_history_company" ON "company_name_history"("exchange", "company_id");
+
+-- CreateIndex
+CREATE INDEX "idx_security_events_date" ON "security_events"("event_date");
+
+-- CreateIndex
+CREATE UNIQUE INDEX "security_events_exchange_stock_code_event_date_event_type_key" ON "security_events"("exchange", "stock_code", "event_date", "event_type");
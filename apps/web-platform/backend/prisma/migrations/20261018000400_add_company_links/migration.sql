@@ -0,0 +1,17 @@
+-- CreateTable
+CREATE TABLE "company_links" (
+    "exchange" TEXT NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "linked_exchange" TEXT NOT NULL,
+    "linked_company_id" TEXT NOT NULL,
+    "link_type" TEXT NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "company_links_pkey" PRIMARY KEY ("exchange","company_id","linked_exchange","linked_company_id")
+);
+
+-- AddForeignKey
+ALTER TABLE "company_links" ADD CONSTRAINT "company_links_exchange_company_id_fkey" FOREIGN KEY ("exchange", "company_id") REFERENCES "companies"("exchange", "company_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
+
+-- AddForeignKey
+ALTER TABLE "company_links" ADD CONSTRAINT "company_links_linked_exchange_linked_company_id_fkey" FOREIGN KEY ("linked_exchange", "linked_company_id") REFERENCES "companies"("exchange", "company_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
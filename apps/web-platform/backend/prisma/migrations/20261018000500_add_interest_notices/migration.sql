@@ -0,0 +1,39 @@
+-- CreateTable
+CREATE TABLE "interest_notices" (
+    "exchange" TEXT NOT NULL,
+    "serial_number" TEXT NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "form_type" TEXT NOT NULL,
+    "filer_name" TEXT NOT NULL,
+    "reason_code" TEXT,
+    "capacity" TEXT,
+    "shares_involved" BIGINT,
+    "average_price" TEXT,
+    "shares_before" BIGINT,
+    "percent_before" DOUBLE PRECISION,
+    "shares_after" BIGINT,
+    "percent_after" DOUBLE PRECISION,
+    "event_date" DATE NOT NULL,
+    "source_url" TEXT,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "interest_notices_pkey" PRIMARY KEY ("exchange","serial_number")
+);
+
+-- CreateTable
+CREATE TABLE "interest_sync" (
+    "exchange" TEXT NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "synced_to" DATE NOT NULL,
+    "updated_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "interest_sync_pkey" PRIMARY KEY ("exchange","company_id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_interest_notices_company" ON "interest_notices"("exchange", "company_id", "event_date");
+
+-- AddForeignKey
+ALTER TABLE "interest_notices" ADD CONSTRAINT "interest_notices_exchange_company_id_fkey" FOREIGN KEY ("exchange", "company_id") REFERENCES "companies"("exchange", "company_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
@@ -0,0 +1,56 @@
+-- CreateTable
+CREATE TABLE "ccass_snapshots" (
+    "exchange" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "holding_date" DATE NOT NULL,
+    "company_id" TEXT NOT NULL,
+    "issued_shares" BIGINT,
+    "participants" INTEGER NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "ccass_snapshots_pkey" PRIMARY KEY ("exchange","stock_code","holding_date")
+);
+
+-- CreateTable
+CREATE TABLE "ccass_holdings" (
+    "exchange" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "holding_date" DATE NOT NULL,
+    "participant_id" TEXT NOT NULL,
+    "participant_name" TEXT NOT NULL,
+    "address" TEXT,
+    "shareholding" BIGINT NOT NULL,
+    "percent" DOUBLE PRECISION,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "ccass_holdings_pkey" PRIMARY KEY ("exchange","stock_code","holding_date","participant_id")
+);
+
+-- CreateTable
+CREATE TABLE "ccass_changes" (
+    "exchange" TEXT NOT NULL,
+    "stock_code" TEXT NOT NULL,
+    "holding_date" DATE NOT NULL,
+    "previous_date" DATE NOT NULL,
+    "participant_id" TEXT NOT NULL,
+    "participant_name" TEXT NOT NULL,
+    "shares_before" BIGINT NOT NULL,
+    "shares_after" BIGINT NOT NULL,
+    "change" BIGINT NOT NULL,
+    "percent_before" DOUBLE PRECISION,
+    "percent_after" DOUBLE PRECISION,
+
+    CONSTRAINT "ccass_changes_pkey" PRIMARY KEY ("exchange","stock_code","holding_date","participant_id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_ccass_holdings_participant" ON "ccass_holdings"("exchange", "participant_id", "holding_date");
+
+-- AddForeignKey
+ALTER TABLE "ccass_snapshots" ADD CONSTRAINT "ccass_snapshots_exchange_company_id_fkey" FOREIGN KEY ("exchange", "company_id") REFERENCES "companies"("exchange", "company_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
+
+-- AddForeignKey
+ALTER TABLE "ccass_holdings" ADD CONSTRAINT "ccass_holdings_exchange_stock_code_holding_date_fkey" FOREIGN KEY ("exchange", "stock_code", "holding_date") REFERENCES "ccass_snapshots"("exchange", "stock_code", "holding_date") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+-- AddForeignKey
+ALTER TABLE "ccass_changes" ADD CONSTRAINT "ccass_changes_exchange_stock_code_holding_date_fkey" FOREIGN KEY ("exchange", "stock_code", "holding_date") REFERENCES "ccass_snapshots"("exchange", "stock_code", "holding_date") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -0,0 +1,25 @@
+-- CreateTable
+CREATE TABLE "filing_documents" (
+    "exchange" TEXT NOT NULL,
+    "document_id" TEXT NOT NULL,
+    "filing_source_id" TEXT NOT NULL,
+    "seq" INTEGER NOT NULL,
+    "title" TEXT,
+    "source_url" TEXT NOT NULL,
+    "file_extension" TEXT,
+    "pdf_s3_key" TEXT,
+    "local_path" TEXT,
+    "file_size" BIGINT,
+    "processing_status" TEXT NOT NULL DEFAULT 'PENDING',
+    "processing_error" TEXT,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "filing_documents_pkey" PRIMARY KEY ("exchange","document_id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_filing_documents_filing" ON "filing_documents"("exchange", "filing_source_id");
+
+-- AddForeignKey
+ALTER TABLE "filing_documents" ADD CONSTRAINT "filing_documents_exchange_filing_source_id_fkey" FOREIGN KEY ("exchange", "filing_source_id") REFERENCES "filings"("exchange", "source_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
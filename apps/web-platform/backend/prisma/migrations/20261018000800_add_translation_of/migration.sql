@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "filings" ADD COLUMN "translation_of" TEXT;
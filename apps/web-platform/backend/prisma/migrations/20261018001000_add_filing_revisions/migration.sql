@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "filing_revisions" (
+    "id" BIGSERIAL NOT NULL,
+    "exchange" TEXT NOT NULL,
+    "source_id" TEXT NOT NULL,
+    "field" TEXT NOT NULL,
+    "old_value" TEXT,
+    "new_value" TEXT,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "filing_revisions_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_filing_revisions_filing" ON "filing_revisions"("exchange", "source_id");
+
+-- AddForeignKey
+ALTER TABLE "filing_revisions" ADD CONSTRAINT "filing_revisions_exchange_source_id_fkey" FOREIGN KEY ("exchange", "source_id") REFERENCES "filings"("exchange", "source_id") ON DELETE NO ACTION ON UPDATE NO ACTION;
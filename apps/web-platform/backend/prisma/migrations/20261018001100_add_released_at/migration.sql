@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "filings" ADD COLUMN "released_at" TIMESTAMPTZ(6);
+
+-- CreateIndex
+CREATE INDEX "idx_filings_released_at" ON "filings"("exchange", "released_at");